@@ -82,7 +82,11 @@ func runAgent(cmd *cobra.Command, args []string) {
 		host = &mcp.Host{Clients: make(map[string]*mcp.Client)}
 	}
 
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// 会话期间持续同步磁盘上的文件变化
+	watchProject(ctx, project)
+
 	// 创建基于 SchemeConfig 的工具
 	schemeTools, err := host.GetTools(ctx)
 	if err != nil {
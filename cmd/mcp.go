@@ -238,6 +238,16 @@ func runMCPServer() {
 		return
 	}
 
+	// 服务运行期间持续同步磁盘上的文件变化
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchProject(ctx, project)
+
+	// 记录 MCP 工具对文件的修改，便于撤销
+	if err := project.EnableJournal(); err != nil {
+		fmt.Printf("启用操作日志失败: %v\n", err)
+	}
+
 	// 创建 Tong MCP 服务器
 	mcpSrv, err := mcpserver.NewTongMCPServer(project)
 	if err != nil {
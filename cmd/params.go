@@ -10,10 +10,17 @@ import (
 
 var (
 	workDir         string
+	workDirs        []string
 	extensions      []string
 	excludePatterns []string
 	repoURL         string
 	skipGitIgnore   bool
+	noCache         bool
+	gitRev          string
+	contentBudget   string
+	kindLanguages   []string
+	skipGenerated   bool
+	skipVendored    bool
 	debugMode       bool
 
 	promptName  string
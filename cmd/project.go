@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	projectSubcommand "github.com/sjzsdu/tong/cmd/project"
+	"github.com/sjzsdu/tong/helper/filekind"
 	"github.com/sjzsdu/tong/lang"
+	"github.com/sjzsdu/tong/share"
 	"github.com/spf13/cobra"
 )
 
@@ -18,13 +20,20 @@ var projectCmd = &cobra.Command{
   pack       打包项目文件
   search     搜索项目节点
   blame      统计作者/时间粒度的提交变更
+  diff       比较两个目录的差异
   rag        基于项目节点索引并检索文档
   markdown   启动Markdown文档服务，优雅展示项目中的所有.md文件
   uml        智能生成 UML 类图文档（两阶段：大纲 + 并发生成）
+  undo       撤销最近的文件变更
+  redo       重做已撤销的文件变更
+  history    显示文件变更的操作日志
 
 示例：
   tong project tree                    # 显示当前目录的树状结构
   tong project tree --stats            # 显示树状结构和统计信息
+  tong project pack --rev v1.0.0       # 打包指定 git 版本的项目（不检出）
+  tong project tree -d ../api -d web=../frontend  # 把多个仓库作为一个工作区显示
+  tong project pack --lang go --skip-generated     # 只打包手写的 Go 代码
   tong project uml                     # 智能生成 UML 架构文档`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// 在执行任何子命令之前，先创建项目实例
@@ -35,6 +44,20 @@ var projectCmd = &cobra.Command{
 		}
 		// 将项目实例设置到子命令的 project 包中
 		projectSubcommand.SetSharedProject(proj)
+		projectSubcommand.SetKindFilter(kindFilter())
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		// 保存本次运行中计算出的文件哈希，供下次使用
+		if sharedProject != nil {
+			if err := sharedProject.SaveSnapshot(); err != nil && share.GetDebug() {
+				fmt.Printf("保存项目快照失败: %v\n", err)
+			}
+			if cache := sharedProject.ContentCache(); cache != nil && share.GetDebug() {
+				stats := cache.Stats()
+				fmt.Printf("内容缓存: 命中 %d, 未命中 %d, 卸载 %d, 占用 %d/%d 字节\n",
+					stats.Hits, stats.Misses, stats.Evictions, stats.Used, stats.Budget)
+			}
+		}
 	},
 	Run: runproject,
 }
@@ -46,20 +69,43 @@ func init() {
 	projectCmd.AddCommand(projectSubcommand.RagCmd)
 	projectCmd.AddCommand(projectSubcommand.TreeCmd)
 	projectCmd.AddCommand(projectSubcommand.PackCmd)
+	projectCmd.AddCommand(projectSubcommand.UnpackCmd)
 	projectCmd.AddCommand(projectSubcommand.SearchCmd)
+	projectCmd.AddCommand(projectSubcommand.ReplaceCmd)
+	projectCmd.AddCommand(projectSubcommand.IndexCmd)
+	projectCmd.AddCommand(projectSubcommand.SymbolsCmd)
 	projectCmd.AddCommand(projectSubcommand.BlameCmd)
+	projectCmd.AddCommand(projectSubcommand.DiffCmd)
 	projectCmd.AddCommand(projectSubcommand.MarkdownCommand)
 	projectCmd.AddCommand(projectSubcommand.UmlCommand)
+	projectCmd.AddCommand(projectSubcommand.UndoCmd)
+	projectCmd.AddCommand(projectSubcommand.RedoCmd)
+	projectCmd.AddCommand(projectSubcommand.HistoryCmd)
 
 	initProjectArgs(projectCmd)
+	projectCmd.PersistentFlags().StringVar(&gitRev, "rev", "", lang.T("Git revision to build the project from (branch, tag, commit or HEAD~N)"))
+	projectCmd.PersistentFlags().StringSliceVar(&kindLanguages, "lang", []string{}, lang.T("Only include files of these languages, e.g. go,python"))
+	projectCmd.PersistentFlags().BoolVar(&skipGenerated, "skip-generated", false, lang.T("Skip generated code and lock files"))
+	projectCmd.PersistentFlags().BoolVar(&skipVendored, "skip-vendored", false, lang.T("Skip files in vendor, node_modules and other third-party directories"))
+}
+
+// kindFilter 根据 --lang、--skip-generated 与 --skip-vendored 生成文件分类过滤器
+func kindFilter() filekind.Filter {
+	return filekind.Filter{
+		Languages:     kindLanguages,
+		SkipGenerated: skipGenerated,
+		SkipVendored:  skipVendored,
+	}
 }
 
 func initProjectArgs(cmd *cobra.Command) {
-	cmd.PersistentFlags().StringVarP(&workDir, "directory", "d", ".", lang.T("Work directory path"))
+	cmd.PersistentFlags().StringArrayVarP(&workDirs, "directory", "d", []string{"."}, lang.T("Work directory path, repeat (optionally as name=path) to open several roots as one workspace"))
 	cmd.PersistentFlags().StringSliceVarP(&extensions, "extensions", "e", []string{"*"}, lang.T("File extensions to include"))
 	cmd.PersistentFlags().StringSliceVarP(&excludePatterns, "exclude", "x", []string{}, lang.T("Glob patterns to exclude"))
 	cmd.PersistentFlags().StringVarP(&repoURL, "repository", "r", "", lang.T("Git repository URL to clone and pack"))
 	cmd.PersistentFlags().BoolVarP(&skipGitIgnore, "no-gitignore", "n", false, lang.T("Disable .gitignore rules"))
+	cmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, lang.T("Disable project snapshot cache"))
+	cmd.PersistentFlags().StringVar(&contentBudget, "content-budget", "", lang.T("Memory budget for loaded file contents, e.g. 256MB (unlimited by default)"))
 }
 
 func runproject(cmd *cobra.Command, args []string) {
@@ -31,7 +31,8 @@ var BlameCmd = &cobra.Command{
 按时间粒度与作者聚合，输出各周期各作者的行数占比（计数）。
 
 默认使用作者邮箱聚合（--use-email=true），时间粒度为周（--granularity=week）。
-可通过 --since/--until 指定时间范围（格式：YYYY-MM-DD）。`,
+可通过 --since/--until 指定时间范围（格式：YYYY-MM-DD），
+通过 --where 按查询条件筛选文件，例如 --where 'lang:go !generated'。`,
 	Args: cobra.NoArgs,
 	Run:  runBlame,
 }
@@ -46,6 +47,7 @@ func init() {
 	BlameCmd.Flags().BoolVar(&blameIncludeHidden, "hidden", false, "包含隐藏文件/目录")
 	BlameCmd.Flags().BoolVar(&blameUseEmail, "use-email", true, "按作者邮箱聚合（否则按作者名聚合）")
 	BlameCmd.Flags().StringVar(&blameSubdir, "subdir", ".", "限定统计的子目录（相对项目根）")
+	BlameCmd.Flags().StringVar(&whereExpr, "where", "", whereUsage)
 }
 
 func runBlame(cmd *cobra.Command, args []string) {
@@ -95,6 +97,11 @@ func runBlame(cmd *cobra.Command, args []string) {
 	opts.Extensions = normalizeExts(blameExtensions)
 	opts.IncludeHidden = blameIncludeHidden
 	opts.UseEmail = blameUseEmail
+	opts.Where, err = parseWhere()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
 
 	// 执行分析
 	ctx := context.Background()
@@ -0,0 +1,107 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sjzsdu/tong/helper"
+	projindex "github.com/sjzsdu/tong/project/index"
+	"github.com/spf13/cobra"
+)
+
+var indexRebuild bool
+
+var IndexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "管理内容搜索索引",
+	Long: `index 子命令管理项目的三元组内容索引，索引保存在 ~/.tong/index 下。
+
+建立索引后，search、replace 与 MCP 的 fs_search 会自动使用索引，只读取可能匹配的文件；
+建立索引之后变化的文件会被直接读取，因此结果始终与不使用索引时一致，重新执行 build 即可增量更新。
+
+示例：
+  tong project index build
+  tong project index status
+  tong project index drop`,
+}
+
+var indexBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "建立或增量更新索引",
+	Args:  cobra.NoArgs,
+	Run:   runIndexBuild,
+}
+
+var indexStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "显示索引状态",
+	Args:  cobra.NoArgs,
+	Run:   runIndexStatus,
+}
+
+var indexDropCmd = &cobra.Command{
+	Use:   "drop",
+	Short: "删除索引",
+	Args:  cobra.NoArgs,
+	Run:   runIndexDrop,
+}
+
+func init() {
+	indexBuildCmd.Flags().BoolVar(&indexRebuild, "rebuild", false, "忽略已有索引，完整重建")
+	IndexCmd.AddCommand(indexBuildCmd, indexStatusCmd, indexDropCmd)
+}
+
+func runIndexBuild(cmd *cobra.Command, args []string) {
+	if sharedProject == nil {
+		fmt.Printf("错误: 未找到共享的项目实例\n")
+		os.Exit(1)
+	}
+	_, stats, err := projindex.Build(context.Background(), sharedProject, indexRebuild)
+	if err != nil {
+		fmt.Printf("建立索引失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已索引 %d 个文件（重新读取 %d，沿用 %d，跳过 %d）\n", stats.Files, stats.Indexed, stats.Reused, stats.Skipped)
+}
+
+func runIndexStatus(cmd *cobra.Command, args []string) {
+	if sharedProject == nil {
+		fmt.Printf("错误: 未找到共享的项目实例\n")
+		os.Exit(1)
+	}
+	ix, err := projindex.Load(sharedProject)
+	if errors.Is(err, projindex.ErrNoIndex) {
+		fmt.Println("尚未建立索引，使用 tong project index build 建立")
+		return
+	}
+	if err != nil {
+		fmt.Printf("读取索引失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	status := ix.Status(sharedProject)
+	state := "最新"
+	if !status.IsFresh() {
+		state = "已过期（重新执行 build 更新）"
+	}
+	fmt.Printf("状态:     %s\n", state)
+	fmt.Printf("建立时间: %s\n", status.Built.Format(helper.TimeLayout))
+	fmt.Printf("文件:     %d（未变化 %d，已变化 %d，新增 %d，已删除 %d）\n",
+		status.Files, status.Fresh, status.Changed, status.Added, status.Removed)
+	fmt.Printf("三元组:   %d\n", status.Trigrams)
+	fmt.Printf("索引大小: %.1f KB\n", float64(status.Size)/1024)
+}
+
+func runIndexDrop(cmd *cobra.Command, args []string) {
+	if sharedProject == nil {
+		fmt.Printf("错误: 未找到共享的项目实例\n")
+		os.Exit(1)
+	}
+	if err := projindex.Drop(sharedProject); err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("索引已删除")
+}
@@ -0,0 +1,129 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sjzsdu/tong/project"
+	"github.com/spf13/cobra"
+)
+
+var (
+	journalForce bool
+	journalSteps int
+	journalClear bool
+)
+
+var UndoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "撤销最近的文件变更",
+	Long: `按操作日志逐步撤销通过 tong（包括 MCP 工具）对项目文件所做的写入、创建、删除与事务提交。
+
+若文件在日志之外被修改，默认拒绝撤销，可使用 --force 强制覆盖。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runJournalStep(true)
+	},
+}
+
+var RedoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "重做已撤销的文件变更",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runJournalStep(false)
+	},
+}
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "显示文件变更的操作日志",
+	Args:  cobra.NoArgs,
+	Run:   runHistory,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{UndoCmd, RedoCmd} {
+		cmd.Flags().BoolVar(&journalForce, "force", false, "忽略日志之外的修改，强制执行")
+		cmd.Flags().IntVar(&journalSteps, "steps", 1, fmt.Sprintf("执行的步数（1 到 %d）", project.MaxJournalSteps))
+	}
+	HistoryCmd.Flags().BoolVar(&journalClear, "clear", false, "清空操作日志")
+}
+
+// openSharedJournal 为共享项目启用操作日志
+func openSharedJournal() {
+	if sharedProject == nil {
+		fmt.Printf("错误: 未找到共享的项目实例\n")
+		os.Exit(1)
+	}
+	if err := sharedProject.EnableJournal(); err != nil {
+		fmt.Printf("打开操作日志失败: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runJournalStep(undo bool) {
+	if journalSteps < 1 || journalSteps > project.MaxJournalSteps {
+		fmt.Printf("错误: steps 必须在 1 到 %d 之间\n", project.MaxJournalSteps)
+		os.Exit(1)
+	}
+	openSharedJournal()
+
+	for i := 0; i < journalSteps; i++ {
+		var record *project.JournalRecord
+		var err error
+		if undo {
+			record, err = sharedProject.Undo(journalForce)
+		} else {
+			record, err = sharedProject.Redo(journalForce)
+		}
+		if errors.Is(err, project.ErrNothingToUndo) || errors.Is(err, project.ErrNothingToRedo) {
+			if i == 0 {
+				fmt.Println(err)
+			}
+			return
+		}
+		if err != nil {
+			fmt.Printf("执行失败: %v\n", err)
+			os.Exit(1)
+		}
+
+		action := "已撤销"
+		if !undo {
+			action = "已重做"
+		}
+		fmt.Printf("%s #%d %s %s\n", action, record.ID, record.Op, strings.Join(record.Paths, ", "))
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) {
+	openSharedJournal()
+	journal := sharedProject.Journal()
+
+	if journalClear {
+		if err := journal.Clear(); err != nil {
+			fmt.Printf("清空操作日志失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("操作日志已清空")
+		return
+	}
+
+	records, cursor := journal.History()
+	if len(records) == 0 {
+		fmt.Println("操作日志为空")
+		return
+	}
+
+	// "*" 表示已应用，" " 表示已撤销（可重做）
+	for i, record := range records {
+		mark := " "
+		if i < cursor {
+			mark = "*"
+		}
+		fmt.Printf("%s #%-4d %s  %-6s %s\n", mark, record.ID, record.Time.Format("2006-01-02 15:04:05"),
+			record.Op, strings.Join(record.Paths, ", "))
+	}
+}
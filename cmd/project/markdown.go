@@ -3,8 +3,10 @@ package project
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
@@ -489,15 +491,12 @@ func handleMarkdownRaw(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 查找文件节点
-	node, err := proj.FindNode(filePath)
-	if err != nil {
+	// 读取文件内容（确保获取最新内容）
+	content, err := fs.ReadFile(proj, strings.TrimPrefix(filePath, "/"))
+	if errors.Is(err, fs.ErrNotExist) {
 		http.Error(w, fmt.Sprintf("文件不存在: %v", err), http.StatusNotFound)
 		return
 	}
-
-	// 读取文件内容（确保获取最新内容）
-	content, err := node.ReadContent()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("读取文件失败: %v", err), http.StatusInternalServerError)
 		return
@@ -905,38 +904,31 @@ func handleMarkdownImages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 查找图片文件节点
-	node, err := proj.FindNode(imagePath)
+	// 项目实现了 fs.FS，使用不以 / 开头的路径访问
+	name := strings.TrimPrefix(imagePath, "/")
+	info, err := fs.Stat(proj, name)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("图片不存在: %v", err), http.StatusNotFound)
 		return
 	}
 
 	// 检查是否为文件
-	if node.IsDir {
+	if info.IsDir() {
 		http.Error(w, "路径不是图片文件", http.StatusBadRequest)
 		return
 	}
 
-	// 读取图片内容
-	content, err := node.ReadContent()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("读取图片失败: %v", err), http.StatusInternalServerError)
-		return
-	}
-
 	// 设置正确的Content-Type
 	contentType := "application/octet-stream"
-	if ext := strings.ToLower(filepath.Ext(node.Name)); ext != "" {
+	if ext := strings.ToLower(filepath.Ext(name)); ext != "" {
 		if mime, ok := mimeTypes[ext[1:]]; ok {
 			contentType = mime
 		}
 	}
 	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
 
-	// 返回图片内容
-	w.Write(content)
+	// 由标准库处理 Content-Length、Range 与缓存头
+	http.ServeFileFS(w, r, proj, name)
 }
 
 // handleMarkdownContent 处理直接提供的markdown内容
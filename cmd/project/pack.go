@@ -13,11 +13,20 @@ import (
 )
 
 var (
-	outputFile    string
-	includeHidden bool
-	excludeExts   []string
-	showProgress  bool
-	useStdio      bool
+	outputFile     string
+	includeHidden  bool
+	excludeExts    []string
+	showProgress   bool
+	useStdio       bool
+	packFormat     string
+	packMaxTokens  int
+	packRank       string
+	packQuery      string
+	packSinceRef   string
+	packStaged     bool
+	packNeighbors  int
+	packSignatures bool
+	packSplitSize  string
 )
 
 var PackCmd = &cobra.Command{
@@ -27,30 +36,58 @@ var PackCmd = &cobra.Command{
 
 支持的功能：
 - 打包指定目录或文件
-- 从输出文件扩展名自动推断格式
+- 输出格式：markdown、xml（<documents><document path=...>，适合作为大模型提示词）、json、jsonl 与 text，
+  由 --format 指定，未指定时从输出文件扩展名（.md .xml .json .jsonl .txt）推断，默认 markdown
 - 包含或排除隐藏文件
 - 排除指定扩展名的文件
 - 显示打包进度
+- --max-tokens 限制打包结果的 token 数：按 --rank 策略（path 路径顺序、recent 最近修改、smallest 小文件、
+  churn git 修改次数、query 与 --query 关键词的相关度）依次放入完整文件，放不下的文件在结尾列出
+- --signatures 只保留声明与文档注释、丢弃函数体，生成整个仓库的 API 概要：Go 使用 go/ast，
+  Python、TypeScript/JavaScript 与 Java 使用轻量解析器，其他语言保留完整内容；文档带有标记，不能用 unpack 写回
+- --split-size 把输出拆成多个分卷（name.part001.md、name.part002.md …）并生成索引文件 name.index.json，
+  大小可写作字节数（8MB、500k）或 token 数（100kt、50000tokens）；单个文件只有自身超出大小时才会被拆开
+- --since-ref 与 --staged 只打包 git 变更的文件，生成评审材料：每个文件包含变更后的完整内容与差异块，
+  --neighbors N 为每个包含变更的目录（同一个包）附带 N 个未变更的文件作为上下文
 
 示例：
   tong project pack                    # 打包当前目录到./packed.md
   tong project pack /path/to/dir       # 打包指定目录到./packed.md
   tong project pack --file ./output.md # 指定输出文件路径(Markdown格式)
   tong project pack --file ./output.txt # 指定输出文件路径(文本格式)
+  tong project pack --file ./output.xml # XML 格式
+  tong project pack --stdio --format jsonl  # 每行一个文件的 JSON 记录
 	tong project pack --stdio            # 直接将内容输出到终端
   tong project pack --hidden           # 包含隐藏文件
   tong project pack --exclude-exts .js,.css  # 排除指定扩展名的文件
-  tong project pack --progress         # 显示打包进度`,
+  tong project pack --progress         # 显示打包进度
+  tong project pack --where 'lang:go !generated'  # 只打包满足查询条件的文件
+  tong project pack --max-tokens 100000 --rank recent  # 限制 token 数，最近修改的文件优先
+  tong project pack --max-tokens 50000 --query "auth token"  # 与查询相关的文件优先
+  tong project pack --signatures --stdio  # 只输出声明与文档注释
+  tong project pack -f out/context.md --split-size 2MB  # 每卷不超过 2MB
+  tong project pack --since-ref main --stdio --format xml  # 当前分支相对 main 的改动
+  tong project pack --staged --neighbors 2 --stdio      # 暂存的改动，附带同目录的 2 个文件`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  runPack,
 }
 
 func init() {
-	PackCmd.Flags().StringVarP(&outputFile, "file", "f", "", "指定输出文件路径 (从扩展名推断格式: .md 为markdown, .txt 为text)，为空则复制到剪贴板")
+	PackCmd.Flags().StringVarP(&outputFile, "file", "f", "", "指定输出文件路径 (从扩展名推断格式: .md .xml .json .jsonl .txt)，为空则复制到剪贴板")
 	PackCmd.Flags().BoolVarP(&includeHidden, "hidden", "a", false, "包含隐藏文件")
 	PackCmd.Flags().StringSliceVarP(&excludeExts, "exclude-exts", "m", []string{}, "排除的文件扩展名，用逗号分隔")
 	PackCmd.Flags().BoolVarP(&showProgress, "progress", "p", false, "显示打包进度")
 	PackCmd.Flags().BoolVar(&useStdio, "stdio", false, "将打包内容输出到终端 (stdout)")
+	PackCmd.Flags().StringVar(&packFormat, "format", "", "输出格式：markdown|xml|json|jsonl|text，默认从输出文件扩展名推断")
+	PackCmd.Flags().IntVar(&packMaxTokens, "max-tokens", 0, "打包结果的 token 预算，0 表示不限制")
+	PackCmd.Flags().StringVar(&packRank, "rank", "", "有预算时的文件优先级：path|recent|smallest|churn|query，指定 --query 时默认为 query")
+	PackCmd.Flags().StringVar(&packQuery, "query", "", "按相关度排序时使用的关键词（空格分隔）")
+	PackCmd.Flags().BoolVar(&packSignatures, "signatures", false, "只保留声明与文档注释（支持 Go、Python、TypeScript/JavaScript、Java）")
+	PackCmd.Flags().StringVar(&packSplitSize, "split-size", "", "分卷大小，如 8MB、500k（字节）或 100kt（token），需要与 --file 一起使用")
+	PackCmd.Flags().StringVar(&packSinceRef, "since-ref", "", "只打包相对该修订版本（与 HEAD 的合并基础）变更的文件，附带差异块")
+	PackCmd.Flags().BoolVar(&packStaged, "staged", false, "只打包暂存区相对 HEAD 变更的文件，附带差异块")
+	PackCmd.Flags().IntVar(&packNeighbors, "neighbors", 0, "与 --since-ref/--staged 一起使用，每个包含变更的目录附带的未变更文件数")
+	PackCmd.Flags().StringVar(&whereExpr, "where", "", whereUsage)
 }
 
 func runPack(cmd *cobra.Command, args []string) {
@@ -81,21 +118,61 @@ func runPack(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	// 从输出文件路径提取格式
-	format := "markdown" // 默认格式
-	if outputFile != "" {
-		ext := strings.ToLower(filepath.Ext(outputFile))
-		if ext == ".txt" {
-			format = "text"
-		} else if ext == ".md" {
-			format = "markdown"
-		}
+	// 确定格式：--format 优先，否则从输出文件路径提取
+	format := packFormat
+	if format == "" {
+		format = pack.FormatFromPath(outputFile)
 	}
 
 	// 准备打包选项
 	options := pack.DefaultOptions()
 	options.ExcludeExts = excludeExts
 	options.IncludeHidden = includeHidden
+	options.Kinds = kindFilter
+	options.Where, err = parseWhere()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	if packMaxTokens < 0 {
+		fmt.Println("错误: max-tokens 不能为负数")
+		os.Exit(1)
+	}
+	options.MaxTokens = packMaxTokens
+	rank := packRank
+	if rank == "" && packQuery != "" {
+		rank = string(pack.RankQuery)
+	}
+	options.Rank, err = pack.ParseRankStrategy(rank)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	options.RankQuery = packQuery
+	options.Signatures = packSignatures
+	options.Split, err = pack.ParseSplitSize(packSplitSize)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	if options.Split.Size > 0 && (useStdio || outputFile == "") {
+		fmt.Println("错误: 分卷打包需要通过 --file 指定输出文件")
+		os.Exit(1)
+	}
+	if packSinceRef != "" || packStaged {
+		if packSinceRef != "" && packStaged {
+			fmt.Println("错误: --since-ref 与 --staged 不能同时使用")
+			os.Exit(1)
+		}
+		options.Changes = &pack.ChangeOptions{
+			SinceRef:  packSinceRef,
+			Staged:    packStaged,
+			Neighbors: packNeighbors,
+		}
+	} else if packNeighbors != 0 {
+		fmt.Println("错误: --neighbors 需要与 --since-ref 或 --staged 一起使用")
+		os.Exit(1)
+	}
 
 	// 获取格式化器
 	formatter := pack.GetFormatter(format)
@@ -113,7 +190,10 @@ func runPack(cmd *cobra.Command, args []string) {
 			fmt.Printf("打包失败: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println(content)
+		fmt.Print(content)
+		if !strings.HasSuffix(content, "\n") {
+			fmt.Println()
+		}
 	} else if outputFile == "" {
 		// 输出到剪贴板
 		content, err := pack.PackToString(targetNode, options)
@@ -146,7 +226,19 @@ func runPack(cmd *cobra.Command, args []string) {
 			os.Exit(1)
 		}
 
-		fmt.Printf("打包成功! 文件已保存到: %s\n", outputFile)
+		if options.Split.Size > 0 {
+			output := outputFile
+			if filepath.Ext(output) == "" {
+				output += formatter.FileExtension()
+			}
+			fmt.Printf("打包成功! 共 %d 个分卷（每卷不超过 %s）:\n", len(options.Volumes), options.Split)
+			for _, v := range options.Volumes {
+				fmt.Printf("  %s  %d bytes, ~%d tokens, %d 个文件\n", v.Path, v.Size, v.Tokens, len(v.Files))
+			}
+			fmt.Printf("索引文件: %s\n", pack.IndexPath(output))
+		} else {
+			fmt.Printf("打包成功! 文件已保存到: %s\n", outputFile)
+		}
 
 		// 只在输出到文件时打印文件列表
 		if len(options.IncludedFiles) > 0 {
@@ -157,6 +249,10 @@ func runPack(cmd *cobra.Command, args []string) {
 		} else {
 			fmt.Println("\n没有可打包的文本文件。")
 		}
+		if len(options.OmittedFiles) > 0 {
+			fmt.Printf("\n因超出 %d tokens 预算未打包 %d 个文件:\n", options.MaxTokens, len(options.OmittedFiles))
+			fmt.Println(buildTreeFromPaths(options.OmittedFiles))
+		}
 	}
 }
 
@@ -48,6 +48,7 @@ func init() {
 	RagCmd.Flags().IntVarP(&chunkOverlap, "chunk-overlap", "", 0, lang.T("文本分块重叠大小"))
 	RagCmd.Flags().StringVar(&docsDir, "docs-dir", "", lang.T("文档目录路径（相对项目根或绝对路径）"))
 	RagCmd.Flags().StringVar(&ragSubdir, "subdir", "", lang.T("限定索引的子目录（相对项目根）"))
+	RagCmd.Flags().StringVar(&whereExpr, "where", "", lang.T(whereUsage))
 
 	RagCmd.Flags().BoolVarP(&syncOnly, "sync", "", false, lang.T("仅同步文档，不启动交互会话"))
 	RagCmd.Flags().BoolVarP(&autoSync, "auto-sync", "", false, lang.T("启用自动同步"))
@@ -94,8 +95,10 @@ func runRag(cmd *cobra.Command, args []string) {
 
 	if !indexed || forceReindex {
 		log.Println(lang.T("开始执行文档索引..."))
-		if err := ragSystem.IndexDocuments(ctx, options.DocsDir); err != nil {
-			log.Fatalf("文档索引失败: %v", err)
+		for _, dir := range docsDirsOf(options.DocsDir) {
+			if err := ragSystem.IndexDocuments(ctx, dir); err != nil {
+				log.Fatalf("文档索引失败: %v", err)
+			}
 		}
 	}
 	if autoSync {
@@ -189,9 +192,14 @@ func resolveOptions(cmd *cobra.Command, cfg *schema.SchemaConfig, projectRoot st
 		finalTargetPath = projectRoot
 	}
 
-	if _, err := GetTargetNode(finalTargetPath); err != nil {
+	targetNode, err := GetTargetNode(finalTargetPath)
+	if err != nil {
 		log.Fatalf("目标路径无效: %v", err)
 	}
+	// 工作区中的目录换算为所属根目录下的磁盘路径；工作区根节点在索引时展开为各个根目录
+	if sharedProject.IsWorkspace() && targetNode.Path != "/" {
+		finalTargetPath = sharedProject.GetAbsolutePath(targetNode.Path)
+	}
 
 	if collectionName == "" {
 		collectionName = filepath.Base(projectRoot)
@@ -213,6 +221,7 @@ func resolveOptions(cmd *cobra.Command, cfg *schema.SchemaConfig, projectRoot st
 			Stream: ragStreamMode,
 		},
 		DocsDir: finalTargetPath,
+		Filter:  documentFilter(),
 		Sync: rag.SyncOptions{
 			ForceReindex: forceReindex,
 			SyncInterval: time.Duration(syncInterval) * time.Second,
@@ -221,6 +230,43 @@ func resolveOptions(cmd *cobra.Command, cfg *schema.SchemaConfig, projectRoot st
 	return options
 }
 
+// documentFilter 根据分类过滤与 --where 查询生成文档过滤器，查询按文件在项目中的节点求值
+func documentFilter() rag.DocumentFilter {
+	where, err := parseWhere()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	filter := rag.DocumentFilter{Kinds: kindFilter}
+	if where.IsEmpty() {
+		return filter
+	}
+	filter.Include = func(path string) bool {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return false
+		}
+		projPath, ok := sharedProject.ProjectPathOf(abs)
+		if !ok {
+			return false
+		}
+		node, err := sharedProject.FindNode(projPath)
+		return err == nil && where.Match(node)
+	}
+	return filter
+}
+
+// docsDirsOf 返回需要索引的目录，工作区目录展开为各个根目录
+func docsDirsOf(docsDir string) []string {
+	if !sharedProject.IsWorkspace() || docsDir != sharedProject.GetRootPath() {
+		return []string{docsDir}
+	}
+	var dirs []string
+	for _, root := range sharedProject.WorkspaceRoots() {
+		dirs = append(dirs, root.Path)
+	}
+	return dirs
+}
+
 func orDefault(v, d int) int {
 	if v > 0 {
 		return v
@@ -0,0 +1,184 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sjzsdu/tong/helper"
+	projsearch "github.com/sjzsdu/tong/project/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replaceRegex         bool
+	replaceIgnoreCase    bool
+	replaceNameContains  string
+	replaceNameRegex     string
+	replaceExtensions    []string
+	replaceIncludeHidden bool
+	replaceDepth         int
+	replaceSubdir        string
+	replaceContext       int
+	replaceYes           bool
+	replaceDryRun        bool
+	replaceNoColor       bool
+)
+
+var ReplaceCmd = &cobra.Command{
+	Use:   "replace <pattern> <replacement>",
+	Short: "在项目中批量查找并替换文本",
+	Long: `replace 子命令在项目（或 --subdir 指定的子目录）中查找 pattern 并替换为 replacement。
+
+• 默认按字面量匹配；--regex 时 pattern 为正则，replacement 可用 $1、${name} 引用捕获组。
+• 文件过滤条件与 search 相同：--name / --name-regex / --ext / --hidden / --depth / --where。
+• 替换前逐个文件输出差异预览并请求确认；--yes 跳过确认，--dry-run 只预览不写入。
+• 所有文件在一次操作中写入：预览之后有文件被修改时整体放弃，写入后可用 tong project undo 整体撤销。
+
+示例：
+  tong project replace OldName NewName --ext go
+  tong project replace 'fmt\.Println\((.*)\)' 'log.Println($1)' --regex --yes
+  tong project replace v1.2.0 v1.3.0 --where 'lang:markdown' --dry-run`,
+	Args: cobra.ExactArgs(2),
+	Run:  runReplace,
+}
+
+func init() {
+	ReplaceCmd.Flags().BoolVar(&replaceRegex, "regex", false, "pattern 为正则表达式，replacement 支持 $1 等捕获组引用")
+	ReplaceCmd.Flags().BoolVar(&replaceIgnoreCase, "ignore-case", false, "大小写不敏感匹配")
+	ReplaceCmd.Flags().StringVar(&replaceNameContains, "name", "", "只替换名称包含该子串的文件")
+	ReplaceCmd.Flags().StringVar(&replaceNameRegex, "name-regex", "", "只替换名称匹配该正则的文件（优先于 name）")
+	ReplaceCmd.Flags().StringSliceVar(&replaceExtensions, "ext", []string{}, "文件扩展名过滤，例如: go,md；为空表示不过滤")
+	ReplaceCmd.Flags().BoolVar(&replaceIncludeHidden, "hidden", false, "包含隐藏文件/目录")
+	ReplaceCmd.Flags().IntVar(&replaceDepth, "depth", 0, "限制搜索深度（根为0，0表示不限制）")
+	ReplaceCmd.Flags().StringVar(&replaceSubdir, "subdir", ".", "限定替换的子目录（相对项目根）")
+	ReplaceCmd.Flags().StringVar(&whereExpr, "where", "", whereUsage)
+	ReplaceCmd.Flags().IntVarP(&replaceContext, "unified", "U", 3, "差异预览中的上下文行数")
+	ReplaceCmd.Flags().BoolVarP(&replaceYes, "yes", "y", false, "不询问，直接写入")
+	ReplaceCmd.Flags().BoolVar(&replaceDryRun, "dry-run", false, "只预览差异，不写入文件")
+	ReplaceCmd.Flags().BoolVar(&replaceNoColor, "no-color", false, "不使用颜色（非终端输出时自动关闭）")
+}
+
+func runReplace(cmd *cobra.Command, args []string) {
+	if sharedProject == nil {
+		fmt.Printf("错误: 未找到共享的项目实例\n")
+		os.Exit(1)
+	}
+	if args[0] == "" {
+		fmt.Println("错误: pattern 不能为空")
+		os.Exit(1)
+	}
+	if replaceDepth < 0 || replaceContext < 0 {
+		fmt.Println("错误: depth 与 unified 不能为负数")
+		os.Exit(1)
+	}
+
+	targetPath := replaceSubdir
+	if !filepath.IsAbs(targetPath) {
+		targetPath = filepath.Join(sharedProject.GetRootPath(), targetPath)
+	}
+	targetNode, err := GetTargetNode(targetPath)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	opts := projsearch.DefaultSearchOptions()
+	opts.NameContains = replaceNameContains
+	opts.NameRegex = replaceNameRegex
+	opts.Extensions = normalizeExts(replaceExtensions)
+	opts.Kinds = kindFilter
+	opts.IncludeHidden = replaceIncludeHidden
+	opts.MaxDepth = replaceDepth
+	opts.CaseInsensitive = replaceIgnoreCase
+	where, err := parseWhere()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	opts.Where = where
+
+	plan, err := projsearch.PlanReplace(context.Background(), targetNode, opts, &projsearch.ReplaceOptions{
+		Pattern:     args[0],
+		Replacement: args[1],
+		Regex:       replaceRegex,
+		Context:     replaceContext,
+	})
+	if err != nil {
+		fmt.Printf("替换出错: %v\n", err)
+		os.Exit(1)
+	}
+	if len(plan) == 0 {
+		fmt.Println("未找到匹配项")
+		return
+	}
+
+	printReplacePlan(os.Stdout, plan, !replaceNoColor && isTerminal(os.Stdout))
+	if replaceDryRun {
+		return
+	}
+	if !replaceYes {
+		ok, err := helper.PromptYesNo(fmt.Sprintf("确认写入以上 %d 个文件? (y/n): ", len(plan)), false)
+		if err != nil || !ok {
+			fmt.Println("已取消")
+			return
+		}
+	}
+
+	// 在事务中写入，所有文件一起提交，任一文件失败时不修改磁盘；记录到操作日志以便撤销
+	openSharedJournal()
+	tx := sharedProject.Begin()
+	written, err := projsearch.ApplyReplace(tx, plan)
+	if err != nil {
+		tx.Rollback()
+		fmt.Printf("替换出错: %v\n", err)
+		os.Exit(1)
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("写入出错: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已替换 %d 个文件\n", written)
+}
+
+// printReplacePlan 逐个文件输出替换预览：文件头、替换处数与着色的差异块，末尾输出汇总
+func printReplacePlan(w io.Writer, plan []projsearch.FileReplacement, color bool) {
+	paint := func(text, c string) string {
+		if !color {
+			return text
+		}
+		return helper.ColorText(text, c)
+	}
+
+	total, ins, del := 0, 0, 0
+	for _, r := range plan {
+		name := strings.TrimPrefix(r.Path, "/")
+		fmt.Fprintf(w, "%s (%d 处替换)\n", paint(name, helper.ColorPurple), r.Count)
+		printHunks(w, r.Diff, paint)
+		fmt.Fprintln(w)
+		total += r.Count
+		ins += r.Insertions
+		del += r.Deletions
+	}
+	fmt.Fprintf(w, "共 %d 个文件，%d 处替换，+%d -%d\n", len(plan), total, ins, del)
+}
+
+// printHunks 输出统一格式的差异块，按行首的 @@、+、- 着色
+func printHunks(w io.Writer, hunks string, paint func(text, c string) string) {
+	for _, line := range strings.SplitAfter(hunks, "\n") {
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "@@"):
+			line = paint(strings.TrimSuffix(line, "\n"), helper.ColorCyan) + "\n"
+		case strings.HasPrefix(line, "+"):
+			line = paint(strings.TrimSuffix(line, "\n"), helper.ColorGreen) + "\n"
+		case strings.HasPrefix(line, "-"):
+			line = paint(strings.TrimSuffix(line, "\n"), helper.ColorRed) + "\n"
+		}
+		fmt.Fprint(w, line)
+	}
+}
@@ -3,9 +3,15 @@ package project
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/sjzsdu/tong/helper"
 	projsearch "github.com/sjzsdu/tong/project/search"
 	"github.com/spf13/cobra"
 )
@@ -23,6 +29,14 @@ var (
 	searchIgnoreCase      bool
 	searchAny             bool
 	searchSubdir          string
+	searchBefore          int
+	searchAfter           int
+	searchContext         int
+	searchFilesOnly       bool
+	searchNoColor         bool
+	searchFuzzy           bool
+	searchTop             int
+	searchFormat          string
 )
 
 var SearchCmd = &cobra.Command{
@@ -38,21 +52,34 @@ var SearchCmd = &cobra.Command{
 • 若提供显式条件（任意 name/content 相关 flag），位置参数仅作为补充忽略，不再自动注入。
 • 若未提供显式条件：
     - 有位置参数 => 作为名称子串条件 (NameContains)
-    - 无位置参数 => 报错（必须至少给一个条件）
+    - 无位置参数 => 报错（必须至少给一个条件，--where 也算作条件）
+• --where 使用查询语言（见 project/query），与其他条件同时生效。
 • 同时指定名称与内容条件默认 AND，可用 --any 改为 OR。
 • 默认区分大小写；使用 --ignore-case 开启不敏感匹配。
+• 有内容条件时按 grep 风格逐行输出 "路径:行:列:内容"，-C/-A/-B 附带上下文行，-l 只列出文件。
+• --fuzzy 按模糊匹配查找路径（类似 fzf）：查询字符按顺序出现即可，分隔符、驼峰边界、连续匹配与文件名中的匹配得分更高，
+  按得分输出前 --top 个结果；多个词之间为 AND，查询含大写字母时区分大小写，其他条件作为过滤条件。
+• --format 选择机器可读的输出：json（单个文档）、jsonl（每行一条记录）、vimgrep（路径:行:列:内容，可作为编辑器 quickfix 列表）、
+  sarif（SARIF 2.1.0，供 CI 标注）；有内容条件时每条记录为一处匹配，否则为文件或目录。
 
 示例：
   tong project search README                       # 名称包含 README（默认名称匹配）
   tong project search --name README                # 同上（无位置参数，用 flag）
-  tong project search --content TODO               # 内容包含 TODO
+  tong project search --content TODO               # 内容包含 TODO，逐行输出匹配位置
+  tong project search --content TODO -C 2          # 同时输出前后各 2 行上下文
+  tong project search --content TODO -l            # 只列出匹配的文件
   tong project search '.*_test\\.go$' --name-regex  # 名称正则
   tong project search --name util --content util   # 名称 AND 内容同时匹配
   tong project search --name util --content util --any # 名称 OR 内容
   tong project search README --ext go,md           # 扩展过滤（逗号或多次传参）
   tong project search --name README --ignore-case  # 名称忽略大小写
   tong project search --name README --depth 2      # 深度限制（根为 0）
-  tong project search --content license --any      # 与其他条件 OR`,
+  tong project search --content license --any      # 与其他条件 OR
+  tong project search --where 'lang:go content:/TODO/ !generated'  # 查询条件
+  tong project search --fuzzy prjsrch                               # 模糊查找，如 project/search/search.go
+  tong project search --fuzzy cmd srch --ext go --top 5            # 多个词，只看 go 文件
+  tong project search --content TODO --format vimgrep               # 供 vim :cexpr 或 quickfix 使用
+  tong project search --content-regex 'FIXME|XXX' --format sarif > search.sarif`,
 	Args: cobra.ArbitraryArgs,
 	RunE: runSearch,
 }
@@ -71,6 +98,15 @@ func init() {
 	SearchCmd.Flags().BoolVar(&searchIgnoreCase, "ignore-case", false, "大小写不敏感匹配（对子串与正则均生效）")
 	SearchCmd.Flags().BoolVar(&searchAny, "any", false, "名称与内容条件采用 OR 逻辑（默认 AND）")
 	SearchCmd.Flags().StringVar(&searchSubdir, "subdir", ".", "限定搜索的子目录（相对项目根）")
+	SearchCmd.Flags().StringVar(&whereExpr, "where", "", whereUsage)
+	SearchCmd.Flags().IntVarP(&searchContext, "context", "C", 0, "内容匹配前后各输出的上下文行数")
+	SearchCmd.Flags().IntVarP(&searchAfter, "after-context", "A", 0, "内容匹配之后输出的上下文行数")
+	SearchCmd.Flags().IntVarP(&searchBefore, "before-context", "B", 0, "内容匹配之前输出的上下文行数")
+	SearchCmd.Flags().BoolVarP(&searchFilesOnly, "files-with-matches", "l", false, "只列出匹配的文件，不输出匹配行")
+	SearchCmd.Flags().BoolVar(&searchNoColor, "no-color", false, "不高亮输出（非终端输出时自动关闭）")
+	SearchCmd.Flags().BoolVar(&searchFuzzy, "fuzzy", false, "按模糊匹配查找路径，按得分排序输出")
+	SearchCmd.Flags().IntVar(&searchTop, "top", 20, "--fuzzy 时输出的结果数")
+	SearchCmd.Flags().StringVar(&searchFormat, "format", "text", "输出格式：text|json|jsonl|vimgrep|sarif")
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
@@ -86,6 +122,12 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no shared project")
 	}
 
+	format, err := projsearch.ParseFormat(searchFormat)
+	if err != nil {
+		fmt.Printf("错误: 不支持的输出格式: %s\n", searchFormat)
+		return err
+	}
+
 	// 基于项目根路径来处理子目录
 	var finalTargetPath string
 	if filepath.IsAbs(searchSubdir) {
@@ -109,19 +151,32 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	opts.ContentRegex = searchContentRegex
 	// 判断用户是否提供显式条件
 	hasExplicit := opts.NameContains != "" || opts.NameRegex != "" || opts.ContentContains != "" || opts.ContentRegex != ""
-	if !hasExplicit {
-		if query == "" {
-			fmt.Println("错误: 缺少查询条件。请提供位置参数或使用 --name/--content 其中之一。")
+	if searchFuzzy {
+		// 模糊查找时所有位置参数组成查询，显式条件只作为过滤条件
+		query = strings.Join(args, " ")
+		if strings.TrimSpace(query) == "" {
+			fmt.Println("错误: --fuzzy 需要提供查询，例如: tong project search --fuzzy prjsrch")
 			return fmt.Errorf("missing query")
 		}
+	} else if !hasExplicit && query != "" {
 		// 只有在无显式条件时才注入位置参数
 		opts.NameContains = query
+	} else if !hasExplicit && whereExpr == "" {
+		fmt.Println("错误: 缺少查询条件。请提供位置参数或使用 --name/--content/--where 其中之一。")
+		return fmt.Errorf("missing query")
 	}
 	// 用户显式选择 OR
 	if searchAny {
 		opts.MatchAny = true
 	}
 	opts.Extensions = normalizeExts(searchExtensions)
+	opts.Kinds = kindFilter
+	where, err := parseWhere()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	opts.Where = where
 	opts.IncludeHidden = searchIncludeHidden
 	opts.IncludeDirs = searchIncludeDirs
 	opts.IncludeFiles = searchIncludeFiles
@@ -139,14 +194,61 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("empty result configuration")
 	}
 
-	// 执行搜索
+	if searchContext < 0 || searchAfter < 0 || searchBefore < 0 {
+		fmt.Println("错误: 上下文行数不能为负数")
+		return fmt.Errorf("invalid context")
+	}
+	opts.BeforeContext = max(searchBefore, searchContext)
+	opts.AfterContext = max(searchAfter, searchContext)
+
 	ctx := context.Background()
+	color := !searchNoColor && isTerminal(os.Stdout)
+	if searchFuzzy {
+		results, err := projsearch.FuzzyFind(ctx, targetNode, query, opts, searchTop)
+		if err != nil {
+			fmt.Printf("搜索出错: %v\n", err)
+			return err
+		}
+		if format != projsearch.FormatText {
+			return projsearch.WriteRecords(os.Stdout, format, projsearch.FuzzyRecords(results))
+		}
+		if len(results) == 0 {
+			fmt.Println("未找到匹配项")
+			return nil
+		}
+		printFuzzy(os.Stdout, results, color)
+		return nil
+	}
+
+	// 有内容条件时输出逐行匹配
+	if (opts.ContentContains != "" || opts.ContentRegex != "") && !searchFilesOnly {
+		matches, err := projsearch.SearchMatches(ctx, targetNode, opts)
+		if err != nil {
+			fmt.Printf("搜索出错: %v\n", err)
+			return err
+		}
+		if format != projsearch.FormatText {
+			return projsearch.WriteRecords(os.Stdout, format, projsearch.MatchRecords(matches))
+		}
+		if len(matches) == 0 {
+			fmt.Println("未找到匹配项")
+			return nil
+		}
+		printMatches(os.Stdout, matches, opts.BeforeContext > 0 || opts.AfterContext > 0, color)
+		return nil
+	}
+
+	// 执行搜索
 	matched, err := projsearch.Search(ctx, targetNode, opts)
 	if err != nil {
 		fmt.Printf("搜索出错: %v\n", err)
 		return err
 	}
 
+	if format != projsearch.FormatText {
+		return projsearch.WriteRecords(os.Stdout, format, projsearch.NodeRecords(matched))
+	}
+
 	// 输出结果（使用项目相对路径）
 	if len(matched) == 0 {
 		fmt.Println("未找到匹配项")
@@ -167,6 +269,117 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// printMatches 按 grep 风格输出逐行匹配：匹配行为 "路径:行:列:内容"，上下文行为 "路径-行-内容"
+// 同一行的多处匹配合并输出，withContext 为 true 时不相邻的片段之间用 "--" 分隔
+func printMatches(w io.Writer, matches []projsearch.Match, withContext, color bool) {
+	paint := func(text, c string) string {
+		if !color {
+			return text
+		}
+		return helper.ColorText(text, c)
+	}
+
+	type outLine struct {
+		text   string
+		column int     // 匹配行的第一处匹配列号，上下文行为 0
+		spans  [][]int // 匹配在行内的字节区间
+	}
+
+	printed := false
+	for start := 0; start < len(matches); {
+		path := matches[start].Path
+		end := start
+		lines := make(map[int]*outLine)
+		for ; end < len(matches) && matches[end].Path == path; end++ {
+			m := matches[end]
+			for i, text := range m.Before {
+				no := m.Line - len(m.Before) + i
+				if lines[no] == nil {
+					lines[no] = &outLine{text: text}
+				}
+			}
+			for i, text := range m.After {
+				no := m.Line + 1 + i
+				if lines[no] == nil {
+					lines[no] = &outLine{text: text}
+				}
+			}
+			line := lines[m.Line]
+			if line == nil || line.column == 0 {
+				line = &outLine{text: m.LineText, column: m.Column}
+				lines[m.Line] = line
+			}
+			line.spans = append(line.spans, []int{m.Column - 1, m.Column - 1 + len(m.Text)})
+		}
+
+		numbers := make([]int, 0, len(lines))
+		for no := range lines {
+			numbers = append(numbers, no)
+		}
+		sort.Ints(numbers)
+
+		name := strings.TrimPrefix(path, "/")
+		for i, no := range numbers {
+			if withContext && printed && (i == 0 || no != numbers[i-1]+1) {
+				fmt.Fprintln(w, paint("--", helper.ColorCyan))
+			}
+			printed = true
+			line := lines[no]
+			if line.column == 0 {
+				fmt.Fprintf(w, "%s-%s-%s\n", paint(name, helper.ColorPurple), paint(strconv.Itoa(no), helper.ColorGreen), line.text)
+				continue
+			}
+			text := line.text
+			if color {
+				text = highlightSpans(text, line.spans)
+			}
+			fmt.Fprintf(w, "%s:%s:%d:%s\n", paint(name, helper.ColorPurple), paint(strconv.Itoa(no), helper.ColorGreen), line.column, text)
+		}
+		start = end
+	}
+}
+
+// printFuzzy 输出模糊查找结果：得分与路径，color 为 true 时高亮匹配的字符
+func printFuzzy(w io.Writer, results []projsearch.FuzzyMatch, color bool) {
+	for _, r := range results {
+		path := r.Path
+		if color {
+			spans := make([][]int, 0, len(r.Positions))
+			for _, pos := range r.Positions {
+				_, size := utf8.DecodeRuneInString(path[pos:])
+				spans = append(spans, []int{pos, pos + size})
+			}
+			path = highlightSpans(path, spans)
+		}
+		if r.IsDir {
+			path += "/"
+		}
+		fmt.Fprintf(w, "%5d  %s\n", r.Score, path)
+	}
+}
+
+// highlightSpans 高亮行内的匹配区间（区间按起点递增且不重叠）
+func highlightSpans(text string, spans [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span[0] < last || span[1] > len(text) {
+			continue
+		}
+		b.WriteString(text[last:span[0]])
+		b.WriteString(helper.ColorText(text[span[0]:span[1]], helper.ColorRedBold))
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// isTerminal 判断文件是否为终端
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func normalizeExts(exts []string) []string {
 	if len(exts) == 0 {
 		return exts
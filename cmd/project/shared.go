@@ -5,17 +5,48 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/sjzsdu/tong/helper/filekind"
 	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/query"
 )
 
 // 共享的项目实例
 var sharedProject *project.Project
 
+// 共享的文件分类过滤器，pack、search、tree 与 rag 使用
+var kindFilter filekind.Filter
+
+// --where 查询条件，search、pack、blame、rag 与 tree 共用
+var whereExpr string
+
+// whereUsage --where 的帮助信息
+const whereUsage = "节点查询条件，例如: 'lang:go size>10k mtime<7d path:cmd/** !generated content:/TODO/'"
+
 // SetSharedProject 设置共享的项目实例
 func SetSharedProject(proj *project.Project) {
 	sharedProject = proj
 }
 
+// SetKindFilter 设置共享的文件分类过滤器
+func SetKindFilter(filter filekind.Filter) {
+	kindFilter = filter
+}
+
+// parseWhere 解析 --where 查询条件，未指定时返回 nil
+func parseWhere() (*query.Query, error) {
+	if whereExpr == "" {
+		return nil, nil
+	}
+	return query.Parse(whereExpr)
+}
+
+// matchNode 组合共享的文件分类过滤器与查询条件
+func matchNode(where *query.Query) func(*project.Node) bool {
+	return func(n *project.Node) bool {
+		return n.MatchKind(kindFilter) && where.Match(n)
+	}
+}
+
 // GetTargetNode 根据路径参数获取对应的项目节点
 // 这是一个通用函数，可以被多个子命令使用
 func GetTargetNode(targetPath string) (*project.Node, error) {
@@ -34,26 +65,25 @@ func GetTargetNode(targetPath string) (*project.Node, error) {
 	proj := sharedProject
 	projectRoot := proj.GetRootPath()
 
-	// 如果目标路径与项目根路径不同，需要找到对应的节点
+	// 目标路径就是项目根路径时使用根节点；否则先按磁盘路径换算（工作区按根目录换算），
+	// 不在任何根目录中时视为相对项目根路径的项目路径
+	projPath := "/"
 	if absPath != projectRoot {
-		// 计算相对路径
-		relPath, err := filepath.Rel(projectRoot, absPath)
-		if err != nil {
-			return nil, fmt.Errorf("无法计算相对路径: %v", err)
-		}
-		
-		// 查找目标节点
-		targetNode, err := proj.FindNode("/" + relPath)
-		if err != nil {
-			return nil, fmt.Errorf("找不到目标路径: %v", err)
+		var ok bool
+		projPath, ok = proj.ProjectPathOf(absPath)
+		if !ok {
+			relPath, err := filepath.Rel(projectRoot, absPath)
+			if err != nil {
+				return nil, fmt.Errorf("无法计算相对路径: %v", err)
+			}
+			projPath = "/" + filepath.ToSlash(relPath)
 		}
-		return targetNode, nil
-	} else {
-		// 目标路径就是项目根路径，使用根节点
-		targetNode, err := proj.FindNode("/")
-		if err != nil {
-			return nil, fmt.Errorf("无法获取根节点: %v", err)
-		}
-		return targetNode, nil
 	}
+
+	// 查找目标节点
+	targetNode, err := proj.FindNode(projPath)
+	if err != nil {
+		return nil, fmt.Errorf("找不到目标路径: %v", err)
+	}
+	return targetNode, nil
 }
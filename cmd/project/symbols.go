@@ -0,0 +1,141 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project/gosearch"
+	projsearch "github.com/sjzsdu/tong/project/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	symbolsKinds      []string
+	symbolsReceiver   string
+	symbolsParams     []string
+	symbolsResults    []string
+	symbolsImplements string
+	symbolsHasMethod  string
+	symbolsPackage    string
+	symbolsExported   bool
+	symbolsJSON       bool
+	symbolsHidden     bool
+	symbolsSubdir     string
+)
+
+var SymbolsCmd = &cobra.Command{
+	Use:   "symbols [名称正则]",
+	Short: "按结构查询 Go 声明（函数、方法、类型、接口、字段）",
+	Long: `symbols 子命令用 go/ast 解析子树中的 Go 文件，按种类、名称、接收者、参数与返回值类型、
+实现的接口等条件查询声明，输出 "路径:行:列: 签名"。
+
+• 位置参数为名称正则，--kind 取 func|method|struct|interface|type|field（type 包含 struct 与 interface）。
+• --param/--result 可多次指定，每个类型都必须出现；不含包名的类型忽略包限定符（Context 匹配 context.Context）。
+• --receiver T 匹配 T 的方法与字段，写作 *T 时只匹配指针接收者。
+• --implements 查找实现了接口的类型（含指针接收者的方法，不含嵌入字段提升的方法），
+  接口在项目中查找，找不到时支持 error、fmt.Stringer、io.Reader/Writer/Closer 等常用接口。
+
+示例：
+  tong project symbols --param context.Context --result error   # 接收 context 并返回 error 的函数
+  tong project symbols --kind type --has-method Close           # 声明了 Close 方法的类型
+  tong project symbols --implements io.Closer
+  tong project symbols '^New' --kind func --exported
+  tong project symbols --receiver '*Project' --json`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runSymbols,
+}
+
+func init() {
+	SymbolsCmd.Flags().StringSliceVar(&symbolsKinds, "kind", []string{}, "声明种类：func,method,struct,interface,type,field")
+	SymbolsCmd.Flags().StringVar(&symbolsReceiver, "receiver", "", "接收者或所属结构体类型名，*T 只匹配指针接收者")
+	SymbolsCmd.Flags().StringArrayVar(&symbolsParams, "param", []string{}, "参数中必须包含的类型（可多次指定）")
+	SymbolsCmd.Flags().StringArrayVar(&symbolsResults, "result", []string{}, "返回值中必须包含的类型（可多次指定）")
+	SymbolsCmd.Flags().StringVar(&symbolsImplements, "implements", "", "实现了指定接口的类型，如 Store 或 io.Closer")
+	SymbolsCmd.Flags().StringVar(&symbolsHasMethod, "has-method", "", "声明了指定名称方法的类型")
+	SymbolsCmd.Flags().StringVar(&symbolsPackage, "package", "", "只匹配指定包名")
+	SymbolsCmd.Flags().BoolVar(&symbolsExported, "exported", false, "只匹配导出的声明")
+	SymbolsCmd.Flags().BoolVar(&symbolsJSON, "json", false, "以 JSON 输出")
+	SymbolsCmd.Flags().BoolVar(&symbolsHidden, "hidden", false, "包含隐藏文件/目录")
+	SymbolsCmd.Flags().StringVar(&symbolsSubdir, "subdir", ".", "限定查询的子目录（相对项目根）")
+	SymbolsCmd.Flags().StringVar(&whereExpr, "where", "", whereUsage)
+}
+
+func runSymbols(cmd *cobra.Command, args []string) {
+	if sharedProject == nil {
+		fmt.Printf("错误: 未找到共享的项目实例\n")
+		os.Exit(1)
+	}
+
+	var finalTargetPath string
+	if filepath.IsAbs(symbolsSubdir) {
+		finalTargetPath = symbolsSubdir
+	} else {
+		finalTargetPath = filepath.Join(sharedProject.GetRootPath(), symbolsSubdir)
+	}
+	targetNode, err := GetTargetNode(finalTargetPath)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	opts := projsearch.DefaultSearchOptions()
+	opts.IncludeHidden = symbolsHidden
+	opts.Kinds = kindFilter
+	opts.Where, err = parseWhere()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	q := &gosearch.Query{
+		Receiver:     symbolsReceiver,
+		Params:       symbolsParams,
+		Results:      symbolsResults,
+		Implements:   symbolsImplements,
+		HasMethod:    symbolsHasMethod,
+		Package:      symbolsPackage,
+		ExportedOnly: symbolsExported,
+	}
+	if len(args) > 0 {
+		q.Name = args[0]
+	}
+	for _, k := range symbolsKinds {
+		for _, part := range strings.Split(k, ",") {
+			if part = strings.TrimSpace(strings.ToLower(part)); part != "" {
+				q.Kinds = append(q.Kinds, gosearch.Kind(part))
+			}
+		}
+	}
+
+	ix, err := gosearch.Build(context.Background(), targetNode, opts)
+	if err != nil {
+		fmt.Printf("解析出错: %v\n", err)
+		os.Exit(1)
+	}
+	symbols, err := ix.Find(q)
+	if err != nil {
+		fmt.Printf("查询出错: %v\n", err)
+		os.Exit(1)
+	}
+
+	if symbolsJSON {
+		if symbols == nil {
+			symbols = []*gosearch.Symbol{}
+		}
+		fmt.Println(helper.ToJSON(symbols))
+		return
+	}
+	for _, s := range symbols {
+		fmt.Println(s.String())
+	}
+	for _, path := range ix.Failed {
+		fmt.Fprintf(os.Stderr, "无法解析: %s\n", path)
+	}
+	if len(symbols) == 0 {
+		fmt.Println("没有匹配的声明")
+	}
+}
@@ -35,7 +35,8 @@ var TreeCmd = &cobra.Command{
   tong project tree --depth 2          # 限制显示深度为2层
   tong project tree --no-files         # 只显示目录，不显示文件
   tong project tree --hidden           # 显示隐藏文件
-  tong project tree --stats            # 显示统计信息`,
+  tong project tree --stats            # 显示统计信息
+  tong project tree --where 'size>100k' # 只显示满足查询条件的文件`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  runTree,
 }
@@ -46,6 +47,7 @@ func init() {
 	TreeCmd.Flags().BoolVarP(&showHidden, "hidden", "a", false, "显示隐藏文件")
 	TreeCmd.Flags().BoolVarP(&noFiles, "no-files", "", false, "不显示文件，只显示目录")
 	TreeCmd.Flags().BoolVarP(&showStats, "stats", "s", false, "显示统计信息")
+	TreeCmd.Flags().StringVar(&whereExpr, "where", "", whereUsage)
 }
 
 func runTree(cmd *cobra.Command, args []string) {
@@ -78,13 +80,19 @@ func runTree(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	where, err := parseWhere()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
 	// 处理 --no-files 标志
 	if noFiles {
 		showFiles = false
 	}
 
 	// 使用新的 tree 包生成树状结构
-	output := tree.TreeWithOptions(targetNode, showFiles, showHidden, depth)
+	output := tree.TreeWithFilter(targetNode, showFiles, showHidden, depth, matchNode(where))
 	fmt.Print(output)
 
 	// 显示统计信息
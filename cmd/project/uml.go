@@ -16,8 +16,10 @@ import (
 
 	"github.com/sjzsdu/langchaingo-cn/llms"
 	"github.com/sjzsdu/tong/config"
+	"github.com/sjzsdu/tong/helper"
 	"github.com/sjzsdu/tong/lang"
 	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/pack"
 	"github.com/sjzsdu/tong/project/tree"
 	"github.com/sjzsdu/tong/prompt"
 	"github.com/sjzsdu/tong/schema"
@@ -817,8 +819,8 @@ func collectCodeBatches(proj *project.Project) ([]*CodeBatch, error) {
 			return nil
 		}
 
-		// 只处理 .go 文件
-		if !strings.HasSuffix(node.Name, ".go") {
+		// 只处理 Go 文件
+		if node.Kind().Language != "go" {
 			return nil
 		}
 
@@ -868,7 +870,7 @@ func collectCodeBatches(proj *project.Project) ([]*CodeBatch, error) {
 		}
 
 		// 估算 token 数
-		tokenCount := estimateTokens(content)
+		tokenCount := helper.EstimateTokens(content)
 
 		// 如果超过限制，尝试拆分
 		if tokenCount > umlMaxTokens {
@@ -918,8 +920,11 @@ func packFileNodes(fileNodes []FileNode) (string, []string, []*project.Node, err
 
 		// 如果启用签名模式，提取签名
 		if umlSignatureOnly {
-			signature := compressToSignatures(string(content))
-			builder.WriteString(signature)
+			if signature, ok := pack.ExtractSignatures("go", fn.Path, content); ok {
+				builder.WriteString(signature)
+			} else {
+				builder.WriteString(string(content))
+			}
 		} else {
 			builder.WriteString(string(content))
 		}
@@ -933,27 +938,6 @@ func packFileNodes(fileNodes []FileNode) (string, []string, []*project.Node, err
 	return builder.String(), files, nodes, nil
 }
 
-// compressToSignatures 压缩到签名（简化版）
-func compressToSignatures(code string) string {
-	var result strings.Builder
-	lines := strings.Split(code, "\n")
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		// 保留 package, import, type, const, var, func 声明
-		if strings.HasPrefix(trimmed, "package ") ||
-			strings.HasPrefix(trimmed, "import ") ||
-			strings.HasPrefix(trimmed, "type ") ||
-			strings.HasPrefix(trimmed, "const ") ||
-			strings.HasPrefix(trimmed, "var ") ||
-			strings.HasPrefix(trimmed, "func ") {
-			result.WriteString(line + "\n")
-		}
-	}
-
-	return result.String()
-}
-
 // splitByFileNodes 按文件数拆分
 func splitByFileNodes(fileNodes []FileNode, maxTokens int) []*CodeBatch {
 	var batches []*CodeBatch
@@ -968,7 +952,7 @@ func splitByFileNodes(fileNodes []FileNode, maxTokens int) []*CodeBatch {
 		}
 
 		fileContent := fmt.Sprintf("// FILE: %s\n%s\n\n", fn.Path, string(content))
-		fileTokens := estimateTokens(fileContent)
+		fileTokens := helper.EstimateTokens(fileContent)
 
 		// 如果当前批次为空或者加入会超限
 		if currentBatch == nil || currentBatch.TokenCount+fileTokens > maxTokens {
@@ -1003,12 +987,6 @@ func splitByFileNodes(fileNodes []FileNode, maxTokens int) []*CodeBatch {
 	return batches
 }
 
-// estimateTokens 估算 token 数
-func estimateTokens(text string) int {
-	// 简单估算：1 token ≈ 4 字符
-	return len(text) / 4
-}
-
 // findNodeByPath 根据路径查找节点
 func findNodeByPath(root *project.Node, targetPath string) *project.Node {
 	if root == nil {
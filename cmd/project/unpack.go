@@ -0,0 +1,145 @@
+package project
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project/pack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	unpackFormat  string
+	unpackSubdir  string
+	unpackContext int
+	unpackYes     bool
+	unpackDryRun  bool
+	unpackNoColor bool
+)
+
+var UnpackCmd = &cobra.Command{
+	Use:   "unpack <bundle>...",
+	Short: "从打包文档还原项目文件",
+	Long: `unpack 子命令解析 pack 生成的打包文档（例如交给大模型修改后返回的版本），还原每个文件的路径与内容并写回项目。
+
+• 支持 pack 的全部格式（markdown、xml、json、jsonl、text），默认按内容推断，--format 可强制指定。
+• 文档中的路径相对打包时的目录，打包的是子目录时用 --subdir 指定同一个目录。
+• 可以传入多个分卷，或分卷索引 name.index.json；被拆到多个分卷中的文件会重新合并。
+• 写入前逐个文件输出差异预览并请求确认；--yes 跳过确认，--dry-run 只预览不写入。
+• 只新建或修改项目中的文件，文档中没有的文件保持不变；被忽略规则排除的磁盘文件不会被覆盖；差异块与未打包的文件列表被忽略。
+• pack --signatures 生成的文档只包含签名，解包会用声明覆盖完整源码，因此被拒绝。
+• 所有文件在一次操作中写入，写入后可用 tong project undo 整体撤销。
+
+示例：
+  tong project pack -f context.md && tong project unpack edited.md
+  tong project unpack reply.xml --subdir src --dry-run
+  tong project unpack out/context.index.json --yes`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runUnpack,
+}
+
+func init() {
+	UnpackCmd.Flags().StringVar(&unpackFormat, "format", "", "打包文档的格式：markdown|xml|json|jsonl|text，默认按内容推断")
+	UnpackCmd.Flags().StringVar(&unpackSubdir, "subdir", ".", "写入的目录（相对项目根），应与打包时的目录一致")
+	UnpackCmd.Flags().IntVarP(&unpackContext, "unified", "U", 3, "差异预览中的上下文行数")
+	UnpackCmd.Flags().BoolVarP(&unpackYes, "yes", "y", false, "不询问，直接写入")
+	UnpackCmd.Flags().BoolVar(&unpackDryRun, "dry-run", false, "只预览差异，不写入文件")
+	UnpackCmd.Flags().BoolVar(&unpackNoColor, "no-color", false, "不使用颜色（非终端输出时自动关闭）")
+}
+
+func runUnpack(cmd *cobra.Command, args []string) {
+	if sharedProject == nil {
+		fmt.Printf("错误: 未找到共享的项目实例\n")
+		os.Exit(1)
+	}
+	if unpackContext < 0 {
+		fmt.Println("错误: unified 不能为负数")
+		os.Exit(1)
+	}
+	if unpackFormat != "" && pack.GetFormatter(unpackFormat) == nil {
+		fmt.Printf("不支持的格式: %s\n", unpackFormat)
+		os.Exit(1)
+	}
+
+	targetPath := unpackSubdir
+	if !filepath.IsAbs(targetPath) {
+		targetPath = filepath.Join(sharedProject.GetRootPath(), targetPath)
+	}
+	targetNode, err := GetTargetNode(targetPath)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	files, err := pack.ReadBundles(args, unpackFormat)
+	if err != nil {
+		fmt.Printf("解析打包文档出错: %v\n", err)
+		os.Exit(1)
+	}
+	plan, err := pack.PlanUnpack(targetNode, files, unpackContext)
+	if err != nil {
+		fmt.Printf("解包出错: %v\n", err)
+		os.Exit(1)
+	}
+	if len(plan) == 0 {
+		fmt.Printf("打包文档中的 %d 个文件与项目一致，无需写入\n", len(files))
+		return
+	}
+
+	printUnpackPlan(os.Stdout, plan, !unpackNoColor && isTerminal(os.Stdout))
+	if unpackDryRun {
+		return
+	}
+	if !unpackYes {
+		ok, err := helper.PromptYesNo(fmt.Sprintf("确认写入以上 %d 个文件? (y/n): ", len(plan)), false)
+		if err != nil || !ok {
+			fmt.Println("已取消")
+			return
+		}
+	}
+
+	// 在事务中写入，所有文件一起提交，任一文件失败时不修改磁盘；记录到操作日志以便撤销
+	openSharedJournal()
+	tx := sharedProject.Begin()
+	written, err := pack.ApplyUnpack(tx, plan)
+	if err != nil {
+		tx.Rollback()
+		fmt.Printf("解包出错: %v\n", err)
+		os.Exit(1)
+	}
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("写入出错: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已写入 %d 个文件\n", written)
+}
+
+// printUnpackPlan 逐个文件输出解包预览：文件头、新建或修改与着色的差异块，末尾输出汇总
+func printUnpackPlan(w io.Writer, plan []pack.UnpackChange, color bool) {
+	paint := func(text, c string) string {
+		if !color {
+			return text
+		}
+		return helper.ColorText(text, c)
+	}
+
+	added, ins, del := 0, 0, 0
+	for _, c := range plan {
+		name := strings.TrimPrefix(c.Path, "/")
+		kind := "修改"
+		if c.Added {
+			kind = "新建"
+			added++
+		}
+		fmt.Fprintf(w, "%s (%s +%d -%d)\n", paint(name, helper.ColorPurple), kind, c.Insertions, c.Deletions)
+		printHunks(w, c.Diff, paint)
+		fmt.Fprintln(w)
+		ins += c.Insertions
+		del += c.Deletions
+	}
+	fmt.Fprintf(w, "共 %d 个文件（新建 %d 个），+%d -%d\n", len(plan), added, ins, del)
+}
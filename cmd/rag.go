@@ -96,8 +96,10 @@ func runRag(cmd *cobra.Command, args []string) {
 
 	if !indexed || forceReindex {
 		log.Println(lang.T("开始执行文档索引..."))
-		if err := ragSystem.IndexDocuments(ctx, options.DocsDir); err != nil {
-			log.Fatalf("文档索引失败: %v", err)
+		for _, dir := range docsDirsOf(options.DocsDir) {
+			if err := ragSystem.IndexDocuments(ctx, dir); err != nil {
+				log.Fatalf("文档索引失败: %v", err)
+			}
 		}
 	}
 	if autoSync {
@@ -191,9 +193,14 @@ func resolveOptions(cmd *cobra.Command, cfg *schema.SchemaConfig, projectRoot st
 		finalTargetPath = projectRoot
 	}
 
-	if _, err := GetTargetNode(finalTargetPath); err != nil {
+	targetNode, err := GetTargetNode(finalTargetPath)
+	if err != nil {
 		log.Fatalf("目标路径无效: %v", err)
 	}
+	// 工作区中的目录换算为所属根目录下的磁盘路径；工作区根节点在索引时展开为各个根目录
+	if sharedProject.IsWorkspace() && targetNode.Path != "/" {
+		finalTargetPath = sharedProject.GetAbsolutePath(targetNode.Path)
+	}
 
 	if collectionName == "" {
 		collectionName = filepath.Base(projectRoot)
@@ -215,6 +222,7 @@ func resolveOptions(cmd *cobra.Command, cfg *schema.SchemaConfig, projectRoot st
 			Stream: ragStreamMode,
 		},
 		DocsDir: finalTargetPath,
+		Filter:  rag.DocumentFilter{Kinds: kindFilter()},
 		Sync: rag.SyncOptions{
 			ForceReindex: forceReindex,
 			SyncInterval: time.Duration(syncInterval) * time.Second,
@@ -223,6 +231,18 @@ func resolveOptions(cmd *cobra.Command, cfg *schema.SchemaConfig, projectRoot st
 	return options
 }
 
+// docsDirsOf 返回需要索引的目录，工作区目录展开为各个根目录
+func docsDirsOf(docsDir string) []string {
+	if !sharedProject.IsWorkspace() || docsDir != sharedProject.GetRootPath() {
+		return []string{docsDir}
+	}
+	var dirs []string
+	for _, root := range sharedProject.WorkspaceRoots() {
+		dirs = append(dirs, root.Path)
+	}
+	return dirs
+}
+
 func orDefault(v, d int) int {
 	if v > 0 {
 		return v
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/sjzsdu/tong/helper"
 	"github.com/sjzsdu/tong/project"
@@ -17,7 +20,7 @@ func GetConfig() (*schema.SchemaConfig, error) {
 	if shareConfig != nil {
 		return shareConfig, nil
 	}
-	targetPath, err := helper.GetTargetPath(workDir, repoURL)
+	targetPath, err := helper.GetTargetPath(targetWorkDir(), repoURL)
 	if err != nil {
 		fmt.Printf("failed to get target path: %v\n", err)
 		return nil, err
@@ -35,7 +38,7 @@ func GetProject() (*project.Project, error) {
 	if sharedProject != nil {
 		return sharedProject, nil
 	}
-	targetPath, err := helper.GetTargetPath(workDir, repoURL)
+	targetPath, err := helper.GetTargetPath(targetWorkDir(), repoURL)
 	if err != nil {
 		fmt.Printf("failed to get target path: %v\n", err)
 		return nil, err
@@ -47,29 +50,133 @@ func GetProject() (*project.Project, error) {
 		Excludes:         excludePatterns,
 	}
 
-	// 构建项目树
-	project, err := buildProjectTreeWithOptions(targetPath, options)
+	// 多个 -d 或 tong.json 的 workspace.roots 把多个根目录挂载为一个工作区
+	roots, err := workspaceRoots(targetPath)
 	if err != nil {
 		fmt.Printf("%v\n", err)
 		return nil, err
 	}
+
+	// 构建项目树，指定 --rev 时直接读取 git 对象
+	var project *project.Project
+	switch {
+	case len(roots) > 0 && gitRev != "":
+		err = fmt.Errorf("--rev cannot be used with a multi-root workspace")
+	case len(roots) > 0:
+		project, err = buildWorkspaceWithOptions(targetPath, roots, options)
+	case gitRev != "":
+		project, err = buildProjectTreeFromRev(targetPath, gitRev, options)
+	default:
+		project, err = buildProjectTreeWithOptions(targetPath, options)
+	}
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return nil, err
+	}
+	if contentBudget != "" {
+		budget, err := helper.ParseByteSize(contentBudget)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return nil, err
+		}
+		project.SetContentBudget(budget)
+	}
 	sharedProject = project
 	return project, nil
 }
 
 // 构建项目树并返回
 func buildProjectTreeWithOptions(targetPath string, options helper.WalkDirOptions) (*project.Project, error) {
-	// 构建项目树
-	project, err := project.BuildProjectTree(targetPath, options)
+	// 构建项目树，默认使用快照缓存
+	build := project.BuildProjectTreeCached
+	if noCache {
+		build = project.BuildProjectTree
+	}
+	project, err := build(targetPath, options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build project tree: %v", err)
 	}
 	return project, nil
 }
 
+// buildWorkspaceWithOptions 构建多根工作区，默认每个根目录使用快照缓存
+func buildWorkspaceWithOptions(targetPath string, roots []project.WorkspaceRoot, options helper.WalkDirOptions) (*project.Project, error) {
+	build := project.BuildWorkspaceCached
+	if noCache {
+		build = project.BuildWorkspace
+	}
+	workspace, err := build(targetPath, roots, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build workspace: %v", err)
+	}
+	return workspace, nil
+}
+
+// workspaceRoots 返回工作区的根目录：多个 -d 时按命令行（可写作 name=path），
+// 否则取 targetPath 下 tong.json 的 workspace.roots；都没有时返回 nil，表示普通的单根项目
+func workspaceRoots(targetPath string) ([]project.WorkspaceRoot, error) {
+	var roots []project.WorkspaceRoot
+	if len(workDirs) > 1 {
+		for _, dir := range workDirs {
+			name, path, ok := strings.Cut(dir, "=")
+			if !ok {
+				name, path = "", dir
+			}
+			absPath, err := helper.GetAbsPath(path)
+			if err != nil {
+				return nil, err
+			}
+			roots = append(roots, project.WorkspaceRoot{Name: name, Path: absPath})
+		}
+		return roots, nil
+	}
+	if repoURL != "" {
+		return nil, nil
+	}
+
+	// 只读取目标目录中的 tong.json，根目录的相对路径基于该目录
+	config, err := schema.LoadMCPConfig(targetPath, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range config.Workspace.Roots {
+		roots = append(roots, project.WorkspaceRoot{Name: root.Name, Path: root.Path})
+	}
+	return roots, nil
+}
+
+// targetWorkDir 返回配置文件与项目名称所基于的目录：单个 -d 时为该目录，多个 -d 时为当前目录
+func targetWorkDir() string {
+	switch {
+	case len(workDirs) > 1:
+		return "."
+	case len(workDirs) == 1:
+		return workDirs[0]
+	}
+	return workDir
+}
+
+// buildProjectTreeFromRev 从 git 修订版本构建项目树，不使用快照缓存
+func buildProjectTreeFromRev(targetPath, rev string, options helper.WalkDirOptions) (*project.Project, error) {
+	project, err := project.BuildProjectTreeFromGit(targetPath, rev, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build project tree from %s: %v", rev, err)
+	}
+	return project, nil
+}
+
+// watchProject 在后台监听项目文件变化，保持长时间运行命令中的项目树为最新状态
+func watchProject(ctx context.Context, proj *project.Project) {
+	go func() {
+		if err := proj.Watch(ctx); err != nil {
+			log.Printf("文件监控启动失败: %v", err)
+		}
+	}()
+}
+
 // IsGitRoot 判断指定路径是否为 git 项目的根目录
 func IsGitRoot() bool {
-	targetPath, err := helper.GetTargetPath(workDir, repoURL)
+	targetPath, err := helper.GetTargetPath(targetWorkDir(), repoURL)
 	if err != nil {
 		fmt.Printf("failed to get target path: %v\n", err)
 		return false
@@ -78,7 +185,7 @@ func IsGitRoot() bool {
 }
 
 func GetProjectName() string {
-	targetPath, err := helper.GetTargetPath(workDir, repoURL)
+	targetPath, err := helper.GetTargetPath(targetWorkDir(), repoURL)
 	if err != nil {
 		fmt.Printf("failed to get target path: %v\n", err)
 		return "unknown"
@@ -113,26 +220,25 @@ func GetTargetNode(targetPath string) (*project.Node, error) {
 
 	projectRoot := proj.GetRootPath()
 
-	// 如果目标路径与项目根路径不同，需要找到对应的节点
+	// 目标路径就是项目根路径时使用根节点；否则先按磁盘路径换算（工作区按根目录换算），
+	// 不在任何根目录中时视为相对项目根路径的项目路径
+	projPath := "/"
 	if absPath != projectRoot {
-		// 计算相对路径
-		relPath, err := filepath.Rel(projectRoot, absPath)
-		if err != nil {
-			return nil, fmt.Errorf("无法计算相对路径: %v", err)
+		var ok bool
+		projPath, ok = proj.ProjectPathOf(absPath)
+		if !ok {
+			relPath, err := filepath.Rel(projectRoot, absPath)
+			if err != nil {
+				return nil, fmt.Errorf("无法计算相对路径: %v", err)
+			}
+			projPath = "/" + filepath.ToSlash(relPath)
 		}
+	}
 
-		// 查找目标节点
-		targetNode, err := proj.FindNode("/" + relPath)
-		if err != nil {
-			return nil, fmt.Errorf("找不到目标路径: %v", err)
-		}
-		return targetNode, nil
-	} else {
-		// 目标路径就是项目根路径，使用根节点
-		targetNode, err := proj.FindNode("/")
-		if err != nil {
-			return nil, fmt.Errorf("无法获取根节点: %v", err)
-		}
-		return targetNode, nil
+	// 查找目标节点
+	targetNode, err := proj.FindNode(projPath)
+	if err != nil {
+		return nil, fmt.Errorf("找不到目标路径: %v", err)
 	}
+	return targetNode, nil
 }
@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/helper/ignore"
 	"github.com/stretchr/testify/assert/yaml"
 )
 
@@ -62,12 +64,9 @@ func BuildDirData(dirPath string, configFile string) (*DirData, error) {
 		}
 	}
 
-	// 2. 读取并解析 .gitignore 文件
-	gitignorePatterns, err := readGitignore(dirPath)
-	if err != nil {
-		// 读取 .gitignore 文件失败，忽略错误，继续执行
-		gitignorePatterns = nil
-	}
+	// 2. 读取忽略规则（全局排除、.gitignore 与 .tongignore）
+	matcher := helper.NewIgnoreMatcher(dirPath, helper.WalkDirOptions{})
+	helper.LoadIgnoreFiles(matcher, dirPath, "", helper.WalkDirOptions{})
 
 	// 3. 遍历目录，填入子目录和其他文件
 	entries, err := os.ReadDir(dirPath)
@@ -77,19 +76,19 @@ func BuildDirData(dirPath string, configFile string) (*DirData, error) {
 
 	for _, entry := range entries {
 		name := entry.Name()
-		if name == configFile || name == ".gitignore" || isExcludedDir(name) {
+		if name == configFile || isIgnoreFile(name) || isExcludedDir(name) {
 			continue
 		}
 
 		fullPath := filepath.Join(dirPath, name)
 
-		// 检查是否应该被 .gitignore 忽略
-		if gitignorePatterns != nil && shouldIgnore(fullPath, gitignorePatterns) {
+		// 检查是否应该被忽略
+		if matcher.Match(name, entry.IsDir()) {
 			continue
 		}
 
 		if entry.IsDir() {
-			subMap, err := buildSubMap(fullPath)
+			subMap, err := buildSubMap(fullPath, name, matcher)
 			if err != nil {
 				return nil, err
 			}
@@ -118,55 +117,9 @@ func isExcludedDir(name string) bool {
 	return excludedDirs[name]
 }
 
-// readGitignore 读取并解析 .gitignore 文件，返回需要忽略的路径列表
-func readGitignore(dirPath string) ([]string, error) {
-	gitignorePath := filepath.Join(dirPath, ".gitignore")
-	content, err := os.ReadFile(gitignorePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil // .gitignore 文件不存在，返回空列表
-		}
-		return nil, err
-	}
-
-	lines := strings.Split(string(content), "\n")
-	var patterns []string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// 跳过空行和注释行
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		// 跳过以 ! 开头的否定模式（暂不支持）
-		if strings.HasPrefix(line, "!") {
-			continue
-		}
-		patterns = append(patterns, line)
-	}
-
-	return patterns, nil
-}
-
-// shouldIgnore 检查路径是否应该被忽略
-func shouldIgnore(path string, patterns []string) bool {
-	baseName := filepath.Base(path)
-
-	for _, pattern := range patterns {
-		// 简单的模式匹配（支持 * 通配符）
-		if matched, _ := filepath.Match(pattern, baseName); matched {
-			return true
-		}
-		// 检查目录模式
-		if strings.HasSuffix(pattern, "/") {
-			dirPattern := strings.TrimSuffix(pattern, "/")
-			if matched, _ := filepath.Match(dirPattern, baseName); matched {
-				return true
-			}
-		}
-	}
-
-	return false
+// isIgnoreFile 检查是否为忽略规则文件，这些文件不进入 map
+func isIgnoreFile(name string) bool {
+	return name == ignore.GitignoreFile || name == ignore.TongignoreFile
 }
 
 // extractFrontmatterDescription 从文件内容中提取 frontmatter 中的 description 字段
@@ -215,8 +168,8 @@ func extractFrontmatterDescription(content string) string {
 	return ""
 }
 
-// buildSubMap 递归构建子目录的 map
-func buildSubMap(dirPath string) (map[string]interface{}, error) {
+// buildSubMap 递归构建子目录的 map，relDir 为子目录相对根目录的斜杠路径
+func buildSubMap(dirPath, relDir string, matcher *ignore.Matcher) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
 	entries, err := os.ReadDir(dirPath)
@@ -224,27 +177,24 @@ func buildSubMap(dirPath string) (map[string]interface{}, error) {
 		return nil, fmt.Errorf("无法读取目录 %s: %w", dirPath, err)
 	}
 
-	// 读取并解析当前目录的 .gitignore 文件
-	gitignorePatterns, err := readGitignore(dirPath)
-	if err != nil {
-		// 读取 .gitignore 文件失败，忽略错误，继续执行
-		gitignorePatterns = nil
-	}
+	// 读取当前目录的忽略文件，规则对子目录同样生效
+	helper.LoadIgnoreFiles(matcher, dirPath, relDir, helper.WalkDirOptions{})
 
 	for _, entry := range entries {
 		name := entry.Name()
-		if name == ".gitignore" || isExcludedDir(name) {
+		if isIgnoreFile(name) || isExcludedDir(name) {
 			continue
 		}
 		fullPath := filepath.Join(dirPath, name)
+		relPath := relDir + "/" + name
 
-		// 检查是否应该被 .gitignore 忽略
-		if gitignorePatterns != nil && shouldIgnore(fullPath, gitignorePatterns) {
+		// 检查是否应该被忽略
+		if matcher.Match(relPath, entry.IsDir()) {
 			continue
 		}
 
 		if entry.IsDir() {
-			subMap, err := buildSubMap(fullPath)
+			subMap, err := buildSubMap(fullPath, relPath, matcher)
 			if err != nil {
 				return nil, err
 			}
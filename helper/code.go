@@ -3,6 +3,8 @@ package helper
 import (
 	"path/filepath"
 	"strings"
+
+	"github.com/sjzsdu/tong/helper/filekind"
 )
 
 // 常见的程序文件扩展名
@@ -42,73 +44,12 @@ func IsProgramFile(file string) bool {
 
 // GetLanguageFromExtension 根据文件扩展名返回对应的语言标识
 func GetLanguageFromExtension(ext string) string {
-	ext = strings.ToLower(ext)
-	langMap := map[string]string{
-		".go":         "go",
-		".py":         "python",
-		".js":         "javascript",
-		".ts":         "typescript",
-		".jsx":        "jsx",
-		".tsx":        "tsx",
-		".java":       "java",
-		".cpp":        "cpp",
-		".c":          "c",
-		".h":          "c",
-		".hpp":        "cpp",
-		".cs":         "csharp",
-		".php":        "php",
-		".rb":         "ruby",
-		".rs":         "rust",
-		".swift":      "swift",
-		".kt":         "kotlin",
-		".scala":      "scala",
-		".sh":         "bash",
-		".yaml":       "yaml",
-		".yml":        "yaml",
-		".json":       "json",
-		".xml":        "xml",
-		".html":       "html",
-		".css":        "css",
-		".scss":       "scss",
-		".less":       "less",
-		".sql":        "sql",
-		".md":         "markdown",
-		".txt":        "text",
-		".cfg":        "ini",
-		".ini":        "ini",
-		".toml":       "toml",
-		".dockerfile": "dockerfile",
-	}
-
-	if lang, ok := langMap[ext]; ok {
-		return lang
-	}
-	return ""
+	return filekind.LanguageByExt(ext)
 }
 
-// IsTextFile 判断是否为文本文件
+// IsTextFile 判断是否为文本文件，已知的二进制格式以外都视为文本
 func IsTextFile(filename string) bool {
-	// 基于文件扩展名判断
-	ext := strings.ToLower(filepath.Ext(filename))
-
-	// 如果没有扩展名，默认为文本文件
-	if ext == "" {
-		return true
-	}
-
-	textExtensions := map[string]bool{
-		".txt": true, ".md": true, ".go": true, ".py": true, ".js": true,
-		".ts": true, ".java": true, ".cpp": true, ".c": true, ".h": true,
-		".cs": true, ".php": true, ".rb": true, ".rs": true, ".swift": true,
-		".kt": true, ".scala": true, ".sh": true, ".yaml": true, ".yml": true,
-		".json": true, ".xml": true, ".html": true, ".css": true, ".scss": true,
-		".less": true, ".sql": true, ".cfg": true, ".ini": true, ".toml": true,
-		".dockerfile": true, ".gitignore": true, ".gitattributes": true,
-		".editorconfig": true, ".babelrc": true, ".eslintrc": true,
-		".prettierrc": true, ".npmignore": true, ".yarnrc": true,
-	}
-
-	return textExtensions[ext]
+	return !filekind.IsBinaryName(filename)
 }
 
 // ShouldIncludeFile 判断文件是否应该被包含在打包中
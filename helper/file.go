@@ -1,7 +1,6 @@
 package helper
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,6 +11,7 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/sjzsdu/tong/helper/ignore"
 	"github.com/sjzsdu/tong/share"
 )
 
@@ -52,8 +52,8 @@ type WalkDirOptions struct {
 }
 
 func WalkDir(root string, callback WalkFunc, filter FilterFunc, options WalkDirOptions) error {
-	gitignoreRules := make(map[string][]string)
 	root = filepath.Clean(root)
+	matcher := NewIgnoreMatcher(root, options)
 
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -65,25 +65,20 @@ func WalkDir(root string, callback WalkFunc, filter FilterFunc, options WalkDirO
 			Info: info,
 		}
 
-		// 处理 .gitignore 规则
-		if !options.DisableGitIgnore {
+		// 处理 .gitignore、.tongignore 与全局排除规则
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if matcher.Match(relPath, info.IsDir()) {
 			if info.IsDir() {
-				rules, err := ReadGitignore(path)
-				if err == nil && rules != nil {
-					gitignoreRules[path] = rules
-				}
-			}
-
-			excluded, excludeErr := IsPathExcludedByGitignore(path, root, gitignoreRules)
-			if excludeErr != nil {
-				return excludeErr
-			}
-			if excluded {
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
+				return filepath.SkipDir
 			}
+			return nil
+		}
+		if info.IsDir() {
+			LoadIgnoreFiles(matcher, path, relPath, options)
 		}
 
 		// 检查文件扩展名
@@ -257,26 +252,61 @@ func isReadableTextFile(path string) bool {
 
 // ReadGitignore 读取.gitignore文件并返回其中的规则
 func ReadGitignore(dir string) ([]string, error) {
-	gitignorePath := filepath.Join(dir, ".gitignore")
-	file, err := os.Open(gitignorePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
+	return ignore.ReadLines(filepath.Join(dir, ignore.GitignoreFile))
+}
+
+// IgnoreFileNames 返回每个目录下需要读取的忽略文件：.gitignore（未禁用时）与 .tongignore，
+// 同一目录中后读取的 .tongignore 优先
+func IgnoreFileNames(options WalkDirOptions) []string {
+	if options.DisableGitIgnore {
+		return []string{ignore.TongignoreFile}
+	}
+	return []string{ignore.GitignoreFile, ignore.TongignoreFile}
+}
+
+// GlobalIgnoreFiles 返回全局排除文件，按优先级从低到高：git 的 core.excludesFile、~/.tong/ignore，
+// 以及 rootDir 为 git 仓库根目录时的 .git/info/exclude
+func GlobalIgnoreFiles(rootDir string, options WalkDirOptions) []string {
+	var files []string
+	if !options.DisableGitIgnore {
+		if file := GitExcludesFile(rootDir); file != "" {
+			files = append(files, file)
 		}
-		return nil, err
 	}
-	defer file.Close()
+	files = append(files, GetPath("ignore"))
+	if !options.DisableGitIgnore && rootDir != "" {
+		if info, err := os.Stat(filepath.Join(rootDir, ".git")); err == nil && info.IsDir() {
+			files = append(files, filepath.Join(rootDir, ".git", "info", "exclude"))
+		}
+	}
+	return files
+}
 
-	var rules []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" && !strings.HasPrefix(line, "#") {
-			rules = append(rules, line)
+// NewIgnoreMatcher 创建已装入全局排除规则的匹配器，目录级规则由 LoadIgnoreFiles 逐级装入
+func NewIgnoreMatcher(rootDir string, options WalkDirOptions) *ignore.Matcher {
+	matcher := ignore.NewMatcher()
+	for _, file := range GlobalIgnoreFiles(rootDir, options) {
+		lines, err := ignore.ReadLines(file)
+		if err == nil {
+			matcher.AddGlobal(ignore.ParseLines(lines, "")...)
 		}
 	}
+	return matcher
+}
 
-	return rules, scanner.Err()
+// LoadIgnoreFiles 读取目录 dir 下的忽略文件并装入匹配器，relDir 为 dir 相对根目录的斜杠路径
+func LoadIgnoreFiles(matcher *ignore.Matcher, dir, relDir string, options WalkDirOptions) {
+	if relDir == "." {
+		relDir = ""
+	}
+	var rules []*ignore.Rule
+	for _, name := range IgnoreFileNames(options) {
+		lines, err := ignore.ReadLines(filepath.Join(dir, name))
+		if err == nil {
+			rules = append(rules, ignore.ParseLines(lines, relDir)...)
+		}
+	}
+	matcher.SetDir(relDir, rules)
 }
 
 // IsPathExcluded 检查给定路径是否应被排除
@@ -307,9 +337,9 @@ func IsPathExcluded(path string, excludes []string, rootDir string) bool {
 		}
 	}
 
-	// 检查.gitignore规则
+	// 检查根目录的.gitignore规则
 	gitignoreRules, err := ReadGitignore(rootDir)
-	if err != nil {
+	if err != nil || len(gitignoreRules) == 0 {
 		// 如果读取.gitignore出错，我们就忽略它，继续处理
 		return false
 	}
@@ -320,87 +350,9 @@ func IsPathExcluded(path string, excludes []string, rootDir string) bool {
 		return false
 	}
 
-	for _, rule := range gitignoreRules {
-		if strings.HasPrefix(rule, "/") {
-			// 绝对路径规则
-			if matched, _ := filepath.Match(rule[1:], relPath); matched {
-				return true
-			}
-		} else {
-			// 相对路径规则
-			if matched, _ := filepath.Match(rule, filepath.Base(relPath)); matched {
-				return true
-			}
-			if strings.Contains(relPath, rule) {
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
-func IsPathExcludedByGitignore(path, rootDir string, gitignoreRules map[string][]string) (bool, error) {
-	relPath, err := filepath.Rel(rootDir, path)
-	if err != nil {
-		return false, err
-	}
-
-	// Check rules from all parent directories
-	for dir := path; dir != rootDir && dir != "."; dir = filepath.Dir(dir) {
-		if rules, ok := gitignoreRules[dir]; ok {
-			for _, rule := range rules {
-				if matchGitignoreRule(relPath, rule) {
-					return true, nil
-				}
-			}
-		}
-	}
-
-	// Check root directory rules last
-	if rules, ok := gitignoreRules[rootDir]; ok {
-		for _, rule := range rules {
-			if matchGitignoreRule(relPath, rule) {
-				return true, nil
-			}
-		}
-	}
-
-	return false, nil
-}
-
-func matchGitignoreRule(path, rule string) bool {
-	// Skip empty rules
-	if rule == "" {
-		return false
-	}
-
-	// Handle directory-specific rules
-	if rule != "" && strings.HasSuffix(rule, "/") {
-		rule = rule[:len(rule)-1]
-	}
-
-	if strings.HasPrefix(rule, "/") {
-		// Absolute path rule
-		matched, _ := filepath.Match(rule[1:], path)
-		return matched
-	} else {
-		// Relative path rule
-		base := filepath.Base(path)
-		matched, _ := filepath.Match(rule, base)
-		if matched {
-			return true
-		}
-
-		// Check if rule matches any path component
-		components := strings.Split(path, string(filepath.Separator))
-		for _, comp := range components {
-			if matched, _ := filepath.Match(rule, comp); matched {
-				return true
-			}
-		}
-		return false
-	}
+	matcher := ignore.NewMatcher()
+	matcher.SetDir("", ignore.ParseLines(gitignoreRules, ""))
+	return matcher.Match(filepath.ToSlash(relPath), false)
 }
 
 func GetPath(subPath string) string {
@@ -157,3 +157,28 @@ func TestFilterReadableFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestGitExcludesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	// 未设置 core.excludesFile 时使用 git 的默认位置
+	assert.Equal(t, filepath.Join(home, ".config", "git", "ignore"), GitExcludesFile(""))
+
+	os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[core]\n\texcludesFile = ~/global-ignore\n"), 0644)
+	assert.Equal(t, filepath.Join(home, "global-ignore"), GitExcludesFile(""))
+
+	// 仓库配置优先于全局配置
+	repo := t.TempDir()
+	os.MkdirAll(filepath.Join(repo, ".git"), 0755)
+	os.WriteFile(filepath.Join(repo, ".git", "config"), []byte("[core]\n\texcludesfile = /repo-ignore\n"), 0644)
+	assert.Equal(t, "/repo-ignore", GitExcludesFile(repo))
+
+	// 全局排除文件中的规则生效，DisableGitIgnore 时不读取
+	os.WriteFile(filepath.Join(home, "global-ignore"), []byte("*.tmp\n"), 0644)
+	matcher := NewIgnoreMatcher("", WalkDirOptions{})
+	assert.True(t, matcher.Match("a.tmp", false))
+	matcher = NewIgnoreMatcher("", WalkDirOptions{DisableGitIgnore: true})
+	assert.False(t, matcher.Match("a.tmp", false))
+}
@@ -0,0 +1,181 @@
+package filekind
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// SniffLen 内容探测读取的最大字节数
+const SniffLen = 8192
+
+// Kind 文件的分类信息
+type Kind struct {
+	Language   string `json:"language,omitempty"`    // 语言标识，如 go、python，无法识别时为空
+	Binary     bool   `json:"binary,omitempty"`      // 二进制文件
+	Generated  bool   `json:"generated,omitempty"`   // 生成的代码（如带 "Code generated ... DO NOT EDIT" 头）或锁文件
+	Vendored   bool   `json:"vendored,omitempty"`    // 位于 vendor、node_modules 等第三方目录中
+	LFSPointer bool   `json:"lfs_pointer,omitempty"` // Git LFS 指针文件，真实内容不在工作区中
+}
+
+// Detect 根据斜杠分隔的路径与文件开头的内容（最多 SniffLen 字节）识别文件分类
+// 语言依次按 modeline、文件名、扩展名、shebang 与内容特征识别
+func Detect(filePath string, head []byte) Kind {
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	name := path.Base(filePath)
+	kind := Kind{Vendored: IsVendored(filePath)}
+
+	if isLFSPointer(head) {
+		kind.LFSPointer = true
+		kind.Binary = IsBinaryName(name)
+		if !kind.Binary {
+			kind.Language = languageOfName(name)
+		}
+		return kind
+	}
+	if IsBinaryName(name) || isBinaryContent(head) {
+		kind.Binary = true
+		return kind
+	}
+
+	kind.Generated = isGeneratedName(name) || hasGeneratedHeader(head)
+	kind.Language = detectLanguage(name, head)
+	return kind
+}
+
+// DetectFile 读取磁盘文件 diskPath 的开头并识别分类，filePath 为用于判断文件名与目录的斜杠路径
+func DetectFile(diskPath, filePath string) (Kind, error) {
+	f, err := os.Open(diskPath)
+	if err != nil {
+		return Kind{}, err
+	}
+	defer f.Close()
+
+	head, err := ReadHead(f)
+	if err != nil {
+		return Kind{}, err
+	}
+	return Detect(filePath, head), nil
+}
+
+// ReadHead 读取 r 开头最多 SniffLen 字节
+func ReadHead(r io.Reader) ([]byte, error) {
+	buf := make([]byte, SniffLen)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+// detectLanguage 按 modeline、文件名、扩展名、shebang、内容特征的顺序识别语言
+func detectLanguage(name string, head []byte) string {
+	if lang := languageOfModeline(head); lang != "" {
+		return lang
+	}
+	if lang := languageOfName(name); lang != "" {
+		return lang
+	}
+	if lang := languageOfShebang(head); lang != "" {
+		return lang
+	}
+	return languageOfContent(head)
+}
+
+// languageOfName 按文件名与扩展名识别语言
+func languageOfName(name string) string {
+	lower := strings.ToLower(name)
+	if lang, ok := filenames[lower]; ok {
+		return lang
+	}
+	if strings.HasPrefix(lower, "dockerfile.") || strings.HasSuffix(lower, ".dockerfile") {
+		return "dockerfile"
+	}
+	return LanguageByExt(path.Ext(lower))
+}
+
+// LanguageByExt 根据扩展名（带点，如 ".go"）返回语言标识，未知扩展名返回 ""
+func LanguageByExt(ext string) string {
+	return extensions[strings.ToLower(ext)]
+}
+
+// IsBinaryName 根据扩展名判断是否为已知的二进制格式（图片、压缩包、可执行文件等）
+func IsBinaryName(name string) bool {
+	return binaryExtensions[strings.ToLower(path.Ext(name))]
+}
+
+// IsVendored 判断斜杠路径是否位于 vendor、node_modules 等第三方依赖目录中
+func IsVendored(filePath string) bool {
+	dirs := strings.Split(strings.Trim(path.Dir(filePath), "/"), "/")
+	for _, dir := range dirs {
+		if vendorDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// isLFSPointer 判断内容是否为 Git LFS 指针文件
+func isLFSPointer(head []byte) bool {
+	return len(head) < 1024 &&
+		bytes.HasPrefix(head, []byte("version https://git-lfs.github.com/spec/v1\n")) &&
+		bytes.Contains(head, []byte("\noid sha256:"))
+}
+
+// isGeneratedName 判断文件名是否为常见的生成文件或锁文件
+func isGeneratedName(name string) bool {
+	lower := strings.ToLower(name)
+	if generatedNames[lower] {
+		return true
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// commentPrefixes 常见的行注释与块注释开头
+var commentPrefixes = []string{"//", "#", "/*", "<!--", "--"}
+
+// hasGeneratedHeader 检查文件开头的生成代码标记：
+// Go 约定的 "// Code generated ... DO NOT EDIT."、"@generated" 与开头几行注释中的 "DO NOT EDIT"
+func hasGeneratedHeader(head []byte) bool {
+	for i, line := range headLines(head, 20) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "// Code generated ") && strings.HasSuffix(line, " DO NOT EDIT.") {
+			return true
+		}
+		if strings.Contains(line, "@generated") {
+			return true
+		}
+		if i < 5 && isCommentLine(line) && strings.Contains(line, "DO NOT EDIT") {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommentLine 判断去掉首尾空白的行是否为注释
+func isCommentLine(line string) bool {
+	for _, prefix := range commentPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// headLines 返回内容的前 n 行
+func headLines(head []byte, n int) []string {
+	lines := strings.SplitN(string(head), "\n", n+1)
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return lines
+}
@@ -0,0 +1,125 @@
+package filekind
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{"main.go", "package main", "go"},
+		{"a/b/App.TSX", "", "tsx"},
+		{"notes.txt", "hello", "text"},
+		{"Dockerfile", "FROM alpine", "dockerfile"},
+		{"Dockerfile.dev", "FROM alpine", "dockerfile"},
+		{"Makefile", "all:", "makefile"},
+		{"CMakeLists.txt", "project(x)", "cmake"},
+		{"unknown.xyz", "", ""},
+		// shebang
+		{"bin/deploy", "#!/bin/sh\necho hi", "bash"},
+		{"bin/run", "#!/usr/bin/env python3.11\nprint(1)", "python"},
+		{"bin/serve", "#!/usr/bin/env -S node --experimental-modules\n", "javascript"},
+		{"bin/tool", "#!/usr/local/bin/unknown\n", ""},
+		// modeline 优先于扩展名
+		{"script.txt", "# vim: set ft=python :\nprint(1)", "python"},
+		{"config", "# -*- mode: ruby; coding: utf-8 -*-\n", "ruby"},
+		{"config.in", "# -*- shell-script -*-\n", "bash"},
+		{"long.txt", "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n// vi: filetype=golang\n", "go"},
+		// 内容特征
+		{"index", "<?php echo 1;", "php"},
+		{"page", "\n<!DOCTYPE html><html></html>", "html"},
+		{"feed", "<?xml version=\"1.0\"?>", "xml"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Detect(tt.path, []byte(tt.content)).Language, tt.path)
+	}
+}
+
+func TestDetectAttributes(t *testing.T) {
+	k := Detect("/api/types.pb.go", []byte("package api"))
+	assert.True(t, k.Generated)
+	assert.Equal(t, "go", k.Language)
+
+	k = Detect("/zz_generated.go", []byte("// Code generated by controller-gen. DO NOT EDIT.\n\npackage v1\n"))
+	assert.True(t, k.Generated)
+	assert.True(t, Detect("/schema.sql", []byte("-- @generated by sqlc\n")).Generated)
+	assert.True(t, Detect("/go.sum", []byte("github.com/x v1.0.0 h1:abc=\n")).Generated)
+	assert.False(t, Detect("/main.go", []byte("package main\n// Code generated elsewhere\n")).Generated)
+	assert.True(t, Detect("/config.yaml", []byte("# DO NOT EDIT: rendered from templates\nkey: value\n")).Generated)
+	assert.True(t, Detect("/index.html", []byte("<!-- DO NOT EDIT -->\n<html></html>\n")).Generated)
+	// 正文中提到 DO NOT EDIT 的手写文档不算生成文件
+	assert.False(t, Detect("/README.md", []byte("Notes\n\nDO NOT EDIT files under gen/ by hand.\n")).Generated)
+
+	assert.True(t, Detect("/vendor/github.com/x/y.go", nil).Vendored)
+	assert.True(t, Detect("web/node_modules/react/index.js", nil).Vendored)
+	assert.False(t, Detect("/vendor.go", nil).Vendored)
+
+	k = Detect("/logo.png", []byte("text"))
+	assert.True(t, k.Binary)
+	assert.Empty(t, k.Language)
+	assert.True(t, Detect("/data", []byte{'a', 0, 'b'}).Binary)
+	assert.True(t, Detect("/data", []byte{1, 2, 3, 4, 'a'}).Binary)
+	assert.False(t, Detect("/中文.md", []byte("你好，世界")).Binary)
+	truncated := []byte(strings.Repeat("你好", SniffLen/6+1))[:SniffLen]
+	assert.False(t, Detect("/中文.md", truncated).Binary, "truncated multi-byte rune is not binary")
+	assert.False(t, Detect("/empty", nil).Binary)
+
+	pointer := "version https://git-lfs.github.com/spec/v1\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\nsize 12345\n"
+	k = Detect("/assets/model.bin", []byte(pointer))
+	assert.True(t, k.LFSPointer)
+	assert.True(t, k.Binary)
+	k = Detect("/data/train.csv", []byte(pointer))
+	assert.True(t, k.LFSPointer)
+	assert.False(t, k.Binary)
+	assert.Equal(t, "csv", k.Language)
+}
+
+func TestDetectFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run")
+	require.NoError(t, os.WriteFile(path, []byte("#!/usr/bin/env ruby\nputs 1\n"), 0644))
+
+	k, err := DetectFile(path, "/tools/run")
+	require.NoError(t, err)
+	assert.Equal(t, "ruby", k.Language)
+
+	_, err = DetectFile(filepath.Join(dir, "missing"), "/missing")
+	assert.Error(t, err)
+}
+
+func TestFilter(t *testing.T) {
+	goFile := Kind{Language: "go"}
+	generated := Kind{Language: "go", Generated: true}
+	vendored := Kind{Language: "javascript", Vendored: true}
+	binary := Kind{Binary: true}
+	pointer := Kind{Language: "csv", LFSPointer: true}
+
+	var zero Filter
+	assert.True(t, zero.IsZero())
+	for _, k := range []Kind{goFile, generated, vendored, binary, pointer} {
+		assert.True(t, zero.Match(k))
+	}
+
+	f := Filter{SkipBinary: true, SkipGenerated: true, SkipVendored: true}
+	assert.False(t, f.IsZero())
+	assert.True(t, f.Match(goFile))
+	assert.False(t, f.Match(generated))
+	assert.False(t, f.Match(vendored))
+	assert.False(t, f.Match(binary))
+	assert.False(t, f.Match(pointer))
+
+	f = Filter{Languages: []string{"Golang", "js"}}
+	assert.True(t, f.Match(goFile))
+	assert.True(t, f.Match(vendored))
+	assert.False(t, f.Match(binary))
+	assert.False(t, f.Match(Kind{Language: "python"}))
+}
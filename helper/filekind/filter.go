@@ -0,0 +1,36 @@
+package filekind
+
+// Filter 按文件分类筛选文件，零值不过滤任何文件
+type Filter struct {
+	Languages     []string // 只保留这些语言（支持 py、golang 等别名），为空时不限制
+	SkipBinary    bool     // 跳过二进制文件与 Git LFS 指针
+	SkipGenerated bool     // 跳过生成的代码与锁文件
+	SkipVendored  bool     // 跳过第三方依赖目录中的文件
+}
+
+// IsZero 判断过滤器是否不过滤任何文件，调用方可据此跳过内容探测
+func (f Filter) IsZero() bool {
+	return len(f.Languages) == 0 && !f.SkipBinary && !f.SkipGenerated && !f.SkipVendored
+}
+
+// Match 判断分类是否满足过滤条件
+func (f Filter) Match(k Kind) bool {
+	if f.SkipBinary && (k.Binary || k.LFSPointer) {
+		return false
+	}
+	if f.SkipGenerated && k.Generated {
+		return false
+	}
+	if f.SkipVendored && k.Vendored {
+		return false
+	}
+	if len(f.Languages) == 0 {
+		return true
+	}
+	for _, lang := range f.Languages {
+		if k.Language != "" && NormalizeLanguage(lang) == k.Language {
+			return true
+		}
+	}
+	return false
+}
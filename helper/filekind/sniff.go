@@ -0,0 +1,140 @@
+package filekind
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// vimModeline 匹配 vim 的 "vim: set ft=python:"、"vi: filetype=go" 等写法
+var vimModeline = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex):.*?\b(?:ft|filetype|syntax)=([A-Za-z0-9_+#.-]+)`)
+
+// emacsModeline 匹配 emacs 的 "-*- mode: python -*-" 与 "-*- python -*-"
+var emacsModeline = regexp.MustCompile(`-\*-\s*(.+?)\s*-\*-`)
+
+// NormalizeLanguage 把语言名称或别名（如 py、golang、c++）转换为 Detect 使用的语言标识
+func NormalizeLanguage(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if lang, ok := aliases[name]; ok {
+		return lang
+	}
+	return name
+}
+
+// languageOfModeline 从开头或结尾 5 行中的 vim/emacs modeline 识别语言
+func languageOfModeline(head []byte) string {
+	lines := strings.Split(string(head), "\n")
+	candidates := lines
+	if len(lines) > 10 {
+		candidates = append(append([]string{}, lines[:5]...), lines[len(lines)-5:]...)
+	}
+	for _, line := range candidates {
+		if m := vimModeline.FindStringSubmatch(line); m != nil {
+			return NormalizeLanguage(m[1])
+		}
+		if m := emacsModeline.FindStringSubmatch(line); m != nil {
+			if lang := emacsMode(m[1]); lang != "" {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// emacsMode 解析 emacs 文件变量中的 mode
+func emacsMode(vars string) string {
+	if !strings.Contains(vars, ":") {
+		return NormalizeLanguage(vars)
+	}
+	for _, v := range strings.Split(vars, ";") {
+		key, value, ok := strings.Cut(v, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "mode") {
+			return NormalizeLanguage(value)
+		}
+	}
+	return ""
+}
+
+// languageOfShebang 从 "#!" 行的解释器识别语言，支持 "/usr/bin/env -S python3 -u" 与带版本号的解释器
+func languageOfShebang(head []byte) string {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return ""
+	}
+	line, _, _ := strings.Cut(string(head[2:]), "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+				continue
+			}
+			interpreter = path.Base(f)
+			break
+		}
+	}
+	interpreter = strings.ToLower(strings.TrimRight(interpreter, "0123456789."))
+	return interpreters[interpreter]
+}
+
+// languageOfContent 通过内容特征识别没有扩展名的常见格式
+func languageOfContent(head []byte) string {
+	trimmed := bytes.TrimLeft(head, " \t\r\n\ufeff")
+	lower := bytes.ToLower(trimmed[:min(len(trimmed), 64)])
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<?php")):
+		return "php"
+	case bytes.HasPrefix(trimmed, []byte("<?xml")):
+		return "xml"
+	case bytes.HasPrefix(lower, []byte("<!doctype html")), bytes.HasPrefix(lower, []byte("<html")):
+		return "html"
+	}
+	return ""
+}
+
+// isBinaryContent 通过内容粗略判断是否为二进制：
+// 出现 0 字节、不可打印字符（换行、回车、制表符除外）超过 30%，或不是有效 UTF-8 且高位字节超过一半
+// 截断在多字节字符中间的末尾不视为无效 UTF-8
+func isBinaryContent(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+
+	var nonPrintable, highBytes int
+	for _, b := range head {
+		switch {
+		case b == 0:
+			return true
+		case b < 32 && b != '\n' && b != '\r' && b != '\t':
+			nonPrintable++
+		case b >= 0x80:
+			highBytes++
+		}
+	}
+	if float64(nonPrintable)/float64(len(head)) > 0.30 {
+		return true
+	}
+	return !utf8.Valid(trimPartialRune(head)) && float64(highBytes)/float64(len(head)) > 0.50
+}
+
+// trimPartialRune 去掉末尾不完整的 UTF-8 字符（最多 3 个字节）
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i <= utf8.UTFMax-1 && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if c < 0x80 {
+			return b
+		}
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			return b
+		}
+	}
+	return b
+}
@@ -0,0 +1,203 @@
+package filekind
+
+// extensions 扩展名到语言标识的映射
+var extensions = map[string]string{
+	".go":         "go",
+	".py":         "python",
+	".pyi":        "python",
+	".js":         "javascript",
+	".mjs":        "javascript",
+	".cjs":        "javascript",
+	".ts":         "typescript",
+	".mts":        "typescript",
+	".cts":        "typescript",
+	".jsx":        "jsx",
+	".tsx":        "tsx",
+	".vue":        "vue",
+	".svelte":     "svelte",
+	".java":       "java",
+	".kt":         "kotlin",
+	".kts":        "kotlin",
+	".scala":      "scala",
+	".groovy":     "groovy",
+	".gradle":     "groovy",
+	".c":          "c",
+	".h":          "c",
+	".cpp":        "cpp",
+	".cc":         "cpp",
+	".cxx":        "cpp",
+	".hpp":        "cpp",
+	".hh":         "cpp",
+	".hxx":        "cpp",
+	".m":          "objectivec",
+	".mm":         "objectivec",
+	".cs":         "csharp",
+	".fs":         "fsharp",
+	".php":        "php",
+	".rb":         "ruby",
+	".rs":         "rust",
+	".swift":      "swift",
+	".dart":       "dart",
+	".lua":        "lua",
+	".pl":         "perl",
+	".pm":         "perl",
+	".r":          "r",
+	".ex":         "elixir",
+	".exs":        "elixir",
+	".erl":        "erlang",
+	".hs":         "haskell",
+	".clj":        "clojure",
+	".zig":        "zig",
+	".sh":         "bash",
+	".bash":       "bash",
+	".zsh":        "bash",
+	".fish":       "fish",
+	".ps1":        "powershell",
+	".bat":        "batch",
+	".cmd":        "batch",
+	".sql":        "sql",
+	".proto":      "protobuf",
+	".graphql":    "graphql",
+	".tf":         "hcl",
+	".hcl":        "hcl",
+	".cmake":      "cmake",
+	".mk":         "makefile",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".json":       "json",
+	".xml":        "xml",
+	".html":       "html",
+	".htm":        "html",
+	".css":        "css",
+	".scss":       "scss",
+	".sass":       "sass",
+	".less":       "less",
+	".md":         "markdown",
+	".rst":        "rst",
+	".tex":        "latex",
+	".csv":        "csv",
+	".txt":        "text",
+	".cfg":        "ini",
+	".ini":        "ini",
+	".toml":       "toml",
+	".dockerfile": "dockerfile",
+}
+
+// filenames 无扩展名或有固定名称的文件（小写）
+var filenames = map[string]string{
+	"dockerfile":     "dockerfile",
+	"containerfile":  "dockerfile",
+	"makefile":       "makefile",
+	"gnumakefile":    "makefile",
+	"cmakelists.txt": "cmake",
+	"jenkinsfile":    "groovy",
+	"gemfile":        "ruby",
+	"rakefile":       "ruby",
+	"vagrantfile":    "ruby",
+	"podfile":        "ruby",
+	"go.mod":         "gomod",
+	"go.work":        "gomod",
+	".bashrc":        "bash",
+	".zshrc":         "bash",
+	".profile":       "bash",
+}
+
+// binaryExtensions 已知的二进制格式
+var binaryExtensions = map[string]bool{
+	// 图片
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true,
+	".webp": true, ".tif": true, ".tiff": true, ".psd": true, ".heic": true,
+	// 音视频
+	".mp3": true, ".wav": true, ".flac": true, ".ogg": true, ".mp4": true, ".mov": true,
+	".avi": true, ".mkv": true, ".webm": true,
+	// 压缩包
+	".zip": true, ".tar": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true,
+	".7z": true, ".rar": true, ".zst": true, ".jar": true, ".war": true,
+	// 可执行文件与库
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".a": true, ".o": true,
+	".obj": true, ".lib": true, ".class": true, ".pyc": true, ".wasm": true, ".bin": true,
+	// 文档、字体与数据库
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true,
+	".pptx": true, ".ttf": true, ".otf": true, ".woff": true, ".woff2": true, ".eot": true,
+	".db": true, ".sqlite": true, ".sqlite3": true,
+}
+
+// vendorDirs 第三方依赖目录
+var vendorDirs = map[string]bool{
+	"vendor":           true,
+	"node_modules":     true,
+	"bower_components": true,
+	"jspm_packages":    true,
+	"third_party":      true,
+	"third-party":      true,
+	"Pods":             true,
+}
+
+// generatedNames 锁文件等工具生成的文件（小写）
+var generatedNames = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"cargo.lock":        true,
+	"composer.lock":     true,
+	"poetry.lock":       true,
+	"gemfile.lock":      true,
+	"pipfile.lock":      true,
+}
+
+// generatedSuffixes 生成文件的名称后缀
+var generatedSuffixes = []string{
+	".pb.go", ".pb.gw.go", "_pb2.py", "_pb2_grpc.py", ".pb.cc", ".pb.h",
+	".min.js", ".min.css", ".js.map", ".css.map",
+}
+
+// interpreters shebang 中的解释器到语言标识的映射（已去掉版本号）
+var interpreters = map[string]string{
+	"python":  "python",
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"deno":    "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"sh":      "bash",
+	"bash":    "bash",
+	"zsh":     "bash",
+	"ksh":     "bash",
+	"dash":    "bash",
+	"fish":    "fish",
+	"pwsh":    "powershell",
+	"rscript": "r",
+}
+
+// aliases modeline 与 --lang 中常见的别名到语言标识的映射
+var aliases = map[string]string{
+	"golang":       "go",
+	"py":           "python",
+	"python3":      "python",
+	"js":           "javascript",
+	"node":         "javascript",
+	"ts":           "typescript",
+	"rb":           "ruby",
+	"rs":           "rust",
+	"sh":           "bash",
+	"shell":        "bash",
+	"shell-script": "bash",
+	"zsh":          "bash",
+	"c++":          "cpp",
+	"cs":           "csharp",
+	"c#":           "csharp",
+	"objc":         "objectivec",
+	"kt":           "kotlin",
+	"make":         "makefile",
+	"yml":          "yaml",
+	"md":           "markdown",
+	"txt":          "text",
+	"dosini":       "ini",
+	"conf":         "ini",
+	"proto":        "protobuf",
+	"tex":          "latex",
+	"ps1":          "powershell",
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/plumbing/format/config"
 )
 
 // CloneProject 克隆指定的Git仓库到临时目录并返回克隆的路径
@@ -130,3 +131,68 @@ func GetRelativePathToGitRoot(path string) (string, bool) {
 
 	return relPath, true
 }
+
+// GitExcludesFile 返回 git 的全局排除文件 core.excludesFile
+// 依次读取 $XDG_CONFIG_HOME/git/config、~/.gitconfig 与 rootDir 下的 .git/config，后读取的设置优先；
+// 均未设置时为 $XDG_CONFIG_HOME/git/ignore（默认 ~/.config/git/ignore）
+func GitExcludesFile(rootDir string) string {
+	home, _ := os.UserHomeDir()
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && home != "" {
+		xdg = filepath.Join(home, ".config")
+	}
+
+	var configs []string
+	if xdg != "" {
+		configs = append(configs, filepath.Join(xdg, "git", "config"))
+	}
+	if home != "" {
+		configs = append(configs, filepath.Join(home, ".gitconfig"))
+	}
+	if rootDir != "" {
+		configs = append(configs, filepath.Join(rootDir, ".git", "config"))
+	}
+
+	excludes := ""
+	for _, file := range configs {
+		if value := readGitConfigValue(file, "core", "excludesFile"); value != "" {
+			excludes = value
+		}
+	}
+	if excludes == "" {
+		if xdg == "" {
+			return ""
+		}
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	if excludes == "~" || strings.HasPrefix(excludes, "~/") {
+		if home == "" {
+			return ""
+		}
+		excludes = filepath.Join(home, excludes[1:])
+	}
+	return excludes
+}
+
+// readGitConfigValue 读取 git 配置文件中的选项值，文件不存在或未设置时返回空字符串
+func readGitConfigValue(file, section, key string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	cfg := gitconfig.New()
+	if err := gitconfig.NewDecoder(f).Decode(cfg); err != nil {
+		return ""
+	}
+	value := ""
+	for _, s := range cfg.Sections {
+		if s.IsName(section) {
+			if v := s.Option(key); v != "" {
+				value = v
+			}
+		}
+	}
+	return value
+}
@@ -0,0 +1,302 @@
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 目录级忽略文件
+const (
+	// GitignoreFile git 的忽略文件
+	GitignoreFile = ".gitignore"
+	// TongignoreFile tong 专用的忽略文件，语法与 .gitignore 相同，同一目录中优先于 .gitignore
+	TongignoreFile = ".tongignore"
+)
+
+// Rule 一条 gitignore 规则
+type Rule struct {
+	Pattern string // 去掉否定符、开头与结尾斜杠后的模式
+	Base    string // 规则文件所在目录，相对根目录的斜杠路径，根目录为 ""
+	Negate  bool   // 以 ! 开头，重新包含之前被排除的路径
+	DirOnly bool   // 以 / 结尾，只匹配目录
+	re      *regexp.Regexp
+}
+
+// ParseRule 解析 base 目录下忽略文件中的一行，空行与注释返回 nil
+func ParseRule(line, base string) *Rule {
+	line = strings.TrimSuffix(line, "\r")
+	if strings.HasPrefix(line, "#") {
+		return nil
+	}
+	line = trimTrailingSpaces(line)
+
+	r := &Rule{Base: strings.Trim(base, "/")}
+	if strings.HasPrefix(line, "!") {
+		r.Negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.DirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	// 开头或中间包含斜杠的模式相对于规则文件所在目录，否则匹配任意层级的名称
+	anchored := strings.Contains(line, "/")
+	r.Pattern = strings.TrimPrefix(line, "/")
+	re, err := regexp.Compile(compile(r.Pattern, anchored))
+	if err != nil {
+		return nil
+	}
+	r.re = re
+	return r
+}
+
+// ParseLines 解析多行规则
+func ParseLines(lines []string, base string) []*Rule {
+	var rules []*Rule
+	for _, line := range lines {
+		if r := ParseRule(line, base); r != nil {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// ReadLines 读取忽略文件中的规则行（保留行内空白，跳过空行与注释），文件不存在时返回 nil
+func ReadLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return SplitLines(data), nil
+}
+
+// SplitLines 把忽略文件内容拆分为规则行，跳过空行与注释
+func SplitLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Match 判断相对根目录的斜杠路径是否匹配该规则，不考虑父目录
+func (r *Rule) Match(path string, isDir bool) bool {
+	if r.DirOnly && !isDir {
+		return false
+	}
+	if r.Base != "" {
+		if !strings.HasPrefix(path, r.Base+"/") {
+			return false
+		}
+		path = path[len(r.Base)+1:]
+	}
+	return r.re.MatchString(path)
+}
+
+// Matcher 按 git 的优先级组合多个来源的规则：全局规则最低，目录越深优先级越高，
+// 同一来源中后出现的规则优先。Matcher 不是并发安全的
+type Matcher struct {
+	global []*Rule
+	dirs   map[string][]*Rule
+}
+
+// NewMatcher 创建匹配器，global 为全局排除规则
+func NewMatcher(global ...*Rule) *Matcher {
+	return &Matcher{global: global, dirs: make(map[string][]*Rule)}
+}
+
+// AddGlobal 追加全局排除规则
+func (m *Matcher) AddGlobal(rules ...*Rule) {
+	m.global = append(m.global, rules...)
+}
+
+// SetDir 设置目录 base 下忽略文件的规则，替换已有规则
+func (m *Matcher) SetDir(base string, rules []*Rule) {
+	base = strings.Trim(base, "/")
+	if len(rules) == 0 {
+		delete(m.dirs, base)
+		return
+	}
+	m.dirs[base] = rules
+}
+
+// Match 判断相对根目录的斜杠路径是否被忽略。父目录被忽略时，其中的路径无法被重新包含
+func (m *Matcher) Match(path string, isDir bool) bool {
+	path = strings.Trim(path, "/")
+	if path == "" || path == "." {
+		return false
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && m.matchPath(path[:i], true) {
+			return true
+		}
+	}
+	return m.matchPath(path, isDir)
+}
+
+// matchPath 按优先级从低到高检查规则，最后一条匹配的规则决定结果
+func (m *Matcher) matchPath(path string, isDir bool) bool {
+	ignored := false
+	check := func(rules []*Rule) {
+		for _, r := range rules {
+			if r.Match(path, isDir) {
+				ignored = !r.Negate
+			}
+		}
+	}
+	check(m.global)
+	check(m.dirs[""])
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			check(m.dirs[path[:i]])
+		}
+	}
+	return ignored
+}
+
+// trimTrailingSpaces 去掉行尾未被反斜杠转义的空格
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") {
+		trimmed := line[:len(line)-1]
+		if strings.HasSuffix(trimmed, "\\") && !strings.HasSuffix(trimmed, "\\\\") {
+			break
+		}
+		line = trimmed
+	}
+	return line
+}
+
+// compile 把 gitignore 模式转换为匹配相对路径的正则表达式
+func compile(pattern string, anchored bool) string {
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+
+	segments := strings.Split(pattern, "/")
+	needSep := false
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		if seg == "**" {
+			switch {
+			case last && i == 0:
+				b.WriteString(".*")
+			case last:
+				// "abc/**" 匹配 abc 下的所有内容，不包括 abc 本身
+				b.WriteString("/.+")
+			default:
+				// 开头或中间的 "**/" 匹配零个或多个目录
+				if needSep {
+					b.WriteString("/")
+				}
+				b.WriteString("(?:.*/)?")
+				needSep = false
+			}
+			continue
+		}
+		if needSep {
+			b.WriteString("/")
+		}
+		b.WriteString(globSegment(seg))
+		needSep = true
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// globSegment 转换不含斜杠的通配模式：* 与 ? 不匹配斜杠，支持字符组与反斜杠转义
+func globSegment(seg string) string {
+	var b strings.Builder
+	for i := 0; i < len(seg); i++ {
+		c := seg[i]
+		switch c {
+		case '\\':
+			if i+1 < len(seg) {
+				i++
+				b.WriteString(regexp.QuoteMeta(seg[i : i+1]))
+			} else {
+				b.WriteString(`\\`)
+			}
+		case '*':
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			class, n := charClass(seg[i:])
+			if n == 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(class)
+			i += n - 1
+		default:
+			b.WriteString(regexp.QuoteMeta(seg[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// charClass 转换以 [ 开头的字符组，返回正则表达式与消耗的字节数；没有闭合的 ] 时返回 0
+func charClass(s string) (string, int) {
+	var b strings.Builder
+	b.WriteString("[")
+	i := 1
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		b.WriteString("^/")
+		i++
+	}
+	// 紧跟在 [ 或 [! 之后的 ] 是普通字符
+	first := true
+	for i < len(s) {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case c == ']' && !first:
+			b.WriteString("]")
+			return b.String(), i + 1
+		case c == '[' && strings.HasPrefix(s[i:], "[:") && strings.Contains(s[i+2:], ":]"):
+			// POSIX 字符类，如 [:alpha:]
+			end := i + 2 + strings.Index(s[i+2:], ":]") + 2
+			b.WriteString(s[i:end])
+			size = end - i
+		case c == '\\' && i+1 < len(s):
+			escaped, n := utf8.DecodeRuneInString(s[i+1:])
+			b.WriteString(classRune(escaped))
+			size = 1 + n
+		case c == '-':
+			b.WriteString("-")
+		default:
+			b.WriteString(classRune(c))
+		}
+		i += size
+		first = false
+	}
+	return "", 0
+}
+
+// classRune 转义字符组中有特殊含义的字符
+func classRune(c rune) string {
+	switch c {
+	case '\\', ']', '[', '^', '-':
+		return `\` + string(c)
+	}
+	return string(c)
+}
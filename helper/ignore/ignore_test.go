@@ -0,0 +1,174 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// matcherOf 用根目录 .gitignore 的内容创建匹配器
+func matcherOf(lines ...string) *Matcher {
+	m := NewMatcher()
+	m.SetDir("", ParseLines(lines, ""))
+	return m
+}
+
+func TestParseRule(t *testing.T) {
+	assert.Nil(t, ParseRule("", ""))
+	assert.Nil(t, ParseRule("# comment", ""))
+	assert.Nil(t, ParseRule("   ", ""))
+	assert.Nil(t, ParseRule("!", ""))
+
+	r := ParseRule("!/build/  ", "sub/")
+	require.NotNil(t, r)
+	assert.Equal(t, "build", r.Pattern)
+	assert.Equal(t, "sub", r.Base)
+	assert.True(t, r.Negate)
+	assert.True(t, r.DirOnly)
+
+	// 转义的 #、! 与行尾空格
+	assert.True(t, ParseRule(`\#file`, "").Match("#file", false))
+	assert.True(t, ParseRule(`\!important!.txt`, "").Match("!important!.txt", false))
+	assert.False(t, ParseRule(`\!important!.txt`, "").Negate)
+	assert.True(t, ParseRule(`trailing\ `, "").Match("trailing ", false))
+	assert.True(t, ParseRule("trimmed   ", "").Match("trimmed", false))
+}
+
+// 以下用例取自 git 文档 gitignore(5) 的 PATTERN FORMAT 与 EXAMPLES 部分
+func TestRuleMatchGitExamples(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		// 不含斜杠的模式匹配任意层级的名称
+		{"hello.*", "hello.txt", false, true},
+		{"hello.*", "a/b/hello.c", false, true},
+		{"hello.*", "hello", false, false},
+		// 结尾斜杠只匹配目录
+		{"frotz/", "frotz", true, true},
+		{"frotz/", "a/frotz", true, true},
+		{"frotz/", "frotz", false, false},
+		{"doc/frotz/", "doc/frotz", true, true},
+		{"doc/frotz/", "a/doc/frotz", true, false},
+		// 开头或中间的斜杠使模式相对于 .gitignore 所在目录
+		{"doc/frotz", "doc/frotz", false, true},
+		{"/doc/frotz", "doc/frotz", false, true},
+		{"doc/frotz", "a/doc/frotz", false, false},
+		{"/*.c", "cat-file.c", false, true},
+		{"/*.c", "mozilla-sha1/sha1.c", false, false},
+		{"Documentation/*.html", "Documentation/git.html", false, true},
+		{"Documentation/*.html", "Documentation/ppc/ppc.html", false, false},
+		{"Documentation/*.html", "tools/perf/Documentation/perf.html", false, false},
+		// * 不匹配斜杠
+		{"foo/*", "foo/test.json", false, true},
+		{"foo/*", "foo/bar", true, true},
+		{"foo/*", "foo/bar/hello.c", false, false},
+		// 连续的星号
+		{"**/foo", "foo", false, true},
+		{"**/foo", "x/y/foo", true, true},
+		{"**/foo/bar", "foo/bar", false, true},
+		{"**/foo/bar", "a/foo/bar", false, true},
+		{"**/foo/bar", "a/foo/x/bar", false, false},
+		{"abc/**", "abc/x", false, true},
+		{"abc/**", "abc/x/y/z", false, true},
+		{"abc/**", "abc", true, false},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**/b", "a/x/y/c", false, false},
+		{"a**b", "axyb", false, true},
+		{"a**b", "ax/yb", false, false},
+		// ? 与字符组
+		{"?.txt", "a.txt", false, true},
+		{"?.txt", "ab.txt", false, false},
+		{"file[0-9].go", "file7.go", false, true},
+		{"file[!0-9].go", "filex.go", false, true},
+		{"file[!0-9].go", "file7.go", false, false},
+		{"[]x].md", "].md", false, true},
+		{"[[:digit:]]*.log", "1.log", false, true},
+		{"[[:digit:]]*.log", "a.log", false, false},
+		{"unclosed[", "unclosed[", false, true},
+		{"数据*.csv", "目录/数据1.csv", false, true},
+	}
+	for _, tt := range tests {
+		r := ParseRule(tt.pattern, "")
+		require.NotNil(t, r, tt.pattern)
+		assert.Equal(t, tt.want, r.Match(tt.path, tt.isDir), "%q vs %q (dir=%v)", tt.pattern, tt.path, tt.isDir)
+	}
+}
+
+func TestMatcherNegation(t *testing.T) {
+	// gitignore(5)：只保留 foo/bar 目录
+	m := matcherOf("/*", "!/foo", "/foo/*", "!/foo/bar")
+	assert.True(t, m.Match("README.md", false))
+	assert.True(t, m.Match("src", true))
+	assert.False(t, m.Match("foo", true))
+	assert.True(t, m.Match("foo/other.txt", false))
+	assert.False(t, m.Match("foo/bar", true))
+	assert.False(t, m.Match("foo/bar/baz.go", false))
+
+	// 后出现的规则优先
+	m = matcherOf("*.go", "!keep.go")
+	assert.True(t, m.Match("main.go", false))
+	assert.False(t, m.Match("pkg/keep.go", false))
+	m = matcherOf("!keep.go", "*.go")
+	assert.True(t, m.Match("keep.go", false))
+
+	// 父目录被排除时无法重新包含其中的文件
+	m = matcherOf("build/", "!build/keep.txt")
+	assert.True(t, m.Match("build/keep.txt", false))
+	assert.True(t, m.Match("a/build/x.o", false))
+	assert.False(t, m.Match("build", false), "build/ only matches directories")
+
+	// 排除目录下的内容而不是目录本身时可以重新包含
+	m = matcherOf("build/*", "!build/keep.txt")
+	assert.False(t, m.Match("build/keep.txt", false))
+	assert.True(t, m.Match("build/x.o", false))
+}
+
+func TestMatcherPrecedence(t *testing.T) {
+	m := NewMatcher(ParseLines([]string{"*.log", "secret/"}, "")...)
+	m.SetDir("", ParseLines([]string{"!important.log"}, ""))
+	m.SetDir("sub", ParseLines([]string{"*.tmp", "/local.txt", "!debug.log"}, "sub"))
+	m.SetDir("sub/deep", ParseLines([]string{"!*.tmp"}, "sub/deep"))
+
+	assert.True(t, m.Match("app.log", false), "global rule")
+	assert.True(t, m.Match("a/secret/key", false), "global directory rule")
+	assert.False(t, m.Match("important.log", false), "root rule overrides global")
+	assert.False(t, m.Match("sub/debug.log", false), "deeper rule overrides root")
+	assert.True(t, m.Match("other/debug.log", false), "sub rules only apply below sub")
+	assert.True(t, m.Match("sub/x/a.tmp", false))
+	assert.False(t, m.Match("sub/deep/a.tmp", false))
+	assert.True(t, m.Match("sub/local.txt", false))
+	assert.False(t, m.Match("sub/x/local.txt", false), "anchored to sub")
+	assert.False(t, m.Match("local.txt", false))
+
+	m.SetDir("sub", nil)
+	assert.False(t, m.Match("sub/x/a.tmp", false))
+	assert.False(t, m.Match("", true))
+	assert.False(t, m.Match(".", true))
+}
+
+func TestReadLines(t *testing.T) {
+	dir := t.TempDir()
+	lines, err := ReadLines(filepath.Join(dir, GitignoreFile))
+	require.NoError(t, err)
+	assert.Nil(t, lines)
+
+	content := strings.Join([]string{"# comment", "", "*.log\r", " leading", "dir/ ", "!keep.log"}, "\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, TongignoreFile), []byte(content), 0644))
+	lines, err = ReadLines(filepath.Join(dir, TongignoreFile))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.log", " leading", "dir/ ", "!keep.log"}, lines)
+
+	rules := ParseLines(lines, "")
+	require.Len(t, rules, 4)
+	assert.True(t, rules[1].Match(" leading", false), "leading spaces are significant")
+	assert.True(t, rules[2].DirOnly)
+}
@@ -2,8 +2,10 @@ package helper
 
 import (
 	"encoding/json"
+	"fmt"
 	"math/rand"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -78,3 +80,38 @@ func ToJSONString(v interface{}) string {
 	}
 	return string(data)
 }
+
+// byteUnits 字节大小的单位，按 1024 进制
+var byteUnits = map[string]int64{
+	"":   1,
+	"B":  1,
+	"K":  1 << 10,
+	"KB": 1 << 10,
+	"M":  1 << 20,
+	"MB": 1 << 20,
+	"G":  1 << 30,
+	"GB": 1 << 30,
+}
+
+// ParseByteSize 解析 "512KB"、"256M"、"1GB" 等字节大小，单位不区分大小写，无单位时为字节
+func ParseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := byteUnits[strings.TrimSpace(s[i:])]
+	if !ok {
+		return 0, fmt.Errorf("无效的大小单位: %s", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// EstimateTokens 估算文本的 token 数：简单按 1 token ≈ 4 字节计算
+func EstimateTokens(text string) int {
+	return len(text) / 4
+}
@@ -2,6 +2,7 @@ package helper
 
 import (
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -90,3 +91,30 @@ func TestRandomString(t *testing.T) {
 		})
 	}
 }
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":      0,
+		"1024":   1024,
+		"512KB":  512 << 10,
+		"256m":   256 << 20,
+		"1.5GB":  3 << 29,
+		" 2 MB ": 2 << 20,
+	}
+	for input, want := range tests {
+		got, err := ParseByteSize(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "MB", "12XB", "-1"} {
+		_, err := ParseByteSize(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 0, EstimateTokens("abc"))
+	assert.Equal(t, 25, EstimateTokens(strings.Repeat("x", 100)))
+}
@@ -47,11 +47,9 @@ func runCommand(ctx context.Context, proj *project.Project, req mcp.CallToolRequ
 		// 确保工作目录是项目内的目录
 		wd = proj.NormalizePath(wd)
 		if node, err := proj.FindNode(wd); err == nil && node.IsDir {
-			// 转换为实际文件系统路径
-			workDir = node.Path
-			// 如果是相对路径，转换为绝对路径
-			if !strings.HasPrefix(workDir, "/") {
-				workDir = proj.GetRootPath() + "/" + workDir
+			// 转换为实际文件系统路径，工作区按所属根目录换算，工作区根节点使用工作区目录
+			if abs := proj.GetAbsolutePath(node.Path); abs != "" {
+				workDir = abs
 			}
 		} else {
 			return mcp.NewToolResultError(fmt.Sprintf("无效的工作目录: %s", wd)), nil
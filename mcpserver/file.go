@@ -16,6 +16,8 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		return
 	}
 	toolHandlers = make(map[string]func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error))
+	// 读写、遍历与搜索类工具在会话存在进行中的事务时作用于事务视图
+	txs := newTxManager(proj)
 	// fs_list
 	toolList := mcp.NewTool(
 		"fs_list",
@@ -27,7 +29,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithBoolean("includeHidden", mcp.Description("是否包含隐藏文件/目录，默认 false")),
 	)
 	hList := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsList(ctx, proj, req)
+		return fsList(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolList, hList)
 	toolHandlers["fs_list"] = hList
@@ -39,7 +41,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithString("path", mcp.Required(), mcp.Description("文件路径，如 /README.md")),
 	)
 	hRead := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsRead(ctx, proj, req)
+		return fsRead(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolRead, hRead)
 	toolHandlers["fs_read"] = hRead
@@ -52,7 +54,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithString("content", mcp.Required(), mcp.Description("要写入的文本内容")),
 	)
 	hWrite := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsWrite(ctx, proj, req)
+		return fsWrite(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolWrite, hWrite)
 	toolHandlers["fs_write"] = hWrite
@@ -65,7 +67,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithString("content", mcp.Description("初始文本内容，可选")),
 	)
 	hCreateFile := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsCreateFile(ctx, proj, req)
+		return fsCreateFile(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolCreateFile, hCreateFile)
 	toolHandlers["fs_create_file"] = hCreateFile
@@ -77,7 +79,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithString("path", mcp.Required(), mcp.Description("目录路径")),
 	)
 	hCreateDir := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsCreateDir(ctx, proj, req)
+		return fsCreateDir(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolCreateDir, hCreateDir)
 	toolHandlers["fs_create_dir"] = hCreateDir
@@ -89,11 +91,37 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithString("path", mcp.Required(), mcp.Description("要删除的路径")),
 	)
 	hDelete := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsDelete(ctx, proj, req)
+		return fsDelete(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolDelete, hDelete)
 	toolHandlers["fs_delete"] = hDelete
 
+	// fs_move
+	toolMove := mcp.NewTool(
+		"fs_move",
+		mcp.WithDescription("移动或重命名文件/目录（同时修改项目与磁盘），目标的父目录不存在时自动创建"),
+		mcp.WithString("src", mcp.Required(), mcp.Description("源路径，如 /src/old.go")),
+		mcp.WithString("dst", mcp.Required(), mcp.Description("目标路径，如 /src/new.go；目标已存在时报错")),
+	)
+	hMove := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsMove(ctx, txs.store(ctx), req)
+	}
+	s.AddTool(toolMove, hMove)
+	toolHandlers["fs_move"] = hMove
+
+	// fs_copy
+	toolCopy := mcp.NewTool(
+		"fs_copy",
+		mcp.WithDescription("复制文件或整个目录，目标的父目录不存在时自动创建"),
+		mcp.WithString("src", mcp.Required(), mcp.Description("源路径")),
+		mcp.WithString("dst", mcp.Required(), mcp.Description("目标路径；目标已存在时报错")),
+	)
+	hCopy := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsCopy(ctx, txs.store(ctx), req)
+	}
+	s.AddTool(toolCopy, hCopy)
+	toolHandlers["fs_copy"] = hCopy
+
 	// fs_tree
 	toolTree := mcp.NewTool(
 		"fs_tree",
@@ -104,7 +132,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithNumber("maxDepth", mcp.Description("最大深度（0 表示不限制）")),
 	)
 	hTree := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsTree(ctx, proj, req)
+		return fsTree(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolTree, hTree)
 	toolHandlers["fs_tree"] = hTree
@@ -112,7 +140,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 	// fs_search
 	toolSearch := mcp.NewTool(
 		"fs_search",
-		mcp.WithDescription("搜索名称与/或内容，支持正则、扩展名、深度、隐藏项等"),
+		mcp.WithDescription("搜索名称与/或内容，支持正则、扩展名、深度、隐藏项等；有内容条件时同时返回逐行匹配（路径、行、列、匹配文本与上下文）"),
 		mcp.WithString("path", mcp.Required(), mcp.Description("搜索根路径")),
 		mcp.WithString("nameContains", mcp.Description("名称包含的子串，可选")),
 		mcp.WithString("nameRegex", mcp.Description("名称正则，可选")),
@@ -125,13 +153,78 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithBoolean("caseInsensitive", mcp.Description("大小写不敏感，默认 true")),
 		mcp.WithBoolean("matchAny", mcp.Description("名称或内容任一匹配即命中，默认 false(与逻辑)")),
 		mcp.WithNumber("maxDepth", mcp.Description("最大深度，0 不限")),
+		mcp.WithNumber("context", mcp.Description("有内容条件时，每处匹配前后附带的上下文行数，默认 0")),
+		mcp.WithNumber("maxMatches", mcp.Description("最多返回的行级匹配数，默认 200，0 不限")),
 	)
 	hSearch := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsSearch(ctx, proj, req)
+		return fsSearch(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolSearch, hSearch)
 	toolHandlers["fs_search"] = hSearch
 
+	// fs_find
+	toolFind := mcp.NewTool(
+		"fs_find",
+		mcp.WithDescription("按模糊匹配查找文件路径（类似 fzf），适合只记得部分文件名的场景；返回按得分排序的路径、得分与匹配字符位置"),
+		mcp.WithString("query", mcp.Required(), mcp.Description("查询，如 prjsrch；空格分隔多个词，查询含大写字母时区分大小写")),
+		mcp.WithString("path", mcp.Description("查找的根路径，默认 /")),
+		mcp.WithString("extensions", mcp.Description("扩展名列表（逗号分隔，如: go,md 或 *）")),
+		mcp.WithBoolean("includeHidden", mcp.Description("包含隐藏项，默认 false")),
+		mcp.WithBoolean("includeDirs", mcp.Description("结果中包含目录，默认 false")),
+		mcp.WithNumber("maxDepth", mcp.Description("最大深度，0 不限")),
+		mcp.WithNumber("limit", mcp.Description("最多返回的结果数，默认 20")),
+	)
+	hFind := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsFind(ctx, txs.store(ctx), req)
+	}
+	s.AddTool(toolFind, hFind)
+	toolHandlers["fs_find"] = hFind
+
+	// go_symbols
+	toolSymbols := mcp.NewTool(
+		"go_symbols",
+		mcp.WithDescription("用 go/ast 结构化查询 Go 声明（函数、方法、结构体、接口、字段），可按种类、名称、接收者、参数与返回值类型、实现的接口过滤，返回签名与位置"),
+		mcp.WithString("path", mcp.Description("查询的根路径，默认 /")),
+		mcp.WithString("kinds", mcp.Description("种类列表（逗号分隔）：func,method,struct,interface,type,field；type 包含 struct 与 interface")),
+		mcp.WithString("name", mcp.Description("名称正则")),
+		mcp.WithString("receiver", mcp.Description("接收者或所属结构体类型名，*T 只匹配指针接收者")),
+		mcp.WithString("params", mcp.Description("参数中必须包含的类型，多个用 ; 分隔，如 context.Context;string；不含包名时忽略包限定符")),
+		mcp.WithString("results", mcp.Description("返回值中必须包含的类型，多个用 ; 分隔，如 error")),
+		mcp.WithString("implements", mcp.Description("实现了指定接口的类型，如 Store、io.Closer、error")),
+		mcp.WithString("hasMethod", mcp.Description("声明了指定名称方法的类型")),
+		mcp.WithString("package", mcp.Description("只匹配指定包名")),
+		mcp.WithBoolean("exportedOnly", mcp.Description("只匹配导出的声明，默认 false")),
+		mcp.WithNumber("limit", mcp.Description("最多返回的结果数，默认 100，0 不限")),
+	)
+	hSymbols := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return goSymbols(ctx, txs.store(ctx), req)
+	}
+	s.AddTool(toolSymbols, hSymbols)
+	toolHandlers["go_symbols"] = hSymbols
+
+	// fs_replace
+	toolReplace := mcp.NewTool(
+		"fs_replace",
+		mcp.WithDescription("在目录下批量查找并替换文件内容，返回每个文件的替换处数与统一格式差异；dryRun 为 true 时只预览不写入。所有文件在一次操作中写入，可用 fs_undo 整体撤销"),
+		mcp.WithString("path", mcp.Required(), mcp.Description("替换的根路径")),
+		mcp.WithString("pattern", mcp.Required(), mcp.Description("要查找的内容，默认按字面量匹配")),
+		mcp.WithString("replacement", mcp.Required(), mcp.Description("替换文本；regex 为 true 时支持 $1、${name} 引用捕获组")),
+		mcp.WithBoolean("regex", mcp.Description("pattern 是否为正则，默认 false")),
+		mcp.WithBoolean("caseInsensitive", mcp.Description("大小写不敏感，默认 false")),
+		mcp.WithString("nameContains", mcp.Description("只替换名称包含该子串的文件，可选")),
+		mcp.WithString("nameRegex", mcp.Description("只替换名称匹配该正则的文件，可选")),
+		mcp.WithString("extensions", mcp.Description("扩展名列表（逗号分隔，如: go,md 或 *）")),
+		mcp.WithBoolean("includeHidden", mcp.Description("包含隐藏项，默认 false")),
+		mcp.WithNumber("maxDepth", mcp.Description("最大深度，0 不限")),
+		mcp.WithNumber("context", mcp.Description("差异的上下文行数，默认 3")),
+		mcp.WithBoolean("dryRun", mcp.Description("只预览不写入，默认 false")),
+	)
+	hReplace := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsReplace(ctx, txs.store(ctx), req)
+	}
+	s.AddTool(toolReplace, hReplace)
+	toolHandlers["fs_replace"] = hReplace
+
 	// fs_stat
 	toolStat := mcp.NewTool(
 		"fs_stat",
@@ -140,7 +233,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithBoolean("hash", mcp.Description("是否计算文件/目录哈希，默认 false")),
 	)
 	hStat := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsStat(ctx, proj, req)
+		return fsStat(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolStat, hStat)
 	toolHandlers["fs_stat"] = hStat
@@ -152,7 +245,7 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 		mcp.WithString("path", mcp.Required(), mcp.Description("路径")),
 	)
 	hHash := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return fsHash(ctx, proj, req)
+		return fsHash(ctx, txs.store(ctx), req)
 	}
 	s.AddTool(toolHash, hHash)
 	toolHandlers["fs_hash"] = hHash
@@ -178,4 +271,74 @@ func RegisterFileTools(s *server.MCPServer, proj *project.Project) {
 	}
 	s.AddTool(toolSync, hSync)
 	toolHandlers["fs_sync"] = hSync
+
+	// fs_begin
+	toolBegin := mcp.NewTool(
+		"fs_begin",
+		mcp.WithDescription("开始事务：之后的文件读写、遍历与搜索（fs_read/fs_write/fs_create_file/fs_create_dir/fs_delete/fs_move/fs_copy/fs_replace/fs_list/fs_tree/fs_search/fs_find/fs_stat/fs_hash/go_symbols）只作用于事务视图，直到 fs_commit 或 fs_rollback"),
+	)
+	hBegin := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsBegin(ctx, txs, req)
+	}
+	s.AddTool(toolBegin, hBegin)
+	toolHandlers["fs_begin"] = hBegin
+
+	// fs_commit
+	toolCommit := mcp.NewTool(
+		"fs_commit",
+		mcp.WithDescription("提交事务：将事务中的所有修改一次性写入磁盘，失败时全部撤销"),
+	)
+	hCommit := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsCommit(ctx, txs, req)
+	}
+	s.AddTool(toolCommit, hCommit)
+	toolHandlers["fs_commit"] = hCommit
+
+	// fs_rollback
+	toolRollback := mcp.NewTool(
+		"fs_rollback",
+		mcp.WithDescription("回滚事务：丢弃事务中的所有修改"),
+	)
+	hRollback := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsRollback(ctx, txs, req)
+	}
+	s.AddTool(toolRollback, hRollback)
+	toolHandlers["fs_rollback"] = hRollback
+
+	// fs_undo
+	toolUndo := mcp.NewTool(
+		"fs_undo",
+		mcp.WithDescription("按操作日志撤销最近的文件变更（写入、创建、删除、移动、复制或事务提交）"),
+		mcp.WithNumber("steps", mcp.Description("撤销的步数，默认 1")),
+		mcp.WithBoolean("force", mcp.Description("文件在日志之外被修改时仍强制撤销，默认 false")),
+	)
+	hUndo := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsUndo(ctx, proj, req)
+	}
+	s.AddTool(toolUndo, hUndo)
+	toolHandlers["fs_undo"] = hUndo
+
+	// fs_redo
+	toolRedo := mcp.NewTool(
+		"fs_redo",
+		mcp.WithDescription("重做已撤销的文件变更"),
+		mcp.WithNumber("steps", mcp.Description("重做的步数，默认 1")),
+		mcp.WithBoolean("force", mcp.Description("文件在日志之外被修改时仍强制重做，默认 false")),
+	)
+	hRedo := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsRedo(ctx, proj, req)
+	}
+	s.AddTool(toolRedo, hRedo)
+	toolHandlers["fs_redo"] = hRedo
+
+	// fs_history
+	toolHistory := mcp.NewTool(
+		"fs_history",
+		mcp.WithDescription("列出操作日志，cursor 之前的条目为已应用，之后的可重做"),
+	)
+	hHistory := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return fsHistory(ctx, proj, req)
+	}
+	s.AddTool(toolHistory, hHistory)
+	toolHandlers["fs_history"] = hHistory
 }
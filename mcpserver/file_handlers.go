@@ -10,6 +10,7 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/sjzsdu/tong/helper"
 	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/gosearch"
 	prjsearch "github.com/sjzsdu/tong/project/search"
 	prjtree "github.com/sjzsdu/tong/project/tree"
 )
@@ -18,7 +19,7 @@ import (
 const timeLayout = "2006-01-02 15:04:05"
 
 // ensureParentDirs 确保文件路径的父目录在项目与磁盘中就绪（逐级创建缺失目录）
-func ensureParentDirs(proj *project.Project, path string) error {
+func ensureParentDirs(proj fileStore, path string) error {
 	p := proj.NormalizePath(path)
 	// 提取父目录路径
 	dir := "/"
@@ -44,7 +45,7 @@ func ensureParentDirs(proj *project.Project, path string) error {
 	return nil
 }
 
-func fsList(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsList(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	dir, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError("missing or invalid path parameter: required argument \"path\" not found"), nil
@@ -56,7 +57,7 @@ func fsList(ctx context.Context, proj *project.Project, req mcp.CallToolRequest)
 	includeHidden := req.GetBool("includeHidden", false)
 
 	dir = proj.NormalizePath(dir)
-	n, err := proj.FindNode(dir)
+	n, err := viewNode(proj, dir)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("目录不存在: %s", dir)), nil
 	}
@@ -95,7 +96,7 @@ func fsList(ctx context.Context, proj *project.Project, req mcp.CallToolRequest)
 	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"dir": dir, "items": items})), nil
 }
 
-func fsRead(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsRead(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError("missing or invalid path parameter: required argument \"path\" not found"), nil
@@ -108,14 +109,14 @@ func fsRead(ctx context.Context, proj *project.Project, req mcp.CallToolRequest)
 	if n.IsDir {
 		return mcp.NewToolResultError("不能读取目录"), nil
 	}
-	b, err := n.ReadContent()
+	b, err := proj.ReadFile(p)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 	return mcp.NewToolResultText(string(b)), nil
 }
 
-func fsWrite(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsWrite(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
@@ -141,7 +142,7 @@ func fsWrite(ctx context.Context, proj *project.Project, req mcp.CallToolRequest
 	return mcp.NewToolResultText(helper.ToJSON(res)), nil
 }
 
-func fsCreateFile(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsCreateFile(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
@@ -164,7 +165,7 @@ func fsCreateFile(ctx context.Context, proj *project.Project, req mcp.CallToolRe
 	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"path": p, "created": true})), nil
 }
 
-func fsCreateDir(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsCreateDir(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
@@ -176,7 +177,7 @@ func fsCreateDir(ctx context.Context, proj *project.Project, req mcp.CallToolReq
 	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"path": p, "created": true})), nil
 }
 
-func fsDelete(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsDelete(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
@@ -185,24 +186,59 @@ func fsDelete(ctx context.Context, proj *project.Project, req mcp.CallToolReques
 	if err := proj.DeleteNode(p); err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	abs := proj.GetAbsolutePath(strings.TrimPrefix(p, "/"))
-	if stat, statErr := os.Stat(abs); statErr == nil {
-		if stat.IsDir() {
-			_ = os.RemoveAll(abs)
-		} else {
-			_ = os.Remove(abs)
-		}
-	}
 	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"path": p, "deleted": true})), nil
 }
 
-func fsTree(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsMove(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	src, dst, err := transferPaths(proj, req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := proj.MoveNode(src, dst); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"src": src, "dst": dst, "moved": true})), nil
+}
+
+func fsCopy(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	src, dst, err := transferPaths(proj, req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := proj.CopyNode(src, dst); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"src": src, "dst": dst, "copied": true})), nil
+}
+
+// transferPaths 读取 fs_move/fs_copy 的源与目标路径，并确保目标的父目录存在
+func transferPaths(proj fileStore, req mcp.CallToolRequest) (string, string, error) {
+	src, err := req.RequireString("src")
+	if err != nil {
+		return "", "", err
+	}
+	dst, err := req.RequireString("dst")
+	if err != nil {
+		return "", "", err
+	}
+	src = proj.NormalizePath(src)
+	dst = proj.NormalizePath(dst)
+	if _, err := proj.FindNode(src); err != nil {
+		return "", "", fmt.Errorf("路径不存在: %s", src)
+	}
+	if err := ensureParentDirs(proj, dst); err != nil {
+		return "", "", err
+	}
+	return src, dst, nil
+}
+
+func fsTree(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 	p = proj.NormalizePath(p)
-	n, err := proj.FindNode(p)
+	n, err := viewNode(proj, p)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("路径不存在: %s", p)), nil
 	}
@@ -216,13 +252,13 @@ func fsTree(ctx context.Context, proj *project.Project, req mcp.CallToolRequest)
 	return mcp.NewToolResultText(txt), nil
 }
 
-func fsSearch(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsSearch(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 	p = proj.NormalizePath(p)
-	n, err := proj.FindNode(p)
+	n, err := viewNode(proj, p)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("路径不存在: %s", p)), nil
 	}
@@ -247,6 +283,11 @@ func fsSearch(ctx context.Context, proj *project.Project, req mcp.CallToolReques
 	opts.MatchAny = req.GetBool("matchAny", false)
 	opts.MaxDepth = req.GetInt("maxDepth", 0)
 
+	lines := req.GetInt("context", 0)
+	opts.BeforeContext = lines
+	opts.AfterContext = lines
+	opts.MaxMatches = req.GetInt("maxMatches", 200)
+
 	matched, err := prjsearch.Search(ctx, n, opts)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
@@ -255,16 +296,193 @@ func fsSearch(ctx context.Context, proj *project.Project, req mcp.CallToolReques
 	for _, m := range matched {
 		paths = append(paths, m.Path)
 	}
-	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"count": len(paths), "paths": paths})), nil
+	res := map[string]any{"count": len(paths), "paths": paths}
+	// 有内容条件时返回逐行匹配
+	if opts.ContentContains != "" || opts.ContentRegex != "" {
+		matches, err := prjsearch.LineMatches(ctx, matched, opts)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if matches == nil {
+			matches = []prjsearch.Match{}
+		}
+		res["matches"] = matches
+		res["truncated"] = opts.MaxMatches > 0 && len(matches) >= opts.MaxMatches
+	}
+	return mcp.NewToolResultText(helper.ToJSON(res)), nil
+}
+
+func fsFind(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	p := proj.NormalizePath(req.GetString("path", "/"))
+	n, err := viewNode(proj, p)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("路径不存在: %s", p)), nil
+	}
+
+	opts := prjsearch.DefaultSearchOptions()
+	extStr := req.GetString("extensions", "")
+	if strings.TrimSpace(extStr) != "" {
+		parts := strings.Split(extStr, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		opts.Extensions = parts
+	}
+	opts.IncludeHidden = req.GetBool("includeHidden", false)
+	opts.IncludeDirs = req.GetBool("includeDirs", false)
+	opts.MaxDepth = req.GetInt("maxDepth", 0)
+
+	results, err := prjsearch.FuzzyFind(ctx, n, query, opts, req.GetInt("limit", 20))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	// 返回以 / 开头的项目路径，与其他 fs_* 工具一致
+	for i := range results {
+		results[i].Path = "/" + results[i].Path
+	}
+	if results == nil {
+		results = []prjsearch.FuzzyMatch{}
+	}
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"count": len(results), "results": results})), nil
+}
+
+func goSymbols(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	p := proj.NormalizePath(req.GetString("path", "/"))
+	n, err := viewNode(proj, p)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("路径不存在: %s", p)), nil
+	}
+
+	splitList := func(s, sep string) []string {
+		var out []string
+		for _, part := range strings.Split(s, sep) {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		return out
+	}
+	q := &gosearch.Query{
+		Name:         req.GetString("name", ""),
+		Receiver:     req.GetString("receiver", ""),
+		Params:       splitList(req.GetString("params", ""), ";"),
+		Results:      splitList(req.GetString("results", ""), ";"),
+		Implements:   req.GetString("implements", ""),
+		HasMethod:    req.GetString("hasMethod", ""),
+		Package:      req.GetString("package", ""),
+		ExportedOnly: req.GetBool("exportedOnly", false),
+	}
+	for _, k := range splitList(req.GetString("kinds", ""), ",") {
+		q.Kinds = append(q.Kinds, gosearch.Kind(strings.ToLower(k)))
+	}
+
+	ix, err := gosearch.Build(ctx, n, nil)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	symbols, err := ix.Find(q)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	total := len(symbols)
+	if limit := req.GetInt("limit", 100); limit > 0 && len(symbols) > limit {
+		symbols = symbols[:limit]
+	}
+	if symbols == nil {
+		symbols = []*gosearch.Symbol{}
+	}
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{
+		"count":   total,
+		"symbols": symbols,
+		"failed":  ix.Failed,
+	})), nil
+}
+
+// fsReplace 在 store 中查找并替换文件（有进行中的事务时预览与写入都基于事务视图）
+// 直接写入项目时使用临时事务，所有文件一起提交并记为一条操作日志
+func fsReplace(ctx context.Context, store fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	p, err := req.RequireString("path")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	pattern, err := req.RequireString("pattern")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	replacement, err := req.RequireString("replacement")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	p = store.NormalizePath(p)
+	n, err := viewNode(store, p)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("路径不存在: %s", p)), nil
+	}
+
+	opts := prjsearch.DefaultSearchOptions()
+	opts.NameContains = req.GetString("nameContains", "")
+	opts.NameRegex = req.GetString("nameRegex", "")
+	extStr := req.GetString("extensions", "")
+	if strings.TrimSpace(extStr) != "" {
+		parts := strings.Split(extStr, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		opts.Extensions = parts
+	}
+	opts.IncludeHidden = req.GetBool("includeHidden", false)
+	opts.CaseInsensitive = req.GetBool("caseInsensitive", false)
+	opts.MaxDepth = req.GetInt("maxDepth", 0)
+
+	plan, err := prjsearch.PlanReplace(ctx, n, opts, &prjsearch.ReplaceOptions{
+		Pattern:     pattern,
+		Replacement: replacement,
+		Regex:       req.GetBool("regex", false),
+		Context:     req.GetInt("context", 3),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if plan == nil {
+		plan = []prjsearch.FileReplacement{}
+	}
+	total := 0
+	for _, r := range plan {
+		total += r.Count
+	}
+	dryRun := req.GetBool("dryRun", false)
+	res := map[string]any{"files": plan, "count": total, "dryRun": dryRun, "applied": false}
+	if dryRun || len(plan) == 0 {
+		return mcp.NewToolResultText(helper.ToJSON(res)), nil
+	}
+
+	if pj, ok := store.(*project.Project); ok {
+		tx := pj.Begin()
+		if _, err := prjsearch.ApplyReplace(tx, plan); err != nil {
+			tx.Rollback()
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err := tx.Commit(); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	} else if _, err := prjsearch.ApplyReplace(store, plan); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	res["applied"] = true
+	return mcp.NewToolResultText(helper.ToJSON(res)), nil
 }
 
-func fsStat(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsStat(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 	p = proj.NormalizePath(p)
-	n, err := proj.FindNode(p)
+	n, err := viewNode(proj, p)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("路径不存在: %s", p)), nil
 	}
@@ -273,13 +491,18 @@ func fsStat(ctx context.Context, proj *project.Project, req mcp.CallToolRequest)
 		info["size"] = n.Info.Size()
 		info["mode"] = n.Info.Mode().String()
 		info["modTime"] = n.Info.ModTime().Format(helper.TimeLayout)
-	} else {
-		abs := proj.GetAbsolutePath(strings.TrimPrefix(p, "/"))
+	} else if base, ok := proj.(*project.Project); ok {
+		abs := base.GetAbsolutePath(strings.TrimPrefix(p, "/"))
 		if st, e := os.Stat(abs); e == nil {
 			info["size"] = st.Size()
 			info["mode"] = st.Mode().String()
 			info["modTime"] = st.ModTime().Format(helper.TimeLayout)
 		}
+	} else if !n.IsDir {
+		// 事务中新建或修改的文件尚未落盘，大小取事务中的内容
+		if content, e := n.ReadContent(); e == nil {
+			info["size"] = len(content)
+		}
 	}
 	if req.GetBool("hash", false) {
 		h, herr := n.CalculateHash()
@@ -290,13 +513,13 @@ func fsStat(ctx context.Context, proj *project.Project, req mcp.CallToolRequest)
 	return mcp.NewToolResultText(helper.ToJSON(info)), nil
 }
 
-func fsHash(ctx context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func fsHash(ctx context.Context, proj fileStore, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	p, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 	p = proj.NormalizePath(p)
-	n, err := proj.FindNode(p)
+	n, err := viewNode(proj, p)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("路径不存在: %s", p)), nil
 	}
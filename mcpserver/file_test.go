@@ -197,6 +197,26 @@ func TestFSListTreeSearchStatHash(t *testing.T) {
 		t.Fatalf("expected search count >= 1")
 	}
 
+	// search by content returns line-level matches
+	res, err = hSearch(ctx, pkgmcp.NewToolCallRequest("fs_search", map[string]interface{}{"path": "/b", "contentContains": "mcp", "context": 1}))
+	if err != nil {
+		t.Fatalf("content search err: %v", err)
+	}
+	var contentObj struct {
+		Matches []struct {
+			Path   string `json:"path"`
+			Line   int    `json:"line"`
+			Column int    `json:"column"`
+			Text   string `json:"text"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal([]byte(textFromResult(t, res)), &contentObj); err != nil {
+		t.Fatalf("unmarshal content search: %v", err)
+	}
+	if len(contentObj.Matches) != 1 || contentObj.Matches[0].Path != "/b/readme.md" || contentObj.Matches[0].Line != 1 || contentObj.Matches[0].Column != 7 || contentObj.Matches[0].Text != "mcp" {
+		t.Fatalf("unexpected line matches: %+v", contentObj.Matches)
+	}
+
 	// stat + hash
 	hStat := getToolHandler(t, s, "fs_stat")
 	res, err = hStat(ctx, pkgmcp.NewToolCallRequest("fs_stat", map[string]interface{}{"path": "/b/readme.md", "hash": true}))
@@ -243,3 +263,380 @@ func TestFSSaveAndSync(t *testing.T) {
 		t.Fatalf("sync err: %v", err)
 	}
 }
+
+func TestFSTransaction(t *testing.T) {
+	ctx := context.Background()
+	s, proj := newTestServerAndProject(t)
+
+	call := func(name string, args map[string]interface{}) *mcppkg.CallToolResult {
+		t.Helper()
+		res, err := getToolHandler(t, s, name)(ctx, pkgmcp.NewToolCallRequest(name, args))
+		if err != nil {
+			t.Fatalf("%s err: %v", name, err)
+		}
+		if res.IsError {
+			t.Fatalf("%s failed: %s", name, textFromResult(t, res))
+		}
+		return res
+	}
+
+	call("fs_create_file", map[string]interface{}{"path": "/keep.txt", "content": "old"})
+
+	// commit
+	call("fs_begin", map[string]interface{}{})
+	call("fs_write", map[string]interface{}{"path": "/keep.txt", "content": "new"})
+	call("fs_create_file", map[string]interface{}{"path": "/tx/a.txt", "content": "a"})
+	if got := textFromResult(t, call("fs_read", map[string]interface{}{"path": "/keep.txt"})); got != "new" {
+		t.Fatalf("read inside transaction mismatch: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(proj.GetRootPath(), "tx", "a.txt")); err == nil {
+		t.Fatalf("file written to disk before commit")
+	}
+	if data, _ := os.ReadFile(filepath.Join(proj.GetRootPath(), "keep.txt")); string(data) != "old" {
+		t.Fatalf("disk changed before commit: %q", data)
+	}
+
+	// 遍历、搜索与元信息使用事务视图
+	if got := textFromResult(t, call("fs_list", map[string]interface{}{"path": "/tx"})); !strings.Contains(got, "/tx/a.txt") {
+		t.Fatalf("fs_list should see file created in transaction: %s", got)
+	}
+	if got := textFromResult(t, call("fs_tree", map[string]interface{}{"path": "/"})); !strings.Contains(got, "a.txt") {
+		t.Fatalf("fs_tree should see file created in transaction: %s", got)
+	}
+	if got := textFromResult(t, call("fs_search", map[string]interface{}{"path": "/", "contentContains": "new"})); !strings.Contains(got, "/keep.txt") {
+		t.Fatalf("fs_search should see content written in transaction: %s", got)
+	}
+	if got := textFromResult(t, call("fs_stat", map[string]interface{}{"path": "/tx/a.txt"})); !strings.Contains(got, `"size": 1`) {
+		t.Fatalf("fs_stat should report transaction content: %s", got)
+	}
+
+	res, err := getToolHandler(t, s, "fs_begin")(ctx, pkgmcp.NewToolCallRequest("fs_begin", map[string]interface{}{}))
+	if err != nil || !res.IsError {
+		t.Fatalf("nested fs_begin should fail")
+	}
+
+	call("fs_commit", map[string]interface{}{})
+	if data, _ := os.ReadFile(filepath.Join(proj.GetRootPath(), "keep.txt")); string(data) != "new" {
+		t.Fatalf("commit not persisted: %q", data)
+	}
+	if _, err := proj.FindNode("/tx/a.txt"); err != nil {
+		t.Fatalf("committed file missing from project: %v", err)
+	}
+
+	// rollback
+	call("fs_begin", map[string]interface{}{})
+	call("fs_delete", map[string]interface{}{"path": "/keep.txt"})
+	if got := textFromResult(t, call("fs_list", map[string]interface{}{"path": "/"})); strings.Contains(got, "/keep.txt") {
+		t.Fatalf("fs_list should not see file deleted in transaction: %s", got)
+	}
+	call("fs_rollback", map[string]interface{}{})
+	if _, err := os.Stat(filepath.Join(proj.GetRootPath(), "keep.txt")); err != nil {
+		t.Fatalf("rolled back delete removed file: %v", err)
+	}
+	if got := textFromResult(t, call("fs_read", map[string]interface{}{"path": "/keep.txt"})); got != "new" {
+		t.Fatalf("read after rollback mismatch: %q", got)
+	}
+
+	res, err = getToolHandler(t, s, "fs_commit")(ctx, pkgmcp.NewToolCallRequest("fs_commit", map[string]interface{}{}))
+	if err != nil || !res.IsError {
+		t.Fatalf("fs_commit without transaction should fail")
+	}
+}
+
+func TestFSUndoRedoHistory(t *testing.T) {
+	ctx := context.Background()
+	s, proj := newTestServerAndProject(t)
+
+	call := func(name string, args map[string]interface{}) *mcppkg.CallToolResult {
+		t.Helper()
+		res, err := getToolHandler(t, s, name)(ctx, pkgmcp.NewToolCallRequest(name, args))
+		if err != nil {
+			t.Fatalf("%s err: %v", name, err)
+		}
+		return res
+	}
+
+	// 未启用日志时返回错误
+	if res := call("fs_undo", map[string]interface{}{}); !res.IsError {
+		t.Fatalf("fs_undo without journal should fail")
+	}
+
+	oldDir := project.JournalDir
+	project.JournalDir = t.TempDir()
+	t.Cleanup(func() { project.JournalDir = oldDir })
+	if err := proj.EnableJournal(); err != nil {
+		t.Fatalf("enable journal: %v", err)
+	}
+
+	call("fs_create_file", map[string]interface{}{"path": "/a.txt", "content": "v1"})
+	call("fs_write", map[string]interface{}{"path": "/a.txt", "content": "v2"})
+	call("fs_delete", map[string]interface{}{"path": "/a.txt"})
+
+	var history struct {
+		Records []project.JournalRecord `json:"records"`
+		Cursor  int                     `json:"cursor"`
+	}
+	if err := json.Unmarshal([]byte(textFromResult(t, call("fs_history", map[string]interface{}{}))), &history); err != nil {
+		t.Fatalf("unmarshal history: %v", err)
+	}
+	if len(history.Records) != 3 || history.Cursor != 3 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+
+	// 步数超出范围时返回错误而不是 panic
+	for _, steps := range []int{0, -1, project.MaxJournalSteps + 1} {
+		if res := call("fs_undo", map[string]interface{}{"steps": steps}); !res.IsError {
+			t.Fatalf("fs_undo with steps %d should fail", steps)
+		}
+	}
+
+	diskPath := filepath.Join(proj.GetRootPath(), "a.txt")
+	if res := call("fs_undo", map[string]interface{}{"steps": 2}); res.IsError {
+		t.Fatalf("fs_undo failed: %s", textFromResult(t, res))
+	}
+	if data, _ := os.ReadFile(diskPath); string(data) != "v1" {
+		t.Fatalf("undo mismatch: %q", data)
+	}
+
+	if res := call("fs_redo", map[string]interface{}{}); res.IsError {
+		t.Fatalf("fs_redo failed: %s", textFromResult(t, res))
+	}
+	if got := textFromResult(t, call("fs_read", map[string]interface{}{"path": "/a.txt"})); got != "v2" {
+		t.Fatalf("redo mismatch: %q", got)
+	}
+}
+
+func TestFSMoveCopy(t *testing.T) {
+	ctx := context.Background()
+	s, proj := newTestServerAndProject(t)
+
+	call := func(name string, args map[string]interface{}) *mcppkg.CallToolResult {
+		t.Helper()
+		res, err := getToolHandler(t, s, name)(ctx, pkgmcp.NewToolCallRequest(name, args))
+		if err != nil {
+			t.Fatalf("%s err: %v", name, err)
+		}
+		return res
+	}
+	root := proj.GetRootPath()
+
+	call("fs_create_file", map[string]interface{}{"path": "/a/old.txt", "content": "hi"})
+	if res := call("fs_move", map[string]interface{}{"src": "/a/old.txt", "dst": "/b/c/new.txt"}); res.IsError {
+		t.Fatalf("fs_move failed: %s", textFromResult(t, res))
+	}
+	if _, err := proj.FindNode("/a/old.txt"); err == nil {
+		t.Fatalf("source still exists after move")
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "b", "c", "new.txt")); string(data) != "hi" {
+		t.Fatalf("moved file mismatch: %q", data)
+	}
+
+	if res := call("fs_copy", map[string]interface{}{"src": "/b", "dst": "/copy/b"}); res.IsError {
+		t.Fatalf("fs_copy failed: %s", textFromResult(t, res))
+	}
+	if got := textFromResult(t, call("fs_read", map[string]interface{}{"path": "/copy/b/c/new.txt"})); got != "hi" {
+		t.Fatalf("copied file mismatch: %q", got)
+	}
+	if res := call("fs_copy", map[string]interface{}{"src": "/b", "dst": "/copy/b"}); !res.IsError {
+		t.Fatalf("fs_copy onto existing path should fail")
+	}
+	if res := call("fs_move", map[string]interface{}{"src": "/missing", "dst": "/x"}); !res.IsError {
+		t.Fatalf("fs_move of missing path should fail")
+	}
+
+	// 事务中的移动在提交时落盘
+	call("fs_begin", map[string]interface{}{})
+	if res := call("fs_move", map[string]interface{}{"src": "/copy", "dst": "/moved"}); res.IsError {
+		t.Fatalf("fs_move in transaction failed: %s", textFromResult(t, res))
+	}
+	if _, err := os.Stat(filepath.Join(root, "copy")); err != nil {
+		t.Fatalf("move applied before commit: %v", err)
+	}
+	call("fs_commit", map[string]interface{}{})
+	if _, err := os.Stat(filepath.Join(root, "copy")); err == nil {
+		t.Fatalf("source directory still on disk after commit")
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "moved", "b", "c", "new.txt")); string(data) != "hi" {
+		t.Fatalf("committed move mismatch: %q", data)
+	}
+}
+
+func TestFSReplace(t *testing.T) {
+	ctx := context.Background()
+	s, proj := newTestServerAndProject(t)
+
+	call := func(name string, args map[string]interface{}) *mcppkg.CallToolResult {
+		t.Helper()
+		res, err := getToolHandler(t, s, name)(ctx, pkgmcp.NewToolCallRequest(name, args))
+		if err != nil {
+			t.Fatalf("%s err: %v", name, err)
+		}
+		return res
+	}
+	root := proj.GetRootPath()
+
+	call("fs_create_file", map[string]interface{}{"path": "/src/a.go", "content": "var version = \"v1.2\"\n"})
+	call("fs_create_file", map[string]interface{}{"path": "/docs/a.md", "content": "release v1.2\n"})
+
+	var out struct {
+		Files []struct {
+			Path  string `json:"path"`
+			Count int    `json:"count"`
+			Diff  string `json:"diff"`
+		} `json:"files"`
+		Count   int  `json:"count"`
+		Applied bool `json:"applied"`
+	}
+	args := map[string]interface{}{"path": "/", "pattern": `v(\d+)\.(\d+)`, "replacement": "v$1.3", "regex": true, "dryRun": true}
+	res := call("fs_replace", args)
+	if res.IsError {
+		t.Fatalf("fs_replace dry run failed: %s", textFromResult(t, res))
+	}
+	if err := json.Unmarshal([]byte(textFromResult(t, res)), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(out.Files) != 2 || out.Count != 2 || out.Applied || !strings.Contains(out.Files[1].Diff, "+var version = \"v1.3\"") {
+		t.Fatalf("unexpected dry run result: %+v", out)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "src", "a.go")); string(data) != "var version = \"v1.2\"\n" {
+		t.Fatalf("dry run modified file: %q", data)
+	}
+
+	args["dryRun"] = false
+	args["extensions"] = "go"
+	if res := call("fs_replace", args); res.IsError {
+		t.Fatalf("fs_replace failed: %s", textFromResult(t, res))
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "src", "a.go")); string(data) != "var version = \"v1.3\"\n" {
+		t.Fatalf("replace mismatch: %q", data)
+	}
+	if got := textFromResult(t, call("fs_read", map[string]interface{}{"path": "/docs/a.md"})); got != "release v1.2\n" {
+		t.Fatalf("filtered file should not change: %q", got)
+	}
+
+	// 事务中的替换在提交时落盘
+	call("fs_begin", map[string]interface{}{})
+	if res := call("fs_replace", map[string]interface{}{"path": "/docs", "pattern": "release", "replacement": "$0 notes"}); res.IsError {
+		t.Fatalf("fs_replace in transaction failed: %s", textFromResult(t, res))
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "docs", "a.md")); string(data) != "release v1.2\n" {
+		t.Fatalf("replace applied before commit: %q", data)
+	}
+	// 事务中修改或新建的文件按事务中的内容预览与替换
+	call("fs_write", map[string]interface{}{"path": "/src/a.go", "content": "var version = \"v2.0\"\n"})
+	call("fs_create_file", map[string]interface{}{"path": "/src/b.go", "content": "var next = \"v2.0\"\n"})
+	if res := call("fs_replace", map[string]interface{}{"path": "/src", "pattern": "v2.0", "replacement": "v2.1"}); res.IsError {
+		t.Fatalf("fs_replace on transaction content failed: %s", textFromResult(t, res))
+	}
+	call("fs_commit", map[string]interface{}{})
+	if data, _ := os.ReadFile(filepath.Join(root, "docs", "a.md")); string(data) != "$0 notes v1.2\n" {
+		t.Fatalf("committed replace mismatch: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "src", "a.go")); string(data) != "var version = \"v2.1\"\n" {
+		t.Fatalf("replace of edited file mismatch: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "src", "b.go")); string(data) != "var next = \"v2.1\"\n" {
+		t.Fatalf("replace of created file mismatch: %q", data)
+	}
+
+	if res := call("fs_replace", map[string]interface{}{"path": "/", "pattern": "(", "replacement": "x", "regex": true}); !res.IsError {
+		t.Fatalf("invalid regex should fail")
+	}
+}
+
+func TestFSFind(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestServerAndProject(t)
+
+	call := func(name string, args map[string]interface{}) *mcppkg.CallToolResult {
+		t.Helper()
+		res, err := getToolHandler(t, s, name)(ctx, pkgmcp.NewToolCallRequest(name, args))
+		if err != nil {
+			t.Fatalf("%s err: %v", name, err)
+		}
+		return res
+	}
+	call("fs_create_file", map[string]interface{}{"path": "/project/search/search.go", "content": "package search\n"})
+	call("fs_create_file", map[string]interface{}{"path": "/project/search/README.md", "content": "# search\n"})
+	call("fs_create_file", map[string]interface{}{"path": "/docs/prose.md", "content": "x\n"})
+
+	var out struct {
+		Count   int `json:"count"`
+		Results []struct {
+			Path      string `json:"path"`
+			Score     int    `json:"score"`
+			Positions []int  `json:"positions"`
+		} `json:"results"`
+	}
+	res := call("fs_find", map[string]interface{}{"query": "prjsrch"})
+	if res.IsError {
+		t.Fatalf("fs_find failed: %s", textFromResult(t, res))
+	}
+	if err := json.Unmarshal([]byte(textFromResult(t, res)), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Count != 2 || out.Results[0].Path != "/project/search/search.go" || len(out.Results[0].Positions) != 7 {
+		t.Fatalf("unexpected fs_find result: %+v", out)
+	}
+
+	res = call("fs_find", map[string]interface{}{"query": "search", "extensions": "md", "limit": 1})
+	if err := json.Unmarshal([]byte(textFromResult(t, res)), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Count != 1 || out.Results[0].Path != "/project/search/README.md" {
+		t.Fatalf("unexpected filtered result: %+v", out)
+	}
+
+	if res := call("fs_find", map[string]interface{}{}); !res.IsError {
+		t.Fatalf("missing query should fail")
+	}
+}
+
+func TestGoSymbols(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestServerAndProject(t)
+
+	call := func(name string, args map[string]interface{}) *mcppkg.CallToolResult {
+		t.Helper()
+		res, err := getToolHandler(t, s, name)(ctx, pkgmcp.NewToolCallRequest(name, args))
+		if err != nil {
+			t.Fatalf("%s err: %v", name, err)
+		}
+		return res
+	}
+	src := "package store\n\nimport \"context\"\n\ntype Store struct{}\n\nfunc (s *Store) Get(ctx context.Context, key string) ([]byte, error) { return nil, nil }\n\nfunc (s *Store) Close() error { return nil }\n\nfunc helper(n int) int { return n }\n"
+	call("fs_create_file", map[string]interface{}{"path": "/store/store.go", "content": src})
+
+	var out struct {
+		Count   int `json:"count"`
+		Symbols []struct {
+			Name      string `json:"name"`
+			Kind      string `json:"kind"`
+			Signature string `json:"signature"`
+			Line      int    `json:"line"`
+		} `json:"symbols"`
+	}
+	res := call("go_symbols", map[string]interface{}{"params": "context.Context", "results": "error"})
+	if res.IsError {
+		t.Fatalf("go_symbols failed: %s", textFromResult(t, res))
+	}
+	if err := json.Unmarshal([]byte(textFromResult(t, res)), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Count != 1 || out.Symbols[0].Name != "Get" || out.Symbols[0].Line != 7 ||
+		out.Symbols[0].Signature != "func (s *Store) Get(ctx context.Context, key string) ([]byte, error)" {
+		t.Fatalf("unexpected go_symbols result: %+v", out)
+	}
+
+	res = call("go_symbols", map[string]interface{}{"implements": "io.Closer", "path": "/store"})
+	if err := json.Unmarshal([]byte(textFromResult(t, res)), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Count != 1 || out.Symbols[0].Name != "Store" || out.Symbols[0].Kind != "struct" {
+		t.Fatalf("unexpected implements result: %+v", out)
+	}
+
+	if res := call("go_symbols", map[string]interface{}{"implements": "Missing"}); !res.IsError {
+		t.Fatalf("unknown interface should fail")
+	}
+}
@@ -0,0 +1,53 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project"
+)
+
+func fsUndo(_ context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return journalStep(proj, req, proj.Undo)
+}
+
+func fsRedo(_ context.Context, proj *project.Project, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return journalStep(proj, req, proj.Redo)
+}
+
+// journalStep 按 steps 参数多次执行撤销或重做，返回已执行的日志条目
+func journalStep(proj *project.Project, req mcp.CallToolRequest, step func(force bool) (*project.JournalRecord, error)) (*mcp.CallToolResult, error) {
+	steps := req.GetInt("steps", 1)
+	if steps < 1 || steps > project.MaxJournalSteps {
+		return mcp.NewToolResultError(fmt.Sprintf("steps 必须在 1 到 %d 之间", project.MaxJournalSteps)), nil
+	}
+	force := req.GetBool("force", false)
+
+	applied := make([]project.JournalRecord, 0, steps)
+	for i := 0; i < steps; i++ {
+		record, err := step(force)
+		if errors.Is(err, project.ErrNothingToUndo) || errors.Is(err, project.ErrNothingToRedo) {
+			if len(applied) == 0 {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			break
+		}
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		applied = append(applied, *record)
+	}
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"applied": applied})), nil
+}
+
+func fsHistory(_ context.Context, proj *project.Project, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	journal := proj.Journal()
+	if journal == nil {
+		return mcp.NewToolResultError(project.ErrJournalDisabled.Error()), nil
+	}
+	records, cursor := journal.History()
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"records": records, "cursor": cursor})), nil
+}
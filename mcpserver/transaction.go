@@ -0,0 +1,133 @@
+package mcpserver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project"
+)
+
+// fileStore fs_* 工具使用的文件操作接口，由 project.Project 与 project.Transaction 实现
+type fileStore interface {
+	NormalizePath(path string) string
+	FindNode(path string) (*project.Node, error)
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, content []byte) error
+	CreateFileNode(path string) error
+	CreateFileWithContent(path string, content []byte) error
+	CreateDir(path string) error
+	DeleteNode(path string) error
+	MoveNode(src, dst string) error
+	CopyNode(src, dst string) error
+}
+
+// txManager 按 MCP 会话管理进行中的事务
+type txManager struct {
+	proj   *project.Project
+	mu     sync.Mutex
+	active map[string]*project.Transaction
+}
+
+// newTxManager 创建事务管理器
+func newTxManager(proj *project.Project) *txManager {
+	return &txManager{
+		proj:   proj,
+		active: make(map[string]*project.Transaction),
+	}
+}
+
+// sessionKey 返回当前请求所属会话的标识，无会话（如 STDIO 之外的直接调用）时为空字符串
+func sessionKey(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// store 返回当前会话应使用的文件操作对象：有进行中的事务时为事务，否则为项目本身
+func (m *txManager) store(ctx context.Context) fileStore {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if tx, ok := m.active[sessionKey(ctx)]; ok {
+		return tx
+	}
+	return m.proj
+}
+
+// viewNode 返回会话视图中 path 处的节点：有进行中的事务时为事务视图中子树的副本，否则为项目树中的节点
+func viewNode(store fileStore, path string) (*project.Node, error) {
+	if tx, ok := store.(*project.Transaction); ok {
+		return tx.View(path)
+	}
+	return store.FindNode(path)
+}
+
+// begin 为当前会话开始事务
+func (m *txManager) begin(ctx context.Context) (*project.Transaction, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := sessionKey(ctx)
+	if _, exists := m.active[key]; exists {
+		return nil, false
+	}
+	tx := m.proj.Begin()
+	m.active[key] = tx
+	return tx, true
+}
+
+// take 取出并移除当前会话的事务
+func (m *txManager) take(ctx context.Context) (*project.Transaction, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := sessionKey(ctx)
+	tx, ok := m.active[key]
+	if ok {
+		delete(m.active, key)
+	}
+	return tx, ok
+}
+
+// restore 提交失败时将事务放回，允许调用方修正后重试或回滚
+func (m *txManager) restore(ctx context.Context, tx *project.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active[sessionKey(ctx)] = tx
+}
+
+func fsBegin(ctx context.Context, txs *txManager, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if _, ok := txs.begin(ctx); !ok {
+		return mcp.NewToolResultError("当前会话已有进行中的事务，请先 fs_commit 或 fs_rollback"), nil
+	}
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"transaction": "started"})), nil
+}
+
+func fsCommit(ctx context.Context, txs *txManager, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tx, ok := txs.take(ctx)
+	if !ok {
+		return mcp.NewToolResultError("当前会话没有进行中的事务"), nil
+	}
+	changes := tx.Changes()
+	if err := tx.Commit(); err != nil {
+		txs.restore(ctx, tx)
+		return mcp.NewToolResultError("提交失败，磁盘已恢复原状，事务仍可继续或回滚: " + err.Error()), nil
+	}
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"committed": true, "changes": changes})), nil
+}
+
+func fsRollback(ctx context.Context, txs *txManager, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tx, ok := txs.take(ctx)
+	if !ok {
+		return mcp.NewToolResultError("当前会话没有进行中的事务"), nil
+	}
+	changes := tx.Changes()
+	if err := tx.Rollback(); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(helper.ToJSON(map[string]any{"rolledBack": true, "discarded": len(changes)})), nil
+}
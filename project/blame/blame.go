@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/query"
 )
 
 // 粒度定义
@@ -39,7 +40,8 @@ type Options struct {
 	MaxWorkers    int
 	Extensions    []string
 	IncludeHidden bool
-	UseEmail      bool // true: 按邮箱聚合；false: 按作者名聚合
+	UseEmail      bool         // true: 按邮箱聚合；false: 按作者名聚合
+	Where         *query.Query // 节点查询条件，nil 不过滤
 }
 
 // DefaultOptions 默认配置
@@ -96,6 +98,10 @@ func Analyze(ctx context.Context, root *project.Node, opts *Options) (*Report, e
 		if !allowByExt(n.Name, opts.Extensions) {
 			return nil, nil
 		}
+		// 查询条件
+		if !opts.Where.Match(n) {
+			return nil, nil
+		}
 		// 限定在子树（root 下自然成立，这里冗余校验）
 		if subtreePrefix != "" && !isUnder(strings.TrimPrefix(n.Path, "/"), subtreePrefix) {
 			return nil, nil
@@ -3,7 +3,6 @@ package project
 import (
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/sjzsdu/tong/helper"
 )
@@ -60,16 +59,36 @@ func BuildProjectTree(targetPath string, options helper.WalkDirOptions) (*Projec
 	}
 	doc.nodes["/"] = doc.root
 
-	gitignoreRules := make(map[string][]string)
 	targetPath = filepath.Clean(targetPath)
 
-	// 添加一个选项，控制是否立即加载文件内容
-	loadContent := options.LoadContent
+	// 过滤规则保存在项目中，Watch 增量更新时复用同一套规则
+	filter := newPathFilter(targetPath, options)
+	filter.loadDir(targetPath)
+	doc.filter = filter
 
-	// 标记是否处理过任何文件或目录（除了根目录）
+	// 遍历目录构建节点，processedAny 标记是否处理过任何文件或目录（除了根目录）
+	processedAny, err := doc.walkDisk(targetPath, filter, options.LoadContent)
+	if err != nil {
+		return nil, err
+	}
+
+	// 如果没有处理任何文件或目录（除了根目录），则清空根节点的子节点
+	if !processedAny {
+		doc.root.mu.Lock()
+		doc.root.Children = make(map[string]*Node)
+		doc.root.mu.Unlock()
+	}
+
+	return doc, nil
+}
+
+// walkDisk 从 start 开始遍历磁盘，把未被过滤的文件与目录加入项目树
+// 返回值 processedAny 表示是否遇到过根目录以外的路径
+func (p *Project) walkDisk(start string, filter *pathFilter, loadContent bool) (bool, error) {
+	targetPath := filter.rootPath
 	processedAny := false
 
-	err := filepath.Walk(targetPath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -82,40 +101,21 @@ func BuildProjectTree(targetPath string, options helper.WalkDirOptions) (*Projec
 		// 标记已处理文件或目录
 		processedAny = true
 
-		// 检查是否是需要排除的目录
-		if info.IsDir() {
-			name := info.Name()
-			// 排除 . 和 .. 目录
-			if name == "." || name == ".." {
-				return nil
-			}
-
-			// 对于非根目录的情况才检查排除规则
-			if path != targetPath && excludedDirs[name] {
-				return filepath.SkipDir
-			}
-
-			// 处理 .gitignore 规则
-			if !options.DisableGitIgnore {
-				rules, serr := helper.ReadGitignore(path)
-				if serr == nil && rules != nil {
-					gitignoreRules[path] = rules
-				}
-			}
+		// 排除 . 和 .. 目录
+		if info.IsDir() && (info.Name() == "." || info.Name() == "..") {
+			return nil
 		}
 
-		// 处理 .gitignore 规则
-		if !options.DisableGitIgnore {
-			excluded, excludeErr := helper.IsPathExcludedByGitignore(path, targetPath, gitignoreRules)
-			if excludeErr != nil {
-				return excludeErr
-			}
-			if excluded {
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
+		// 检查排除目录、.gitignore、扩展名与自定义排除规则
+		skip, skipDir, skipErr := filter.skip(path, info)
+		if skipErr != nil {
+			return skipErr
+		}
+		if skipDir {
+			return filepath.SkipDir
+		}
+		if skip {
+			return nil
 		}
 
 		// 获取相对路径
@@ -131,56 +131,10 @@ func BuildProjectTree(targetPath string, options helper.WalkDirOptions) (*Projec
 		}
 
 		if info.IsDir() {
-			if info.Name() == "." {
-				return nil
-			}
+			// 处理子目录的 .gitignore 规则
+			filter.loadDir(path)
 			// 创建目录节点
-			return doc.CreateDir(projPath)
-		}
-
-		// 检查文件扩展名
-		if len(options.Extensions) > 0 {
-			ext := filepath.Ext(path)
-			if len(ext) > 0 {
-				ext = ext[1:] // 移除开头的点
-			}
-			if !helper.StringSliceContains(options.Extensions, ext) && !helper.StringSliceContains(options.Extensions, "*") {
-				return nil
-			}
-		}
-
-		// 检查排除规则（根据 DisableGitIgnore 参数决定是否检查 .gitignore 规则）
-		if options.DisableGitIgnore {
-			// 只检查自定义排除规则，不检查 .gitignore 规则
-			for _, pattern := range options.Excludes {
-				// 使用完整路径进行匹配
-				matched, err := filepath.Match(pattern, filepath.Base(path))
-				if err == nil && matched {
-					return nil
-				}
-
-				// 检查相对路径
-				relPath, err := filepath.Rel(targetPath, path)
-				if err == nil {
-					matched, err = filepath.Match(pattern, relPath)
-					if err == nil && matched {
-						return nil
-					}
-				}
-
-				// 对于包含 ** 的模式，需要特殊处理
-				if strings.Contains(pattern, "**") {
-					pattern = strings.ReplaceAll(pattern, "**", "*")
-					if strings.Contains(path, pattern) {
-						return nil
-					}
-				}
-			}
-		} else {
-			// 检查自定义排除规则和 .gitignore 规则
-			if helper.IsPathExcluded(path, options.Excludes, targetPath) {
-				return nil
-			}
+			return p.CreateDir(projPath)
 		}
 
 		// 创建文件节点，根据选项决定是否立即加载内容
@@ -190,23 +144,12 @@ func BuildProjectTree(targetPath string, options helper.WalkDirOptions) (*Projec
 			if err != nil {
 				return nil // 跳过无法读取的文件
 			}
-			return doc.CreateFileWithContent(projPath, content)
+			return p.CreateFileWithContent(projPath, content)
 		} else {
 			// 只创建节点，不加载内容
-			return doc.CreateFileNode(projPath)
+			return p.CreateFileNode(projPath)
 		}
 	})
 
-	if err != nil {
-		return nil, err
-	}
-
-	// 如果没有处理任何文件或目录（除了根目录），则清空根节点的子节点
-	if !processedAny {
-		doc.root.mu.Lock()
-		doc.root.Children = make(map[string]*Node)
-		doc.root.mu.Unlock()
-	}
-
-	return doc, nil
+	return processedAny, err
 }
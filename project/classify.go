@@ -0,0 +1,77 @@
+package project
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sjzsdu/tong/helper/filekind"
+)
+
+// Kind 返回文件的分类信息（语言、二进制、生成代码、第三方依赖与 Git LFS 指针），目录返回零值
+// 只读取文件开头用于探测，结果缓存在节点上，磁盘文件的大小或修改时间变化、或内容被修改后重新识别
+func (n *Node) Kind() filekind.Kind {
+	if n.IsDir {
+		return filekind.Kind{}
+	}
+
+	stamp, onDisk := n.diskStamp()
+	n.mu.RLock()
+	if n.kind != nil && (!onDisk || n.kindStamp.equal(stamp)) {
+		kind := *n.kind
+		n.mu.RUnlock()
+		return kind
+	}
+	n.mu.RUnlock()
+
+	kind := filekind.Detect(n.Path, n.head())
+	n.mu.Lock()
+	n.kind = &kind
+	n.kindStamp = stamp
+	n.mu.Unlock()
+	return kind
+}
+
+// MatchKind 判断文件是否满足分类过滤条件，目录总是满足；过滤器为零值时不读取文件内容
+func (n *Node) MatchKind(filter filekind.Filter) bool {
+	if n.IsDir || filter.IsZero() {
+		return true
+	}
+	return filter.Match(n.Kind())
+}
+
+// head 返回文件开头用于分类的内容：已加载的内容直接使用，否则只读取前 filekind.SniffLen 字节
+func (n *Node) head() []byte {
+	n.mu.RLock()
+	if n.ContentLoaded && n.Content != nil {
+		content := n.Content
+		n.mu.RUnlock()
+		return content
+	}
+	n.mu.RUnlock()
+
+	p := n.GetProject()
+	if p == nil || n.Path == "" || n.Path == "/" {
+		return nil
+	}
+
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	if p.source != nil {
+		r, err = p.source.Open(strings.TrimPrefix(n.Path, "/"))
+	} else {
+		r, err = os.Open(p.fsPath(n.Path))
+	}
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+
+	head, err := filekind.ReadHead(r)
+	if err != nil {
+		return nil
+	}
+	return head
+}
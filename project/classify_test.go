@@ -0,0 +1,87 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/helper/filekind"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeKind(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":           "package main",
+		"api/types.pb.go":   "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api",
+		"vendor/lib/lib.go": "package lib",
+		"scripts/deploy":    "#!/usr/bin/env bash\necho deploy",
+		"assets/logo.png":   "\x89PNG\r\n\x1a\n\x00",
+		"assets/model.onnx": "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 10\n",
+		"docs/guide.md":     "# Guide",
+		"third_party/x.js":  "module.exports = 1",
+	})
+	proj, err := BuildProjectTree(dir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+
+	kindOf := func(path string) filekind.Kind {
+		node, err := proj.FindNode(path)
+		require.NoError(t, err)
+		return node.Kind()
+	}
+	assert.Equal(t, filekind.Kind{Language: "go"}, kindOf("/main.go"))
+	assert.Equal(t, filekind.Kind{Language: "go", Generated: true}, kindOf("/api/types.pb.go"))
+	assert.Equal(t, filekind.Kind{Language: "go", Vendored: true}, kindOf("/vendor/lib/lib.go"))
+	assert.Equal(t, "bash", kindOf("/scripts/deploy").Language)
+	assert.True(t, kindOf("/assets/logo.png").Binary)
+	assert.True(t, kindOf("/assets/model.onnx").LFSPointer)
+	assert.Equal(t, filekind.Kind{}, proj.Root().Kind())
+
+	// 只读取开头，不加载整个文件
+	node, err := proj.FindNode("/main.go")
+	require.NoError(t, err)
+	assert.False(t, node.ContentLoaded)
+
+	// 修改内容后重新识别
+	require.NoError(t, proj.WriteFile("/scripts/deploy", []byte("#!/usr/bin/env python3\nprint(1)")))
+	assert.Equal(t, "python", kindOf("/scripts/deploy").Language)
+
+	// 磁盘文件变化后重新识别
+	docPath := filepath.Join(dir, "docs", "guide.md")
+	require.NoError(t, os.WriteFile(docPath, []byte("<!-- vim: set ft=html : -->"), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(docPath, later, later))
+	assert.Equal(t, "html", kindOf("/docs/guide.md").Language)
+
+	filter := filekind.Filter{Languages: []string{"golang"}, SkipGenerated: true, SkipVendored: true}
+	var matched []string
+	for _, path := range []string{"/main.go", "/api/types.pb.go", "/vendor/lib/lib.go", "/docs/guide.md", "/third_party/x.js"} {
+		node, err := proj.FindNode(path)
+		require.NoError(t, err)
+		if node.MatchKind(filter) {
+			matched = append(matched, path)
+		}
+	}
+	assert.Equal(t, []string{"/main.go"}, matched)
+	assert.True(t, proj.Root().MatchKind(filter))
+}
+
+func TestNodeKindFromFS(t *testing.T) {
+	proj, err := BuildProjectTreeFromFS(fstest.MapFS{
+		"tools/run": {Data: []byte("#!/usr/bin/env node\n")},
+		"data.bin":  {Data: []byte{0, 1, 2}},
+	}, helper.WalkDirOptions{})
+	require.NoError(t, err)
+
+	node, err := proj.FindNode("/tools/run")
+	require.NoError(t, err)
+	assert.Equal(t, "javascript", node.Kind().Language)
+	node, err = proj.FindNode("/data.bin")
+	require.NoError(t, err)
+	assert.True(t, node.Kind().Binary)
+}
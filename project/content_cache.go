@@ -0,0 +1,163 @@
+package project
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ContentCache 项目级文件内容缓存，按字节预算以 LRU 方式卸载未修改节点的 Content
+// 已修改（未保存）的节点不会被卸载，也不计入预算
+type ContentCache struct {
+	mu      sync.Mutex
+	budget  int64
+	used    int64
+	lru     *list.List // 队首为最近使用
+	entries map[*Node]*list.Element
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// cacheEntry LRU 链表中的一项
+type cacheEntry struct {
+	node *Node
+	size int64
+}
+
+// ContentCacheStats 内容缓存的统计信息
+type ContentCacheStats struct {
+	Budget    int64 `json:"budget"`
+	Used      int64 `json:"used"`
+	Entries   int   `json:"entries"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
+
+// NewContentCache 创建字节预算为 budget 的内容缓存
+func NewContentCache(budget int64) *ContentCache {
+	return &ContentCache{
+		budget:  budget,
+		lru:     list.New(),
+		entries: make(map[*Node]*list.Element),
+	}
+}
+
+// SetContentBudget 为项目设置内容缓存的字节预算，budget <= 0 时关闭缓存
+// 关闭缓存不会卸载已加载的内容
+func (p *Project) SetContentBudget(budget int64) {
+	if budget <= 0 {
+		p.contentCache.Store(nil)
+		return
+	}
+	if cache := p.contentCache.Load(); cache != nil {
+		cache.SetBudget(budget)
+		return
+	}
+	p.contentCache.Store(NewContentCache(budget))
+}
+
+// ContentCache 返回项目的内容缓存，未设置预算时返回 nil
+func (p *Project) ContentCache() *ContentCache {
+	if p == nil {
+		return nil
+	}
+	return p.contentCache.Load()
+}
+
+// SetBudget 调整字节预算，超出部分立即卸载
+func (c *ContentCache) SetBudget(budget int64) {
+	c.mu.Lock()
+	c.budget = budget
+	victims := c.shrink(nil)
+	c.mu.Unlock()
+	c.evict(victims)
+}
+
+// Stats 返回缓存的统计信息
+func (c *ContentCache) Stats() ContentCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ContentCacheStats{
+		Budget:    c.budget,
+		Used:      c.used,
+		Entries:   len(c.entries),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// access 记录一次内容读取，loaded 为 true 表示未命中、内容刚从磁盘读取
+// 调用方不能持有任何节点锁，否则卸载其他节点时可能死锁
+func (c *ContentCache) access(n *Node, size int, loaded bool) {
+	if n.IsModified() {
+		c.remove(n)
+		return
+	}
+
+	c.mu.Lock()
+	if loaded {
+		c.misses++
+	} else {
+		c.hits++
+	}
+	if elem, ok := c.entries[n]; ok {
+		entry := elem.Value.(*cacheEntry)
+		c.used += int64(size) - entry.size
+		entry.size = int64(size)
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[n] = c.lru.PushFront(&cacheEntry{node: n, size: int64(size)})
+		c.used += int64(size)
+	}
+	victims := c.shrink(n)
+	c.mu.Unlock()
+
+	c.evict(victims)
+}
+
+// remove 把节点移出缓存（内容被修改或手动卸载时），不改变节点内容
+func (c *ContentCache) remove(n *Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[n]; ok {
+		c.used -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+		delete(c.entries, n)
+	}
+}
+
+// shrink 从队尾取出节点直到用量不超过预算，keep 为刚访问的节点，不会被取出。调用方需持有 c.mu
+func (c *ContentCache) shrink(keep *Node) []*Node {
+	var victims []*Node
+	for elem := c.lru.Back(); elem != nil && c.used > c.budget; {
+		prev := elem.Prev()
+		entry := elem.Value.(*cacheEntry)
+		if entry.node != keep {
+			c.used -= entry.size
+			c.lru.Remove(elem)
+			delete(c.entries, entry.node)
+			victims = append(victims, entry.node)
+		}
+		elem = prev
+	}
+	return victims
+}
+
+// evict 卸载被取出节点的内容，跳过已修改或在此期间重新进入缓存的节点
+func (c *ContentCache) evict(victims []*Node) {
+	for _, n := range victims {
+		n.mu.Lock()
+		c.mu.Lock()
+		_, readmitted := c.entries[n]
+		if !readmitted && !n.modified && n.ContentLoaded {
+			n.Content = nil
+			n.ContentLoaded = false
+			c.evictions++
+		}
+		c.mu.Unlock()
+		n.mu.Unlock()
+	}
+}
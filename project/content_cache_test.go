@@ -0,0 +1,129 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCacheTestProject 创建包含 dirs*files 个 100 字节文件的项目
+func newCacheTestProject(t *testing.T, dirs, files int) *Project {
+	t.Helper()
+	tempDir := t.TempDir()
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(tempDir, fmt.Sprintf("d%d", d))
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		for f := 0; f < files; f++ {
+			content := strings.Repeat(fmt.Sprintf("%d", f%10), 100)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.txt", f)), []byte(content), 0644))
+		}
+	}
+	proj := NewProject(tempDir)
+	require.NoError(t, proj.SyncFromFS())
+	return proj
+}
+
+func TestContentCacheLRU(t *testing.T) {
+	proj := newCacheTestProject(t, 1, 5)
+	proj.SetContentBudget(300)
+	cache := proj.ContentCache()
+	require.NotNil(t, cache)
+
+	read := func(path string) *Node {
+		node, err := proj.FindNode(path)
+		require.NoError(t, err)
+		_, err = node.ReadContent()
+		require.NoError(t, err)
+		return node
+	}
+
+	f0 := read("/d0/f0.txt")
+	f1 := read("/d0/f1.txt")
+	read("/d0/f2.txt")
+	read("/d0/f0.txt") // f0 成为最近使用，f1 最久未使用
+	read("/d0/f3.txt")
+
+	assert.True(t, f0.ContentLoaded)
+	assert.False(t, f1.ContentLoaded, "least recently used content should be evicted")
+	stats := cache.Stats()
+	assert.Equal(t, ContentCacheStats{Budget: 300, Used: 300, Entries: 3, Hits: 1, Misses: 4, Evictions: 1}, stats)
+
+	// 被卸载的内容可以重新加载
+	content, err := f1.ReadContent()
+	require.NoError(t, err)
+	assert.Len(t, content, 100)
+
+	// 缩小预算立即生效
+	cache.SetBudget(100)
+	assert.Equal(t, int64(100), cache.Stats().Used)
+	assert.True(t, f1.ContentLoaded)
+}
+
+func TestContentCacheKeepsDirtyNodes(t *testing.T) {
+	proj := newCacheTestProject(t, 1, 4)
+	proj.SetContentBudget(200)
+
+	dirty, err := proj.FindNode("/d0/f0.txt")
+	require.NoError(t, err)
+	_, err = dirty.ReadContent()
+	require.NoError(t, err)
+	require.NoError(t, dirty.WriteContent([]byte("changed")))
+
+	for i := 1; i < 4; i++ {
+		node, err := proj.FindNode(fmt.Sprintf("/d0/f%d.txt", i))
+		require.NoError(t, err)
+		_, err = node.ReadContent()
+		require.NoError(t, err)
+	}
+
+	content, err := dirty.ReadContent()
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(content))
+	stats := proj.ContentCache().Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.LessOrEqual(t, stats.Used, int64(200))
+}
+
+func TestContentCacheConcurrent(t *testing.T) {
+	proj := newCacheTestProject(t, 8, 25)
+	proj.SetContentBudget(2000)
+
+	for round := 0; round < 3; round++ {
+		results := ProcessConcurrentBFSTyped(context.Background(), proj.Root(), 16, func(node *Node) (int, error) {
+			if node.IsDir {
+				return 0, nil
+			}
+			content, err := node.ReadContent()
+			return len(content), err
+		})
+		for path, result := range results {
+			require.NoError(t, result.Err, path)
+			if strings.HasSuffix(path, ".txt") {
+				assert.Equal(t, 100, result.Value, path)
+			}
+		}
+	}
+
+	stats := proj.ContentCache().Stats()
+	assert.LessOrEqual(t, stats.Used, stats.Budget)
+	assert.Equal(t, int64(3*8*25), stats.Hits+stats.Misses)
+	assert.Positive(t, stats.Evictions)
+
+	files, err := proj.GetAllFiles()
+	require.NoError(t, err)
+	loaded := 0
+	for _, path := range files {
+		node, err := proj.FindNode(path)
+		require.NoError(t, err)
+		if node.ContentLoaded {
+			loaded++
+		}
+	}
+	assert.Equal(t, stats.Entries, loaded)
+}
@@ -0,0 +1,347 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sjzsdu/tong/project"
+)
+
+// ChangeType 文件变更类型
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Removed  ChangeType = "removed"
+	Modified ChangeType = "modified"
+)
+
+// FileChange 单个文件的变更
+type FileChange struct {
+	// 相对比较根的路径，以 / 开头
+	Path string     `json:"path"`
+	Type ChangeType `json:"type"`
+	// 二进制文件只报告变更，不生成文本差异
+	Binary bool `json:"binary,omitempty"`
+	// 新增与删除的行数
+	Insertions int `json:"insertions"`
+	Deletions  int `json:"deletions"`
+	// 统一格式（unified）的差异块，不含文件头
+	Hunks string `json:"hunks,omitempty"`
+}
+
+// Options 比较选项
+type Options struct {
+	// 差异块中的上下文行数
+	Context int
+	// 是否包含隐藏文件/目录（以 . 开头）
+	IncludeHidden bool
+}
+
+// DefaultOptions 返回默认比较选项
+func DefaultOptions() *Options {
+	return &Options{
+		Context:       3,
+		IncludeHidden: true,
+	}
+}
+
+// Result 比较结果，Changes 按路径排序
+type Result struct {
+	Changes []FileChange `json:"changes"`
+}
+
+// Diff 比较以 a、b 为根的两棵子树，哈希相同的子树直接跳过
+func Diff(ctx context.Context, a, b *project.Node, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	d := &differ{ctx: ctx, opts: opts}
+	if err := d.compare("", a, b); err != nil {
+		return nil, err
+	}
+	sort.Slice(d.changes, func(i, j int) bool {
+		return d.changes[i].Path < d.changes[j].Path
+	})
+	return &Result{Changes: d.changes}, nil
+}
+
+type differ struct {
+	ctx     context.Context
+	opts    *Options
+	changes []FileChange
+}
+
+// compare 比较同一路径上的两个节点，任一侧可以为 nil
+func (d *differ) compare(path string, a, b *project.Node) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		return d.collect(path, b, Added)
+	case b == nil:
+		return d.collect(path, a, Removed)
+	case a.IsDir != b.IsDir:
+		// 同名的文件与目录互相替换
+		if err := d.collect(path, a, Removed); err != nil {
+			return err
+		}
+		return d.collect(path, b, Added)
+	}
+
+	hashA, err := a.CalculateHash()
+	if err != nil {
+		return err
+	}
+	hashB, err := b.CalculateHash()
+	if err != nil {
+		return err
+	}
+	if hashA == hashB {
+		return nil
+	}
+
+	if !a.IsDir {
+		return d.addFile(path, a, b, Modified)
+	}
+
+	childrenA, childrenB := d.children(a), d.children(b)
+	names := make([]string, 0, len(childrenA)+len(childrenB))
+	for name := range childrenA {
+		names = append(names, name)
+	}
+	for name := range childrenB {
+		if _, ok := childrenA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := d.compare(path+"/"+name, childrenA[name], childrenB[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// children 返回目录下参与比较的子节点
+func (d *differ) children(node *project.Node) map[string]*project.Node {
+	children := make(map[string]*project.Node)
+	for _, child := range node.GetChildrenNodes() {
+		if !d.opts.IncludeHidden && strings.HasPrefix(child.Name, ".") {
+			continue
+		}
+		children[child.Name] = child
+	}
+	return children
+}
+
+// collect 把只存在于一侧的节点（及其子树中的文件）记为新增或删除
+func (d *differ) collect(path string, node *project.Node, typ ChangeType) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	if !node.IsDir {
+		if typ == Added {
+			return d.addFile(path, nil, node, typ)
+		}
+		return d.addFile(path, node, nil, typ)
+	}
+
+	children := d.children(node)
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := d.collect(path+"/"+name, children[name], typ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFile 读取两侧内容并生成文件变更
+func (d *differ) addFile(path string, a, b *project.Node, typ ChangeType) error {
+	contentA, err := readNode(a)
+	if err != nil {
+		return err
+	}
+	contentB, err := readNode(b)
+	if err != nil {
+		return err
+	}
+
+	change := FileChange{Path: path, Type: typ}
+	if isBinary(contentA) || isBinary(contentB) {
+		change.Binary = true
+	} else {
+		change.Hunks, change.Insertions, change.Deletions = unifiedHunks(contentA, contentB, d.opts.Context)
+	}
+	d.changes = append(d.changes, change)
+	return nil
+}
+
+func readNode(node *project.Node) ([]byte, error) {
+	if node == nil {
+		return nil, nil
+	}
+	content, err := node.ReadContent()
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", node.Path, err)
+	}
+	return content, nil
+}
+
+// isBinary 与 git 一致，按前 8000 字节中是否包含 NUL 判断二进制文件
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// splitLines 按行切分并保留换行符，末行缺少换行符时保持原样
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Unified 生成两段文本之间统一格式的差异块（不含文件头），返回差异文本与增删行数
+func Unified(a, b []byte, contextLines int) (string, int, int) {
+	return unifiedHunks(a, b, contextLines)
+}
+
+// unifiedHunks 生成统一格式的差异块，返回差异文本与增删行数
+func unifiedHunks(a, b []byte, contextLines int) (string, int, int) {
+	linesA, linesB := splitLines(a), splitLines(b)
+
+	var buf strings.Builder
+	insertions, deletions := 0, 0
+	writeLine := func(prefix byte, line string) {
+		buf.WriteByte(prefix)
+		buf.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	matcher := difflib.NewMatcher(linesA, linesB)
+	for _, group := range matcher.GetGroupedOpCodes(contextLines) {
+		first, last := group[0], group[len(group)-1]
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", formatRange(first.I1, last.I2), formatRange(first.J1, last.J2))
+		for _, op := range group {
+			if op.Tag == 'e' {
+				for _, line := range linesA[op.I1:op.I2] {
+					writeLine(' ', line)
+				}
+				continue
+			}
+			if op.Tag == 'r' || op.Tag == 'd' {
+				for _, line := range linesA[op.I1:op.I2] {
+					writeLine('-', line)
+					deletions++
+				}
+			}
+			if op.Tag == 'r' || op.Tag == 'i' {
+				for _, line := range linesB[op.J1:op.J2] {
+					writeLine('+', line)
+					insertions++
+				}
+			}
+		}
+	}
+	return buf.String(), insertions, deletions
+}
+
+// formatRange 格式化差异块头中的行范围
+func formatRange(start, stop int) string {
+	length := stop - start
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, length)
+	}
+}
+
+// Summary 统计新增、删除与修改的文件数
+func (r *Result) Summary() (added, removed, modified int) {
+	for _, change := range r.Changes {
+		switch change.Type {
+		case Added:
+			added++
+		case Removed:
+			removed++
+		case Modified:
+			modified++
+		}
+	}
+	return added, removed, modified
+}
+
+// Text 生成便于阅读的变更列表
+func (r *Result) Text() string {
+	if len(r.Changes) == 0 {
+		return "没有差异\n"
+	}
+
+	var buf strings.Builder
+	marks := map[ChangeType]string{Added: "A", Removed: "D", Modified: "M"}
+	for _, change := range r.Changes {
+		stat := fmt.Sprintf("+%d -%d", change.Insertions, change.Deletions)
+		if change.Binary {
+			stat = "binary"
+		}
+		fmt.Fprintf(&buf, "%s  %s  (%s)\n", marks[change.Type], change.Path, stat)
+	}
+	added, removed, modified := r.Summary()
+	fmt.Fprintf(&buf, "\n%d 个文件变更：新增 %d，删除 %d，修改 %d\n", len(r.Changes), added, removed, modified)
+	return buf.String()
+}
+
+// Patch 生成 git 风格的补丁，可通过 git apply 应用到 a 侧
+func (r *Result) Patch() string {
+	var buf strings.Builder
+	for _, change := range r.Changes {
+		path := strings.TrimPrefix(change.Path, "/")
+		oldName, newName := "a/"+path, "b/"+path
+		fmt.Fprintf(&buf, "diff --git %s %s\n", oldName, newName)
+		switch change.Type {
+		case Added:
+			buf.WriteString("new file mode 100644\n")
+			oldName = "/dev/null"
+		case Removed:
+			buf.WriteString("deleted file mode 100644\n")
+			newName = "/dev/null"
+		}
+		if change.Binary {
+			fmt.Fprintf(&buf, "Binary files %s and %s differ\n", oldName, newName)
+			continue
+		}
+		if change.Hunks == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+		buf.WriteString(change.Hunks)
+	}
+	return buf.String()
+}
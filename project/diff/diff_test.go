@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sjzsdu/tong/project"
+)
+
+// buildTree 在临时目录中写入文件并构建项目树
+func buildTree(t *testing.T, files map[string]string) *project.Node {
+	t.Helper()
+	dir := t.TempDir()
+	for p, c := range files {
+		full := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(c), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+
+	proj := project.NewProject(dir)
+	if err := proj.SyncFromFS(); err != nil {
+		t.Fatalf("sync project: %v", err)
+	}
+	return proj.Root()
+}
+
+func TestDiff(t *testing.T) {
+	a := buildTree(t, map[string]string{
+		"README.md":        "# Readme\n",
+		"src/main.go":      "package main\n\nfunc main() {\n\tprintln(\"a\")\n}\n",
+		"src/same/x.go":    "package same\n",
+		"docs/old.md":      "old\n",
+		"docs/nested/n.md": "nested\n",
+		"swap":             "file\n",
+		"bin.dat":          "a\x00b",
+		"no_newline.txt":   "one\ntwo",
+	})
+	b := buildTree(t, map[string]string{
+		"README.md":      "# Readme\n",
+		"src/main.go":    "package main\n\nfunc main() {\n\tprintln(\"b\")\n}\n",
+		"src/same/x.go":  "package same\n",
+		"src/new.go":     "package main\n",
+		"swap/inner.txt": "dir\n",
+		"bin.dat":        "a\x00c",
+		"no_newline.txt": "one\ntwo\n",
+	})
+
+	result, err := Diff(context.Background(), a, b, nil)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+
+	want := []struct {
+		path string
+		typ  ChangeType
+	}{
+		{"/bin.dat", Modified},
+		{"/docs/nested/n.md", Removed},
+		{"/docs/old.md", Removed},
+		{"/no_newline.txt", Modified},
+		{"/src/main.go", Modified},
+		{"/src/new.go", Added},
+		{"/swap", Removed},
+		{"/swap/inner.txt", Added},
+	}
+	if len(result.Changes) != len(want) {
+		t.Fatalf("unexpected changes: %+v", result.Changes)
+	}
+	for i, w := range want {
+		if result.Changes[i].Path != w.path || result.Changes[i].Type != w.typ {
+			t.Fatalf("change %d = %s %s, want %s %s", i, result.Changes[i].Type, result.Changes[i].Path, w.typ, w.path)
+		}
+	}
+
+	if !result.Changes[0].Binary {
+		t.Fatalf("bin.dat should be binary")
+	}
+	main := result.Changes[4]
+	wantHunks := "@@ -1,5 +1,5 @@\n package main\n \n func main() {\n-\tprintln(\"a\")\n+\tprintln(\"b\")\n }\n"
+	if main.Hunks != wantHunks || main.Insertions != 1 || main.Deletions != 1 {
+		t.Fatalf("unexpected hunks:\n%s", main.Hunks)
+	}
+	wantNoNewline := "@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+two\n"
+	if result.Changes[3].Hunks != wantNoNewline {
+		t.Fatalf("unexpected no-newline hunks:\n%s", result.Changes[3].Hunks)
+	}
+
+	added, removed, modified := result.Summary()
+	if added != 2 || removed != 3 || modified != 3 {
+		t.Fatalf("unexpected summary: %d %d %d", added, removed, modified)
+	}
+}
+
+func TestDiffPatch(t *testing.T) {
+	a := buildTree(t, map[string]string{"a.txt": "1\n2\n", "gone.txt": "bye\n"})
+	b := buildTree(t, map[string]string{"a.txt": "1\n3\n", "new.txt": "hi\n"})
+
+	result, err := Diff(context.Background(), a, b, nil)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+
+	want := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n 1\n-2\n+3\n" +
+		"diff --git a/gone.txt b/gone.txt\ndeleted file mode 100644\n--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n" +
+		"diff --git a/new.txt b/new.txt\nnew file mode 100644\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hi\n"
+	if got := result.Patch(); got != want {
+		t.Fatalf("unexpected patch:\n%s", got)
+	}
+}
+
+func TestDiffIdenticalAndRenamed(t *testing.T) {
+	files := map[string]string{"src/a.go": "package a\n", "b.txt": "b\n"}
+	result, err := Diff(context.Background(), buildTree(t, files), buildTree(t, files), nil)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(result.Changes) != 0 {
+		t.Fatalf("identical trees should have no changes: %+v", result.Changes)
+	}
+
+	// 内容相同但名称不同的目录不能被当作未变化而跳过
+	renamed := map[string]string{"src/c.go": "package a\n", "b.txt": "b\n"}
+	result, err = Diff(context.Background(), buildTree(t, files), buildTree(t, renamed), nil)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(result.Changes) != 2 {
+		t.Fatalf("rename should be reported as remove + add: %+v", result.Changes)
+	}
+}
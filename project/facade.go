@@ -67,16 +67,10 @@ func (pjt *Project) getFileOrDirContent(filePath string) (string, error) {
 		return "", fmt.Errorf("检查路径失败: %w", err)
 	}
 
-	// 获取相对于项目的路径
-	relPath, err := filepath.Rel(pjt.rootPath, absPath)
-	if err != nil {
-		return "", fmt.Errorf("获取相对路径失败: %w", err)
-	}
-
 	// 转换为项目路径格式
-	projPath := "/" + filepath.ToSlash(relPath)
-	if relPath == "." {
-		projPath = "/"
+	projPath, ok := pjt.projectPathOf(absPath)
+	if !ok {
+		return "", fmt.Errorf("路径不在项目中: %s", absPath)
 	}
 
 	// 从项目中查找节点
@@ -106,9 +100,8 @@ func (pjt *Project) getFileOrDirContent(filePath string) (string, error) {
 			return "", fmt.Errorf("读取文件内容失败: %w", err)
 		}
 
-		// 添加文件扩展名对应的语言标识
-		ext := filepath.Ext(node.Name)
-		lang := helper.GetLanguageFromExtension(ext)
+		// 添加文件对应的语言标识
+		lang := node.Kind().Language
 
 		// 返回格式化的内容
 		return fmt.Sprintf("文件内容(%s):\n```%s\n%s\n```", absPath, lang, content), nil
@@ -140,8 +133,7 @@ func formatProjectNode(node *Node, path string, builder *strings.Builder) error
 		builder.WriteString(fmt.Sprintf("## %s\n\n", nodePath))
 
 		// 添加代码块
-		ext := filepath.Ext(nodePath)
-		lang := helper.GetLanguageFromExtension(ext)
+		lang := node.Kind().Language
 		builder.WriteString(fmt.Sprintf("```%s\n%s\n```\n\n", lang, content))
 
 		return nil
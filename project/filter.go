@@ -0,0 +1,225 @@
+package project
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/helper/ignore"
+)
+
+// pathFilter 封装构建项目树时使用的过滤规则（排除目录、忽略文件、扩展名与自定义排除），
+// 供 BuildProjectTree 与 Watch 共用，保证两者对同一路径得出相同的结论
+type pathFilter struct {
+	rootPath string
+	options  helper.WalkDirOptions
+	mu       sync.Mutex
+
+	// ignoreNames 每个目录下读取的忽略文件名，globalFiles 全局排除文件
+	ignoreNames []string
+	globalFiles []string
+	// ignoreRules 忽略文件路径 -> 规则行，matcher 由这些规则编译而成
+	ignoreRules map[string][]string
+	matcher     *ignore.Matcher
+	loadedDirs  map[string]bool
+
+	// fsys 不为 nil 时 rootPath 为虚拟根路径，忽略文件从 fsys 中读取
+	fsys fs.FS
+}
+
+// newPathFilter 创建路径过滤器，读取全局排除规则
+func newPathFilter(rootPath string, options helper.WalkDirOptions) *pathFilter {
+	rootPath = filepath.Clean(rootPath)
+	return &pathFilter{
+		rootPath:    rootPath,
+		options:     options,
+		ignoreNames: helper.IgnoreFileNames(options),
+		globalFiles: helper.GlobalIgnoreFiles(rootPath, options),
+		ignoreRules: make(map[string][]string),
+		matcher:     helper.NewIgnoreMatcher(rootPath, options),
+		loadedDirs:  make(map[string]bool),
+	}
+}
+
+// newFSFilter 创建从 fsys 读取忽略文件的过滤器，全局规则只包含 ~/.tong/ignore
+func newFSFilter(fsys fs.FS, options helper.WalkDirOptions) *pathFilter {
+	return &pathFilter{
+		rootPath:    fsRoot,
+		options:     options,
+		ignoreNames: helper.IgnoreFileNames(options),
+		globalFiles: helper.GlobalIgnoreFiles("", options),
+		ignoreRules: make(map[string][]string),
+		matcher:     helper.NewIgnoreMatcher("", options),
+		loadedDirs:  make(map[string]bool),
+		fsys:        fsys,
+	}
+}
+
+// newSystemFilter 创建只排除系统目录的过滤器，不读取任何忽略文件
+func newSystemFilter(rootPath string) *pathFilter {
+	return &pathFilter{
+		rootPath:    filepath.Clean(rootPath),
+		ignoreRules: make(map[string][]string),
+		matcher:     ignore.NewMatcher(),
+		loadedDirs:  make(map[string]bool),
+	}
+}
+
+// isIgnoreFile 判断文件名是否为需要读取的忽略文件
+func (f *pathFilter) isIgnoreFile(name string) bool {
+	return helper.StringSliceContains(f.ignoreNames, name)
+}
+
+// loadDir 读取目录下的忽略文件（已读取过则跳过）
+func (f *pathFilter) loadDir(dir string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.loadedDirs[dir] {
+		return
+	}
+	f.loadedDirs[dir] = true
+	for _, name := range f.ignoreNames {
+		file := filepath.Join(dir, name)
+		lines, err := f.readLines(file)
+		if err == nil && lines != nil {
+			f.ignoreRules[file] = lines
+		} else {
+			delete(f.ignoreRules, file)
+		}
+	}
+	f.compileDir(dir)
+}
+
+// compileDir 用已读取的规则行更新目录的匹配规则，调用方需持有 f.mu
+func (f *pathFilter) compileDir(dir string) {
+	base := f.relPath(dir)
+	var rules []*ignore.Rule
+	for _, name := range f.ignoreNames {
+		rules = append(rules, ignore.ParseLines(f.ignoreRules[filepath.Join(dir, name)], base)...)
+	}
+	f.matcher.SetDir(base, rules)
+}
+
+// readLines 读取忽略文件中的规则行，文件不存在时返回 nil
+func (f *pathFilter) readLines(file string) ([]string, error) {
+	if f.fsys == nil {
+		return ignore.ReadLines(file)
+	}
+	rel, err := filepath.Rel(f.rootPath, file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fs.ReadFile(f.fsys, filepath.ToSlash(rel))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ignore.SplitLines(data), nil
+}
+
+// relPath 返回路径相对根目录的斜杠路径，根目录为 ""
+func (f *pathFilter) relPath(path string) string {
+	rel, err := filepath.Rel(f.rootPath, path)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// reloadDir 丢弃目录已缓存的忽略规则并重新读取
+func (f *pathFilter) reloadDir(dir string) {
+	f.mu.Lock()
+	delete(f.loadedDirs, dir)
+	f.mu.Unlock()
+	f.loadDir(dir)
+}
+
+// loadAncestors 确保从根目录到 dir 的每一级目录规则均已读取
+func (f *pathFilter) loadAncestors(dir string) {
+	rel, err := filepath.Rel(f.rootPath, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return
+	}
+	f.loadDir(f.rootPath)
+	if rel == "." {
+		return
+	}
+	cur := f.rootPath
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		cur = filepath.Join(cur, part)
+		f.loadDir(cur)
+	}
+}
+
+// skip 判断路径是否应被排除
+// 返回值 skipDir 表示该路径是目录且整个子树都应跳过
+func (f *pathFilter) skip(path string, info os.FileInfo) (skip bool, skipDir bool, err error) {
+	if info.IsDir() {
+		name := info.Name()
+		if excludedDirs[name] {
+			return true, true, nil
+		}
+	}
+
+	// 处理 .gitignore、.tongignore 与全局排除规则
+	f.mu.Lock()
+	ignored := f.matcher.Match(f.relPath(path), info.IsDir())
+	f.mu.Unlock()
+	if ignored {
+		return true, info.IsDir(), nil
+	}
+
+	if info.IsDir() {
+		return false, false, nil
+	}
+
+	// 检查文件扩展名
+	if len(f.options.Extensions) > 0 {
+		ext := filepath.Ext(path)
+		if len(ext) > 0 {
+			ext = ext[1:] // 移除开头的点
+		}
+		if !helper.StringSliceContains(f.options.Extensions, ext) && !helper.StringSliceContains(f.options.Extensions, "*") {
+			return true, false, nil
+		}
+	}
+
+	// 检查自定义排除规则
+	return f.matchExcludes(path), false, nil
+}
+
+// matchExcludes 只检查自定义排除规则，不检查 .gitignore 规则
+func (f *pathFilter) matchExcludes(path string) bool {
+	for _, pattern := range f.options.Excludes {
+		// 使用完整路径进行匹配
+		matched, err := filepath.Match(pattern, filepath.Base(path))
+		if err == nil && matched {
+			return true
+		}
+
+		// 检查相对路径
+		relPath, err := filepath.Rel(f.rootPath, path)
+		if err == nil {
+			matched, err = filepath.Match(pattern, relPath)
+			if err == nil && matched {
+				return true
+			}
+		}
+
+		// 对于包含 ** 的模式，需要特殊处理
+		if strings.Contains(pattern, "**") {
+			pattern = strings.ReplaceAll(pattern, "**", "*")
+			if strings.Contains(path, pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
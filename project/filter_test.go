@@ -0,0 +1,97 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles 在 dir 下写入文件
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		full := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+}
+
+func TestBuildProjectTreeIgnoreFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeFiles(t, home, map[string]string{".tong/ignore": "*.bak\n"})
+
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		".gitignore":         "*.log\n!keep.log\nout/\n/local.txt\n",
+		".tongignore":        "fixtures/\n!important.log\n",
+		"app.log":            "",
+		"keep.log":           "",
+		"important.log":      "",
+		"local.txt":          "",
+		"notes.bak":          "",
+		"out/main.o":         "",
+		"fixtures/data.json": "",
+		"src/local.txt":      "",
+		"src/main.go":        "",
+		"src/.gitignore":     "*.tmp\n!debug.log\n",
+		"src/debug.log":      "",
+		"src/x.tmp":          "",
+		"src/gen/a.tmp":      "",
+	})
+
+	proj, err := BuildProjectTree(tempDir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/.gitignore", "/.tongignore", "/important.log", "/keep.log",
+		"/src", "/src/.gitignore", "/src/debug.log", "/src/gen", "/src/local.txt", "/src/main.go",
+	}, projectPaths(proj))
+
+	// 禁用 .gitignore 时 .tongignore 与全局规则仍然生效
+	proj, err = BuildProjectTree(tempDir, helper.WalkDirOptions{DisableGitIgnore: true})
+	require.NoError(t, err)
+	paths := projectPaths(proj)
+	assert.Contains(t, paths, "/app.log")
+	assert.Contains(t, paths, "/out/main.o")
+	assert.NotContains(t, paths, "/fixtures")
+	assert.NotContains(t, paths, "/notes.bak")
+
+	// fs.FS 来源使用相同的规则
+	fsProj, err := BuildProjectTreeFromFS(os.DirFS(tempDir), helper.WalkDirOptions{})
+	require.NoError(t, err)
+	files, err := fsProj.GetAllFiles()
+	require.NoError(t, err)
+	expected, err := BuildProjectTree(tempDir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+	expectedFiles, err := expected.GetAllFiles()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, expectedFiles, files)
+}
+
+func TestSnapshotTongignoreChangeRebuilds(t *testing.T) {
+	useTempSnapshotDir(t)
+	t.Setenv("HOME", t.TempDir())
+
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{"a.go": "", "sub/b.go": "", "sub/c.txt": ""})
+	options := helper.WalkDirOptions{}
+
+	_, err := BuildProjectTreeCached(tempDir, options)
+	require.NoError(t, err)
+
+	writeFiles(t, tempDir, map[string]string{"sub/.tongignore": "*.txt\n"})
+	touchDir(t, filepath.Join(tempDir, "sub"))
+	proj, err := BuildProjectTreeCached(tempDir, options)
+	require.NoError(t, err)
+	assert.NotContains(t, projectPaths(proj), "/sub/c.txt")
+
+	// 新建全局排除文件同样使快照失效
+	writeFiles(t, os.Getenv("HOME"), map[string]string{".tong/ignore": "*.go\n"})
+	proj, err = BuildProjectTreeCached(tempDir, options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/sub", "/sub/.tongignore"}, projectPaths(proj))
+}
@@ -0,0 +1,271 @@
+package project
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+)
+
+// Project 实现 fs.FS、fs.ReadDirFS、fs.StatFS 与 fs.ReadFileFS，
+// 可直接交给 template.ParseFS、http.FS、fs.WalkDir 等标准库代码使用。
+// fs.FS 的路径为不以 / 开头的斜杠分隔路径，"." 表示项目根目录
+var (
+	_ fs.FS         = (*Project)(nil)
+	_ fs.ReadDirFS  = (*Project)(nil)
+	_ fs.StatFS     = (*Project)(nil)
+	_ fs.ReadFileFS = (*Project)(nil)
+)
+
+// ErrNotOnDisk 项目来自 fs.FS 或 git 修订版本，没有可写入或监听的磁盘目录
+var ErrNotOnDisk = errors.New("project is not backed by a disk directory")
+
+// fsRoot 由 fs.FS 构建的项目在过滤规则中使用的虚拟根路径
+var fsRoot = string(filepath.Separator)
+
+// memFileInfo 不对应磁盘文件的文件信息，实现 os.FileInfo
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memFileInfo) Sys() interface{}   { return nil }
+
+// newMemFileInfo 为内存中创建的文件或目录生成文件信息
+func newMemFileInfo(name string, isDir bool, size int) os.FileInfo {
+	mode := os.FileMode(0644)
+	if isDir {
+		mode = os.ModeDir | 0755
+	}
+	return &memFileInfo{name: name, size: int64(size), mode: mode, modTime: time.Now()}
+}
+
+// BuildProjectTreeFromFS 从任意 fs.FS 构建项目树，不访问本地磁盘
+// 过滤规则与 BuildProjectTree 相同（.gitignore 从 fsys 中读取）；文件内容按需从 fsys 读取，
+// 之后的修改只保存在内存中，SaveToFS、Watch、EnableJournal 与事务提交返回 ErrNotOnDisk
+func BuildProjectTreeFromFS(fsys fs.FS, options helper.WalkDirOptions) (*Project, error) {
+	return buildProjectTreeFromSource("", fsys, options)
+}
+
+// buildProjectTreeFromSource 以 fsys 为内容来源构建项目树，rootPath 仅用于标识项目
+func buildProjectTreeFromSource(rootPath string, fsys fs.FS, options helper.WalkDirOptions) (*Project, error) {
+	doc := NewProject(rootPath)
+	doc.inGit = false
+	doc.source = fsys
+
+	filter := newFSFilter(fsys, options)
+	filter.loadDir(fsRoot)
+	doc.filter = filter
+
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	if err := doc.walkSource(filter, options.LoadContent); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// walkSource 遍历 p.source，把未被过滤的文件与目录加入项目树，调用方需持有 p.mu 写锁
+func (p *Project) walkSource(filter *pathFilter, loadContent bool) error {
+	return fs.WalkDir(p.source, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		virtualPath := filepath.Join(fsRoot, filepath.FromSlash(name))
+		skip, skipDir, err := filter.skip(virtualPath, info)
+		if err != nil {
+			return err
+		}
+		if skipDir {
+			return fs.SkipDir
+		}
+		if skip {
+			return nil
+		}
+
+		projPath := "/" + name
+		if d.IsDir() {
+			filter.loadDir(virtualPath)
+			return p.createDirInternal(projPath, info)
+		}
+		if err := p.createFileNodeInternal(projPath, info); err != nil {
+			return err
+		}
+		if loadContent {
+			content, err := fs.ReadFile(p.source, name)
+			if err != nil {
+				return nil // 跳过无法读取的文件
+			}
+			node := p.nodes[projPath]
+			node.Content = content
+			node.ContentLoaded = true
+		}
+		return nil
+	})
+}
+
+// fsNode 按 fs.FS 路径查找节点，错误均为 *fs.PathError
+func (p *Project) fsNode(op, name string) (*Node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	// fs.FS 路径中的反斜杠不是分隔符，而项目路径会把它当作分隔符处理
+	if strings.Contains(name, "\\") {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	projPath := "/"
+	if name != "." {
+		projPath += name
+	}
+	node, err := p.FindNode(projPath)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return node, nil
+}
+
+// Open 实现 fs.FS
+func (p *Project) Open(name string) (fs.File, error) {
+	node, err := p.fsNode("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.IsDir {
+		return &projectDir{node: node, info: node.fileInfo(name)}, nil
+	}
+	content, err := node.ReadContent()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &projectFile{Reader: bytes.NewReader(content), info: node.fileInfo(name)}, nil
+}
+
+// Stat 实现 fs.StatFS
+func (p *Project) Stat(name string) (fs.FileInfo, error) {
+	node, err := p.fsNode("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return node.fileInfo(name), nil
+}
+
+// ReadDir 实现 fs.ReadDirFS，返回按名称排序的目录项
+func (p *Project) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := p.fsNode("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.IsDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return node.dirEntries(), nil
+}
+
+// fileInfo 返回 fs.FS 使用的文件信息，名称取自 fs 路径，大小以内存中的内容为准
+func (n *Node) fileInfo(name string) fs.FileInfo {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	fi := &memFileInfo{name: path.Base(name)}
+	switch {
+	case n.Info != nil:
+		fi.size = n.Info.Size()
+		fi.mode = n.Info.Mode()
+		fi.modTime = n.Info.ModTime()
+	case n.IsDir:
+		fi.mode = fs.ModeDir | 0755
+	default:
+		fi.mode = 0644
+	}
+	if n.IsDir {
+		fi.size = 0
+	} else if n.ContentLoaded {
+		fi.size = int64(len(n.Content))
+	}
+	return fi
+}
+
+// dirEntries 返回按名称排序的子节点目录项
+func (n *Node) dirEntries() []fs.DirEntry {
+	children := n.GetChildrenNodes()
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.fileInfo(child.Name)))
+	}
+	return entries
+}
+
+// projectFile 打开的文件，内容在 Open 时读取
+type projectFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *projectFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *projectFile) Close() error               { return nil }
+
+// projectDir 打开的目录，实现 fs.ReadDirFile
+type projectDir struct {
+	node    *Node
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *projectDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *projectDir) Close() error               { return nil }
+
+func (d *projectDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// ReadDir 实现 fs.ReadDirFile，n <= 0 时返回剩余的全部目录项
+func (d *projectDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.entries = d.node.dirEntries()
+	}
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// readSource 从 fs.FS 来源读取项目路径对应的文件
+func (p *Project) readSource(projPath string) ([]byte, error) {
+	return fs.ReadFile(p.source, strings.TrimPrefix(projPath, "/"))
+}
@@ -0,0 +1,135 @@
+package project
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMapFS() fstest.MapFS {
+	return fstest.MapFS{
+		".gitignore":              {Data: []byte("*.log\n")},
+		"README.md":               {Data: []byte("# Readme\n")},
+		"src/main.go":             {Data: []byte("package main\n")},
+		"src/util/util.go":        {Data: []byte("package util\n")},
+		"src/debug.log":           {Data: []byte("ignored")},
+		"templates/a.tmpl":        {Data: []byte(`{{define "a"}}hello {{.}}{{end}}`)},
+		"node_modules/x/index.js": {Data: []byte("module.exports = {}")},
+	}
+}
+
+func TestBuildProjectTreeFromFS(t *testing.T) {
+	proj, err := BuildProjectTreeFromFS(newMapFS(), DefaultWalkDirOptions())
+	require.NoError(t, err)
+
+	files, err := proj.GetAllFiles()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/.gitignore", "/README.md", "/src/main.go", "/src/util/util.go", "/templates/a.tmpl"}, files)
+
+	content, err := proj.ReadFile("/src/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(content))
+
+	hash, err := proj.Root().CalculateHash()
+	require.NoError(t, err)
+	assert.NotEmpty(t, hash)
+
+	// 扩展名过滤同样生效
+	goOnly, err := BuildProjectTreeFromFS(newMapFS(), helper.WalkDirOptions{Extensions: []string{"go"}})
+	require.NoError(t, err)
+	files, err = goOnly.GetAllFiles()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/src/main.go", "/src/util/util.go"}, files)
+}
+
+func TestFSProjectWritesStayInMemory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	proj, err := BuildProjectTreeFromFS(newMapFS(), DefaultWalkDirOptions())
+	require.NoError(t, err)
+
+	require.NoError(t, proj.CreateDir("/pkg"))
+	require.NoError(t, proj.CreateFile("/pkg/a.go", []byte("package pkg\n")))
+	require.NoError(t, proj.WriteFile("/src/main.go", []byte("package changed\n")))
+
+	content, err := fs.ReadFile(proj, "src/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package changed\n", string(content))
+	info, err := fs.Stat(proj, "pkg/a.go")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("package pkg\n")), info.Size())
+
+	entries, err := os.ReadDir(".")
+	require.NoError(t, err)
+	assert.Empty(t, entries, "fs.FS projects must not write to the working directory")
+
+	assert.Error(t, proj.SaveToFS())
+	assert.Error(t, proj.EnableJournal())
+}
+
+func TestProjectImplementsFS(t *testing.T) {
+	proj, err := BuildProjectTreeFromFS(newMapFS(), DefaultWalkDirOptions())
+	require.NoError(t, err)
+
+	sub, err := fs.Sub(proj, "src")
+	require.NoError(t, err)
+	require.NoError(t, fstest.TestFS(sub, "main.go", "util/util.go"))
+
+	var walked []string
+	require.NoError(t, fs.WalkDir(proj, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	}))
+	assert.Equal(t, []string{".", ".gitignore", "README.md", "src", "src/main.go", "src/util", "src/util/util.go", "templates", "templates/a.tmpl"}, walked)
+
+	_, err = proj.Open("../etc/passwd")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+	_, err = proj.Stat("missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = fs.ReadFile(proj, "missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	tmpl, err := template.ParseFS(proj, "templates/*.tmpl")
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, tmpl.ExecuteTemplate(&out, "a", "fs"))
+	assert.Equal(t, "hello fs", out.String())
+
+	srv := httptest.NewServer(http.FileServer(http.FS(proj)))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/README.md")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "# Readme\n", string(body))
+}
+
+func TestDiskProjectImplementsFS(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "util.go"), []byte("package main\n"), 0644))
+	require.NoError(t, proj.SyncFromFS())
+
+	sub, err := fs.Sub(proj, "src")
+	require.NoError(t, err)
+	require.NoError(t, fstest.TestFS(sub, "main.go", "util.go"))
+
+	entries, err := fs.ReadDir(proj, ".")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "docs", entries[0].Name())
+	assert.True(t, entries[0].IsDir())
+}
@@ -0,0 +1,237 @@
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/sjzsdu/tong/helper"
+)
+
+// BuildProjectTreeFromGit 从 git 仓库的任意修订版本构建项目树，不检出也不修改工作区
+// rev 支持分支、标签、提交哈希以及 HEAD~N 等表达式；repoPath 为仓库子目录时只包含该子树。
+// 文件内容在首次读取时才从 git 对象中解出，过滤规则与 BuildProjectTree 相同（.gitignore 取自该版本）
+func BuildProjectTreeFromGit(repoPath, rev string, options helper.WalkDirOptions) (*Project, error) {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git.PlainOpenWithOptions(absPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("打开 git 仓库失败: %w", err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("解析修订版本 %s 失败: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 失败: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 的目录树失败: %w", hash, err)
+	}
+
+	// repoPath 位于仓库子目录时，只取对应的子树
+	if wt, err := repo.Worktree(); err == nil {
+		rel, err := filepath.Rel(wt.Filesystem.Root(), absPath)
+		if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			tree, err = tree.Tree(filepath.ToSlash(rel))
+			if err != nil {
+				return nil, fmt.Errorf("修订版本 %s 中不存在目录 %s: %w", rev, rel, err)
+			}
+		}
+	}
+
+	fsys := &gitFS{store: repo.Storer, root: tree, modTime: commit.Committer.When}
+	proj, err := buildProjectTreeFromSource(absPath, fsys, options)
+	if err != nil {
+		return nil, err
+	}
+	proj.inGit = true
+	return proj, nil
+}
+
+// gitFS 以 fs.FS 的形式只读访问 git 目录树，子模块不可见
+type gitFS struct {
+	store   storer.EncodedObjectStorer
+	root    *object.Tree
+	modTime time.Time
+}
+
+// gitEntry git 目录树中的一项
+type gitEntry struct {
+	name string
+	mode filemode.FileMode
+	hash plumbing.Hash
+}
+
+// lookup 按 fs.FS 路径查找目录树中的条目
+func (g *gitFS) lookup(op, name string) (gitEntry, error) {
+	if !fs.ValidPath(name) {
+		return gitEntry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return gitEntry{name: ".", mode: filemode.Dir, hash: g.root.Hash}, nil
+	}
+	entry, err := g.root.FindEntry(name)
+	if err != nil || entry.Mode == filemode.Submodule {
+		return gitEntry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return gitEntry{name: path.Base(name), mode: entry.Mode, hash: entry.Hash}, nil
+}
+
+// Open 实现 fs.FS，文件内容在打开时从 blob 中读取
+func (g *gitFS) Open(name string) (fs.File, error) {
+	entry, err := g.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info := g.fileInfo(entry)
+	if entry.mode == filemode.Dir {
+		entries, err := g.readTree(entry.hash)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &gitDir{info: info, entries: entries}, nil
+	}
+
+	blob, err := object.GetBlob(g.store, entry.hash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return &projectFile{Reader: bytes.NewReader(content), info: info}, nil
+}
+
+// Stat 实现 fs.StatFS
+func (g *gitFS) Stat(name string) (fs.FileInfo, error) {
+	entry, err := g.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return g.fileInfo(entry), nil
+}
+
+// ReadDir 实现 fs.ReadDirFS
+func (g *gitFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entry, err := g.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.mode != filemode.Dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	entries, err := g.readTree(entry.hash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+// readTree 读取目录树对象，返回按名称排序的目录项
+func (g *gitFS) readTree(hash plumbing.Hash) ([]fs.DirEntry, error) {
+	tree, err := object.GetTree(g.store, hash)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		if e.Mode == filemode.Submodule {
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(g.fileInfo(gitEntry{name: e.Name, mode: e.Mode, hash: e.Hash})))
+	}
+	// git 中目录按 "name/" 排序，fs.ReadDir 要求按名称排序
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// fileInfo 生成条目的文件信息，文件大小在首次调用 Size 时才读取
+func (g *gitFS) fileInfo(entry gitEntry) fs.FileInfo {
+	mode, err := entry.mode.ToOSFileMode()
+	if err != nil {
+		mode = 0644
+	}
+	return &gitFileInfo{
+		memFileInfo: memFileInfo{name: entry.name, mode: mode, modTime: g.modTime},
+		store:       g.store,
+		hash:        entry.hash,
+	}
+}
+
+// gitFileInfo 延迟读取 blob 大小的文件信息
+type gitFileInfo struct {
+	memFileInfo
+	store storer.EncodedObjectStorer
+	hash  plumbing.Hash
+	once  sync.Once
+}
+
+func (fi *gitFileInfo) Size() int64 {
+	if fi.mode.IsDir() {
+		return 0
+	}
+	fi.once.Do(func() {
+		if obj, err := fi.store.EncodedObject(plumbing.BlobObject, fi.hash); err == nil {
+			fi.size = obj.Size()
+		}
+	})
+	return fi.size
+}
+
+// gitDir 打开的 git 目录，实现 fs.ReadDirFile
+type gitDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *gitDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *gitDir) Close() error               { return nil }
+
+func (d *gitDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// ReadDir 实现 fs.ReadDirFile，n <= 0 时返回剩余的全部目录项
+func (d *gitDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,324 @@
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GitChange git 中单个文件的变更，Old 与 New 为变更前后的内容
+type GitChange struct {
+	// 相对 repoPath 的斜杠路径
+	Path string
+	Old  []byte
+	New  []byte
+	// 新增的文件没有 Old，删除的文件没有 New
+	Added   bool
+	Deleted bool
+}
+
+// GitChangesSince 返回当前工作区相对 rev 的变更，用于评审分支改动
+// 比较基础为 rev 与 HEAD 的合并基础（与 git diff rev...HEAD 相同），并包含已暂存与未暂存的修改，不含未跟踪的文件
+// repoPath 为仓库子目录时只返回该目录下的变更，结果按路径排序
+func GitChangesSince(repoPath, rev string) ([]GitChange, error) {
+	repo, root, prefix, err := openGitWorktree(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("解析修订版本 %s 失败: %w", rev, err)
+	}
+	base, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 失败: %w", hash, err)
+	}
+	tip, err := headTree(repo)
+	if err != nil {
+		return nil, err
+	}
+	if ref, err := repo.Head(); err == nil {
+		if head, err := repo.CommitObject(ref.Hash()); err == nil {
+			if bases, err := base.MergeBase(head); err == nil && len(bases) > 0 {
+				base = bases[0]
+			}
+		}
+	}
+	baseTree, err := base.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 的目录树失败: %w", base.Hash, err)
+	}
+
+	// 候选路径：基础版本到 HEAD 之间提交的变更，加上工作区与暂存区中的修改
+	paths := make(map[string]bool)
+	if tip != nil {
+		changes, err := object.DiffTree(baseTree, tip)
+		if err != nil {
+			return nil, fmt.Errorf("比较目录树失败: %w", err)
+		}
+		for _, c := range changes {
+			paths[c.From.Name] = true
+			paths[c.To.Name] = true
+		}
+	}
+	status, err := worktreeStatus(repo)
+	if err != nil {
+		return nil, err
+	}
+	for path, s := range status {
+		if s.Worktree == git.Untracked || (s.Worktree == git.Unmodified && s.Staging == git.Unmodified) {
+			continue
+		}
+		paths[path] = true
+	}
+
+	var result []GitChange
+	for _, path := range sortedGitPaths(paths, prefix) {
+		oldContent, oldOK := treeFileContent(baseTree, path)
+		newContent, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+		newOK := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+		}
+		if change, ok := newGitChange(path, prefix, oldContent, oldOK, newContent, newOK); ok {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// GitStagedChanges 返回暂存区相对 HEAD 的变更（即 git diff --cached），结果按路径排序
+func GitStagedChanges(repoPath string) ([]GitChange, error) {
+	repo, _, prefix, err := openGitWorktree(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := headTree(repo)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("读取暂存区失败: %w", err)
+	}
+	status, err := worktreeStatus(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool)
+	for path, s := range status {
+		if s.Staging != git.Unmodified && s.Staging != git.Untracked {
+			paths[path] = true
+		}
+	}
+
+	var result []GitChange
+	for _, path := range sortedGitPaths(paths, prefix) {
+		oldContent, oldOK := treeFileContent(tree, path)
+		var newContent []byte
+		newOK := false
+		if entry, err := idx.Entry(path); err == nil {
+			blob, err := repo.BlobObject(entry.Hash)
+			if err != nil {
+				return nil, fmt.Errorf("读取暂存的 %s 失败: %w", path, err)
+			}
+			if newContent, err = readBlob(blob); err != nil {
+				return nil, fmt.Errorf("读取暂存的 %s 失败: %w", path, err)
+			}
+			newOK = true
+		}
+		if change, ok := newGitChange(path, prefix, oldContent, oldOK, newContent, newOK); ok {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// GitChurn 统计 repoPath 下每个文件在 HEAD 历史中被修改的非合并提交数，用于按修改次数排序
+// 键为相对 repoPath 的斜杠路径，计数与 git log --no-merges --name-only --relative 相同（不检测重命名）；仓库还没有提交时返回空结果
+func GitChurn(repoPath string) (map[string]int, error) {
+	repo, _, prefix, err := openGitWorktree(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	ref, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return counts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 HEAD 失败: %w", err)
+	}
+	commits, err := repo.Log(&git.LogOptions{From: ref.Hash()})
+	if err != nil {
+		return nil, fmt.Errorf("读取提交历史失败: %w", err)
+	}
+	err = commits.ForEach(func(c *object.Commit) error {
+		if c.NumParents() > 1 {
+			return nil
+		}
+		tree, err := c.Tree()
+		if err != nil {
+			return fmt.Errorf("读取提交 %s 的目录树失败: %w", c.Hash, err)
+		}
+		var parentTree *object.Tree
+		if c.NumParents() == 1 {
+			parent, err := c.Parent(0)
+			if err != nil {
+				return fmt.Errorf("读取提交 %s 的父提交失败: %w", c.Hash, err)
+			}
+			if parentTree, err = parent.Tree(); err != nil {
+				return fmt.Errorf("读取提交 %s 的目录树失败: %w", parent.Hash, err)
+			}
+		}
+		changes, err := object.DiffTree(parentTree, tree)
+		if err != nil {
+			return fmt.Errorf("比较提交 %s 失败: %w", c.Hash, err)
+		}
+		for _, change := range changes {
+			for i, name := range []string{change.From.Name, change.To.Name} {
+				if name == "" || (i == 1 && name == change.From.Name) {
+					continue
+				}
+				if prefix != "" {
+					if !strings.HasPrefix(name, prefix+"/") {
+						continue
+					}
+					name = strings.TrimPrefix(name, prefix+"/")
+				}
+				counts[name]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// openGitWorktree 打开 repoPath 所在的仓库，返回工作区根目录与 repoPath 相对根目录的斜杠前缀
+func openGitWorktree(repoPath string) (*git.Repository, string, string, error) {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, "", "", err
+	}
+	repo, err := git.PlainOpenWithOptions(absPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, "", "", fmt.Errorf("打开 git 仓库失败: %w", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, "", "", fmt.Errorf("仓库没有工作区: %w", err)
+	}
+	root := wt.Filesystem.Root()
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil, "", "", fmt.Errorf("%s 不在仓库 %s 中", absPath, root)
+	}
+	if rel == "." {
+		rel = ""
+	}
+	return repo, root, filepath.ToSlash(rel), nil
+}
+
+// headTree 返回 HEAD 的目录树，仓库还没有提交时返回 nil
+func headTree(repo *git.Repository) (*object.Tree, error) {
+	ref, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 HEAD 失败: %w", err)
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 失败: %w", ref.Hash(), err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("读取提交 %s 的目录树失败: %w", ref.Hash(), err)
+	}
+	return tree, nil
+}
+
+func worktreeStatus(repo *git.Repository) (git.Status, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("仓库没有工作区: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("读取工作区状态失败: %w", err)
+	}
+	return status, nil
+}
+
+// sortedGitPaths 返回位于 prefix 目录下的路径并排序
+func sortedGitPaths(paths map[string]bool, prefix string) []string {
+	var out []string
+	for path := range paths {
+		if path == "" || (prefix != "" && !strings.HasPrefix(path, prefix+"/")) {
+			continue
+		}
+		out = append(out, path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// treeFileContent 读取目录树中的文件内容，文件不存在（或为子模块等非普通文件）时返回 false
+func treeFileContent(tree *object.Tree, path string) ([]byte, bool) {
+	if tree == nil {
+		return nil, false
+	}
+	file, err := tree.File(path)
+	if err != nil {
+		return nil, false
+	}
+	content, err := readBlob(&file.Blob)
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+func readBlob(blob *object.Blob) ([]byte, error) {
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// newGitChange 根据两侧内容生成变更，两侧都不存在或内容相同时返回 false
+func newGitChange(path, prefix string, oldContent []byte, oldOK bool, newContent []byte, newOK bool) (GitChange, bool) {
+	if !oldOK && !newOK {
+		return GitChange{}, false
+	}
+	if oldOK && newOK && bytes.Equal(oldContent, newContent) {
+		return GitChange{}, false
+	}
+	if prefix != "" {
+		path = strings.TrimPrefix(path, prefix+"/")
+	}
+	return GitChange{
+		Path:    path,
+		Old:     oldContent,
+		New:     newContent,
+		Added:   !oldOK,
+		Deleted: !newOK,
+	}, true
+}
@@ -0,0 +1,198 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGitTestRepo 创建包含两次提交的临时仓库，第一次提交打上标签 v1
+func newGitTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(files map[string]string, msg string) {
+		for name, content := range files {
+			full := filepath.Join(dir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+			require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+			_, err := wt.Add(name)
+			require.NoError(t, err)
+		}
+		_, err := wt.Commit(msg, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+	}
+
+	commit(map[string]string{
+		".gitignore":      "*.log\n",
+		"README.md":       "# v1\n",
+		"src/main.go":     "package main\n",
+		"src/lib/lib.go":  "package lib\n",
+		"src/notes.txt":   "notes\n",
+		"build/debug.log": "tracked but ignored\n",
+	}, "first")
+	head, err := repo.Head()
+	require.NoError(t, err)
+	_, err = repo.CreateTag("v1", head.Hash(), nil)
+	require.NoError(t, err)
+
+	commit(map[string]string{
+		"README.md":  "# v2\n",
+		"src/new.go": "package main\n",
+	}, "second")
+	return dir
+}
+
+func TestBuildProjectTreeFromGit(t *testing.T) {
+	dir := newGitTestRepo(t)
+	// 工作区中未提交的修改不应出现在历史版本中
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# dirty\n"), 0644))
+
+	proj, err := BuildProjectTreeFromGit(dir, "HEAD~1", DefaultWalkDirOptions())
+	require.NoError(t, err)
+	assert.True(t, proj.IsInGit())
+
+	files, err := proj.GetAllFiles()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/.gitignore", "/README.md", "/src/main.go", "/src/lib/lib.go", "/src/notes.txt"}, files)
+
+	node, err := proj.FindNode("/README.md")
+	require.NoError(t, err)
+	assert.False(t, node.ContentLoaded, "content should be loaded lazily")
+	assert.Equal(t, int64(len("# v1\n")), node.Info.Size())
+	content, err := proj.ReadFile("/README.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# v1\n", string(content))
+
+	require.NoError(t, fstest.TestFS(proj.source, "README.md", "src/main.go", "src/lib/lib.go"))
+
+	// 标签与 HEAD 同样可以解析
+	tagged, err := BuildProjectTreeFromGit(dir, "v1", DefaultWalkDirOptions())
+	require.NoError(t, err)
+	taggedHash, err := tagged.Root().CalculateHash()
+	require.NoError(t, err)
+	projHash, err := proj.Root().CalculateHash()
+	require.NoError(t, err)
+	assert.Equal(t, projHash, taggedHash)
+
+	latest, err := BuildProjectTreeFromGit(dir, "HEAD", DefaultWalkDirOptions())
+	require.NoError(t, err)
+	content, err = latest.ReadFile("/README.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# v2\n", string(content))
+	_, err = latest.FindNode("/src/new.go")
+	assert.NoError(t, err)
+
+	// 修改只保存在内存中，不会写入工作区
+	require.NoError(t, proj.WriteFile("/README.md", []byte("# changed\n")))
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# dirty\n", string(data))
+	assert.ErrorIs(t, proj.SaveToFS(), ErrNotOnDisk)
+	assert.ErrorIs(t, proj.EnableJournal(), ErrNotOnDisk)
+
+	_, err = BuildProjectTreeFromGit(dir, "no-such-rev", DefaultWalkDirOptions())
+	assert.Error(t, err)
+}
+
+func TestBuildProjectTreeFromGitSubdirAndFilters(t *testing.T) {
+	dir := newGitTestRepo(t)
+
+	proj, err := BuildProjectTreeFromGit(filepath.Join(dir, "src"), "v1", helper.WalkDirOptions{Extensions: []string{"go"}})
+	require.NoError(t, err)
+	files, err := proj.GetAllFiles()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/main.go", "/lib/lib.go"}, files)
+
+	// 子目录在该版本中不存在
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "later"), 0755))
+	_, err = BuildProjectTreeFromGit(filepath.Join(dir, "later"), "v1", DefaultWalkDirOptions())
+	assert.Error(t, err)
+}
+
+func TestGitChanges(t *testing.T) {
+	dir := newGitTestRepo(t)
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	repo, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	// 暂存 lib.go 之后继续修改工作区，其余为未暂存的修改、删除与未跟踪的文件
+	write("src/lib/lib.go", "package lib\n\nfunc Staged() {}\n")
+	_, err = wt.Add("src/lib/lib.go")
+	require.NoError(t, err)
+	write("src/lib/lib.go", "package lib\n\nfunc Worktree() {}\n")
+	write("src/main.go", "package main\n\nfunc main() {}\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "src/notes.txt")))
+	write("untracked.go", "package main\n")
+
+	changes, err := GitChangesSince(dir, "v1")
+	require.NoError(t, err)
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	assert.Equal(t, []string{"README.md", "src/lib/lib.go", "src/main.go", "src/new.go", "src/notes.txt"}, paths)
+	assert.Equal(t, "# v1\n", string(changes[0].Old))
+	assert.Equal(t, "# v2\n", string(changes[0].New))
+	assert.Equal(t, "package lib\n\nfunc Worktree() {}\n", string(changes[1].New))
+	assert.True(t, changes[3].Added)
+	assert.True(t, changes[4].Deleted)
+	assert.Nil(t, changes[4].New)
+
+	// 子目录只包含其中的变更，路径相对子目录
+	changes, err = GitChangesSince(filepath.Join(dir, "src"), "v1")
+	require.NoError(t, err)
+	paths = nil
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	assert.Equal(t, []string{"lib/lib.go", "main.go", "new.go", "notes.txt"}, paths)
+
+	staged, err := GitStagedChanges(dir)
+	require.NoError(t, err)
+	require.Len(t, staged, 1)
+	assert.Equal(t, "src/lib/lib.go", staged[0].Path)
+	assert.Equal(t, "package lib\n", string(staged[0].Old))
+	assert.Equal(t, "package lib\n\nfunc Staged() {}\n", string(staged[0].New))
+
+	_, err = GitChangesSince(dir, "no-such-rev")
+	assert.Error(t, err)
+	_, err = GitStagedChanges(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestGitChurn(t *testing.T) {
+	dir := newGitTestRepo(t)
+
+	churn, err := GitChurn(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, churn["README.md"])
+	assert.Equal(t, 1, churn["src/main.go"])
+	assert.Equal(t, 1, churn["src/new.go"])
+
+	// 子目录的路径相对子目录
+	churn, err = GitChurn(filepath.Join(dir, "src"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"main.go": 1, "lib/lib.go": 1, "notes.txt": 1, "new.go": 1}, churn)
+
+	_, err = GitChurn(t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,355 @@
+package gosearch
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/search"
+)
+
+// Kind 声明种类
+type Kind string
+
+const (
+	KindFunc      Kind = "func"
+	KindMethod    Kind = "method"
+	KindStruct    Kind = "struct"
+	KindInterface Kind = "interface"
+	KindType      Kind = "type" // 其他命名类型与别名；查询时 type 同时匹配 struct 与 interface
+	KindField     Kind = "field"
+)
+
+// Symbol 一个 Go 声明
+type Symbol struct {
+	Kind      Kind   `json:"kind"`
+	Name      string `json:"name"`
+	Package   string `json:"package"`
+	Receiver  string `json:"receiver,omitempty"` // 方法的接收者类型名（不含 * 与类型参数），字段所属的结构体名
+	Pointer   bool   `json:"pointer,omitempty"`  // 方法是否为指针接收者
+	Signature string `json:"signature"`
+	// 函数与方法的参数、返回值类型（按声明顺序，a, b int 展开为两个 int）
+	Params  []string `json:"params,omitempty"`
+	Results []string `json:"results,omitempty"`
+	// 接口声明的方法签名；其他命名类型声明的方法名
+	Methods  []string `json:"methods,omitempty"`
+	Path     string   `json:"path"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Exported bool     `json:"exported"`
+
+	dir    string      // 所在目录，同一目录的声明视为同一个包
+	embeds []string    // 接口嵌入的其他接口
+	sigs   []methodSig // 接口的方法（不含嵌入）；方法自身的签名
+}
+
+// methodSig 用于判断接口实现的方法签名，类型去掉包限定符后比较
+type methodSig struct {
+	name string
+	sig  string
+}
+
+// Index 项目中 Go 文件的声明索引
+type Index struct {
+	Symbols []*Symbol
+	Files   int
+	// 解析失败的文件（项目路径）
+	Failed []string
+
+	// 目录 + 类型名 -> 该类型声明的方法
+	methods map[string][]*Symbol
+}
+
+// Build 解析 root 子树下按 opts 过滤后的 .go 文件，建立声明索引（按路径、行号排序）
+// opts 为 nil 时使用默认搜索选项；扩展名条件总是限定为 go
+func Build(ctx context.Context, root *project.Node, opts *search.SearchOptions) (*Index, error) {
+	var o search.SearchOptions
+	if opts != nil {
+		o = *opts
+	} else {
+		o = *search.DefaultSearchOptions()
+	}
+	o.Extensions = []string{"go"}
+	o.IncludeFiles = true
+	o.IncludeDirs = false
+	nodes, err := search.Search(ctx, root, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	type parsed struct {
+		symbols []*Symbol
+		err     error
+	}
+	results := make([]parsed, len(nodes))
+	workers := runtime.NumCPU()
+	if o.MaxWorkers > 0 {
+		workers = o.MaxWorkers
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, n *project.Node) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			content, err := n.ReadContent()
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			results[i].symbols, results[i].err = parseFile(n.Path, content)
+		}(i, n)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ix := &Index{Files: len(nodes), methods: make(map[string][]*Symbol)}
+	for i, r := range results {
+		if r.err != nil {
+			ix.Failed = append(ix.Failed, nodes[i].Path)
+			continue
+		}
+		ix.Symbols = append(ix.Symbols, r.symbols...)
+	}
+	sort.SliceStable(ix.Symbols, func(i, j int) bool {
+		a, b := ix.Symbols[i], ix.Symbols[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	// 方法归入所属类型，命名类型记录方法名
+	for _, s := range ix.Symbols {
+		if s.Kind == KindMethod {
+			key := s.dir + "\x00" + s.Receiver
+			ix.methods[key] = append(ix.methods[key], s)
+		}
+	}
+	for _, s := range ix.Symbols {
+		if s.Kind == KindStruct || s.Kind == KindType {
+			for _, m := range ix.methods[s.dir+"\x00"+s.Name] {
+				s.Methods = append(s.Methods, m.Name)
+			}
+		}
+	}
+	return ix, nil
+}
+
+// parseFile 解析单个文件中的顶层声明
+func parseFile(filePath string, content []byte) ([]*Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	pkg := file.Name.Name
+	dir := path.Dir(filePath)
+	newSymbol := func(kind Kind, name string, pos token.Pos) *Symbol {
+		p := fset.Position(pos)
+		return &Symbol{
+			Kind:     kind,
+			Name:     name,
+			Package:  pkg,
+			Path:     filePath,
+			Line:     p.Line,
+			Column:   p.Column,
+			Exported: ast.IsExported(name),
+			dir:      dir,
+		}
+	}
+
+	var symbols []*Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			s := newSymbol(KindFunc, d.Name.Name, d.Name.Pos())
+			recv := ""
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				s.Kind = KindMethod
+				s.Receiver, s.Pointer = receiverName(d.Recv.List[0].Type)
+				recv = "(" + fieldList(d.Recv, true) + ") "
+				s.sigs = []methodSig{{name: d.Name.Name, sig: unqualify(funcSignature(d.Type, false))}}
+			}
+			s.Params = fieldTypes(d.Type.Params)
+			s.Results = fieldTypes(d.Type.Results)
+			s.Signature = "func " + recv + d.Name.Name + typeParams(d.Type.TypeParams) + funcSignature(d.Type, true)
+			symbols = append(symbols, s)
+
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				s := newSymbol(KindType, ts.Name.Name, ts.Name.Pos())
+				head := "type " + ts.Name.Name + typeParams(ts.TypeParams) + " "
+				switch t := ts.Type.(type) {
+				case *ast.StructType:
+					s.Kind = KindStruct
+					s.Signature = head + "struct"
+					symbols = append(symbols, s)
+					for _, f := range t.Fields.List {
+						typ := types.ExprString(f.Type)
+						names := f.Names
+						if len(names) == 0 {
+							// 嵌入字段以类型名为字段名
+							name, _ := receiverName(f.Type)
+							names = []*ast.Ident{{Name: name, NamePos: f.Type.Pos()}}
+						}
+						for _, name := range names {
+							field := newSymbol(KindField, name.Name, name.Pos())
+							field.Receiver = ts.Name.Name
+							field.Signature = ts.Name.Name + "." + name.Name + " " + typ
+							symbols = append(symbols, field)
+						}
+					}
+					continue
+				case *ast.InterfaceType:
+					s.Kind = KindInterface
+					s.Signature = head + "interface"
+					for _, m := range t.Methods.List {
+						ft, ok := m.Type.(*ast.FuncType)
+						if !ok {
+							// 嵌入的接口或类型约束
+							s.embeds = append(s.embeds, types.ExprString(m.Type))
+							continue
+						}
+						for _, name := range m.Names {
+							s.Methods = append(s.Methods, name.Name+funcSignature(ft, true))
+							s.sigs = append(s.sigs, methodSig{name: name.Name, sig: unqualify(funcSignature(ft, false))})
+						}
+					}
+				default:
+					if ts.Assign.IsValid() {
+						s.Signature = head + "= " + types.ExprString(ts.Type)
+					} else {
+						s.Signature = head + types.ExprString(ts.Type)
+					}
+				}
+				symbols = append(symbols, s)
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// receiverName 返回接收者（或嵌入字段）的类型名及是否为指针
+func receiverName(expr ast.Expr) (string, bool) {
+	pointer := false
+	if star, ok := expr.(*ast.StarExpr); ok {
+		pointer = true
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name, pointer
+	case *ast.SelectorExpr:
+		return t.Sel.Name, pointer
+	}
+	return types.ExprString(expr), pointer
+}
+
+// funcSignature 返回形如 (ctx context.Context, name string) error 的签名，withNames 为 false 时省略参数名
+func funcSignature(ft *ast.FuncType, withNames bool) string {
+	sig := "(" + fieldList(ft.Params, withNames) + ")"
+	if ft.Results == nil || len(ft.Results.List) == 0 {
+		return sig
+	}
+	results := fieldList(ft.Results, withNames)
+	if len(ft.Results.List) == 1 && (len(ft.Results.List[0].Names) == 0 || !withNames) && !strings.Contains(results, ",") {
+		return sig + " " + results
+	}
+	return sig + " (" + results + ")"
+}
+
+// fieldList 把参数列表格式化为逗号分隔的字符串
+func fieldList(fl *ast.FieldList, withNames bool) string {
+	if fl == nil {
+		return ""
+	}
+	var parts []string
+	for _, f := range fl.List {
+		typ := types.ExprString(f.Type)
+		if !withNames || len(f.Names) == 0 {
+			n := len(f.Names)
+			if n == 0 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				parts = append(parts, typ)
+			}
+			continue
+		}
+		names := make([]string, len(f.Names))
+		for i, name := range f.Names {
+			names[i] = name.Name
+		}
+		parts = append(parts, strings.Join(names, ", ")+" "+typ)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fieldTypes 返回参数列表中每个参数的类型
+func fieldTypes(fl *ast.FieldList) []string {
+	if fl == nil {
+		return nil
+	}
+	var out []string
+	for _, f := range fl.List {
+		typ := types.ExprString(f.Type)
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			out = append(out, typ)
+		}
+	}
+	return out
+}
+
+// typeParams 格式化类型参数列表，如 [K comparable, V any]
+func typeParams(fl *ast.FieldList) string {
+	if fl == nil || len(fl.List) == 0 {
+		return ""
+	}
+	return "[" + fieldList(fl, true) + "]"
+}
+
+// String 返回 路径:行:列: 签名 形式的描述，字段以 field 开头
+func (s *Symbol) String() string {
+	sig := s.Signature
+	if s.Kind == KindField {
+		sig = "field " + sig
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", strings.TrimPrefix(s.Path, "/"), s.Line, s.Column, sig)
+}
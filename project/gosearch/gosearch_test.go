@@ -0,0 +1,156 @@
+package gosearch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildIndex(t *testing.T) *Index {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"store/store.go": `package store
+
+import (
+	"context"
+	"io"
+)
+
+// Store 存储接口
+type Store interface {
+	io.Closer
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+type Memory struct {
+	data map[string][]byte
+	io.Writer
+}
+
+func NewMemory() *Memory { return &Memory{} }
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) { return m.data[key], nil }
+
+func (m *Memory) Close() error { return nil }
+
+type Set[T comparable] map[T]struct{}
+
+func (s Set[T]) Add(v T) { s[v] = struct{}{} }
+`,
+		"store/disk.go": `package store
+
+import "context"
+
+type Disk struct{ Root string }
+
+func (d Disk) Get(_ context.Context, key string) ([]byte, error) { return nil, nil }
+
+func load(ctx context.Context, paths ...string) (n int, err error) { return 0, nil }
+
+type getter interface {
+	Get(context.Context, string) ([]byte, error)
+}
+`,
+		"cmd/main.go": `package main
+
+type ID = string
+
+func (ID) bad( {
+`,
+		"README.md": "# not go\n",
+	}
+	for name, content := range files {
+		full := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+	ix, err := Build(context.Background(), proj.Root(), nil)
+	require.NoError(t, err)
+	return ix
+}
+
+func names(symbols []*Symbol) []string {
+	out := make([]string, len(symbols))
+	for i, s := range symbols {
+		out[i] = s.Name
+	}
+	return out
+}
+
+func find(t *testing.T, ix *Index, q *Query) []string {
+	t.Helper()
+	symbols, err := ix.Find(q)
+	require.NoError(t, err)
+	return names(symbols)
+}
+
+func TestBuild(t *testing.T) {
+	ix := buildIndex(t)
+	assert.Equal(t, 3, ix.Files)
+	assert.Equal(t, []string{"/cmd/main.go"}, ix.Failed)
+
+	byName := map[string]*Symbol{}
+	for _, s := range ix.Symbols {
+		if key := string(s.Kind) + " " + s.Name; byName[key] == nil {
+			byName[key] = s
+		}
+	}
+
+	get := byName["method Get"]
+	require.NotNil(t, get)
+	assert.Equal(t, "Disk", get.Receiver, "symbols are sorted by path, disk.go first")
+	assert.False(t, get.Pointer)
+	assert.Equal(t, "func (d Disk) Get(_ context.Context, key string) ([]byte, error)", get.Signature)
+	assert.Equal(t, []string{"context.Context", "string"}, get.Params)
+	assert.Equal(t, "/store/disk.go", get.Path)
+	assert.Equal(t, 7, get.Line)
+
+	load := byName["func load"]
+	require.NotNil(t, load)
+	assert.Equal(t, "func load(ctx context.Context, paths ...string) (n int, err error)", load.Signature)
+	assert.Equal(t, []string{"int", "error"}, load.Results)
+	assert.False(t, load.Exported)
+
+	assert.Equal(t, "type Set[T comparable] map[T]struct{}", byName["type Set"].Signature)
+	assert.Equal(t, "Set", byName["method Add"].Receiver)
+	assert.Equal(t, []string{"Get", "Close"}, byName["struct Memory"].Methods)
+	assert.Equal(t, []string{"Get(ctx context.Context, key string) ([]byte, error)"}, byName["interface Store"].Methods)
+	assert.Equal(t, "Memory.Writer io.Writer", byName["field Writer"].Signature)
+	assert.Equal(t, "store/store.go:16:2: field Memory.Writer io.Writer", byName["field Writer"].String())
+	assert.Equal(t, "store/store.go:9:6: type Store interface", byName["interface Store"].String())
+}
+
+func TestFind(t *testing.T) {
+	ix := buildIndex(t)
+
+	assert.Equal(t, []string{"Get", "load", "Get"}, find(t, ix, &Query{Params: []string{"context.Context"}, Results: []string{"error"}}))
+	assert.Equal(t, []string{"Get", "load", "Get"}, find(t, ix, &Query{Params: []string{"Context"}}))
+	assert.Equal(t, []string{"load"}, find(t, ix, &Query{Params: []string{"...string"}}))
+	assert.Equal(t, []string{"Get", "Close"}, find(t, ix, &Query{Receiver: "*Memory"}))
+	assert.Empty(t, find(t, ix, &Query{Receiver: "*Disk"}))
+	assert.Equal(t, []string{"Disk", "getter", "Store", "Memory", "Set"}, find(t, ix, &Query{Kinds: []Kind{KindType}}))
+	assert.Equal(t, []string{"NewMemory"}, find(t, ix, &Query{Kinds: []Kind{KindFunc}, ExportedOnly: true}))
+	assert.Equal(t, []string{"Root", "data", "Writer"}, find(t, ix, &Query{Kinds: []Kind{KindField}}))
+	assert.Equal(t, []string{"Memory"}, find(t, ix, &Query{HasMethod: "Close"}))
+	assert.Equal(t, []string{"Memory"}, find(t, ix, &Query{Name: "^Mem", Kinds: []Kind{KindStruct}}))
+
+	// 实现接口：嵌入的 io.Closer 使用内置定义
+	assert.Equal(t, []string{"Memory"}, find(t, ix, &Query{Implements: "Store"}))
+	assert.Equal(t, []string{"Memory"}, find(t, ix, &Query{Implements: "store.Store"}))
+	assert.Equal(t, []string{"Disk", "Memory"}, find(t, ix, &Query{Implements: "getter"}))
+	assert.Equal(t, []string{"Memory"}, find(t, ix, &Query{Implements: "io.Closer"}))
+
+	_, err := ix.Find(&Query{Implements: "Missing"})
+	assert.Error(t, err)
+	_, err = ix.Find(&Query{Name: "("})
+	assert.Error(t, err)
+}
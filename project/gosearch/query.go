@@ -0,0 +1,224 @@
+package gosearch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Query 声明查询条件，各条件之间为“与”关系，零值匹配所有声明
+type Query struct {
+	// 种类，为空不过滤；type 同时匹配 struct、interface 与其他命名类型
+	Kinds []Kind
+	// 名称正则
+	Name string
+	// 接收者类型名（方法）或所属结构体名（字段），写作 *T 时只匹配指针接收者
+	Receiver string
+	// 参数与返回值中必须出现的类型，如 context.Context、error
+	// 不含包名时忽略声明中的包限定符，即 Context 匹配 context.Context
+	Params  []string
+	Results []string
+	// 实现了指定接口的类型（含指针接收者的方法），接口可写作 Name 或 包名.Name
+	// 项目中找不到时使用内置的 error、fmt.Stringer 与 io 常用接口
+	Implements string
+	// 声明了指定名称方法的类型
+	HasMethod string
+	// 包名
+	Package string
+	// 只匹配导出的声明
+	ExportedOnly bool
+}
+
+// builtinInterfaces 项目之外的常用接口
+var builtinInterfaces = map[string][]methodSig{
+	"error":          {{"Error", "() string"}},
+	"fmt.Stringer":   {{"String", "() string"}},
+	"io.Reader":      {{"Read", "([]byte) (int, error)"}},
+	"io.Writer":      {{"Write", "([]byte) (int, error)"}},
+	"io.Closer":      {{"Close", "() error"}},
+	"io.ReadCloser":  {{"Read", "([]byte) (int, error)"}, {"Close", "() error"}},
+	"io.WriteCloser": {{"Write", "([]byte) (int, error)"}, {"Close", "() error"}},
+	"io.ReadWriter":  {{"Read", "([]byte) (int, error)"}, {"Write", "([]byte) (int, error)"}},
+	"sort.Interface": {{"Len", "() int"}, {"Less", "(int, int) bool"}, {"Swap", "(int, int)"}},
+}
+
+var qualifierRe = regexp.MustCompile(`\b[A-Za-z_][A-Za-z0-9_]*\.`)
+
+// unqualify 去掉类型中的包限定符，如 *project.Node -> *Node
+func unqualify(typ string) string {
+	return qualifierRe.ReplaceAllString(typ, "")
+}
+
+// typeMatches 判断声明中的类型是否满足查询类型
+func typeMatches(want, got string) bool {
+	want = strings.ReplaceAll(want, " ", "")
+	got = strings.ReplaceAll(got, " ", "")
+	if want == got {
+		return true
+	}
+	return !strings.Contains(want, ".") && want == unqualify(got)
+}
+
+// containsTypes 判断 want 中的每个类型都出现在 got 中
+func containsTypes(want, got []string) bool {
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if typeMatches(w, g) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Find 返回满足查询条件的声明，q 为 nil 时返回全部
+func (ix *Index) Find(q *Query) ([]*Symbol, error) {
+	if q == nil {
+		q = &Query{}
+	}
+	var nameRe *regexp.Regexp
+	if q.Name != "" {
+		re, err := regexp.Compile(q.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern: %w", err)
+		}
+		nameRe = re
+	}
+	var iface []methodSig
+	if q.Implements != "" {
+		sigs, err := ix.interfaceMethods(q.Implements)
+		if err != nil {
+			return nil, err
+		}
+		iface = sigs
+	}
+	receiver, pointerOnly := strings.TrimPrefix(q.Receiver, "*"), strings.HasPrefix(q.Receiver, "*")
+
+	var out []*Symbol
+	for _, s := range ix.Symbols {
+		if len(q.Kinds) > 0 && !kindMatches(q.Kinds, s.Kind) {
+			continue
+		}
+		if nameRe != nil && !nameRe.MatchString(s.Name) {
+			continue
+		}
+		if q.Package != "" && s.Package != q.Package {
+			continue
+		}
+		if q.ExportedOnly && !s.Exported {
+			continue
+		}
+		if receiver != "" && (s.Receiver != receiver || (pointerOnly && !s.Pointer)) {
+			continue
+		}
+		if len(q.Params) > 0 || len(q.Results) > 0 {
+			if s.Kind != KindFunc && s.Kind != KindMethod {
+				continue
+			}
+			if !containsTypes(q.Params, s.Params) || !containsTypes(q.Results, s.Results) {
+				continue
+			}
+		}
+		if q.HasMethod != "" || iface != nil {
+			if s.Kind != KindStruct && s.Kind != KindType {
+				continue
+			}
+			if q.HasMethod != "" && !contains(s.Methods, q.HasMethod) {
+				continue
+			}
+			if iface != nil && !ix.implements(s, iface) {
+				continue
+			}
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// kindMatches 判断种类是否在查询列表中
+func kindMatches(kinds []Kind, k Kind) bool {
+	for _, want := range kinds {
+		if want == k || (want == KindType && (k == KindStruct || k == KindInterface)) {
+			return true
+		}
+	}
+	return false
+}
+
+// implements 判断类型（或其指针）的方法集是否包含接口的全部方法
+func (ix *Index) implements(s *Symbol, iface []methodSig) bool {
+	have := make(map[string]string)
+	for _, m := range ix.methods[s.dir+"\x00"+s.Name] {
+		have[m.Name] = m.sigs[0].sig
+	}
+	for _, want := range iface {
+		if sig, ok := have[want.name]; !ok || sig != want.sig {
+			return false
+		}
+	}
+	return true
+}
+
+// interfaceMethods 解析接口名称并展开嵌入的接口，返回全部方法
+func (ix *Index) interfaceMethods(name string) ([]methodSig, error) {
+	sigs, err := ix.expandInterface(name, "", map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("interface %s has no methods", name)
+	}
+	return sigs, nil
+}
+
+// expandInterface 查找接口（优先同目录）并递归展开嵌入的接口
+func (ix *Index) expandInterface(name, dir string, seen map[string]bool) ([]methodSig, error) {
+	pkg, base := "", name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		pkg, base = name[:i], name[i+1:]
+	}
+	var found *Symbol
+	for _, s := range ix.Symbols {
+		if s.Kind != KindInterface || s.Name != base || (pkg != "" && s.Package != pkg) {
+			continue
+		}
+		if found == nil || (dir != "" && s.dir == dir) {
+			found = s
+		}
+	}
+	if found == nil {
+		if sigs, ok := builtinInterfaces[name]; ok {
+			return sigs, nil
+		}
+		return nil, fmt.Errorf("interface %s not found", name)
+	}
+	key := found.dir + "\x00" + found.Name
+	if seen[key] {
+		return nil, nil
+	}
+	seen[key] = true
+
+	sigs := append([]methodSig(nil), found.sigs...)
+	for _, embed := range found.embeds {
+		embedded, err := ix.expandInterface(embed, found.dir, seen)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, embedded...)
+	}
+	return sigs, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,434 @@
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project"
+)
+
+// 内容搜索的三元组倒排索引：把每个文件内容（不区分大小写）中出现的三字节片段持久化到
+// ~/.tong/index/<项目>，搜索时先用索引排除不可能匹配的文件，再对剩余文件做实际匹配
+
+// IndexDir 索引存放目录，测试时可替换
+var IndexDir = helper.GetPath("index")
+
+// MaxFileSize 超过该大小的文件不建立索引，搜索时直接读取
+var MaxFileSize int64 = 1 << 20
+
+// 索引格式版本，结构变化时递增以使旧索引失效
+const indexVersion = 1
+
+// ErrNoIndex 项目尚未建立索引
+var ErrNoIndex = errors.New("content index not built")
+
+// Index 项目的内容索引
+type Index struct {
+	rootPath string
+	built    time.Time
+	files    []fileEntry
+	byPath   map[string]int
+	postings map[uint32][]int32 // 三元组 -> 包含它的文件序号（升序）
+}
+
+// fileEntry 已索引的文件，大小与修改时间用于快速判断文件是否变化，变化后再比较内容哈希
+type fileEntry struct {
+	Path    string
+	Hash    string
+	Size    int64
+	ModTime time.Time
+}
+
+// indexFile 索引文件内容
+type indexFile struct {
+	Version  int
+	RootPath string
+	Built    time.Time
+	Files    []fileEntry
+	Postings map[uint32][]int32
+}
+
+// Status 索引相对于当前项目树的状态
+type Status struct {
+	RootPath string    `json:"rootPath"`
+	Built    time.Time `json:"built"`
+	Files    int       `json:"files"`    // 已索引的文件数
+	Fresh    int       `json:"fresh"`    // 自建立索引后未变化的文件数
+	Changed  int       `json:"changed"`  // 已变化的文件数
+	Added    int       `json:"added"`    // 新增（未索引）的文件数
+	Removed  int       `json:"removed"`  // 已删除的文件数
+	Trigrams int       `json:"trigrams"` // 不同三元组的个数
+	Size     int64     `json:"size"`     // 索引文件大小（字节）
+}
+
+// IsFresh 索引是否覆盖了项目中的所有可索引文件
+func (s *Status) IsFresh() bool {
+	return s.Changed == 0 && s.Added == 0 && s.Removed == 0
+}
+
+// BuildStats 建立索引的统计
+type BuildStats struct {
+	Files   int `json:"files"`   // 索引中的文件数
+	Reused  int `json:"reused"`  // 沿用原有索引的文件数
+	Indexed int `json:"indexed"` // 重新读取并索引的文件数
+	Skipped int `json:"skipped"` // 跳过的文件数（二进制、过大或有未保存的修改）
+}
+
+// Build 为项目建立或增量更新索引并写入磁盘
+// 已有索引时，大小与修改时间未变或内容哈希相同的文件沿用原有结果；rebuild 为 true 时忽略已有索引
+func Build(ctx context.Context, p *project.Project, rebuild bool) (*Index, *BuildStats, error) {
+	if !p.IsOnDisk() {
+		return nil, nil, project.ErrNotOnDisk
+	}
+	rootPath, err := filepath.Abs(p.GetRootPath())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 已有索引无法读取时按完整重建处理
+	var old *Index
+	if !rebuild {
+		old, _ = Load(p)
+	}
+	var oldTrigrams map[int][]uint32
+	if old != nil {
+		oldTrigrams = old.forward()
+	}
+
+	ix := &Index{
+		rootPath: rootPath,
+		built:    time.Now(),
+		byPath:   make(map[string]int),
+		postings: make(map[uint32][]int32),
+	}
+	stats := &BuildStats{}
+	err = p.Visit(func(path string, n *project.Node, depth int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if n.IsDir {
+			return nil
+		}
+		info, ok := indexable(p, n)
+		if !ok {
+			stats.Skipped++
+			return nil
+		}
+		entry := fileEntry{Path: n.Path, Size: info.Size(), ModTime: info.ModTime()}
+
+		if old != nil {
+			if id, exists := old.byPath[n.Path]; exists {
+				prev := old.files[id]
+				if prev.Size == entry.Size && prev.ModTime.Equal(entry.ModTime) {
+					entry.Hash = prev.Hash
+					ix.add(entry, oldTrigrams[id])
+					stats.Reused++
+					return nil
+				}
+				if hash, err := n.CalculateHash(); err == nil && hash == prev.Hash {
+					entry.Hash = hash
+					ix.add(entry, oldTrigrams[id])
+					stats.Reused++
+					release(n)
+					return nil
+				}
+			}
+		}
+
+		content, err := n.ReadContent()
+		if err != nil {
+			stats.Skipped++
+			return nil
+		}
+		hash := sha256.Sum256(content)
+		entry.Hash = hex.EncodeToString(hash[:])
+		ix.add(entry, contentTrigrams(content))
+		stats.Indexed++
+		release(n)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	stats.Files = len(ix.files)
+
+	if err := ix.save(); err != nil {
+		return nil, nil, err
+	}
+	forget(rootPath)
+	return ix, stats, nil
+}
+
+// Load 读取项目的索引，未建立时返回 ErrNoIndex
+func Load(p *project.Project) (*Index, error) {
+	if !p.IsOnDisk() {
+		return nil, ErrNoIndex
+	}
+	rootPath, err := filepath.Abs(p.GetRootPath())
+	if err != nil {
+		return nil, err
+	}
+	return load(rootPath)
+}
+
+// Drop 删除项目的索引
+func Drop(p *project.Project) error {
+	rootPath, err := filepath.Abs(p.GetRootPath())
+	if err != nil {
+		return err
+	}
+	forget(rootPath)
+	if err := os.RemoveAll(indexDir(rootPath)); err != nil {
+		return fmt.Errorf("删除索引失败: %w", err)
+	}
+	return nil
+}
+
+// Status 对比项目树与索引，统计文件的变化情况
+func (ix *Index) Status(p *project.Project) *Status {
+	s := &Status{
+		RootPath: ix.rootPath,
+		Built:    ix.built,
+		Files:    len(ix.files),
+		Trigrams: len(ix.postings),
+	}
+	if info, err := os.Stat(indexFilePath(ix.rootPath)); err == nil {
+		s.Size = info.Size()
+	}
+
+	seen := make(map[string]bool)
+	p.Visit(func(path string, n *project.Node, depth int) error {
+		if n.IsDir {
+			return nil
+		}
+		id, exists := ix.byPath[n.Path]
+		if !exists {
+			if _, ok := indexable(p, n); ok {
+				s.Added++
+			}
+			return nil
+		}
+		seen[n.Path] = true
+		if ix.fresh(p, n, id) {
+			s.Fresh++
+		} else {
+			s.Changed++
+		}
+		return nil
+	})
+	s.Removed = len(ix.files) - len(seen)
+	return s
+}
+
+// MayContain 判断文件是否可能满足查询：文件已索引且自建立索引后未变化，并且缺少查询的某个三元组时返回 false，
+// 其他情况（未索引、已变化、有未保存的修改或查询为 nil）一律返回 true，由调用方读取内容确认
+func (ix *Index) MayContain(n *project.Node, q *Query) bool {
+	if ix == nil || q == nil || n.IsDir {
+		return true
+	}
+	id, exists := ix.byPath[n.Path]
+	if !exists {
+		return true
+	}
+	p := n.GetProject()
+	if p == nil || !ix.fresh(p, n, id) {
+		return true
+	}
+	for _, t := range q.trigrams {
+		list := ix.postings[t]
+		i := sort.Search(len(list), func(i int) bool { return list[i] >= int32(id) })
+		if i == len(list) || list[i] != int32(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// fresh 判断已索引的文件自建立索引后是否未变化
+func (ix *Index) fresh(p *project.Project, n *project.Node, id int) bool {
+	if n.IsModified() {
+		return false
+	}
+	info, err := os.Stat(p.GetAbsolutePath(n.Path))
+	if err != nil {
+		return false
+	}
+	entry := ix.files[id]
+	return entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime())
+}
+
+// add 加入一个文件及其三元组
+func (ix *Index) add(entry fileEntry, trigrams []uint32) {
+	id := int32(len(ix.files))
+	ix.files = append(ix.files, entry)
+	ix.byPath[entry.Path] = int(id)
+	for _, t := range trigrams {
+		ix.postings[t] = append(ix.postings[t], id)
+	}
+}
+
+// forward 由倒排表还原每个文件的三元组
+func (ix *Index) forward() map[int][]uint32 {
+	result := make(map[int][]uint32, len(ix.files))
+	for t, ids := range ix.postings {
+		for _, id := range ids {
+			result[int(id)] = append(result[int(id)], t)
+		}
+	}
+	return result
+}
+
+// save 把索引写入磁盘，先写临时文件再重命名
+func (ix *Index) save() error {
+	dir := indexDir(ix.rootPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建索引目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "index.*.tmp")
+	if err != nil {
+		return fmt.Errorf("写入索引失败: %w", err)
+	}
+	data := indexFile{
+		Version:  indexVersion,
+		RootPath: ix.rootPath,
+		Built:    ix.built,
+		Files:    ix.files,
+		Postings: ix.postings,
+	}
+	if err := gob.NewEncoder(tmp).Encode(&data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入索引失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入索引失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), indexFilePath(ix.rootPath)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入索引失败: %w", err)
+	}
+	return nil
+}
+
+// load 读取根路径对应的索引文件
+func load(rootPath string) (*Index, error) {
+	f, err := os.Open(indexFilePath(rootPath))
+	if os.IsNotExist(err) {
+		return nil, ErrNoIndex
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var data indexFile
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return nil, fmt.Errorf("读取索引失败: %w", err)
+	}
+	if data.Version != indexVersion || data.RootPath != rootPath {
+		return nil, ErrNoIndex
+	}
+	ix := &Index{
+		rootPath: data.RootPath,
+		built:    data.Built,
+		files:    data.Files,
+		byPath:   make(map[string]int, len(data.Files)),
+		postings: data.Postings,
+	}
+	if ix.postings == nil {
+		ix.postings = make(map[uint32][]int32)
+	}
+	for i, entry := range ix.files {
+		ix.byPath[entry.Path] = i
+	}
+	return ix, nil
+}
+
+// indexable 判断文件是否可以建立索引，可以时返回磁盘上的文件信息
+func indexable(p *project.Project, n *project.Node) (os.FileInfo, bool) {
+	if n.IsModified() || n.Kind().Binary {
+		return nil, false
+	}
+	info, err := os.Stat(p.GetAbsolutePath(n.Path))
+	if err != nil || !info.Mode().IsRegular() || info.Size() > MaxFileSize {
+		return nil, false
+	}
+	return info, true
+}
+
+// release 索引只需要内容的三元组，读取后释放未修改文件的内容，避免大型项目占用过多内存
+func release(n *project.Node) {
+	if !n.IsModified() {
+		n.UnloadContent()
+	}
+}
+
+// indexDir 返回根路径对应的索引目录
+func indexDir(rootPath string) string {
+	sum := sha256.Sum256([]byte(rootPath))
+	return filepath.Join(IndexDir, hex.EncodeToString(sum[:16]))
+}
+
+// indexFilePath 返回根路径对应的索引文件
+func indexFilePath(rootPath string) string {
+	return filepath.Join(indexDir(rootPath), "index.gob")
+}
+
+// 已加载的索引，按索引文件的修改时间判断是否需要重新读取
+var (
+	loadedMu sync.Mutex
+	loaded   = make(map[string]*loadedIndex)
+)
+
+type loadedIndex struct {
+	ix      *Index
+	modTime time.Time
+}
+
+// For 返回项目已建立的索引，供搜索透明使用；未建立索引或项目不在磁盘上时返回 nil
+// 索引在进程内缓存，索引文件更新后自动重新读取
+func For(p *project.Project) *Index {
+	if p == nil || !p.IsOnDisk() {
+		return nil
+	}
+	rootPath, err := filepath.Abs(p.GetRootPath())
+	if err != nil {
+		return nil
+	}
+	info, err := os.Stat(indexFilePath(rootPath))
+	if err != nil {
+		forget(rootPath)
+		return nil
+	}
+
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+	if cached, ok := loaded[rootPath]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.ix
+	}
+	ix, err := load(rootPath)
+	if err != nil {
+		delete(loaded, rootPath)
+		return nil
+	}
+	loaded[rootPath] = &loadedIndex{ix: ix, modTime: info.ModTime()}
+	return ix
+}
+
+// forget 清除进程内缓存的索引
+func forget(rootPath string) {
+	loadedMu.Lock()
+	delete(loaded, rootPath)
+	loadedMu.Unlock()
+}
@@ -0,0 +1,139 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupProject 创建测试项目并把索引目录指向临时目录
+func setupProject(t *testing.T) (*project.Project, string) {
+	t.Helper()
+	old := IndexDir
+	IndexDir = t.TempDir()
+	t.Cleanup(func() { IndexDir = old })
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":     "package main\n\nfunc main() { println(\"Hello\") }\n",
+		"util/str.go": "package util\n\n// TODO: trim\nfunc Trim() {}\n",
+		"docs/a.md":   "# Guide\nSee Kubernetes docs.\n",
+		"data.bin":    string([]byte{0, 1, 2, 0, 'a', 'b', 'c'}),
+	}
+	for name, content := range files {
+		full := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+	return proj, dir
+}
+
+func findNode(t *testing.T, proj *project.Project, path string) *project.Node {
+	t.Helper()
+	n, err := proj.FindNode(path)
+	require.NoError(t, err)
+	return n
+}
+
+func TestBuildAndMayContain(t *testing.T) {
+	proj, dir := setupProject(t)
+	assert.Nil(t, For(proj))
+	_, err := Load(proj)
+	assert.ErrorIs(t, err, ErrNoIndex)
+
+	ix, stats, err := Build(context.Background(), proj, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Files)
+	assert.Equal(t, 3, stats.Indexed)
+	assert.Equal(t, 1, stats.Skipped, "binary files are not indexed")
+	require.NotNil(t, For(proj))
+
+	mainGo := findNode(t, proj, "/main.go")
+	strGo := findNode(t, proj, "/util/str.go")
+	docs := findNode(t, proj, "/docs/a.md")
+
+	todo := LiteralQuery("TODO")
+	assert.False(t, ix.MayContain(mainGo, todo))
+	assert.True(t, ix.MayContain(strGo, todo))
+	// 索引不区分大小写
+	assert.True(t, ix.MayContain(strGo, LiteralQuery("todo")))
+	assert.True(t, ix.MayContain(mainGo, LiteralQuery("fu")), "short literals cannot be filtered")
+
+	re := RegexQuery(`func\s+(Trim)\(`)
+	assert.Equal(t, "fun rim tri unc", re.String())
+	assert.False(t, ix.MayContain(mainGo, re))
+	assert.True(t, ix.MayContain(strGo, re))
+	assert.Nil(t, RegexQuery(`Trim|main`))
+	assert.Nil(t, RegexQuery(`(`))
+	// 大小写折叠时跳过含 s、k 的三元组
+	assert.Equal(t, "ube", RegexQuery(`(?i)kube`).String())
+	assert.True(t, ix.MayContain(docs, RegexQuery(`(?i)KUBE`)))
+
+	// 修改后的文件不再被索引排除
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // TODO\n"), 0644))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "main.go"), later, later))
+	assert.True(t, ix.MayContain(mainGo, todo))
+}
+
+func TestStatusAndIncrementalBuild(t *testing.T) {
+	proj, dir := setupProject(t)
+	_, _, err := Build(context.Background(), proj, false)
+	require.NoError(t, err)
+
+	ix := For(proj)
+	require.NotNil(t, ix)
+	status := ix.Status(proj)
+	assert.True(t, status.IsFresh())
+	assert.Equal(t, 3, status.Fresh)
+	assert.Positive(t, status.Size)
+
+	// 只修改时间变化、内容变化、新增与删除
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "docs/a.md"), later, later))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "main.go"), later, later))
+	require.NoError(t, proj.CreateFile("/new.go", []byte("package main\n")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "util/str.go")))
+	require.NoError(t, proj.DeleteNode("/util/str.go"))
+
+	status = ix.Status(proj)
+	assert.False(t, status.IsFresh())
+	assert.Equal(t, 0, status.Fresh)
+	assert.Equal(t, 2, status.Changed)
+	assert.Equal(t, 1, status.Added)
+	assert.Equal(t, 1, status.Removed)
+
+	ix, stats, err := Build(context.Background(), proj, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Files)
+	assert.Equal(t, 1, stats.Reused, "docs/a.md only changed mtime, hash is reused")
+	assert.Equal(t, 2, stats.Indexed)
+	assert.True(t, ix.Status(proj).IsFresh())
+	assert.True(t, ix.MayContain(findNode(t, proj, "/docs/a.md"), LiteralQuery("kubernetes")))
+	assert.False(t, ix.MayContain(findNode(t, proj, "/main.go"), LiteralQuery("Hello")))
+
+	_, stats, err = Build(context.Background(), proj, true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Indexed)
+
+	require.NoError(t, Drop(proj))
+	assert.Nil(t, For(proj))
+}
+
+func TestBuildFromFS(t *testing.T) {
+	proj, err := project.BuildProjectTreeFromFS(os.DirFS(t.TempDir()), helper.WalkDirOptions{})
+	require.NoError(t, err)
+	_, _, err = Build(context.Background(), proj, false)
+	assert.ErrorIs(t, err, project.ErrNotOnDisk)
+	assert.Nil(t, For(proj))
+}
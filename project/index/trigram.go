@@ -0,0 +1,122 @@
+package index
+
+import (
+	"bytes"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// Query 内容条件可以推出的必要三元组：文件要满足条件，必须包含其中所有三元组
+type Query struct {
+	trigrams []uint32
+}
+
+// LiteralQuery 子串条件对应的查询，少于三个字节的子串无法缩小范围，返回 nil
+func LiteralQuery(s string) *Query {
+	return newQuery(trigramsOf([]byte(strings.ToLower(s)), nil))
+}
+
+// RegexQuery 正则条件对应的查询，从正则中一定会出现的字面量提取三元组
+// 正则无法解析或没有可用的字面量（如顶层为分支）时返回 nil
+func RegexQuery(expr string) *Query {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	set := make(map[uint32]struct{})
+	for _, lit := range requiredLiterals(re) {
+		for t := range trigramsOf([]byte(strings.ToLower(lit.text)), nil) {
+			// 大小写折叠时 s、k 与部分非 ASCII 字符还会匹配 ſ、K（开尔文符号）、ς 等，它们转为小写后字节不同
+			if lit.fold && hasFoldVariant(t) {
+				continue
+			}
+			set[t] = struct{}{}
+		}
+	}
+	return newQuery(set)
+}
+
+// String 返回查询包含的三元组，用于调试
+func (q *Query) String() string {
+	if q == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(q.trigrams))
+	for _, t := range q.trigrams {
+		parts = append(parts, string([]byte{byte(t >> 16), byte(t >> 8), byte(t)}))
+	}
+	return strings.Join(parts, " ")
+}
+
+// newQuery 由三元组集合创建查询，集合为空时返回 nil
+func newQuery(set map[uint32]struct{}) *Query {
+	if len(set) == 0 {
+		return nil
+	}
+	q := &Query{trigrams: make([]uint32, 0, len(set))}
+	for t := range set {
+		q.trigrams = append(q.trigrams, t)
+	}
+	sort.Slice(q.trigrams, func(i, j int) bool { return q.trigrams[i] < q.trigrams[j] })
+	return q
+}
+
+// literal 正则中一定出现的字面量
+type literal struct {
+	text string
+	fold bool // 是否大小写不敏感
+}
+
+// requiredLiterals 返回正则的任何匹配都必须包含的字面量
+func requiredLiterals(re *syntax.Regexp) []literal {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []literal{{text: string(re.Rune), fold: re.Flags&syntax.FoldCase != 0}}
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return requiredLiterals(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var lits []literal
+		for _, sub := range re.Sub {
+			lits = append(lits, requiredLiterals(sub)...)
+		}
+		return lits
+	}
+	return nil
+}
+
+// trigramsOf 把内容中所有连续三个字节加入集合，内容应已转为小写
+func trigramsOf(data []byte, set map[uint32]struct{}) map[uint32]struct{} {
+	if set == nil {
+		set = make(map[uint32]struct{})
+	}
+	for i := 0; i+3 <= len(data); i++ {
+		set[uint32(data[i])<<16|uint32(data[i+1])<<8|uint32(data[i+2])] = struct{}{}
+	}
+	return set
+}
+
+// contentTrigrams 返回文件内容（不区分大小写）的三元组，按升序排列
+func contentTrigrams(content []byte) []uint32 {
+	set := trigramsOf(bytes.ToLower(content), nil)
+	list := make([]uint32, 0, len(set))
+	for t := range set {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// hasFoldVariant 判断三元组中是否有在大小写折叠下可能匹配其他小写形式的字节
+func hasFoldVariant(t uint32) bool {
+	for _, b := range []byte{byte(t >> 16), byte(t >> 8), byte(t)} {
+		if b == 's' || b == 'k' || b >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,501 @@
+package project
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+)
+
+// 操作日志：记录文件变更前后的状态，支持逐步撤销与重做
+
+// JournalDir 操作日志存放目录，测试时可替换
+var JournalDir = helper.GetPath("journal")
+
+// MaxJournalSteps 一次撤销或重做的最大步数
+const MaxJournalSteps = 1000
+
+var (
+	// ErrJournalDisabled 项目未启用操作日志
+	ErrJournalDisabled = errors.New("journal is not enabled for this project")
+	// ErrNothingToUndo 没有可撤销的操作
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo 没有可重做的操作
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// ErrJournalConflict 文件在日志之外被修改
+	ErrJournalConflict = errors.New("files were changed outside the journal")
+)
+
+// JournalOp 日志记录的操作类型
+type JournalOp string
+
+const (
+	JournalWrite  JournalOp = "write"
+	JournalCreate JournalOp = "create"
+	JournalMkdir  JournalOp = "mkdir"
+	JournalDelete JournalOp = "delete"
+	JournalMove   JournalOp = "move"
+	JournalCopy   JournalOp = "copy"
+	JournalCommit JournalOp = "commit"
+)
+
+// FileState 文件或目录在某一时刻的状态
+type FileState struct {
+	Path    string `json:"path"`
+	Exists  bool   `json:"exists"`
+	IsDir   bool   `json:"isDir,omitempty"`
+	Content []byte `json:"content,omitempty"`
+}
+
+// JournalRecord 日志条目摘要
+type JournalRecord struct {
+	ID    int       `json:"id"`
+	Op    JournalOp `json:"op"`
+	Paths []string  `json:"paths"`
+	Time  time.Time `json:"time"`
+}
+
+// JournalEntry 完整的日志条目，包含操作前后的状态
+type JournalEntry struct {
+	JournalRecord
+	Before []FileState `json:"before"`
+	After  []FileState `json:"after"`
+}
+
+// journalState 日志索引，Cursor 之前（含）的条目处于已应用状态
+type journalState struct {
+	RootPath string          `json:"rootPath"`
+	Cursor   int             `json:"cursor"`
+	Records  []JournalRecord `json:"records"`
+}
+
+// Journal 持久化的操作日志
+type Journal struct {
+	dir   string
+	mu    sync.Mutex
+	state journalState
+}
+
+// OpenJournal 打开（或创建）项目根路径对应的操作日志
+func OpenJournal(rootPath string) (*Journal, error) {
+	absPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(absPath))
+	dir := filepath.Join(JournalDir, hex.EncodeToString(sum[:16]))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建操作日志目录失败: %w", err)
+	}
+
+	j := &Journal{dir: dir, state: journalState{RootPath: absPath}}
+	data, err := os.ReadFile(j.statePath())
+	if err == nil {
+		if err := json.Unmarshal(data, &j.state); err != nil {
+			return nil, fmt.Errorf("读取操作日志失败: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取操作日志失败: %w", err)
+	}
+	return j, nil
+}
+
+// History 返回所有日志条目摘要与当前游标（已应用的条目数）
+func (j *Journal) History() ([]JournalRecord, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	records := make([]JournalRecord, len(j.state.Records))
+	copy(records, j.state.Records)
+	return records, j.state.Cursor
+}
+
+// Clear 清空操作日志
+func (j *Journal) Clear() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, record := range j.state.Records {
+		os.Remove(j.entryPath(record.ID))
+	}
+	j.state.Records = nil
+	j.state.Cursor = 0
+	return j.saveState()
+}
+
+// append 追加日志条目，游标之后已撤销的条目会被丢弃
+func (j *Journal) append(entry *JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, record := range j.state.Records[j.state.Cursor:] {
+		os.Remove(j.entryPath(record.ID))
+	}
+	j.state.Records = j.state.Records[:j.state.Cursor]
+
+	entry.ID = 1
+	if n := len(j.state.Records); n > 0 {
+		entry.ID = j.state.Records[n-1].ID + 1
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(j.entryPath(entry.ID), data, 0644); err != nil {
+		return fmt.Errorf("写入操作日志失败: %w", err)
+	}
+
+	j.state.Records = append(j.state.Records, entry.JournalRecord)
+	j.state.Cursor = len(j.state.Records)
+	return j.saveState()
+}
+
+// load 读取指定位置（从 1 开始）的完整条目，调用方需持有 j.mu
+func (j *Journal) load(index int) (*JournalEntry, error) {
+	record := j.state.Records[index-1]
+	data, err := os.ReadFile(j.entryPath(record.ID))
+	if err != nil {
+		return nil, fmt.Errorf("读取操作日志失败: %w", err)
+	}
+	var entry JournalEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("读取操作日志失败: %w", err)
+	}
+	return &entry, nil
+}
+
+// saveState 写入日志索引，调用方需持有 j.mu
+func (j *Journal) saveState() error {
+	data, err := json.MarshalIndent(j.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := j.statePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入操作日志失败: %w", err)
+	}
+	return os.Rename(tmp, j.statePath())
+}
+
+func (j *Journal) statePath() string {
+	return filepath.Join(j.dir, "journal.json")
+}
+
+func (j *Journal) entryPath(id int) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%06d.json", id))
+}
+
+// EnableJournal 为项目启用操作日志，此后 WriteFile、CreateFile、CreateDir、DeleteNode
+// 与事务提交都会被记录
+func (p *Project) EnableJournal() error {
+	if p.source != nil {
+		return ErrNotOnDisk
+	}
+	if p.rootPath == "" {
+		return errors.New("project has no root path")
+	}
+	j, err := OpenJournal(p.rootPath)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.journal = j
+	p.mu.Unlock()
+	return nil
+}
+
+// Journal 返回项目的操作日志，未启用时为 nil
+func (p *Project) Journal() *Journal {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.journal
+}
+
+// Undo 撤销最近一次已应用的操作
+// force 为 false 时，若文件当前状态与记录的操作结果不一致则拒绝撤销
+func (p *Project) Undo(force bool) (*JournalRecord, error) {
+	return p.stepJournal(true, force)
+}
+
+// Redo 重做最近一次撤销的操作
+func (p *Project) Redo(force bool) (*JournalRecord, error) {
+	return p.stepJournal(false, force)
+}
+
+// stepJournal 执行一次撤销或重做
+func (p *Project) stepJournal(undo bool, force bool) (*JournalRecord, error) {
+	j := p.Journal()
+	if j == nil {
+		return nil, ErrJournalDisabled
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	index := j.state.Cursor + 1
+	if undo {
+		if j.state.Cursor == 0 {
+			return nil, ErrNothingToUndo
+		}
+		index = j.state.Cursor
+	} else if j.state.Cursor >= len(j.state.Records) {
+		return nil, ErrNothingToRedo
+	}
+
+	entry, err := j.load(index)
+	if err != nil {
+		return nil, err
+	}
+	expected, target := entry.After, entry.Before
+	if !undo {
+		expected, target = entry.Before, entry.After
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !force {
+		if path, ok := p.statesMatch(expected); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrJournalConflict, path)
+		}
+	}
+	if err := p.applyStates(target); err != nil {
+		return nil, err
+	}
+
+	if undo {
+		j.state.Cursor--
+	} else {
+		j.state.Cursor++
+	}
+	if err := j.saveState(); err != nil {
+		return nil, err
+	}
+	return &entry.JournalRecord, nil
+}
+
+// recordOp 在操作执行前记录路径的当前状态，返回的函数在操作成功后写入日志
+// 用法：defer p.recordOp(JournalWrite, path)(&err)
+func (p *Project) recordOp(op JournalOp, paths ...string) func(*error) {
+	p.mu.RLock()
+	j := p.journal
+	p.mu.RUnlock()
+	if j == nil {
+		return func(*error) {}
+	}
+
+	paths = append([]string(nil), paths...)
+	for i, path := range paths {
+		paths[i] = p.NormalizePath(path)
+	}
+	statePaths := p.expandStatePaths(paths)
+	before := p.captureStates(statePaths)
+
+	return func(errp *error) {
+		if errp != nil && *errp != nil {
+			return
+		}
+		// 移动或复制目录会在新路径下产生后代，补充记录它们在操作前不存在
+		for _, path := range p.expandStatePaths(paths) {
+			if !containsPath(statePaths, path) {
+				statePaths = append(statePaths, path)
+				before = append(before, FileState{Path: path})
+			}
+		}
+		after := p.captureStates(statePaths)
+		entry := &JournalEntry{
+			JournalRecord: JournalRecord{Op: op, Paths: paths, Time: time.Now()},
+			Before:        before,
+			After:         after,
+		}
+		if err := j.append(entry); err != nil && errp != nil {
+			*errp = err
+		}
+	}
+}
+
+// expandStatePaths 去重并展开路径：项目树中的目录会包含其所有后代节点，
+// 被忽略规则排除（不在项目树中）的磁盘内容不会记入日志
+func (p *Project) expandStatePaths(paths []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			result = append(result, path)
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, path := range paths {
+		add(path)
+		if path == "/" {
+			continue
+		}
+		prefix := path + "/"
+		for nodePath := range p.nodes {
+			if strings.HasPrefix(nodePath, prefix) {
+				add(nodePath)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// containsPath 判断路径列表中是否包含 path
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// captureStates 读取路径当前在磁盘上的状态
+func (p *Project) captureStates(paths []string) []FileState {
+	states := make([]FileState, 0, len(paths))
+	for _, path := range paths {
+		state := FileState{Path: path}
+		info, err := os.Lstat(p.fsPath(path))
+		if err == nil {
+			state.Exists = true
+			state.IsDir = info.IsDir()
+			if !state.IsDir {
+				content, readErr := os.ReadFile(p.fsPath(path))
+				if readErr == nil {
+					state.Content = content
+				}
+			}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// statesMatch 检查磁盘当前状态是否与记录一致，不一致时返回第一个不同的路径
+func (p *Project) statesMatch(states []FileState) (string, bool) {
+	paths := make([]string, len(states))
+	for i, state := range states {
+		paths[i] = state.Path
+	}
+	current := p.captureStates(paths)
+	for i, state := range states {
+		cur := current[i]
+		if cur.Exists != state.Exists || cur.IsDir != state.IsDir || !bytes.Equal(cur.Content, state.Content) {
+			return state.Path, false
+		}
+	}
+	return "", true
+}
+
+// applyStates 将磁盘与项目树恢复到记录的状态，调用方需持有 p.mu 写锁
+func (p *Project) applyStates(states []FileState) error {
+	sorted := make([]FileState, len(states))
+	copy(sorted, states)
+	sort.Slice(sorted, func(i, k int) bool { return sorted[i].Path < sorted[k].Path })
+
+	for _, state := range sorted {
+		if state.Path == "/" {
+			continue
+		}
+		abs := p.fsPath(state.Path)
+		info, statErr := os.Lstat(abs)
+
+		if !state.Exists {
+			if statErr == nil {
+				if err := os.RemoveAll(abs); err != nil {
+					return err
+				}
+			}
+			if node, ok := p.nodes[state.Path]; ok {
+				p.detachNode(node)
+			}
+			continue
+		}
+
+		// 类型发生变化时先移除旧节点
+		if statErr == nil && info.IsDir() != state.IsDir {
+			if err := os.RemoveAll(abs); err != nil {
+				return err
+			}
+		}
+		if node, ok := p.nodes[state.Path]; ok && node.IsDir != state.IsDir {
+			p.detachNode(node)
+		}
+
+		if state.IsDir {
+			if err := os.MkdirAll(abs, 0755); err != nil {
+				return err
+			}
+			if err := p.ensureTreeDirs(state.Path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(abs, state.Content, 0644); err != nil {
+			return err
+		}
+		if err := p.ensureTreeDirs(parentPath(state.Path)); err != nil {
+			return err
+		}
+		newInfo, err := os.Stat(abs)
+		if err != nil {
+			return err
+		}
+		node, ok := p.nodes[state.Path]
+		if !ok {
+			if err := p.createFileNodeInternal(state.Path, newInfo); err != nil {
+				return err
+			}
+			node = p.nodes[state.Path]
+		}
+		node.mu.Lock()
+		node.Content = state.Content
+		node.ContentLoaded = true
+		node.Info = newInfo
+		node.modified = false
+		node.hash = ""
+		node.kind = nil
+		node.mu.Unlock()
+	}
+	return nil
+}
+
+// ensureTreeDirs 确保项目树中存在该目录及其所有父目录，调用方需持有 p.mu 写锁
+func (p *Project) ensureTreeDirs(path string) error {
+	if path == "/" || path == "" {
+		return nil
+	}
+	cur := ""
+	for _, part := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		cur += "/" + part
+		if _, ok := p.nodes[cur]; ok {
+			continue
+		}
+		info, err := os.Stat(p.fsPath(cur))
+		if err != nil {
+			return err
+		}
+		if err := p.createDirInternal(cur, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newJournalTestProject 创建启用了操作日志的测试项目
+func newJournalTestProject(t *testing.T) (*Project, string) {
+	t.Helper()
+	old := JournalDir
+	JournalDir = t.TempDir()
+	t.Cleanup(func() { JournalDir = old })
+
+	proj, tempDir := newTxTestProject(t)
+	require.NoError(t, proj.EnableJournal())
+	return proj, tempDir
+}
+
+func readDisk(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestJournalUndoRedo(t *testing.T) {
+	proj, tempDir := newJournalTestProject(t)
+	mainPath := filepath.Join(tempDir, "src", "main.go")
+
+	require.NoError(t, proj.WriteFile("/src/main.go", []byte("package v2")))
+	require.NoError(t, proj.CreateDir("/pkg"))
+	require.NoError(t, proj.CreateFile("/pkg/a.go", []byte("package pkg")))
+
+	records, cursor := proj.Journal().History()
+	require.Len(t, records, 3)
+	assert.Equal(t, 3, cursor)
+	assert.Equal(t, JournalWrite, records[0].Op)
+	assert.Equal(t, []string{"/src/main.go"}, records[0].Paths)
+	assert.Equal(t, JournalMkdir, records[1].Op)
+	assert.Equal(t, JournalCreate, records[2].Op)
+
+	// 逐步撤销
+	record, err := proj.Undo(false)
+	require.NoError(t, err)
+	assert.Equal(t, JournalCreate, record.Op)
+	assert.NoFileExists(t, filepath.Join(tempDir, "pkg", "a.go"))
+	_, err = proj.FindNode("/pkg/a.go")
+	assert.Error(t, err)
+
+	_, err = proj.Undo(false)
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(tempDir, "pkg"))
+
+	_, err = proj.Undo(false)
+	require.NoError(t, err)
+	assert.Equal(t, "package main", readDisk(t, mainPath))
+	content, err := proj.ReadFile("/src/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(content))
+
+	_, err = proj.Undo(false)
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+
+	// 重做
+	_, err = proj.Redo(false)
+	require.NoError(t, err)
+	assert.Equal(t, "package v2", readDisk(t, mainPath))
+
+	// 新操作会丢弃已撤销的条目
+	require.NoError(t, proj.WriteFile("/src/main.go", []byte("package v3")))
+	records, cursor = proj.Journal().History()
+	assert.Len(t, records, 2)
+	assert.Equal(t, 2, cursor)
+	_, err = proj.Redo(false)
+	assert.ErrorIs(t, err, ErrNothingToRedo)
+}
+
+func TestJournalDeleteDirectory(t *testing.T) {
+	proj, tempDir := newJournalTestProject(t)
+
+	// DeleteNode 同时删除磁盘上的目录
+	require.NoError(t, proj.DeleteNode("/docs"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "docs"))
+
+	_, err := proj.Undo(false)
+	require.NoError(t, err)
+	assert.Equal(t, "# a", readDisk(t, filepath.Join(tempDir, "docs", "a.md")))
+	content, err := proj.ReadFile("/docs/a.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# a", string(content))
+
+	_, err = proj.Redo(false)
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(tempDir, "docs"))
+	_, err = proj.FindNode("/docs")
+	assert.Error(t, err)
+}
+
+func TestJournalSkipsIgnoredPaths(t *testing.T) {
+	old := JournalDir
+	JournalDir = t.TempDir()
+	t.Cleanup(func() { JournalDir = old })
+
+	_, tempDir := newTxTestProject(t)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("node_modules/\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "docs", "node_modules", "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "docs", "node_modules", "lib", "index.js"), []byte("x"), 0644))
+	proj, err := BuildProjectTree(tempDir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+	require.NoError(t, proj.EnableJournal())
+
+	// 只记录项目树中的节点，被忽略的目录不写入日志
+	require.NoError(t, proj.DeleteNode("/docs"))
+	entry, err := proj.Journal().load(1)
+	require.NoError(t, err)
+	var paths []string
+	for _, state := range entry.Before {
+		paths = append(paths, state.Path)
+	}
+	assert.Equal(t, []string{"/docs", "/docs/a.md"}, paths)
+
+	_, err = proj.Undo(false)
+	require.NoError(t, err)
+	assert.Equal(t, "# a", readDisk(t, filepath.Join(tempDir, "docs", "a.md")))
+}
+
+func TestJournalTransactionAndConflict(t *testing.T) {
+	proj, tempDir := newJournalTestProject(t)
+	mainPath := filepath.Join(tempDir, "src", "main.go")
+
+	tx := proj.Begin()
+	require.NoError(t, tx.WriteFile("/src/main.go", []byte("package tx")))
+	require.NoError(t, tx.DeleteNode("/docs"))
+	require.NoError(t, tx.Commit())
+
+	records, _ := proj.Journal().History()
+	require.Len(t, records, 1)
+	assert.Equal(t, JournalCommit, records[0].Op)
+
+	// 日志之外的修改会阻止撤销，除非强制执行
+	require.NoError(t, os.WriteFile(mainPath, []byte("package outside"), 0644))
+	_, err := proj.Undo(false)
+	assert.True(t, errors.Is(err, ErrJournalConflict))
+
+	_, err = proj.Undo(true)
+	require.NoError(t, err)
+	assert.Equal(t, "package main", readDisk(t, mainPath))
+	assert.Equal(t, "# a", readDisk(t, filepath.Join(tempDir, "docs", "a.md")))
+}
+
+func TestJournalPersistence(t *testing.T) {
+	proj, tempDir := newJournalTestProject(t)
+	require.NoError(t, proj.WriteFile("/src/main.go", []byte("package v2")))
+
+	// 重新打开项目后仍可撤销
+	reopened := NewProject(tempDir)
+	require.NoError(t, reopened.SyncFromFS())
+	require.NoError(t, reopened.EnableJournal())
+	_, err := reopened.Undo(false)
+	require.NoError(t, err)
+	assert.Equal(t, "package main", readDisk(t, filepath.Join(tempDir, "src", "main.go")))
+
+	// 未启用日志的项目
+	_, err = NewProject(tempDir).Undo(false)
+	assert.ErrorIs(t, err, ErrJournalDisabled)
+}
@@ -20,8 +20,63 @@ func (node *Node) CalculateHash() (string, error) {
 	return node.calculateFileHash()
 }
 
-// calculateFileHash 计算文件内容的哈希值
+// calculateFileHash 计算文件内容的哈希值，磁盘文件未变化时直接使用缓存
 func (node *Node) calculateFileHash() (string, error) {
+	stamp, onDisk := node.diskStamp()
+	if onDisk {
+		node.mu.RLock()
+		cached := node.hash
+		valid := cached != "" && !node.modified && node.hashStamp.equal(stamp)
+		node.mu.RUnlock()
+		if valid {
+			return cached, nil
+		}
+	}
+
+	// 已加载的内容可能早于磁盘上的版本，只有本次从磁盘读取时才写入缓存
+	node.mu.RLock()
+	fromDisk := onDisk && !node.ContentLoaded
+	node.mu.RUnlock()
+
+	hash, err := node.hashContent()
+	if err != nil || hash == "" || !fromDisk {
+		return hash, err
+	}
+
+	node.mu.Lock()
+	if !node.modified {
+		node.hash = hash
+		node.hashStamp = stamp
+	}
+	node.mu.Unlock()
+	if p := node.GetProject(); p != nil {
+		p.snapshotDirty.Store(true)
+	}
+	return hash, nil
+}
+
+// diskStamp 获取节点对应磁盘文件的大小与修改时间，已修改的节点以内存内容为准
+func (node *Node) diskStamp() (fileStamp, bool) {
+	node.mu.RLock()
+	modified := node.modified
+	node.mu.RUnlock()
+	if modified || node.Path == "" || node.Path == "/" {
+		return fileStamp{}, false
+	}
+
+	p := node.GetProject()
+	if p == nil || p.source != nil {
+		return fileStamp{}, false
+	}
+	info, err := os.Stat(p.fsPath(node.Path))
+	if err != nil {
+		return fileStamp{}, false
+	}
+	return stampOf(info), true
+}
+
+// hashContent 读取内容并计算哈希值
+func (node *Node) hashContent() (string, error) {
 	// 加读锁保护访问
 	node.mu.RLock()
 	defer node.mu.RUnlock()
@@ -67,14 +122,14 @@ func (node *Node) calculateDirHash() (string, error) {
 		return sortedChildren[i].Name < sortedChildren[j].Name
 	})
 
-	// 计算子节点哈希
+	// 计算子节点哈希，带上名称使子节点改名也会反映到目录哈希
 	hashes := make([]string, 0, len(sortedChildren))
 	for _, child := range sortedChildren {
 		hash, err := child.CalculateHash()
 		if err != nil {
 			return "", err
 		}
-		hashes = append(hashes, hash)
+		hashes = append(hashes, child.Name+":"+hash)
 	}
 
 	// 合并哈希值并计算最终哈希
@@ -125,19 +180,32 @@ func (n *Node) GetFiles(basePath string) []string {
 }
 
 // ReadContent 读取节点内容，支持延迟加载
+// 项目设置了内容预算时，读取会计入内容缓存，超出预算后最久未使用的未修改内容被卸载
 func (n *Node) ReadContent() ([]byte, error) {
+	content, loaded, err := n.readContent()
+	if err != nil {
+		return nil, err
+	}
+	if cache := n.GetProject().ContentCache(); cache != nil {
+		cache.access(n, len(content), loaded)
+	}
+	return content, nil
+}
+
+// readContent 返回节点内容，loaded 表示本次是否从磁盘或来源读取
+func (n *Node) readContent() (content []byte, loaded bool, err error) {
 	n.mu.RLock()
 
 	if n.IsDir {
 		n.mu.RUnlock()
-		return nil, errors.New("cannot read directory")
+		return nil, false, errors.New("cannot read directory")
 	}
 
 	// 如果内容已加载，直接返回
 	if n.ContentLoaded && n.Content != nil {
-		content := n.Content
+		content = n.Content
 		n.mu.RUnlock()
-		return content, nil
+		return content, false, nil
 	}
 
 	// 内容未加载，从磁盘读取
@@ -148,16 +216,16 @@ func (n *Node) ReadContent() ([]byte, error) {
 
 	// 双重检查，防止在锁切换期间其他协程已加载内容
 	if n.ContentLoaded && n.Content != nil {
-		return n.Content, nil
+		return n.Content, false, nil
 	}
 
 	// 检查Path是否有效
 	if n.Path == "" {
-		return nil, errors.New("node path is empty")
+		return nil, false, errors.New("node path is empty")
 	}
 
-	// 获取项目根路径
-	var rootPath string
+	// 获取项目实例
+	var project *Project
 	if n.Parent != nil {
 		// 向上查找到根节点
 		root := n
@@ -166,30 +234,31 @@ func (n *Node) ReadContent() ([]byte, error) {
 		}
 
 		// 获取项目实例
-		project := GetProjectByRoot(root)
+		project = GetProjectByRoot(root)
 		if project == nil {
-			return nil, errors.New("cannot find project for node: project not registered")
+			return nil, false, errors.New("cannot find project for node: project not registered")
 		}
-		rootPath = project.GetRootPath()
 	} else {
 		// 如果是根节点，直接使用Path作为绝对路径
-		return nil, errors.New("root node should not have content")
+		return nil, false, errors.New("root node should not have content")
 	}
 
-	// 构建文件系统路径
-	fsPath := filepath.Join(rootPath, n.Path[1:])
-
-	// 使用文件系统路径读取文件内容
-	content, err := os.ReadFile(fsPath)
+	if project.source != nil {
+		// 由 fs.FS 构建的项目从来源中读取
+		content, err = project.readSource(n.Path)
+	} else {
+		// 使用文件系统路径读取文件内容
+		content, err = os.ReadFile(project.fsPath(n.Path))
+	}
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	// 更新节点状态
 	n.Content = content
 	n.ContentLoaded = true
 
-	return n.Content, nil
+	return n.Content, true, nil
 }
 
 // WriteContent 写入节点内容并标记为已修改
@@ -206,8 +275,8 @@ func (n *Node) WriteContent(content []byte) error {
 	n.ContentLoaded = true
 	n.MarkModified()
 
-	// 获取项目根路径并写入文件系统
-	var rootPath string
+	// 获取文件系统路径并写入
+	var fsPath string
 	if n.Parent != nil {
 		// 向上查找到根节点
 		root := n
@@ -220,15 +289,21 @@ func (n *Node) WriteContent(content []byte) error {
 		if project == nil {
 			return errors.New("cannot find project for node: project not registered")
 		}
-		rootPath = project.GetRootPath()
+		// 已修改的内容不受缓存预算约束
+		if cache := project.ContentCache(); cache != nil {
+			cache.remove(n)
+		}
+		if project.source != nil {
+			// 由 fs.FS 构建的项目只修改内存
+			n.Info = newMemFileInfo(n.Name, false, len(content))
+			return nil
+		}
+		fsPath = project.fsPath(n.Path)
 	} else {
 		// 如果是根节点，直接使用Path作为绝对路径
 		return errors.New("root node should not have content")
 	}
 
-	// 构建文件系统路径
-	fsPath := filepath.Join(rootPath, n.Path[1:])
-
 	// 写入文件
 	if err := os.WriteFile(fsPath, content, 0644); err != nil {
 		return err
@@ -255,8 +330,8 @@ func (n *Node) UnloadContent() error {
 
 	// 如果内容已修改，先保存到文件系统
 	if n.modified {
-		// 获取项目根路径
-		var rootPath string
+		// 获取文件系统路径
+		var fsPath string
 		if n.Parent != nil {
 			// 向上查找到根节点
 			root := n
@@ -269,15 +344,16 @@ func (n *Node) UnloadContent() error {
 			if project == nil {
 				return errors.New("cannot find project for node: project not registered")
 			}
-			rootPath = project.GetRootPath()
+			if project.source != nil {
+				// 内存中的修改无处保存，不能卸载
+				return ErrNotOnDisk
+			}
+			fsPath = project.fsPath(n.Path)
 		} else {
 			// 如果是根节点，直接使用Path作为绝对路径
 			return errors.New("root node should not have content")
 		}
 
-		// 构建文件系统路径
-		fsPath := filepath.Join(rootPath, n.Path[1:])
-
 		// 写入文件
 		if err := os.WriteFile(fsPath, n.Content, 0644); err != nil {
 			return err
@@ -288,6 +364,9 @@ func (n *Node) UnloadContent() error {
 	// 卸载内容
 	n.Content = nil
 	n.ContentLoaded = false
+	if cache := n.GetProject().ContentCache(); cache != nil {
+		cache.remove(n)
+	}
 
 	return nil
 }
@@ -344,6 +423,8 @@ func (n *Node) GetChild(name string) (*Node, bool) {
 // MarkModified 标记节点为已修改，并递归标记父节点
 func (n *Node) MarkModified() {
 	n.modified = true
+	n.hash = ""
+	n.kind = nil
 	if n.Parent != nil {
 		n.Parent.MarkModified()
 	}
@@ -396,8 +477,8 @@ func (n *Node) SaveToDisk() error {
 	copy(content, n.Content)
 	n.mu.RUnlock()
 
-	// 获取项目根路径
-	var rootPath string
+	// 获取文件系统路径
+	var fsPath string
 	if n.Parent != nil {
 		// 向上查找到根节点
 		root := n
@@ -410,15 +491,15 @@ func (n *Node) SaveToDisk() error {
 		if project == nil {
 			return errors.New("cannot find project for node")
 		}
-		rootPath = project.GetRootPath()
+		if project.source != nil {
+			return ErrNotOnDisk
+		}
+		fsPath = project.fsPath(n.Path)
 	} else {
 		// 如果是根节点，直接使用Path作为绝对路径
 		return errors.New("root node should not have content")
 	}
 
-	// 构建文件系统路径
-	fsPath := filepath.Join(rootPath, n.Path[1:])
-
 	// 写入文件
 	if err := os.WriteFile(fsPath, content, 0644); err != nil {
 		return err
@@ -546,29 +627,24 @@ func (n *Node) SaveToFS() error {
 	if project == nil {
 		return errors.New("cannot find project for node")
 	}
-
-	// 获取项目根路径
-	rootPath := project.GetRootPath()
+	if project.source != nil {
+		return ErrNotOnDisk
+	}
 
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
 	// 构建当前节点的完整路径
-	var nodePath string
-	if n.Path == "/" {
-		// 根节点
-		nodePath = rootPath
-	} else {
-		// 非根节点
-		nodePath = filepath.Join(rootPath, n.Path[1:])
-	}
+	nodePath := project.fsPath(n.Path)
 
 	// 如果是目录，创建目录并递归保存子节点
 	if n.IsDir {
-		// 创建目录
-		err := os.MkdirAll(nodePath, 0755)
-		if err != nil {
-			return err
+		// 创建目录，工作区根节点没有对应的磁盘目录
+		if nodePath != "" {
+			err := os.MkdirAll(nodePath, 0755)
+			if err != nil {
+				return err
+			}
 		}
 
 		// 递归保存子节点
@@ -579,6 +655,9 @@ func (n *Node) SaveToFS() error {
 			}
 		}
 	} else {
+		if nodePath == "" {
+			return ErrOutsideWorkspace
+		}
 		// 如果是文件，写入文件内容
 		if n.ContentLoaded {
 			// 如果内容已加载，直接写入
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sjzsdu/tong/helper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -296,4 +297,85 @@ func BenchmarkNodeConcurrentProcessing(b *testing.B) {
 			}
 		}
 	})
-}
\ No newline at end of file
+}
+
+// BenchmarkBuildProjectTreeSnapshot 比较完整构建与从快照加载项目树的耗时
+func BenchmarkBuildProjectTreeSnapshot(b *testing.B) {
+	// 创建测试项目
+	tempDir, err := os.MkdirTemp("", "project-snapshot-bench-*")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	snapshotDir, err := os.MkdirTemp("", "project-snapshot-store-*")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(snapshotDir)
+
+	oldSnapshotDir := SnapshotDir
+	SnapshotDir = snapshotDir
+	defer func() { SnapshotDir = oldSnapshotDir }()
+
+	// 创建多层文件结构，并在每层放置 .gitignore
+	for i := 0; i < 20; i++ {
+		for k := 0; k < 5; k++ {
+			dirPath := filepath.Join(tempDir, fmt.Sprintf("dir%d", i), fmt.Sprintf("sub%d", k))
+			if err := os.MkdirAll(dirPath, 0755); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dirPath, ".gitignore"), []byte("*.log\n"), 0644); err != nil {
+				b.Fatal(err)
+			}
+			for j := 0; j < 20; j++ {
+				filePath := filepath.Join(dirPath, fmt.Sprintf("file%d.txt", j))
+				content := strings.Repeat(fmt.Sprintf("Content %d-%d-%d\n", i, k, j), 64)
+				if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	options := helper.WalkDirOptions{}
+
+	// 预先生成带哈希的快照
+	warm, err := BuildProjectTreeCached(tempDir, options)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := warm.Root().CalculateHash(); err != nil {
+		b.Fatal(err)
+	}
+	if err := warm.SaveSnapshot(); err != nil {
+		b.Fatal(err)
+	}
+	UnregisterProject(warm.Root())
+
+	build := func(b *testing.B, cached bool, hash bool) {
+		for i := 0; i < b.N; i++ {
+			var proj *Project
+			var err error
+			if cached {
+				proj, err = BuildProjectTreeCached(tempDir, options)
+			} else {
+				proj, err = BuildProjectTree(tempDir, options)
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			if hash {
+				if _, err := proj.Root().CalculateHash(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			UnregisterProject(proj.Root())
+		}
+	}
+
+	b.Run("Full", func(b *testing.B) { build(b, false, false) })
+	b.Run("Snapshot", func(b *testing.B) { build(b, true, false) })
+	b.Run("FullWithHash", func(b *testing.B) { build(b, false, true) })
+	b.Run("SnapshotWithHash", func(b *testing.B) { build(b, true, true) })
+}
@@ -11,4 +11,15 @@ func DefaultWalkDirOptions() helper.WalkDirOptions {
 		Extensions:       []string{"*"}, // 所有文件类型
 		Excludes:         []string{},    // 不排除任何文件
 	}
-}
\ No newline at end of file
+}
+
+// WalkOptions 返回构建项目树时使用的遍历选项，未通过 BuildProjectTree 构建时返回默认选项
+func (p *Project) WalkOptions() helper.WalkDirOptions {
+	if p.IsWorkspace() {
+		return p.mounts[0].filter.options
+	}
+	if p.filter == nil {
+		return DefaultWalkDirOptions()
+	}
+	return p.filter.options
+}
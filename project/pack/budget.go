@@ -0,0 +1,189 @@
+package pack
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project"
+)
+
+// RankStrategy 有 token 预算时文件的优先级策略，排在前面的文件优先打包
+type RankStrategy string
+
+const (
+	RankPath     RankStrategy = "path"     // 按路径顺序（默认）
+	RankRecent   RankStrategy = "recent"   // 最近修改的优先
+	RankSmallest RankStrategy = "smallest" // 小文件优先
+	RankChurn    RankStrategy = "churn"    // git 历史中修改次数多的优先
+	RankQuery    RankStrategy = "query"    // 与 RankQuery 关键词相关度高的优先
+)
+
+// ParseRankStrategy 解析排序策略名称，空字符串为 path
+func ParseRankStrategy(s string) (RankStrategy, error) {
+	switch r := RankStrategy(strings.ToLower(strings.TrimSpace(s))); r {
+	case "":
+		return RankPath, nil
+	case RankPath, RankRecent, RankSmallest, RankChurn, RankQuery:
+		return r, nil
+	}
+	return "", fmt.Errorf("不支持的排序策略: %s（可选 path、recent、smallest、churn、query）", s)
+}
+
+// OmittedFormatter 可选接口：输出因超出 token 预算未打包的文件列表，在 Footer 之前调用
+type OmittedFormatter interface {
+	Omitted(files []string) string
+}
+
+// selectWithinBudget 按 options.Rank 的顺序依次放入完整文件，放不下的文件跳过并继续尝试后面的文件
+// 头部与尾部计入预算，未打包文件列表不计入；返回的 kept 保持 files 原有顺序，omitted 按路径排序
+func selectWithinBudget(title string, files []textFile, options *PackOptions) (kept []textFile, omitted []string, err error) {
+	ranked := append([]textFile(nil), files...)
+	if err := rankFiles(ranked, options); err != nil {
+		return nil, nil, err
+	}
+
+	used := helper.EstimateTokens(options.Formatter.Header(title)+signaturesMarker(options)) + helper.EstimateTokens(options.Formatter.Footer())
+	keep := make(map[string]bool)
+	for _, file := range ranked {
+		content, err := fileContent(file.node, file.path, options)
+		if err != nil {
+			// 无法读取的文件在打包时同样会被跳过
+			continue
+		}
+		cost := helper.EstimateTokens(options.Formatter.Format(file.node, content, file.path))
+		if used+cost > options.MaxTokens {
+			omitted = append(omitted, file.path)
+			continue
+		}
+		used += cost
+		keep[file.path] = true
+	}
+
+	for _, file := range files {
+		if keep[file.path] {
+			kept = append(kept, file)
+		}
+	}
+	sort.Strings(omitted)
+	return kept, omitted, nil
+}
+
+// rankFiles 按策略对文件稳定排序，优先级相同时保持原有（路径）顺序
+func rankFiles(files []textFile, options *PackOptions) error {
+	var score func(f textFile) int64
+	switch options.Rank {
+	case "", RankPath:
+		return nil
+	case RankRecent:
+		score = func(f textFile) int64 {
+			if f.node.Info == nil {
+				return 0
+			}
+			return f.node.Info.ModTime().UnixNano()
+		}
+	case RankSmallest:
+		score = func(f textFile) int64 {
+			if f.node.Info != nil {
+				return -f.node.Info.Size()
+			}
+			content, err := f.node.ReadContent()
+			if err != nil {
+				return 0
+			}
+			return -int64(len(content))
+		}
+	case RankChurn:
+		if len(files) == 0 {
+			return nil
+		}
+		churn, err := gitChurn(files)
+		if err != nil {
+			return err
+		}
+		score = func(f textFile) int64 { return int64(churn[f.path]) }
+	case RankQuery:
+		terms := strings.Fields(strings.ToLower(options.RankQuery))
+		if len(terms) == 0 {
+			return fmt.Errorf("按相关度排序需要提供查询关键词")
+		}
+		score = func(f textFile) int64 { return relevance(f, terms) }
+	default:
+		return fmt.Errorf("不支持的排序策略: %s", options.Rank)
+	}
+
+	scores := make(map[string]int64, len(files))
+	for _, f := range files {
+		scores[f.path] = score(f)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return scores[files[i].path] > scores[files[j].path]
+	})
+	return nil
+}
+
+// relevance 计算文件与关键词的相关度：路径中每次出现计 10 分，内容中每次出现计 1 分（不区分大小写）
+func relevance(f textFile, terms []string) int64 {
+	path := strings.ToLower(filepath.ToSlash(f.path))
+	content, _ := f.node.ReadContent()
+	lower := bytes.ToLower(content)
+	var total int64
+	for _, term := range terms {
+		total += 10 * int64(strings.Count(path, term))
+		total += int64(bytes.Count(lower, []byte(term)))
+	}
+	return total
+}
+
+// gitChurn 统计每个文件在 git 历史中被修改的提交数，返回以打包相对路径为键的结果
+// 工作区按文件所属的根目录分别读取各自仓库的历史，不在 git 仓库中的文件计为 0
+func gitChurn(files []textFile) (map[string]int, error) {
+	proj := files[0].node.GetProject()
+	if proj == nil || proj.GetRootPath() == "" {
+		return nil, fmt.Errorf("按修改次数排序需要磁盘上的 git 仓库")
+	}
+
+	// 每个根目录的修改次数，键为相对该目录的斜杠路径
+	counts := make(map[string]map[string]int)
+	countsOf := func(dir string) (map[string]int, error) {
+		if c, ok := counts[dir]; ok {
+			return c, nil
+		}
+		c, err := project.GitChurn(dir)
+		if err != nil {
+			return nil, fmt.Errorf("读取 git 历史失败: %w", err)
+		}
+		counts[dir] = c
+		return c, nil
+	}
+
+	churn := make(map[string]int, len(files))
+	for _, f := range files {
+		if !proj.IsPathInGit(f.node.Path) {
+			continue
+		}
+		dir, rel := proj.GetRootPath(), strings.TrimPrefix(f.node.Path, "/")
+		if proj.IsWorkspace() {
+			name, sub, _ := strings.Cut(rel, "/")
+			dir = ""
+			for _, root := range proj.WorkspaceRoots() {
+				if root.Name == name {
+					dir = root.Path
+				}
+			}
+			if dir == "" {
+				continue
+			}
+			rel = sub
+		}
+		c, err := countsOf(dir)
+		if err != nil {
+			return nil, err
+		}
+		churn[f.path] = c[rel]
+	}
+	return churn, nil
+}
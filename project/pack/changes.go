@@ -0,0 +1,248 @@
+package pack
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/helper/filekind"
+	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/diff"
+)
+
+// ChangeOptions 只打包 git 变更文件的选项，生成包含完整内容与差异块的评审材料
+type ChangeOptions struct {
+	// SinceRef 与该修订版本比较（以它和 HEAD 的合并基础为准），包含已提交、已暂存与未暂存的修改
+	SinceRef string
+	// Staged 比较暂存区与 HEAD，与 SinceRef 二选一
+	Staged bool
+	// Neighbors 每个包含变更的目录（同一个包）额外附带的未变更文件数，作为评审上下文
+	Neighbors int
+	// Context 差异块中的上下文行数，0 使用默认的 3 行
+	Context int
+}
+
+// DiffFormatter 可选接口：输出变更文件的差异块，在该文件的完整内容之后调用
+type DiffFormatter interface {
+	Diff(change diff.FileChange) string
+}
+
+// changedFile 打包的变更文件，node 为变更后内容的节点，删除的文件为 nil
+type changedFile struct {
+	node   *project.Node
+	path   string
+	change diff.FileChange
+}
+
+// packChangesToString 只打包目录下相对 git 修订版本或暂存区有变更的文件
+func packChangesToString(dir *project.Node, options *PackOptions) (string, error) {
+	changes := options.Changes
+	if changes.Staged == (changes.SinceRef != "") {
+		return "", fmt.Errorf("需要且只能指定修订版本或暂存区之一")
+	}
+	if options.MaxTokens > 0 {
+		return "", fmt.Errorf("只打包变更文件时不支持 token 预算")
+	}
+	proj := dir.GetProject()
+	if proj == nil || proj.GetRootPath() == "" {
+		return "", fmt.Errorf("只打包变更文件需要磁盘上的 git 仓库")
+	}
+
+	var gitChanges []project.GitChange
+	var err error
+	if changes.Staged {
+		gitChanges, err = project.GitStagedChanges(proj.GetAbsolutePath(dir.Path))
+	} else {
+		gitChanges, err = project.GitChangesSince(proj.GetAbsolutePath(dir.Path), changes.SinceRef)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	contextLines := changes.Context
+	if contextLines <= 0 {
+		contextLines = diff.DefaultOptions().Context
+	}
+	var files []changedFile
+	changed := make(map[string]bool)
+	for _, c := range gitChanges {
+		changed[c.Path] = true
+		if file, ok := newChangedFile(c, proj.GetAbsolutePath(dir.Path), contextLines, options); ok {
+			files = append(files, file)
+		}
+	}
+	neighbors := collectNeighbors(dir, files, changed, options)
+
+	var builder strings.Builder
+	builder.WriteString(options.Formatter.Header(dir.Name))
+	df, withDiff := options.Formatter.(DiffFormatter)
+	for _, file := range files {
+		if file.node != nil {
+			content, _ := file.node.ReadContent()
+			builder.WriteString(options.Formatter.Format(file.node, string(content), file.path))
+			options.IncludedFiles = append(options.IncludedFiles, file.path)
+		}
+		if withDiff {
+			builder.WriteString(df.Diff(file.change))
+		}
+	}
+	for _, file := range neighbors {
+		content, err := fileContent(file.node, file.path, options)
+		if err != nil {
+			continue
+		}
+		builder.WriteString(options.Formatter.Format(file.node, content, file.path))
+		options.IncludedFiles = append(options.IncludedFiles, file.path)
+	}
+	builder.WriteString(options.Formatter.Footer())
+	return builder.String(), nil
+}
+
+// newChangedFile 应用打包过滤条件并生成差异块，被过滤的文件返回 false
+// 变更后的内容可能只存在于暂存区，因此使用独立的内存节点承载；dirPath 为变更路径所相对的磁盘目录
+func newChangedFile(c project.GitChange, dirPath string, contextLines int, options *PackOptions) (changedFile, bool) {
+	name := path.Base(c.Path)
+	if !includeChangedPath(c.Path, options) {
+		return changedFile{}, false
+	}
+
+	content := c.New
+	if c.Deleted {
+		content = c.Old
+	}
+	node := &project.Node{
+		Name:          name,
+		Path:          "/" + c.Path,
+		Info:          newChangedFileInfo(filepath.Join(dirPath, filepath.FromSlash(c.Path)), name, len(content)),
+		Content:       content,
+		ContentLoaded: true,
+	}
+	kind := filekind.Detect(c.Path, content)
+	if !options.Kinds.IsZero() && !options.Kinds.Match(kind) {
+		return changedFile{}, false
+	}
+	if !options.Where.Match(node) {
+		return changedFile{}, false
+	}
+
+	change := diff.FileChange{Path: "/" + c.Path, Type: diff.Modified}
+	switch {
+	case c.Added:
+		change.Type = diff.Added
+	case c.Deleted:
+		change.Type = diff.Removed
+	}
+	if kind.Binary || kind.LFSPointer {
+		change.Binary = true
+	} else {
+		change.Hunks, change.Insertions, change.Deletions = diff.Unified(c.Old, c.New, contextLines)
+	}
+
+	file := changedFile{path: filepath.FromSlash(c.Path), change: change}
+	if !c.Deleted && !change.Binary {
+		file.node = node
+	}
+	return file, true
+}
+
+// changedFileInfo 变更文件的文件信息，供 --where 的 size 与 mtime 条件使用
+type changedFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *changedFileInfo) Name() string       { return fi.name }
+func (fi *changedFileInfo) Size() int64        { return fi.size }
+func (fi *changedFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *changedFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *changedFileInfo) IsDir() bool        { return false }
+func (fi *changedFileInfo) Sys() interface{}   { return nil }
+
+// newChangedFileInfo 大小为打包内容的长度，权限与修改时间取自磁盘文件，文件不在磁盘上时使用当前时间
+func newChangedFileInfo(abs, name string, size int) os.FileInfo {
+	info := &changedFileInfo{name: name, size: int64(size), mode: 0644, modTime: time.Now()}
+	if stat, err := os.Stat(abs); err == nil && stat.Mode().IsRegular() {
+		info.mode, info.modTime = stat.Mode(), stat.ModTime()
+	}
+	return info
+}
+
+// includeChangedPath 按隐藏文件与扩展名规则判断变更路径是否打包，路径中任一级以 . 开头即为隐藏
+func includeChangedPath(p string, options *PackOptions) bool {
+	hidden := false
+	for _, part := range strings.Split(p, "/") {
+		if strings.HasPrefix(part, ".") {
+			hidden = true
+			break
+		}
+	}
+	return helper.ShouldIncludeFile(path.Base(p), hidden, &helper.FileFilterOptions{
+		IncludeHidden: options.IncludeHidden,
+		IncludeExts:   options.IncludeExts,
+		ExcludeExts:   options.ExcludeExts,
+	})
+}
+
+// collectNeighbors 为每个包含变更的目录按文件名顺序挑选 options.Changes.Neighbors 个未变更的文件
+func collectNeighbors(dir *project.Node, files []changedFile, changed map[string]bool, options *PackOptions) []textFile {
+	if options.Changes.Neighbors <= 0 {
+		return nil
+	}
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		if file.node != nil {
+			dirs[path.Dir(strings.TrimPrefix(file.change.Path, "/"))] = true
+		}
+	}
+	names := make([]string, 0, len(dirs))
+	for d := range dirs {
+		names = append(names, d)
+	}
+	sort.Strings(names)
+
+	var neighbors []textFile
+	for _, d := range names {
+		parent := dir
+		if d != "." {
+			parent = findChildNode(dir, d)
+		}
+		if parent == nil {
+			continue
+		}
+		children := parent.GetChildrenNodes()
+		sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+		count := 0
+		for _, child := range children {
+			if count >= options.Changes.Neighbors {
+				break
+			}
+			rel := path.Join(d, child.Name)
+			if child.IsDir || changed[rel] || !shouldIncludeFile(child, options) || !matchKind(child, options) {
+				continue
+			}
+			neighbors = append(neighbors, textFile{node: child, path: filepath.FromSlash(rel)})
+			count++
+		}
+	}
+	return neighbors
+}
+
+// findChildNode 按斜杠分隔的相对路径查找 dir 下的节点
+func findChildNode(dir *project.Node, rel string) *project.Node {
+	node := dir
+	for _, part := range strings.Split(rel, "/") {
+		child, ok := node.GetChild(part)
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
@@ -1,12 +1,17 @@
 package pack
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/sjzsdu/tong/helper"
 	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/diff"
 )
 
 // Formatter 定义打包格式的接口
@@ -32,19 +37,38 @@ func (m *MarkdownFormatter) Format(node *project.Node, content string, relativeP
 	}
 	builder.WriteString("\n")
 
-	// 添加代码块
-	fileExt := filepath.Ext(relativePath)
-	lang := helper.GetLanguageFromExtension(fileExt)
-	builder.WriteString(fmt.Sprintf("```%s\n", lang))
+	// 添加代码块，围栏比内容中最长的连续反引号更长，内容中的代码块不会提前结束它
+	lang := fileLanguage(node, relativePath)
+	fence := markdownFence(content)
+	builder.WriteString(fmt.Sprintf("%s%s\n", fence, lang))
 	builder.WriteString(content)
 	if !strings.HasSuffix(content, "\n") {
 		builder.WriteString("\n")
 	}
-	builder.WriteString("```\n\n")
+	builder.WriteString(fence + "\n\n")
 
 	return builder.String()
 }
 
+// markdownFence 返回至少三个、且比内容中最长的连续反引号多一个的反引号围栏
+func markdownFence(content string) string {
+	longest, run := 0, 0
+	for i := 0; i < len(content); i++ {
+		if content[i] == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		longest = 2
+	}
+	return strings.Repeat("`", longest+1)
+}
+
 // Header 生成文档头部
 func (m *MarkdownFormatter) Header(title string) string {
 	var builder strings.Builder
@@ -62,17 +86,452 @@ func (m *MarkdownFormatter) Footer() string {
 	return builder.String()
 }
 
+// VolumeHeader 生成分卷的文档头部，标题中带有卷号
+func (m *MarkdownFormatter) VolumeHeader(title string, part, total int) string {
+	return m.Header(fmt.Sprintf("%s（第 %d/%d 卷）", title, part, total))
+}
+
+// VolumeFooter 生成分卷的文档尾部，注明卷号与下一卷
+func (m *MarkdownFormatter) VolumeFooter(part, total int) string {
+	var builder strings.Builder
+	builder.WriteString("\n---\n")
+	if part < total {
+		builder.WriteString(fmt.Sprintf("*第 %d/%d 卷，续见第 %d 卷*\n", part, total, part+1))
+	} else {
+		builder.WriteString(fmt.Sprintf("*第 %d/%d 卷（完）*\n", part, total))
+	}
+	builder.WriteString("*文档由 [tong](https://github.com/sjzsdu/tong) 工具自动生成*\n")
+	return builder.String()
+}
+
+// SignaturesMarker 以引用块注明文档只包含签名
+func (m *MarkdownFormatter) SignaturesMarker() string {
+	return fmt.Sprintf("> %s\n\n", signaturesNotice)
+}
+
 // FileExtension 返回文件扩展名
 func (m *MarkdownFormatter) FileExtension() string {
 	return ".md"
 }
 
-// GetFormatter 根据格式名称获取对应的格式化器
+// Parse 从 Markdown 打包文档中还原文件
+func (m *MarkdownFormatter) Parse(data []byte) ([]BundleFile, error) {
+	return parseMarkdownBundle(data)
+}
+
+// Omitted 列出因超出 token 预算未打包的文件
+func (m *MarkdownFormatter) Omitted(files []string) string {
+	var builder strings.Builder
+	builder.WriteString("## ✂️ 未打包的文件\n\n")
+	builder.WriteString(fmt.Sprintf("以下 %d 个文件因超出 token 预算未包含：\n\n", len(files)))
+	for _, f := range files {
+		builder.WriteString(fmt.Sprintf("- `%s`\n", f))
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// Diff 以 diff 代码块输出文件的变更
+func (m *MarkdownFormatter) Diff(change diff.FileChange) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("### 🔀 变更: %s (%s)\n\n", displayChangePath(change), changeStat(change)))
+	builder.WriteString("```diff\n")
+	builder.WriteString(patchText(change))
+	builder.WriteString("```\n\n")
+	return builder.String()
+}
+
+// XMLFormatter XML 格式的打包器，每个文件为一个 <document>，内容放在 CDATA 中，适合作为大模型提示词
+type XMLFormatter struct{}
+
+// Format 格式化单个文件内容为 <document> 元素
+func (x *XMLFormatter) Format(node *project.Node, content string, relativePath string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("<document path=\"%s\"", xmlEscape(relativePath)))
+	if lang := fileLanguage(node, relativePath); lang != "" {
+		builder.WriteString(fmt.Sprintf(" language=\"%s\"", xmlEscape(lang)))
+	}
+	builder.WriteString(fmt.Sprintf(" size=\"%d\">\n", fileSize(node, content)))
+	builder.WriteString("<![CDATA[")
+	builder.WriteString(strings.ReplaceAll(content, "]]>", "]]]]><![CDATA[>"))
+	if !strings.HasSuffix(content, "\n") {
+		builder.WriteString("\n")
+	}
+	builder.WriteString("]]>\n</document>\n")
+	return builder.String()
+}
+
+// Header 生成文档头部
+func (x *XMLFormatter) Header(title string) string {
+	return fmt.Sprintf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<documents project=\"%s\">\n", xmlEscape(title))
+}
+
+// Footer 生成文档尾部
+func (x *XMLFormatter) Footer() string {
+	return "</documents>\n"
+}
+
+// VolumeHeader 生成分卷的文档头部，<documents> 带有 part 与 parts 属性
+func (x *XMLFormatter) VolumeHeader(title string, part, total int) string {
+	return fmt.Sprintf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<documents project=\"%s\" part=\"%d\" parts=\"%d\">\n", xmlEscape(title), part, total)
+}
+
+// VolumeFooter 生成分卷的文档尾部
+func (x *XMLFormatter) VolumeFooter(part, total int) string {
+	return fmt.Sprintf("<!-- part %d/%d -->\n</documents>\n", part, total)
+}
+
+// SignaturesMarker 以 <signatures/> 元素注明文档只包含签名
+func (x *XMLFormatter) SignaturesMarker() string {
+	return "<signatures/>\n"
+}
+
+// FileExtension 返回文件扩展名
+func (x *XMLFormatter) FileExtension() string {
+	return ".xml"
+}
+
+// Parse 从 XML 打包文档中还原文件
+func (x *XMLFormatter) Parse(data []byte) ([]BundleFile, error) {
+	return parseXMLBundle(data)
+}
+
+// Omitted 列出因超出 token 预算未打包的文件
+func (x *XMLFormatter) Omitted(files []string) string {
+	var builder strings.Builder
+	builder.WriteString("<omitted reason=\"token budget\">\n")
+	for _, f := range files {
+		builder.WriteString(fmt.Sprintf("<file path=\"%s\"/>\n", xmlEscape(f)))
+	}
+	builder.WriteString("</omitted>\n")
+	return builder.String()
+}
+
+// Diff 以 <diff> 元素输出文件的变更，补丁放在 CDATA 中
+func (x *XMLFormatter) Diff(change diff.FileChange) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("<diff path=\"%s\" type=\"%s\"", xmlEscape(displayChangePath(change)), change.Type))
+	if change.Binary {
+		builder.WriteString(" binary=\"true\"")
+	}
+	builder.WriteString(fmt.Sprintf(" insertions=\"%d\" deletions=\"%d\">\n", change.Insertions, change.Deletions))
+	builder.WriteString("<![CDATA[")
+	builder.WriteString(strings.ReplaceAll(patchText(change), "]]>", "]]]]><![CDATA[>"))
+	builder.WriteString("]]>\n</diff>\n")
+	return builder.String()
+}
+
+// packedFile JSON 与 JSONL 格式中的文件记录
+type packedFile struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Size     int64  `json:"size"`
+	Content  string `json:"content"`
+}
+
+// JSONFormatter JSON 格式的打包器，输出 {"project": ..., "files": [...]}
+// 需要记录已输出的文件数以插入分隔符，同一实例不能并发用于多次打包
+type JSONFormatter struct {
+	count      int
+	omitted    []string
+	changes    []diff.FileChange
+	signatures bool
+}
+
+// Format 格式化单个文件为 files 数组中的一项
+func (j *JSONFormatter) Format(node *project.Node, content string, relativePath string) string {
+	sep := ",\n"
+	if j.count == 0 {
+		sep = ""
+	}
+	j.count++
+	return sep + "    " + jsonString(newPackedFile(node, content, relativePath))
+}
+
+// Header 生成文档头部，并重置文件计数
+func (j *JSONFormatter) Header(title string) string {
+	j.count = 0
+	j.omitted = nil
+	j.changes = nil
+	j.signatures = false
+	return fmt.Sprintf("{\n  \"project\": %s,\n  \"files\": [\n", jsonString(title))
+}
+
+// Footer 生成文档尾部，有变更时输出 changes 数组，有未打包的文件时输出 omitted 数组，只包含签名时输出 signatures
+func (j *JSONFormatter) Footer() string {
+	end := "\n  ]"
+	if j.count == 0 {
+		end = "  ]"
+	}
+	if j.signatures {
+		end += ",\n  \"signatures\": true"
+	}
+	if len(j.changes) > 0 {
+		end += ",\n  \"changes\": " + jsonString(j.changes)
+	}
+	if len(j.omitted) > 0 {
+		end += ",\n  \"omitted\": " + jsonString(j.omitted)
+	}
+	return end + "\n}\n"
+}
+
+// VolumeHeader 生成分卷的文档头部，带有 part 与 parts 字段，并重置文件计数
+func (j *JSONFormatter) VolumeHeader(title string, part, total int) string {
+	j.Header(title)
+	return fmt.Sprintf("{\n  \"project\": %s,\n  \"part\": %d,\n  \"parts\": %d,\n  \"files\": [\n", jsonString(title), part, total)
+}
+
+// VolumeFooter 生成分卷的文档尾部，卷号已在头部给出
+func (j *JSONFormatter) VolumeFooter(part, total int) string {
+	return j.Footer()
+}
+
+// Omitted 记录未打包的文件，由 Footer 输出
+func (j *JSONFormatter) Omitted(files []string) string {
+	j.omitted = append([]string(nil), files...)
+	return ""
+}
+
+// Diff 记录文件的变更，由 Footer 输出
+func (j *JSONFormatter) Diff(change diff.FileChange) string {
+	j.changes = append(j.changes, change)
+	return ""
+}
+
+// SignaturesMarker 记录文档只包含签名，由 Footer 输出
+func (j *JSONFormatter) SignaturesMarker() string {
+	j.signatures = true
+	return ""
+}
+
+// FileExtension 返回文件扩展名
+func (j *JSONFormatter) FileExtension() string {
+	return ".json"
+}
+
+// Parse 从 JSON 打包文档中还原文件
+func (j *JSONFormatter) Parse(data []byte) ([]BundleFile, error) {
+	return parseJSONBundle(data)
+}
+
+// JSONLFormatter JSON Lines 格式的打包器，每行一个文件记录
+type JSONLFormatter struct{}
+
+// Format 格式化单个文件为一行 JSON
+func (j *JSONLFormatter) Format(node *project.Node, content string, relativePath string) string {
+	return jsonString(newPackedFile(node, content, relativePath)) + "\n"
+}
+
+// Header JSONL 没有文档头部
+func (j *JSONLFormatter) Header(title string) string {
+	return ""
+}
+
+// Footer JSONL 没有文档尾部
+func (j *JSONLFormatter) Footer() string {
+	return ""
+}
+
+// VolumeHeader 分卷的第一行为 {"project": ..., "part": N, "parts": M}
+func (j *JSONLFormatter) VolumeHeader(title string, part, total int) string {
+	return jsonString(struct {
+		Project string `json:"project"`
+		Part    int    `json:"part"`
+		Parts   int    `json:"parts"`
+	}{title, part, total}) + "\n"
+}
+
+// VolumeFooter 卷号已在第一行给出，JSONL 分卷没有尾部
+func (j *JSONLFormatter) VolumeFooter(part, total int) string {
+	return ""
+}
+
+// SignaturesMarker 以单独一行 {"signatures": true} 注明文档只包含签名
+func (j *JSONLFormatter) SignaturesMarker() string {
+	return jsonString(map[string]bool{"signatures": true}) + "\n"
+}
+
+// FileExtension 返回文件扩展名
+func (j *JSONLFormatter) FileExtension() string {
+	return ".jsonl"
+}
+
+// Parse 从 JSON Lines 打包文档中还原文件
+func (j *JSONLFormatter) Parse(data []byte) ([]BundleFile, error) {
+	return parseJSONLBundle(data)
+}
+
+// Omitted 以单独一行 {"omitted": [...]} 列出未打包的文件
+func (j *JSONLFormatter) Omitted(files []string) string {
+	return jsonString(map[string][]string{"omitted": files}) + "\n"
+}
+
+// Diff 以单独一行 {"change": {...}} 输出文件的变更
+func (j *JSONLFormatter) Diff(change diff.FileChange) string {
+	return jsonString(map[string]diff.FileChange{"change": change}) + "\n"
+}
+
+// TextFormatter 纯文本格式的打包器，文件之间用路径标题行分隔
+type TextFormatter struct{}
+
+// Format 格式化单个文件内容为纯文本，内容中形似标题的行会被转义
+func (t *TextFormatter) Format(node *project.Node, content string, relativePath string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("==> %s <==\n", relativePath))
+	builder.WriteString(escapeTextContent(content))
+	if !strings.HasSuffix(content, "\n") {
+		builder.WriteString("\n")
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// textEscapeRe 纯文本内容中需要转义的行：以 ==> 开头，或以反斜杠加 ==> 开头
+var textEscapeRe = regexp.MustCompile(`(?m)^\\*==> `)
+
+// escapeTextContent 在形似标题的内容行前加一个反斜杠，解包时由 unescapeTextContent 去掉
+func escapeTextContent(content string) string {
+	return textEscapeRe.ReplaceAllStringFunc(content, func(m string) string { return `\` + m })
+}
+
+// Header 生成文档头部
+func (t *TextFormatter) Header(title string) string {
+	return fmt.Sprintf("项目打包: %s\n\n", title)
+}
+
+// Footer 纯文本没有文档尾部
+func (t *TextFormatter) Footer() string {
+	return ""
+}
+
+// VolumeHeader 生成分卷的文档头部，标题中带有卷号
+func (t *TextFormatter) VolumeHeader(title string, part, total int) string {
+	return t.Header(fmt.Sprintf("%s（第 %d/%d 卷）", title, part, total))
+}
+
+// VolumeFooter 生成分卷的结束行
+func (t *TextFormatter) VolumeFooter(part, total int) string {
+	return fmt.Sprintf("==> 第 %d/%d 卷结束 <==\n", part, total)
+}
+
+// SignaturesMarker 以单独的标题行注明文档只包含签名
+func (t *TextFormatter) SignaturesMarker() string {
+	return fmt.Sprintf("==> %s <==\n\n", signaturesNotice)
+}
+
+// FileExtension 返回文件扩展名
+func (t *TextFormatter) FileExtension() string {
+	return ".txt"
+}
+
+// Parse 从纯文本打包文档中还原文件
+func (t *TextFormatter) Parse(data []byte) ([]BundleFile, error) {
+	return parseTextBundle(data)
+}
+
+// Omitted 列出因超出 token 预算未打包的文件
+func (t *TextFormatter) Omitted(files []string) string {
+	return "==> 未打包的文件（超出 token 预算） <==\n" + strings.Join(files, "\n") + "\n\n"
+}
+
+// Diff 输出文件的补丁
+func (t *TextFormatter) Diff(change diff.FileChange) string {
+	return fmt.Sprintf("==> 变更: %s (%s) <==\n%s\n", displayChangePath(change), changeStat(change), patchText(change))
+}
+
+// fileLanguage 返回文件的语言，分类结果为空时按扩展名推断
+func fileLanguage(node *project.Node, relativePath string) string {
+	if lang := node.Kind().Language; lang != "" {
+		return lang
+	}
+	return helper.GetLanguageFromExtension(filepath.Ext(relativePath))
+}
+
+// displayChangePath 返回变更文件相对打包目录的路径
+func displayChangePath(change diff.FileChange) string {
+	return strings.TrimPrefix(change.Path, "/")
+}
+
+// changeStat 返回变更类型与增删行数，如 modified +3 -1
+func changeStat(change diff.FileChange) string {
+	if change.Binary {
+		return fmt.Sprintf("%s, binary", change.Type)
+	}
+	return fmt.Sprintf("%s +%d -%d", change.Type, change.Insertions, change.Deletions)
+}
+
+// patchText 生成单个文件的 git 风格补丁
+func patchText(change diff.FileChange) string {
+	return (&diff.Result{Changes: []diff.FileChange{change}}).Patch()
+}
+
+// fileSize 返回文件大小，没有文件信息时使用内容长度
+func fileSize(node *project.Node, content string) int64 {
+	if node.Info != nil {
+		return node.Info.Size()
+	}
+	return int64(len(content))
+}
+
+func newPackedFile(node *project.Node, content string, relativePath string) packedFile {
+	return packedFile{
+		Path:     filepath.ToSlash(relativePath),
+		Language: fileLanguage(node, relativePath),
+		Size:     fileSize(node, content),
+		Content:  content,
+	}
+}
+
+// jsonString 编码为单行 JSON，不转义 HTML 字符
+func jsonString(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "null"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// xmlEscape 转义 XML 属性值
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+// GetFormatter 根据格式名称获取对应的格式化器，名称为空时使用 Markdown，不支持的格式返回 nil
 func GetFormatter(format string) Formatter {
-	switch strings.ToLower(format) {
-	case "markdown", "md":
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "markdown", "md":
 		return &MarkdownFormatter{}
+	case "xml":
+		return &XMLFormatter{}
+	case "json":
+		return &JSONFormatter{}
+	case "jsonl", "ndjson":
+		return &JSONLFormatter{}
+	case "text", "txt", "plain":
+		return &TextFormatter{}
+	default:
+		return nil
+	}
+}
+
+// FormatFromPath 根据输出文件扩展名推断格式名称，无法识别时返回 markdown
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return "xml"
+	case ".json":
+		return "json"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	case ".txt", ".text":
+		return "text"
 	default:
-		return &MarkdownFormatter{} // 默认使用Markdown格式
+		return "markdown"
 	}
 }
@@ -1,5 +1,10 @@
 package pack
 
+import (
+	"github.com/sjzsdu/tong/helper/filekind"
+	"github.com/sjzsdu/tong/project/query"
+)
+
 // PackOptions 打包配置选项
 type PackOptions struct {
 	Formatter     Formatter
@@ -7,8 +12,28 @@ type PackOptions struct {
 	ExcludeExts   []string
 	Recursive     bool
 	IncludeHidden bool
+	// Kinds 按文件分类筛选，二进制文件与 Git LFS 指针总是被跳过
+	Kinds filekind.Filter
+	// Where 节点查询条件（见 project/query），nil 不过滤
+	Where *query.Query
+	// MaxTokens 打包结果的 token 预算（按 helper.EstimateTokens 估算），0 表示不限制
+	MaxTokens int
+	// Rank 有预算时决定文件优先级的策略，见 RankStrategy
+	Rank RankStrategy
+	// RankQuery Rank 为 RankQuery 时用于计算相关度的关键词，空格分隔
+	RankQuery string
+	// Signatures 只保留声明与文档注释（见 SignatureExtractor），没有提取器的语言保留完整内容
+	Signatures bool
+	// Changes 不为 nil 时只打包相对 git 修订版本或暂存区有变更的文件，见 ChangeOptions
+	Changes *ChangeOptions
+	// Split 分卷大小，PackNode 按该大小把结果写入多个分卷（见 VolumePath）并生成索引文件，零值不分卷
+	Split SplitLimit
 	// IncludedFiles 打包过程中实际被包含的文件(相对路径)
 	IncludedFiles []string
+	// OmittedFiles 因超出 token 预算未被打包的文件(相对路径)
+	OmittedFiles []string
+	// Volumes 分卷打包时写出的分卷
+	Volumes []Volume
 }
 
 // DefaultOptions 返回默认的打包选项
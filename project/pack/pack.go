@@ -1,13 +1,13 @@
 package pack
 
 import (
-	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
-	"unicode/utf8"
+	"sync"
 
 	"github.com/sjzsdu/tong/helper"
 	"github.com/sjzsdu/tong/project"
@@ -27,6 +27,10 @@ func PackNode(node *project.Node, outputPath string, options *PackOptions) error
 		options.Formatter = GetFormatter("")
 	}
 
+	if options.Split.Size > 0 {
+		return packVolumes(node, outputPath, options)
+	}
+
 	if node.IsDir {
 		return packDirectory(node, outputPath, options)
 	}
@@ -55,6 +59,16 @@ func PackToString(node *project.Node, options *PackOptions) (string, error) {
 	return packFileToString(node, options)
 }
 
+// PackFS 将任意 fs.FS（如 embed.FS、fstest.MapFS）中的文本文件打包成字符串，
+// 遵循与项目树相同的 .gitignore 与排除规则
+func PackFS(fsys fs.FS, options *PackOptions) (string, error) {
+	proj, err := project.BuildProjectTreeFromFS(fsys, project.DefaultWalkDirOptions())
+	if err != nil {
+		return "", fmt.Errorf("构建项目树失败: %w", err)
+	}
+	return PackToString(proj.Root(), options)
+}
+
 // packDirectory 打包目录及其子目录中的所有文本文件到文件
 func packDirectory(dir *project.Node, outputPath string, options *PackOptions) error {
 	content, err := packDirectoryToString(dir, options)
@@ -81,30 +95,39 @@ func packDirectoryToString(dir *project.Node, options *PackOptions) (string, err
 	if !dir.IsDir {
 		return "", fmt.Errorf("节点不是目录")
 	}
+	if options.Changes != nil {
+		return packChangesToString(dir, options)
+	}
+
+	textFiles, omitted, err := selectTextFiles(dir, options)
+	if err != nil {
+		return "", err
+	}
 
 	var builder strings.Builder
 
 	// 添加文档头部
 	builder.WriteString(options.Formatter.Header(dir.Name))
-
-	// 收集所有文本文件
-	textFiles := collectTextFiles(dir, "", options)
-
-	// 按路径排序，确保一致的输出顺序
-	sort.Slice(textFiles, func(i, j int) bool {
-		return textFiles[i].path < textFiles[j].path
-	})
+	builder.WriteString(signaturesMarker(options))
 
 	// 打包每个文件
 	for _, file := range textFiles {
-		content, err := file.node.ReadContent()
+		content, err := fileContent(file.node, file.path, options)
 		if err != nil {
 			// 跳过无法读取的文件
 			continue
 		}
 
-		formatted := options.Formatter.Format(file.node, string(content), file.path)
+		formatted := options.Formatter.Format(file.node, content, file.path)
 		builder.WriteString(formatted)
+		options.IncludedFiles = append(options.IncludedFiles, file.path)
+	}
+
+	// 列出未打包的文件
+	if len(omitted) > 0 {
+		if of, ok := options.Formatter.(OmittedFormatter); ok {
+			builder.WriteString(of.Omitted(omitted))
+		}
 	}
 
 	// 添加文档尾部
@@ -113,6 +136,33 @@ func packDirectoryToString(dir *project.Node, options *PackOptions) (string, err
 	return builder.String(), nil
 }
 
+// selectTextFiles 收集目录下要打包的文本文件并按路径排序，有 token 预算时按优先级挑选完整文件
+// 未打包的文件追加到 options.OmittedFiles 并一并返回
+func selectTextFiles(dir *project.Node, options *PackOptions) ([]textFile, []string, error) {
+	// 收集所有文本文件，IncludedFiles 只记录实际写入的文件
+	start := len(options.IncludedFiles)
+	textFiles := collectTextFiles(dir, "", options)
+	options.IncludedFiles = options.IncludedFiles[:start]
+
+	// 按路径排序，确保一致的输出顺序
+	sort.Slice(textFiles, func(i, j int) bool {
+		return textFiles[i].path < textFiles[j].path
+	})
+
+	// 有 token 预算时按优先级挑选完整文件
+	var omitted []string
+	if options.MaxTokens > 0 {
+		var err error
+		textFiles, omitted, err = selectWithinBudget(dir.Name, textFiles, options)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	options.OmittedFiles = append(options.OmittedFiles, omitted...)
+
+	return textFiles, omitted, nil
+}
+
 // packFile 打包单个文件到文件
 func packFile(file *project.Node, outputPath string, options *PackOptions) error {
 	content, err := packFileToString(file, options)
@@ -139,25 +189,43 @@ func packFileToString(file *project.Node, options *PackOptions) (string, error)
 	if file.IsDir {
 		return "", fmt.Errorf("节点不是文件")
 	}
+	if options.Changes != nil {
+		return "", fmt.Errorf("只打包变更文件时目标必须是目录")
+	}
 
 	// 检查文件是否应该被包含
 	if !shouldIncludeFile(file, options) {
 		return "", fmt.Errorf("文件类型不在允许范围内")
 	}
 
-	content, err := file.ReadContent()
+	content, err := fileContent(file, file.Name, options)
 	if err != nil {
 		return "", fmt.Errorf("读取文件内容失败: %w", err)
 	}
 
 	var builder strings.Builder
 	builder.WriteString(options.Formatter.Header(file.Name))
-	builder.WriteString(options.Formatter.Format(file, string(content), file.Name))
+	builder.WriteString(signaturesMarker(options))
+	builder.WriteString(options.Formatter.Format(file, content, file.Name))
 	builder.WriteString(options.Formatter.Footer())
 
 	return builder.String(), nil
 }
 
+// fileContent 读取要打包的文件内容，options.Signatures 为 true 时尽量只保留签名
+func fileContent(node *project.Node, relativePath string, options *PackOptions) (string, error) {
+	content, err := node.ReadContent()
+	if err != nil {
+		return "", err
+	}
+	if options.Signatures {
+		if signatures, ok := ExtractSignatures(fileLanguage(node, relativePath), relativePath, content); ok {
+			return signatures, nil
+		}
+	}
+	return string(content), nil
+}
+
 // textFile 表示一个文本文件的信息
 type textFile struct {
 	node *project.Node
@@ -167,8 +235,8 @@ type textFile struct {
 // collectTextFiles 收集所有文本文件
 func collectTextFiles(node *project.Node, currentPath string, options *PackOptions) []textFile {
 	if !node.IsDir {
-		// 如果是文件，检查是否为文本文件(扩展名+内容探测)
-		if shouldIncludeFile(node, options) && !isBinaryNode(node) {
+		// 如果是文件，检查是否为文本文件(扩展名+文件分类)
+		if shouldIncludeFile(node, options) && matchKind(node, options) {
 			path := currentPath
 			if path == "" {
 				path = node.Name
@@ -186,7 +254,7 @@ func collectTextFiles(node *project.Node, currentPath string, options *PackOptio
 	if !options.Recursive {
 		var files []textFile
 		for _, child := range node.Children {
-			if !child.IsDir && shouldIncludeFile(child, options) && !isBinaryNode(child) {
+			if !child.IsDir && shouldIncludeFile(child, options) && matchKind(child, options) {
 				path := filepath.Join(currentPath, child.Name)
 				options.IncludedFiles = append(options.IncludedFiles, path)
 				files = append(files, textFile{node: child, path: path})
@@ -198,51 +266,55 @@ func collectTextFiles(node *project.Node, currentPath string, options *PackOptio
 	// 使用BFS策略并行收集所有文本文件
 	var allFiles []textFile
 
-	// 定义一个可递归的函数来处理节点并收集文件
+	// 定义一个可递归的函数来处理节点并收集文件，并发调用时不修改 options
 	var processNode func(n *project.Node, path string) []textFile
 	processNode = func(n *project.Node, path string) []textFile {
 		if !n.IsDir {
-			if shouldIncludeFile(n, options) && !isBinaryNode(n) {
-				options.IncludedFiles = append(options.IncludedFiles, path)
+			if shouldIncludeFile(n, options) && matchKind(n, options) {
 				return []textFile{{node: n, path: path}}
 			}
 			return []textFile{}
 		}
 
 		var files []textFile
-		for _, child := range n.Children {
+		for _, child := range n.GetChildrenNodes() {
 			childPath := filepath.Join(path, child.Name)
 			files = append(files, processNode(child, childPath)...)
 		}
 		return files
 	}
 
-	// 对于根目录，直接使用BFS遍历
+	// 对于根目录，并发处理第一层子节点
 	if currentPath == "" {
-		// 使用并发处理第一层子节点
-		ctx := context.Background()
-		maxWorkers := 10 // 限制并发数
-
-		processFunc := func(n *project.Node) (interface{}, error) {
-			childPath := n.Name
-			return processNode(n, childPath), nil
+		children := node.GetChildrenNodes()
+		results := make([][]textFile, len(children))
+		sem := make(chan struct{}, 10) // 限制并发数
+		var wg sync.WaitGroup
+		for i, child := range children {
+			wg.Add(1)
+			go func(i int, child *project.Node) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results[i] = processNode(child, child.Name)
+			}(i, child)
 		}
-
-		results := node.ProcessConcurrent(ctx, maxWorkers, processFunc)
+		wg.Wait()
 
 		// 合并结果
-		for _, result := range results {
-			if result.Err == nil {
-				if files, ok := result.Value.([]textFile); ok {
-					allFiles = append(allFiles, files...)
-				}
-			}
+		for _, files := range results {
+			allFiles = append(allFiles, files...)
 		}
 	} else {
 		// 对于子目录，使用普通递归避免过多协程
 		allFiles = processNode(node, currentPath)
 	}
 
+	// 记录包含
+	for _, file := range allFiles {
+		options.IncludedFiles = append(options.IncludedFiles, file.path)
+	}
+
 	return allFiles
 }
 
@@ -265,56 +337,9 @@ func shouldIncludeFile(node *project.Node, options *PackOptions) bool {
 	return helper.ShouldIncludeFile(node.Name, isHidden, filterOptions)
 }
 
-// isBinaryNode 通过内容粗略判断是否是二进制文件
-// 策略：读取前8192字节，统计不可打印字符(排除常见的换行/回车/制表)比例或是否出现0字节
-func isBinaryNode(node *project.Node) bool {
-	content, err := node.ReadContent()
-	if err != nil || len(content) == 0 {
-		return false // 读取失败时不当作二进制，交给扩展名过滤
-	}
-
-	limit := len(content)
-	if limit > 8192 {
-		limit = 8192
-	}
-	data := content[:limit]
-
-	var nonPrintable int
-	for _, b := range data {
-		if b == 0 { // NULL 字节强烈指示二进制
-			return true
-		}
-		// 允许的控制字符: \n, \r, \t
-		if b < 32 && b != 10 && b != 13 && b != 9 {
-			nonPrintable++
-			continue
-		}
-		// 尝试按 UTF-8 解码首字节快速检测
-		if b >= 0x80 { // 高位字节，尝试UTF-8
-			// 简单：跳过完整UTF-8验证，只在无法解析成合法序列时记为不可打印。
-			// 这里保守处理：不直接计入不可打印，除非后面整体UTF-8比例异常。
-			continue
-		}
-	}
-
-	// 如果不可打印字符比例 > 30% 判定为二进制
-	if float64(nonPrintable)/float64(limit) > 0.30 {
-		return true
-	}
-
-	// 额外：如果整体不是有效UTF-8且包含大量高位字节也可能是二进制
-	if !utf8.Valid(data) {
-		// 统计高位字节数量
-		var highBytes int
-		for _, b := range data {
-			if b >= 0x80 {
-				highBytes++
-			}
-		}
-		if float64(highBytes)/float64(limit) > 0.50 { // 超过一半高位且非有效UTF-8
-			return true
-		}
-	}
-
-	return false
+// matchKind 按文件分类判断是否打包：跳过二进制文件与 Git LFS 指针，并应用 options.Kinds 与 options.Where
+func matchKind(node *project.Node, options *PackOptions) bool {
+	filter := options.Kinds
+	filter.SkipBinary = true
+	return node.MatchKind(filter) && options.Where.Match(node)
 }
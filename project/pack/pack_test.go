@@ -1,13 +1,22 @@
 package pack
 
 import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/helper/filekind"
 	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/query"
 )
 
 func TestMarkdownFormatter(t *testing.T) {
@@ -50,6 +59,136 @@ func TestGetFormatter(t *testing.T) {
 	if _, ok := formatter.(*MarkdownFormatter); !ok {
 		t.Error("md format should return MarkdownFormatter")
 	}
+
+	// 其他格式
+	if _, ok := GetFormatter("XML").(*XMLFormatter); !ok {
+		t.Error("xml format should return XMLFormatter")
+	}
+	if _, ok := GetFormatter("json").(*JSONFormatter); !ok {
+		t.Error("json format should return JSONFormatter")
+	}
+	if _, ok := GetFormatter("jsonl").(*JSONLFormatter); !ok {
+		t.Error("jsonl format should return JSONLFormatter")
+	}
+	if _, ok := GetFormatter("txt").(*TextFormatter); !ok {
+		t.Error("txt format should return TextFormatter")
+	}
+	if GetFormatter("pdf") != nil {
+		t.Error("unsupported format should return nil")
+	}
+
+	// 按输出文件扩展名推断
+	for path, want := range map[string]string{
+		"out.md": "markdown", "out.XML": "xml", "out.json": "json", "out.jsonl": "jsonl",
+		"out.txt": "text", "out": "markdown", "out.pdf": "markdown",
+	} {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%s) = %s, expected %s", path, got, want)
+		}
+	}
+}
+
+func TestXMLFormatter(t *testing.T) {
+	formatter := &XMLFormatter{}
+
+	header := formatter.Header("a&b")
+	if !strings.Contains(header, `<documents project="a&amp;b">`) {
+		t.Errorf("Header should contain escaped project name, got %q", header)
+	}
+	if formatter.Footer() != "</documents>\n" {
+		t.Errorf("Unexpected footer %q", formatter.Footer())
+	}
+	if ext := formatter.FileExtension(); ext != ".xml" {
+		t.Errorf("Expected .xml, got %s", ext)
+	}
+
+	node := &project.Node{Name: "main.go"}
+	doc := formatter.Format(node, "if a < b { x := \"]]>\" }", "src/main.go")
+	if !strings.HasPrefix(doc, `<document path="src/main.go" language="go" size="`) {
+		t.Errorf("Unexpected document element %q", doc)
+	}
+	if !strings.Contains(doc, "]]]]><![CDATA[>") {
+		t.Errorf("CDATA terminator should be split, got %q", doc)
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	formatter := &JSONFormatter{}
+	if ext := formatter.FileExtension(); ext != ".json" {
+		t.Errorf("Expected .json, got %s", ext)
+	}
+
+	// 空项目也是合法 JSON
+	var empty map[string]interface{}
+	if err := json.Unmarshal([]byte(formatter.Header("p")+formatter.Footer()), &empty); err != nil {
+		t.Fatalf("empty output should be valid JSON: %v", err)
+	}
+
+	node := &project.Node{Name: "a.py"}
+	out := formatter.Header("p") +
+		formatter.Format(node, "print('<hi>')\n", "a.py") +
+		formatter.Format(&project.Node{Name: "b.txt"}, "x", "dir/b.txt") +
+		formatter.Footer()
+	var doc struct {
+		Project string       `json:"project"`
+		Files   []packedFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("output should be valid JSON: %v\n%s", err, out)
+	}
+	if doc.Project != "p" || len(doc.Files) != 2 {
+		t.Fatalf("Unexpected document %+v", doc)
+	}
+	want := packedFile{Path: "a.py", Language: "python", Size: 14, Content: "print('<hi>')\n"}
+	if doc.Files[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, doc.Files[0])
+	}
+	if !strings.Contains(out, "<hi>") {
+		t.Error("HTML characters should not be escaped")
+	}
+
+	// Header 重置计数，实例可重复使用
+	again := formatter.Header("p") + formatter.Format(node, "x", "a.py") + formatter.Footer()
+	if err := json.Unmarshal([]byte(again), &doc); err != nil || len(doc.Files) != 1 {
+		t.Errorf("Formatter should be reusable after Header: %v", err)
+	}
+}
+
+func TestJSONLFormatter(t *testing.T) {
+	formatter := &JSONLFormatter{}
+	if formatter.Header("p") != "" || formatter.Footer() != "" {
+		t.Error("JSONL should have no header or footer")
+	}
+	if ext := formatter.FileExtension(); ext != ".jsonl" {
+		t.Errorf("Expected .jsonl, got %s", ext)
+	}
+
+	line := formatter.Format(&project.Node{Name: "main.go"}, "package main\n\nfunc main() {}\n", "main.go")
+	if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+		t.Fatalf("Each file should be a single line, got %q", line)
+	}
+	var file packedFile
+	if err := json.Unmarshal([]byte(line), &file); err != nil {
+		t.Fatalf("line should be valid JSON: %v", err)
+	}
+	if file.Path != "main.go" || file.Language != "go" || file.Content != "package main\n\nfunc main() {}\n" {
+		t.Errorf("Unexpected record %+v", file)
+	}
+}
+
+func TestTextFormatter(t *testing.T) {
+	formatter := &TextFormatter{}
+
+	if !strings.Contains(formatter.Header("test-project"), "test-project") {
+		t.Error("Header should contain project name")
+	}
+	if ext := formatter.FileExtension(); ext != ".txt" {
+		t.Errorf("Expected .txt, got %s", ext)
+	}
+	out := formatter.Format(&project.Node{Name: "a.txt"}, "hello", "docs/a.txt")
+	if out != "==> docs/a.txt <==\nhello\n\n" {
+		t.Errorf("Unexpected text output %q", out)
+	}
 }
 
 func TestIsTextFile(t *testing.T) {
@@ -404,3 +543,892 @@ func TestPackNodeWithNonTextFile(t *testing.T) {
 		 t.Error("输出文件不应该包含非文本文件")
 	}
 }
+
+func TestPackFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore": {Data: []byte("*.log\n")},
+		"main.go":    {Data: []byte("package main\n")},
+		"docs/a.md":  {Data: []byte("# A\n")},
+		"debug.log":  {Data: []byte("ignored\n")},
+		"image.bin":  {Data: []byte{0, 1, 2, 3}},
+		".env":       {Data: []byte("SECRET=1\n")},
+	}
+
+	options := DefaultOptions()
+	result, err := PackFS(fsys, options)
+	if err != nil {
+		t.Fatalf("PackFS failed: %v", err)
+	}
+
+	for _, want := range []string{"package main", "# A"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("packed content should contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{"ignored", "SECRET"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("packed content should not contain %q", unwanted)
+		}
+	}
+	if len(options.IncludedFiles) != 2 {
+		t.Errorf("expected 2 included files, got %v", options.IncludedFiles)
+	}
+}
+
+func TestPackKinds(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":          {Data: []byte("package main\n")},
+		"api/api.pb.go":    {Data: []byte("// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n")},
+		"vendor/x/x.go":    {Data: []byte("package x\n")},
+		"scripts/deploy":   {Data: []byte("#!/bin/sh\necho deploy\n")},
+		"assets/model.dat": {Data: []byte("version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 10\n")},
+	}
+
+	options := DefaultOptions()
+	result, err := PackFS(fsys, options)
+	if err != nil {
+		t.Fatalf("PackFS failed: %v", err)
+	}
+	if strings.Contains(result, "git-lfs") {
+		t.Error("LFS pointer files should not be packed")
+	}
+	if !strings.Contains(result, "```bash\n#!/bin/sh") {
+		t.Error("script language should be detected from the shebang")
+	}
+
+	options = DefaultOptions()
+	options.Kinds = filekind.Filter{Languages: []string{"go"}, SkipGenerated: true, SkipVendored: true}
+	if _, err := PackFS(fsys, options); err != nil {
+		t.Fatalf("PackFS failed: %v", err)
+	}
+	if len(options.IncludedFiles) != 1 || options.IncludedFiles[0] != "main.go" {
+		t.Errorf("expected only main.go, got %v", options.IncludedFiles)
+	}
+}
+
+func TestPackFSFormats(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":   {Data: []byte("package main\n")},
+		"docs/a.md": {Data: []byte("# A & <B>\n")},
+	}
+
+	// XML 输出可被标准库解析
+	options := DefaultOptions()
+	options.Formatter = GetFormatter("xml")
+	result, err := PackFS(fsys, options)
+	if err != nil {
+		t.Fatalf("PackFS failed: %v", err)
+	}
+	var docs struct {
+		Documents []struct {
+			Path     string `xml:"path,attr"`
+			Language string `xml:"language,attr"`
+			Content  string `xml:",chardata"`
+		} `xml:"document"`
+	}
+	if err := xml.Unmarshal([]byte(result), &docs); err != nil {
+		t.Fatalf("XML output should be valid: %v\n%s", err, result)
+	}
+	if len(docs.Documents) != 2 || docs.Documents[0].Path != "docs/a.md" || docs.Documents[0].Language != "markdown" ||
+		docs.Documents[0].Content != "\n# A & <B>\n\n" {
+		t.Errorf("Unexpected XML documents %+v", docs.Documents)
+	}
+
+	// JSON 输出可被标准库解析
+	options = DefaultOptions()
+	options.Formatter = GetFormatter("json")
+	result, err = PackFS(fsys, options)
+	if err != nil {
+		t.Fatalf("PackFS failed: %v", err)
+	}
+	var doc struct {
+		Files []packedFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(result), &doc); err != nil {
+		t.Fatalf("JSON output should be valid: %v\n%s", err, result)
+	}
+	if len(doc.Files) != 2 || doc.Files[1].Path != "main.go" || doc.Files[1].Content != "package main\n" || doc.Files[1].Size != 13 {
+		t.Errorf("Unexpected JSON files %+v", doc.Files)
+	}
+}
+
+func TestPackMaxTokens(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"big.go":       strings.Repeat("// big file line\n", 40),
+		"small.go":     "package small\n",
+		"medium.md":    strings.Repeat("budget notes\n", 10),
+		"docs/old.txt": "old budget text\n",
+	}
+	for name, content := range files {
+		full := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// docs/old.txt 最早修改，big.go 最近修改
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"docs/old.txt", "small.go", "medium.md", "big.go"} {
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(filepath.Join(dir, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pack := func(rank RankStrategy, query string, maxTokens int, format string) (string, *PackOptions) {
+		t.Helper()
+		options := DefaultOptions()
+		options.Formatter = GetFormatter(format)
+		options.MaxTokens = maxTokens
+		options.Rank = rank
+		options.RankQuery = query
+		result, err := PackToString(proj.Root(), options)
+		if err != nil {
+			t.Fatalf("PackToString failed: %v", err)
+		}
+		if maxTokens > 0 && helper.EstimateTokens(result) > maxTokens+helper.EstimateTokens(strings.Join(options.OmittedFiles, "\n"))+64 {
+			t.Errorf("result exceeds budget: %d tokens", helper.EstimateTokens(result))
+		}
+		return result, options
+	}
+
+	// 不限制时全部打包
+	_, options := pack(RankPath, "", 0, "")
+	if len(options.IncludedFiles) != 4 || len(options.OmittedFiles) != 0 {
+		t.Fatalf("expected all files without budget, got %v / %v", options.IncludedFiles, options.OmittedFiles)
+	}
+
+	// 小文件优先：big.go 放不下
+	result, options := pack(RankSmallest, "", 200, "markdown")
+	if strings.Join(options.IncludedFiles, ",") != "docs/old.txt,medium.md,small.go" {
+		t.Errorf("unexpected included files %v", options.IncludedFiles)
+	}
+	if strings.Join(options.OmittedFiles, ",") != "big.go" {
+		t.Errorf("unexpected omitted files %v", options.OmittedFiles)
+	}
+	if strings.Contains(result, "big file line") || !strings.Contains(result, "未打包的文件") || !strings.Contains(result, "- `big.go`") {
+		t.Errorf("markdown should list omitted big.go:\n%s", result)
+	}
+
+	// 最近修改优先：big.go 放入后只剩较小的文件能放下
+	_, options = pack(RankRecent, "", 260, "markdown")
+	if !helper.StringSliceContains(options.IncludedFiles, "big.go") || len(options.OmittedFiles) == 0 {
+		t.Errorf("recent ranking should keep big.go, got %v / %v", options.IncludedFiles, options.OmittedFiles)
+	}
+
+	// 按查询相关度：路径与内容中含 budget 的文件优先
+	result, options = pack(RankQuery, "budget", 90, "json")
+	var doc struct {
+		Files   []packedFile `json:"files"`
+		Omitted []string     `json:"omitted"`
+	}
+	if err := json.Unmarshal([]byte(result), &doc); err != nil {
+		t.Fatalf("JSON with omitted files should be valid: %v\n%s", err, result)
+	}
+	if len(doc.Files) != len(options.IncludedFiles) || doc.Files[0].Path != "docs/old.txt" || doc.Files[1].Path != "medium.md" {
+		t.Errorf("unexpected files %+v", doc.Files)
+	}
+	if strings.Join(doc.Omitted, ",") != strings.Join(options.OmittedFiles, ",") || !helper.StringSliceContains(doc.Omitted, "big.go") {
+		t.Errorf("unexpected omitted %v", doc.Omitted)
+	}
+
+	// 查询为空或策略无效时报错
+	options = DefaultOptions()
+	options.MaxTokens = 100
+	options.Rank = RankQuery
+	if _, err := PackToString(proj.Root(), options); err == nil {
+		t.Error("query ranking without query should fail")
+	}
+	if _, err := ParseRankStrategy("random"); err == nil {
+		t.Error("unknown strategy should fail")
+	}
+}
+
+func TestPackMaxTokensChurn(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "repo")
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(msg string) {
+		t.Helper()
+		if _, err := wt.Add("."); err != nil {
+			t.Fatal(err)
+		}
+		_, err := wt.Commit(msg, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(dir, "a.go"), strings.Repeat("a", 200))
+	write(filepath.Join(dir, "hot.go"), strings.Repeat("h", 200))
+	commit("init")
+	for i := 0; i < 3; i++ {
+		write(filepath.Join(dir, "hot.go"), strings.Repeat("h", 201+i))
+		commit("change")
+	}
+
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := DefaultOptions()
+	options.MaxTokens = 150
+	options.Rank = RankChurn
+	if _, err := PackToString(proj.Root(), options); err != nil {
+		t.Fatalf("PackToString failed: %v", err)
+	}
+	if strings.Join(options.IncludedFiles, ",") != "hot.go" || strings.Join(options.OmittedFiles, ",") != "a.go" {
+		t.Errorf("churn ranking should keep hot.go, got %v / %v", options.IncludedFiles, options.OmittedFiles)
+	}
+
+	// 工作区按根目录读取各自的仓库，不在仓库中的文件计为 0
+	write(filepath.Join(base, "plain", "b.go"), strings.Repeat("b", 200))
+	ws, err := project.BuildWorkspace(base, []project.WorkspaceRoot{{Path: "plain"}, {Path: "repo"}}, helper.WalkDirOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options = DefaultOptions()
+	options.MaxTokens = 150
+	options.Rank = RankChurn
+	if _, err := PackToString(ws.Root(), options); err != nil {
+		t.Fatalf("PackToString on workspace failed: %v", err)
+	}
+	if got := strings.Join(options.IncludedFiles, ","); got != filepath.Join("repo", "hot.go") {
+		t.Errorf("churn ranking in a workspace should keep repo/hot.go, got %s", got)
+	}
+}
+
+func TestPackChanges(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		full := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(msg string) {
+		t.Helper()
+		if _, err := wt.Add("."); err != nil {
+			t.Fatal(err)
+		}
+		_, err := wt.Commit(msg, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("pkg/a.go", "package pkg\n\nfunc A() int { return 1 }\n")
+	write("pkg/b.go", "package pkg\n")
+	write("pkg/c.go", "package pkg\n")
+	write("other/d.go", "package other\n")
+	write("README.md", "# readme\n")
+	commit("base")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("base", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 提交一次修改，再在工作区新增并暂存一个文件、删除一个文件
+	write("pkg/a.go", "package pkg\n\nfunc A() int { return 2 }\n")
+	commit("change a")
+	write("pkg/new.go", "package pkg\n\nfunc New() {}\n")
+	if _, err := wt.Add("pkg/new.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "other/d.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := DefaultOptions()
+	options.Changes = &ChangeOptions{SinceRef: "base", Neighbors: 1}
+	result, err := PackToString(proj.Root(), options)
+	if err != nil {
+		t.Fatalf("PackToString failed: %v", err)
+	}
+	if got := strings.Join(options.IncludedFiles, ","); got != "pkg/a.go,pkg/new.go,pkg/b.go" {
+		t.Errorf("unexpected included files: %s", got)
+	}
+	for _, want := range []string{
+		"## 📄 pkg/a.go",
+		"### 🔀 变更: pkg/a.go (modified +1 -1)",
+		"-func A() int { return 1 }\n+func A() int { return 2 }",
+		"deleted file mode 100644",
+		"+++ b/pkg/new.go",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("result should contain %q", want)
+		}
+	}
+	if strings.Contains(result, "pkg/c.go") || strings.Contains(result, "README.md") {
+		t.Error("only one neighbor and no unrelated files should be packed")
+	}
+
+	// JSON 格式在 changes 数组中给出差异
+	options = DefaultOptions()
+	options.Formatter = &JSONFormatter{}
+	options.Changes = &ChangeOptions{SinceRef: "base"}
+	result, err = PackToString(proj.Root(), options)
+	if err != nil {
+		t.Fatalf("PackToString failed: %v", err)
+	}
+	var doc struct {
+		Files   []packedFile `json:"files"`
+		Changes []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(result), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, result)
+	}
+	if len(doc.Files) != 2 || len(doc.Changes) != 3 || doc.Changes[0].Path != "/other/d.go" || doc.Changes[0].Type != "removed" {
+		t.Errorf("unexpected JSON bundle: %+v", doc)
+	}
+
+	// 暂存区只包含新文件
+	options = DefaultOptions()
+	options.Changes = &ChangeOptions{Staged: true}
+	if _, err := PackToString(proj.Root(), options); err != nil {
+		t.Fatalf("PackToString failed: %v", err)
+	}
+	if got := strings.Join(options.IncludedFiles, ","); got != "pkg/new.go" {
+		t.Errorf("staged bundle should only contain pkg/new.go, got %s", got)
+	}
+
+	// --where 的 size 条件按变更后的内容大小过滤
+	options = DefaultOptions()
+	options.Changes = &ChangeOptions{SinceRef: "base"}
+	if options.Where, err = query.Parse("size>30 mtime<1h"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PackToString(proj.Root(), options); err != nil {
+		t.Fatalf("PackToString failed: %v", err)
+	}
+	if got := strings.Join(options.IncludedFiles, ","); got != "pkg/a.go" {
+		t.Errorf("size filter should keep only pkg/a.go, got %s", got)
+	}
+
+	options = DefaultOptions()
+	options.Changes = &ChangeOptions{SinceRef: "base", Staged: true}
+	if _, err := PackToString(proj.Root(), options); err == nil {
+		t.Error("since-ref together with staged should fail")
+	}
+	options = DefaultOptions()
+	options.Changes = &ChangeOptions{SinceRef: "base"}
+	options.MaxTokens = 100
+	if _, err := PackToString(proj.Root(), options); err == nil {
+		t.Error("token budget is not supported for change bundles")
+	}
+}
+
+func TestExtractSignatures(t *testing.T) {
+	cases := []struct {
+		language string
+		path     string
+		source   string
+		want     []string
+		drop     []string
+	}{
+		{
+			language: "go",
+			path:     "a.go",
+			source: `package a
+
+// Add 返回两数之和
+func Add(a, b int) int {
+	// 函数体中的注释
+	return a + b
+}
+
+var handler = func() error { return nil }
+
+type T struct {
+	Name string // 名称
+}
+`,
+			want: []string{"// Add 返回两数之和\nfunc Add(a, b int) int\n", "var handler = func() error {}", "Name string // 名称"},
+			drop: []string{"return a + b", "函数体中的注释"},
+		},
+		{
+			language: "python",
+			path:     "a.py",
+			source: `import os
+
+LIMIT = 10
+
+class User:
+    """A user."""
+    name: str
+
+    @property
+    def title(self) -> str:
+        """Title case name."""
+        value = self.name
+        return value.title()
+
+def main():
+    print("hi")
+
+if __name__ == "__main__":
+    main()
+`,
+			want: []string{"import os", "LIMIT = 10", "class User:\n    \"\"\"A user.\"\"\"\n    name: str", "    @property\n    def title(self) -> str:\n        \"\"\"Title case name.\"\"\"\n        ...", "def main():\n    ..."},
+			drop: []string{"value = self.name", "print(", "__main__"},
+		},
+		{
+			language: "typescript",
+			path:     "a.ts",
+			source: `import { a, b } from "./x";
+
+/** Adds numbers. */
+export function add(x: number, y: number): number {
+  const s = "}";
+  return x + y;
+}
+
+export class Store<T> {
+  private items: T[] = []
+  get(i: number): T {
+    return this.items[i];
+  }
+}
+
+main();
+`,
+			want: []string{`import { a, b } from "./x";`, "/** Adds numbers. */\nexport function add(x: number, y: number): number { ... }", "export class Store<T> {\n  private items: T[] = []\n  get(i: number): T { ... }\n}"},
+			drop: []string{"return x + y", "main()"},
+		},
+		{
+			language: "java",
+			path:     "A.java",
+			source: `package x;
+
+/** Greeter. */
+public class A implements Runnable {
+    private int n = 1;
+
+    /** Runs. */
+    @Override
+    public void run() {
+        System.out.println("{");
+    }
+}
+`,
+			want: []string{"package x;", "/** Greeter. */\npublic class A implements Runnable {", "    private int n = 1;", "    /** Runs. */\n    @Override\n    public void run() { ... }\n}"},
+			drop: []string{"System.out"},
+		},
+	}
+	for _, c := range cases {
+		got, ok := ExtractSignatures(c.language, c.path, []byte(c.source))
+		if !ok {
+			t.Errorf("%s: extraction failed", c.language)
+			continue
+		}
+		for _, want := range c.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: signatures should contain %q, got:\n%s", c.language, want, got)
+			}
+		}
+		for _, drop := range c.drop {
+			if strings.Contains(got, drop) {
+				t.Errorf("%s: signatures should not contain %q, got:\n%s", c.language, drop, got)
+			}
+		}
+	}
+
+	if _, ok := ExtractSignatures("rust", "a.rs", []byte("fn main() {}")); ok {
+		t.Error("rust has no extractor")
+	}
+	if _, ok := ExtractSignatures("go", "bad.go", []byte("package")); ok {
+		t.Error("invalid Go should fall back")
+	}
+}
+
+func TestPackSignatures(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":   {Data: []byte("package main\n\nfunc main() {\n\tprintln(\"body\")\n}\n")},
+		"README.md": {Data: []byte("# body stays\n")},
+	}
+	options := DefaultOptions()
+	options.Signatures = true
+	result, err := PackFS(fsys, options)
+	if err != nil {
+		t.Fatalf("PackFS failed: %v", err)
+	}
+	if !strings.Contains(result, "func main()\n") || strings.Contains(result, "println") {
+		t.Errorf("Go bodies should be dropped:\n%s", result)
+	}
+	if !strings.Contains(result, "# body stays") {
+		t.Error("languages without an extractor keep their content")
+	}
+}
+
+func TestParseSplitSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  SplitLimit
+	}{
+		{"", SplitLimit{}},
+		{"4096", SplitLimit{Size: 4096}},
+		{"500k", SplitLimit{Size: 500 * 1024}},
+		{"8MB", SplitLimit{Size: 8 * 1024 * 1024}},
+		{"100kt", SplitLimit{Size: 100000, Tokens: true}},
+		{"50000 tokens", SplitLimit{Size: 50000, Tokens: true}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSplitSize(tt.input)
+		if err != nil {
+			t.Errorf("ParseSplitSize(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSplitSize(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+	for _, input := range []string{"abc", "-1", "0", "10x"} {
+		if _, err := ParseSplitSize(input); err == nil {
+			t.Errorf("ParseSplitSize(%q) should fail", input)
+		}
+	}
+}
+
+func TestPackVolumes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":     "package a\n" + strings.Repeat("// volume a\n", 40),
+		"b.go":     "package b\n" + strings.Repeat("// volume b\n", 40),
+		"c.go":     "package c\n",
+		"large.go": "package large\n" + strings.Repeat("// large line\n", 200),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"markdown", "json"} {
+		out := filepath.Join(t.TempDir(), "packed"+GetFormatter(format).FileExtension())
+		options := DefaultOptions()
+		options.Formatter = GetFormatter(format)
+		options.Split = SplitLimit{Size: 1500}
+		if err := PackNode(proj.Root(), out, options); err != nil {
+			t.Fatalf("%s: PackNode failed: %v", format, err)
+		}
+		if _, err := os.Stat(out); err == nil {
+			t.Errorf("%s: single output file should not be written", format)
+		}
+		total := len(options.Volumes)
+		if total < 3 {
+			t.Fatalf("%s: expected at least 3 volumes, got %d", format, total)
+		}
+
+		seen := make(map[string][]int)
+		for i, volume := range options.Volumes {
+			if volume.Path != VolumePath(out, i+1) {
+				t.Errorf("%s: volume %d path = %s", format, i+1, volume.Path)
+			}
+			data, err := os.ReadFile(volume.Path)
+			if err != nil {
+				t.Fatalf("%s: read volume failed: %v", format, err)
+			}
+			if len(data) > 1500 {
+				t.Errorf("%s: volume %d exceeds limit: %d bytes", format, i+1, len(data))
+			}
+			for _, file := range volume.Files {
+				seen[file] = append(seen[file], i+1)
+			}
+			switch format {
+			case "markdown":
+				if !strings.Contains(string(data), fmt.Sprintf("第 %d/%d 卷", i+1, total)) {
+					t.Errorf("volume %d header should carry its sequence:\n%s", i+1, data)
+				}
+			case "json":
+				var doc struct {
+					Part  int `json:"part"`
+					Parts int `json:"parts"`
+				}
+				if err := json.Unmarshal(data, &doc); err != nil {
+					t.Fatalf("volume %d is not valid JSON: %v", i+1, err)
+				}
+				if doc.Part != i+1 || doc.Parts != total {
+					t.Errorf("volume %d has part %d/%d", i+1, doc.Part, doc.Parts)
+				}
+			}
+		}
+
+		// 只有超出分卷大小的 large.go 会被拆开
+		for _, name := range []string{"a.go", "b.go", "c.go"} {
+			if len(seen[name]) != 1 {
+				t.Errorf("%s: %s should be in exactly one volume, got %v", format, name, seen[name])
+			}
+		}
+		if len(seen["large.go"]) < 2 {
+			t.Errorf("%s: large.go should span volumes, got %v", format, seen["large.go"])
+		}
+		if len(options.IncludedFiles) != 4 {
+			t.Errorf("%s: IncludedFiles = %v", format, options.IncludedFiles)
+		}
+
+		data, err := os.ReadFile(IndexPath(out))
+		if err != nil {
+			t.Fatalf("%s: read index failed: %v", format, err)
+		}
+		var index volumeIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			t.Fatalf("%s: invalid index: %v", format, err)
+		}
+		if len(index.Volumes) != total || index.Volumes[0].Path != filepath.Base(VolumePath(out, 1)) {
+			t.Errorf("%s: unexpected index: %s", format, data)
+		}
+	}
+
+	options := DefaultOptions()
+	options.Split = SplitLimit{Size: 10}
+	if err := PackNode(proj.Root(), filepath.Join(t.TempDir(), "tiny.md"), options); err == nil {
+		t.Error("a limit smaller than the header should fail")
+	}
+}
+
+func TestParseBundle(t *testing.T) {
+	files := map[string]string{
+		"main.go":      "package main\n",
+		"docs/a.md":    "# Doc\n\n```go\nfmt.Println(\"]]>\")\n```\n",
+		"notes.txt":    "==> not a header\nline\n",
+		"src/empty.js": "",
+	}
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	for _, format := range []string{"markdown", "xml", "json", "jsonl", "text"} {
+		options := DefaultOptions()
+		options.Formatter = GetFormatter(format)
+		result, err := PackFS(fsys, options)
+		if err != nil {
+			t.Fatalf("%s: PackFS failed: %v", format, err)
+		}
+		if got := DetectFormat([]byte(result)); got != format {
+			t.Errorf("DetectFormat = %s, want %s", got, format)
+		}
+		parsed, err := ParseBundle([]byte(result), "")
+		if err != nil {
+			t.Fatalf("%s: ParseBundle failed: %v\n%s", format, err, result)
+		}
+		if len(parsed) != len(files) {
+			t.Errorf("%s: expected %d files, got %d", format, len(files), len(parsed))
+		}
+		for _, file := range parsed {
+			want, ok := files[file.Path]
+			if !ok {
+				t.Errorf("%s: unexpected path %q", format, file.Path)
+				continue
+			}
+			// 除 JSON 外的格式无法区分空文件与只有换行的文件
+			if got := string(file.Content); got != want && !(want == "" && got == "\n") {
+				t.Errorf("%s: %s content = %q, want %q", format, file.Path, got, want)
+			}
+			if file.Signatures {
+				t.Errorf("%s: %s should not be marked as signatures", format, file.Path)
+			}
+		}
+	}
+
+	// 打包文档中的打包文档：内容中形似标题与围栏的行不会被当作文件边界
+	inner := fstest.MapFS{
+		"README.md": &fstest.MapFile{Data: []byte("a\n## 📄 main.go\n==> b.go <==\n\\==> c.go <==\n````\n")},
+	}
+	nested := fstest.MapFS{"main.go": &fstest.MapFile{Data: []byte("package main\n")}}
+	for _, format := range []string{"markdown", "xml", "json", "jsonl", "text"} {
+		options := DefaultOptions()
+		options.Formatter = GetFormatter(format)
+		bundle, err := PackFS(inner, options)
+		if err != nil {
+			t.Fatalf("%s: PackFS failed: %v", format, err)
+		}
+		nested["bundle-"+format+".txt"] = &fstest.MapFile{Data: []byte(bundle)}
+	}
+	for _, format := range []string{"markdown", "xml", "json", "jsonl", "text"} {
+		options := DefaultOptions()
+		options.Formatter = GetFormatter(format)
+		result, err := PackFS(nested, options)
+		if err != nil {
+			t.Fatalf("%s: PackFS failed: %v", format, err)
+		}
+		parsed, err := ParseBundle([]byte(result), format)
+		if err != nil {
+			t.Fatalf("%s: ParseBundle of nested bundle failed: %v", format, err)
+		}
+		if len(parsed) != len(nested) {
+			t.Fatalf("%s: expected %d files, got %d", format, len(nested), len(parsed))
+		}
+		for _, file := range parsed {
+			want, ok := nested[file.Path]
+			if !ok || string(file.Content) != string(want.Data) {
+				t.Errorf("%s: %s does not round trip:\n%q", format, file.Path, file.Content)
+			}
+		}
+	}
+
+	// 分卷中被拆开的文件重新合并
+	parsed, err := ParseBundle([]byte("==> big.go (2/2) <==\nb\n\n==> big.go (1/2) <==\na\n\n"), "text")
+	if err != nil || len(parsed) != 1 || parsed[0].Path != "big.go" || string(parsed[0].Content) != "a\nb\n" {
+		t.Errorf("pieces should be merged, got %+v, %v", parsed, err)
+	}
+	if _, err := ParseBundle([]byte("==> big.go (1/2) <==\na\n\n"), "text"); err == nil {
+		t.Error("a missing piece should fail")
+	}
+}
+
+func TestUnpackSignaturesBundle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":   &fstest.MapFile{Data: []byte("package main\n\nfunc main() {\n\tprintln(1)\n}\n")},
+		"notes.txt": &fstest.MapFile{Data: []byte("notes\n")},
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), fsys["main.go"].Data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只包含签名的打包文档带有标记，解包时被拒绝
+	for _, format := range []string{"markdown", "xml", "json", "jsonl", "text"} {
+		options := DefaultOptions()
+		options.Formatter = GetFormatter(format)
+		options.Signatures = true
+		result, err := PackFS(fsys, options)
+		if err != nil {
+			t.Fatalf("%s: PackFS failed: %v", format, err)
+		}
+		parsed, err := ParseBundle([]byte(result), "")
+		if err != nil {
+			t.Fatalf("%s: ParseBundle failed: %v\n%s", format, err, result)
+		}
+		if len(parsed) != 2 {
+			t.Fatalf("%s: expected 2 files, got %+v", format, parsed)
+		}
+		for _, file := range parsed {
+			if !file.Signatures {
+				t.Errorf("%s: %s should be marked as signatures", format, file.Path)
+			}
+		}
+		if _, err := PlanUnpack(proj.Root(), parsed, 3); err == nil {
+			t.Errorf("%s: a signatures bundle should be rejected", format)
+		}
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":    "package main\n",
+		"util.go":    "package main\n\nfunc util() {}\n",
+		"no_eol.md":  "no newline",
+		".gitignore": "local.json\n",
+		"local.json": `{"secret":1}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := []BundleFile{
+		{Path: "main.go", Content: []byte("package main\n\nfunc main() {}\n")},
+		{Path: "util.go", Content: []byte(files["util.go"])},
+		{Path: "no_eol.md", Content: []byte("no newline\n")},
+		{Path: "pkg/sub/new.go", Content: []byte("package sub\n")},
+	}
+	plan, err := PlanUnpack(proj.Root(), bundle, 3)
+	if err != nil {
+		t.Fatalf("PlanUnpack failed: %v", err)
+	}
+	// 未修改的文件与只差末尾换行的文件不写入
+	if len(plan) != 2 || plan[0].Path != "/main.go" || plan[0].Added || plan[1].Path != "/pkg/sub/new.go" || !plan[1].Added {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	if plan[0].Insertions != 2 || !strings.Contains(plan[0].Diff, "+func main() {}") {
+		t.Errorf("unexpected diff %+v", plan[0])
+	}
+
+	if _, err := PlanUnpack(proj.Root(), []BundleFile{{Path: "../escape.go"}}, 3); err == nil {
+		t.Error("paths outside the target should be rejected")
+	}
+	// 被忽略的文件不在项目树中，不能当作新文件覆盖
+	if _, err := PlanUnpack(proj.Root(), []BundleFile{{Path: "local.json", Content: []byte("{}")}}, 3); err == nil {
+		t.Error("ignored files on disk should be rejected")
+	}
+
+	written, err := ApplyUnpack(proj, plan)
+	if err != nil || written != 2 {
+		t.Fatalf("ApplyUnpack = %d, %v", written, err)
+	}
+	for name, want := range map[string]string{"main.go": "package main\n\nfunc main() {}\n", "pkg/sub/new.go": "package sub\n"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v", name, data, err)
+		}
+	}
+
+	// 预览之后被修改的文件中止写入
+	if _, err := ApplyUnpack(proj, plan); err == nil {
+		t.Error("applying a stale plan should fail")
+	}
+}
@@ -0,0 +1,146 @@
+package pack
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"sync"
+)
+
+// SignatureExtractor 按语言提取源码的签名：保留声明与文档注释，丢弃函数体
+type SignatureExtractor interface {
+	Extract(path string, content []byte) (string, error)
+}
+
+var (
+	signatureMu         sync.RWMutex
+	signatureExtractors = map[string]SignatureExtractor{
+		"go":         goSignatures{},
+		"python":     pythonSignatures{},
+		"javascript": braceSignatures{lang: langScript},
+		"jsx":        braceSignatures{lang: langScript},
+		"typescript": braceSignatures{lang: langScript},
+		"tsx":        braceSignatures{lang: langScript},
+		"java":       braceSignatures{lang: langJava},
+	}
+)
+
+// RegisterSignatureExtractor 注册（或替换）语言的签名提取器，语言标识与 filekind 一致，如 go、python
+func RegisterSignatureExtractor(language string, extractor SignatureExtractor) {
+	signatureMu.Lock()
+	defer signatureMu.Unlock()
+	signatureExtractors[language] = extractor
+}
+
+// GetSignatureExtractor 返回语言的签名提取器
+func GetSignatureExtractor(language string) (SignatureExtractor, bool) {
+	signatureMu.RLock()
+	defer signatureMu.RUnlock()
+	extractor, ok := signatureExtractors[language]
+	return extractor, ok
+}
+
+// SignaturesFormatter 可选接口：只打包签名时在文档头部之后输出标记，解包时据此拒绝写回
+type SignaturesFormatter interface {
+	SignaturesMarker() string
+}
+
+// signaturesNotice Markdown 与纯文本格式中只包含签名的标记文字
+const signaturesNotice = "仅包含签名：函数体已省略，不能用于解包"
+
+// signaturesMarker 返回 options.Signatures 为 true 时的标记，格式化器未实现 SignaturesFormatter 时为空
+func signaturesMarker(options *PackOptions) string {
+	if !options.Signatures {
+		return ""
+	}
+	if sf, ok := options.Formatter.(SignaturesFormatter); ok {
+		return sf.SignaturesMarker()
+	}
+	return ""
+}
+
+// ExtractSignatures 提取文件的签名，语言不支持或解析失败时返回 false
+func ExtractSignatures(language, path string, content []byte) (string, bool) {
+	extractor, ok := GetSignatureExtractor(language)
+	if !ok {
+		return "", false
+	}
+	signatures, err := extractor.Extract(path, content)
+	if err != nil {
+		return "", false
+	}
+	return signatures, true
+}
+
+// goSignatures 使用 go/ast 去掉函数体（包括变量初始化中的函数字面量），保留声明与注释
+type goSignatures struct{}
+
+func (goSignatures) Extract(path string, content []byte) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	// 记录被删除的函数体范围，其中的注释一并丢弃
+	type span struct{ from, to token.Pos }
+	var bodies []span
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Body != nil {
+				bodies = append(bodies, span{n.Body.Pos(), n.Body.End()})
+				n.Body = nil
+			}
+			return false
+		case *ast.FuncLit:
+			bodies = append(bodies, span{n.Body.Lbrace + 1, n.Body.Rbrace})
+			n.Body = &ast.BlockStmt{Lbrace: n.Body.Lbrace, Rbrace: n.Body.Lbrace + 1}
+			return false
+		}
+		return true
+	})
+	comments := file.Comments[:0]
+	for _, group := range file.Comments {
+		inBody := false
+		for _, b := range bodies {
+			if group.Pos() >= b.from && group.End() <= b.to {
+				inBody = true
+				break
+			}
+		}
+		if !inBody {
+			comments = append(comments, group)
+		}
+	}
+	file.Comments = comments
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// trimBlankLines 把连续的空行压缩为一行
+func trimBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	blank := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if blank {
+				continue
+			}
+			blank = true
+			out = append(out, "")
+			continue
+		}
+		blank = false
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n")) + "\n"
+}
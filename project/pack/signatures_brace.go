@@ -0,0 +1,318 @@
+package pack
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 使用花括号的语言
+const (
+	langScript = iota // JavaScript、TypeScript 及其 JSX 变体
+	langJava
+)
+
+// braceSignatures 轻量的花括号语言解析器：保留 import 与声明，类、接口、枚举等容器的成员逐个保留，
+// 函数体、对象字面量等其他代码块替换为 { ... }；文档注释（/** */）保留，其他注释丢弃
+type braceSignatures struct {
+	lang int
+}
+
+var (
+	scriptContainerRe = regexp.MustCompile(`\b(class|interface|enum|namespace)\b|^(export\s+)?(declare\s+)?(module\s+[\w'"]|global$)|\btype\s+\w+(<[^=]*>)?\s*=$`)
+	scriptDeclRe      = regexp.MustCompile(`^(export|import|function|class|interface|type|enum|const|let|var|declare|abstract|async|namespace|module|@)`)
+	scriptImportRe    = regexp.MustCompile(`^(import|export)(\s+type)?$|^import\s.*,$`)
+	javaContainerRe   = regexp.MustCompile(`\b(class|interface|enum|record)\b`)
+)
+
+func (b braceSignatures) Extract(path string, content []byte) (string, error) {
+	s := &braceScanner{src: strings.ReplaceAll(string(content), "\r\n", "\n"), lang: b.lang}
+	s.block(0)
+	return trimBlankLines(s.out.String()), nil
+}
+
+// braceScanner 逐个读取语句，遇到代码块时决定展开还是省略
+type braceScanner struct {
+	src  string
+	pos  int
+	lang int
+	out  strings.Builder
+}
+
+// block 读取一层代码块中的语句，直到对应的 } 或文件结尾
+func (s *braceScanner) block(depth int) {
+	var stmt strings.Builder
+	doc := ""
+	parens := 0
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case strings.HasPrefix(s.src[s.pos:], "/**") && strings.TrimSpace(stmt.String()) == "":
+			end := s.commentEnd()
+			doc = s.src[s.pos:end]
+			s.pos = end
+		case strings.HasPrefix(s.src[s.pos:], "//") || strings.HasPrefix(s.src[s.pos:], "/*"):
+			s.pos = s.commentEnd()
+		case c == '"' || c == '\'' || c == '`':
+			start := s.pos
+			s.skipString()
+			stmt.WriteString(s.src[start:s.pos])
+		case c == '/' && s.regexAllowed(stmt.String()):
+			start := s.pos
+			s.skipRegex()
+			stmt.WriteString(s.src[start:s.pos])
+		case c == '(' || c == '[':
+			parens++
+			stmt.WriteByte(c)
+			s.pos++
+		case c == ')' || c == ']':
+			parens--
+			stmt.WriteByte(c)
+			s.pos++
+		case c == ';' && parens <= 0:
+			s.pos++
+			s.emit(depth, doc, strings.TrimSpace(stmt.String())+";")
+			stmt.Reset()
+			doc = ""
+		case c == '}' && depth > 0:
+			s.pos++
+			s.emit(depth, doc, strings.TrimSpace(stmt.String()))
+			return
+		case c == '\n' && s.lang == langScript && parens <= 0 && s.statementEnds(stmt.String()):
+			// 没有分号的语句按换行结束
+			s.pos++
+			s.emit(depth, doc, strings.TrimSpace(stmt.String()))
+			stmt.Reset()
+			doc = ""
+		case c == '{' && parens <= 0 && scriptImportRe.MatchString(strings.TrimSpace(stmt.String())):
+			// import { a, b } from 与 export { a } 中的花括号原样保留
+			start := s.pos
+			s.pos++
+			s.skipBlock()
+			stmt.WriteString(s.src[start:s.pos])
+		case c == '{' && parens <= 0:
+			s.pos++
+			header := strings.TrimSpace(stmt.String())
+			if s.isContainer(header) && s.keep(depth, header) {
+				s.emit(depth, doc, header+" {")
+				s.block(depth + 1)
+				s.write(depth, "}")
+				if depth == 0 {
+					s.out.WriteString("\n")
+				}
+			} else {
+				s.skipBlock()
+				// 代码块之后的内容（如 } else {、}, 或 })）属于同一语句
+				if s.continues() {
+					rest := strings.TrimRight(stmt.String(), " \t\n")
+					stmt.Reset()
+					stmt.WriteString(rest + " { ... }")
+					continue
+				}
+				s.emit(depth, doc, header+" { ... }")
+			}
+			stmt.Reset()
+			doc = ""
+		case c == '{':
+			// 括号内的代码块（如回调函数或对象字面量参数）
+			s.pos++
+			s.skipBlock()
+			stmt.WriteString("{ ... }")
+		default:
+			stmt.WriteByte(c)
+			s.pos++
+		}
+	}
+	s.emit(depth, doc, strings.TrimSpace(stmt.String()))
+}
+
+// statementEnds 判断换行处语句是否已完整：语句不以运算符结尾，下一行也不以运算符开头
+func (s *braceScanner) statementEnds(stmt string) bool {
+	stmt = strings.TrimSpace(stmt)
+	if stmt == "" || strings.HasPrefix(stmt, "@") || strings.ContainsAny(stmt[len(stmt)-1:], ",=(:|&.+-*/?<[{!") || strings.HasSuffix(stmt, "=>") {
+		return false
+	}
+	next := strings.TrimLeft(s.src[s.pos:], " \t\n")
+	if next == "" {
+		return true
+	}
+	if strings.ContainsAny(next[:1], ".?:|&=,{)>+-*/") && !strings.HasPrefix(next, "/**") {
+		return false
+	}
+	return !strings.HasPrefix(next, "extends") && !strings.HasPrefix(next, "implements")
+}
+
+// continues 判断代码块之后是否紧跟同一语句的内容
+func (s *braceScanner) continues() bool {
+	rest := strings.TrimLeft(s.src[s.pos:], " \t")
+	return rest != "" && strings.ContainsAny(rest[:1], ",)];.") || strings.HasPrefix(rest, "else") || strings.HasPrefix(rest, "catch") || strings.HasPrefix(rest, "finally")
+}
+
+// isContainer 判断代码块是否为需要展开成员的类型声明
+func (s *braceScanner) isContainer(header string) bool {
+	if s.lang == langJava {
+		return javaContainerRe.MatchString(header) && !strings.Contains(header, "new ")
+	}
+	return scriptContainerRe.MatchString(header) && !strings.Contains(header, "=>") && !strings.Contains(header, "new ")
+}
+
+// keep 判断语句是否保留：顶层只保留 import 与声明，容器中的成员全部保留
+func (s *braceScanner) keep(depth int, stmt string) bool {
+	if stmt == "" || stmt == ";" {
+		return false
+	}
+	if depth > 0 || s.lang == langJava {
+		return true
+	}
+	return scriptDeclRe.MatchString(stmt)
+}
+
+// emit 输出保留的语句及其文档注释
+func (s *braceScanner) emit(depth int, doc, stmt string) {
+	if !s.keep(depth, stmt) {
+		return
+	}
+	if doc != "" {
+		if depth == 0 {
+			s.out.WriteString("\n")
+		}
+		// 文档注释的后续行对齐到 /** 的 *
+		for i, line := range strings.Split(doc, "\n") {
+			if line = strings.TrimSpace(line); i > 0 && strings.HasPrefix(line, "*") {
+				line = " " + line
+			}
+			s.out.WriteString(s.indent(depth) + line + "\n")
+		}
+	}
+	s.write(depth, stmt)
+}
+
+// write 按层级缩进输出一段文本，多行文本的每一行重新缩进
+func (s *braceScanner) write(depth int, text string) {
+	prefix := s.indent(depth)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			s.out.WriteString(prefix + line + "\n")
+		}
+	}
+}
+
+// indent 返回层级对应的缩进，Java 每层 4 个空格，其他语言 2 个
+func (s *braceScanner) indent(depth int) string {
+	if s.lang == langJava {
+		return strings.Repeat("    ", depth)
+	}
+	return strings.Repeat("  ", depth)
+}
+
+// skipBlock 跳过当前代码块直到对应的 }，s.pos 位于 { 之后
+func (s *braceScanner) skipBlock() {
+	depth := 1
+	prev := "{"
+	for s.pos < len(s.src) && depth > 0 {
+		c := s.src[s.pos]
+		switch {
+		case strings.HasPrefix(s.src[s.pos:], "//") || strings.HasPrefix(s.src[s.pos:], "/*"):
+			s.pos = s.commentEnd()
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			s.skipString()
+		case c == '/' && s.regexAllowed(prev):
+			s.skipRegex()
+		case c == '{':
+			depth++
+			s.pos++
+		case c == '}':
+			depth--
+			s.pos++
+		default:
+			s.pos++
+		}
+		if c != ' ' && c != '\t' && c != '\n' {
+			prev = string(c)
+		}
+	}
+}
+
+// commentEnd 返回从 s.pos 开始的注释之后的位置
+func (s *braceScanner) commentEnd() int {
+	if strings.HasPrefix(s.src[s.pos:], "//") {
+		if i := strings.IndexByte(s.src[s.pos:], '\n'); i >= 0 {
+			return s.pos + i
+		}
+		return len(s.src)
+	}
+	if i := strings.Index(s.src[s.pos+2:], "*/"); i >= 0 {
+		return s.pos + 2 + i + 2
+	}
+	return len(s.src)
+}
+
+// skipString 跳过字符串字面量，模板字符串中的 ${...} 按代码块跳过
+func (s *braceScanner) skipString() {
+	quote := s.src[s.pos]
+	s.pos++
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\\':
+			s.pos += 2
+			continue
+		case c == quote:
+			s.pos++
+			return
+		case c == '\n' && quote != '`' && s.lang == langScript:
+			return
+		case quote == '`' && strings.HasPrefix(s.src[s.pos:], "${"):
+			s.pos += 2
+			s.skipBlock()
+			continue
+		}
+		s.pos++
+	}
+}
+
+// skipRegex 跳过 JavaScript 正则表达式字面量
+func (s *braceScanner) skipRegex() {
+	s.pos++
+	inClass := false
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\\':
+			s.pos += 2
+			continue
+		case c == '\n':
+			return
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			s.pos++
+			for s.pos < len(s.src) && isIdentByte(s.src[s.pos]) {
+				s.pos++
+			}
+			return
+		}
+		s.pos++
+	}
+}
+
+// regexAllowed 根据前面的内容判断 / 是否开始一个正则表达式（Java 没有正则字面量）
+func (s *braceScanner) regexAllowed(before string) bool {
+	if s.lang != langScript {
+		return false
+	}
+	before = strings.TrimRight(before, " \t\n")
+	if before == "" {
+		return true
+	}
+	if strings.ContainsRune("(,=:[!&|?{};+-*%<>~^", rune(before[len(before)-1])) {
+		return true
+	}
+	return strings.HasSuffix(before, "return") || strings.HasSuffix(before, "typeof")
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
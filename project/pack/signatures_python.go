@@ -0,0 +1,201 @@
+package pack
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pythonSignatures 按缩进识别 Python 的 import、类、函数与赋值，保留装饰器与文档字符串，函数体替换为 ...
+type pythonSignatures struct{}
+
+var (
+	pyDefRe    = regexp.MustCompile(`^(async\s+def|def|class)\s`)
+	pyImportRe = regexp.MustCompile(`^(import|from)\s`)
+	pyAssignRe = regexp.MustCompile(`^([A-Za-z_][\w.]*)\s*(:[^=]*)?=[^=]|^[A-Za-z_]\w*\s*:[^=]+$`)
+	pyStringRe = regexp.MustCompile(`^[rRuUbBfF]{0,2}("""|''')`)
+)
+
+func (pythonSignatures) Extract(path string, content []byte) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	var out []string
+	skipIndent := -1 // 缩进大于它的行属于被丢弃的代码块
+	inString := ""   // 被丢弃的代码中未闭合的三引号
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		indent := pyIndent(line)
+
+		if skipIndent >= 0 {
+			if inString != "" {
+				if strings.Count(line, inString)%2 == 1 {
+					inString = ""
+				}
+				continue
+			}
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") || indent > skipIndent {
+				inString = pyOpenString(line)
+				continue
+			}
+			skipIndent = -1
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		switch {
+		case len(out) == 0 && pyStringRe.MatchString(trimmed):
+			// 模块文档字符串
+			end := pyStringEnd(lines, i)
+			out = append(out, lines[i:end+1]...)
+			i = end
+		case strings.HasPrefix(trimmed, "@"):
+			if indent == 0 {
+				out = append(out, "")
+			}
+			end, _ := pyStatementEnd(lines, i, false)
+			out = append(out, lines[i:end+1]...)
+			i = end
+		case pyDefRe.MatchString(trimmed):
+			if indent == 0 && len(out) > 0 && !strings.HasPrefix(out[len(out)-1], "@") {
+				out = append(out, "")
+			}
+			end, col := pyStatementEnd(lines, i, true)
+			if col >= 0 && pyCode(lines[end][col+1:]) != "" {
+				// 单行定义，如 def f(): return 1
+				header := append([]string(nil), lines[i:end]...)
+				out = append(out, append(header, lines[end][:col+1]+" ...")...)
+				i = end
+				continue
+			}
+			out = append(out, lines[i:end+1]...)
+			i = end
+			bodyIndent := indent + 4
+			if j := pyNextLine(lines, i+1); j < len(lines) && pyIndent(lines[j]) > indent {
+				bodyIndent = pyIndent(lines[j])
+				if pyStringRe.MatchString(strings.TrimSpace(lines[j])) {
+					end := pyStringEnd(lines, j)
+					out = append(out, lines[j:end+1]...)
+					i = end
+				}
+			}
+			if !strings.HasPrefix(trimmed, "class") {
+				out = append(out, strings.Repeat(" ", bodyIndent)+"...")
+				skipIndent = indent
+			}
+		case pyImportRe.MatchString(trimmed):
+			end, _ := pyStatementEnd(lines, i, false)
+			out = append(out, lines[i:end+1]...)
+			i = end
+		case pyAssignRe.MatchString(trimmed):
+			// 多行的值只保留名称
+			if end, _ := pyStatementEnd(lines, i, false); end == i {
+				out = append(out, line)
+			} else {
+				name := pyAssignRe.FindStringSubmatch(trimmed)[1]
+				out = append(out, line[:indent]+name+" = ...")
+				i = end
+			}
+		default:
+			// 其他语句连同其代码块一并丢弃
+			skipIndent = indent
+			inString = pyOpenString(line)
+		}
+	}
+	return trimBlankLines(strings.Join(out, "\n")), nil
+}
+
+// pyIndent 返回行首缩进宽度，制表符按 4 个空格计算
+func pyIndent(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// pyNextLine 返回从 i 开始的第一个非空、非注释行
+func pyNextLine(lines []string, i int) int {
+	for ; i < len(lines); i++ {
+		if t := strings.TrimSpace(lines[i]); t != "" && !strings.HasPrefix(t, "#") {
+			break
+		}
+	}
+	return i
+}
+
+// pyStatementEnd 返回从第 i 行开始、括号配平的语句的最后一行
+// wantColon 为 true 时在括号外的第一个冒号处结束，同时返回冒号所在列（找不到为 -1）
+func pyStatementEnd(lines []string, i int, wantColon bool) (int, int) {
+	depth := 0
+	for j := i; j < len(lines); j++ {
+		line := lines[j]
+		quote := byte(0)
+		for c := 0; c < len(line); c++ {
+			ch := line[c]
+			switch {
+			case quote != 0:
+				if ch == '\\' {
+					c++
+				} else if ch == quote {
+					quote = 0
+				}
+			case ch == '"' || ch == '\'':
+				quote = ch
+			case ch == '#':
+				c = len(line)
+			case ch == '(' || ch == '[' || ch == '{':
+				depth++
+			case ch == ')' || ch == ']' || ch == '}':
+				depth--
+			case ch == ':' && depth == 0 && wantColon:
+				return j, c
+			}
+		}
+		if depth <= 0 && !strings.HasSuffix(strings.TrimSpace(line), "\\") {
+			return j, -1
+		}
+	}
+	return len(lines) - 1, -1
+}
+
+// pyStringEnd 返回从第 i 行开始的三引号字符串的最后一行
+func pyStringEnd(lines []string, i int) int {
+	trimmed := strings.TrimSpace(lines[i])
+	delim := pyStringRe.FindStringSubmatch(trimmed)[1]
+	rest := trimmed[strings.Index(trimmed, delim)+3:]
+	if strings.Contains(rest, delim) {
+		return i
+	}
+	for j := i + 1; j < len(lines); j++ {
+		if strings.Contains(lines[j], delim) {
+			return j
+		}
+	}
+	return len(lines) - 1
+}
+
+// pyOpenString 返回行中未闭合的三引号，没有时返回空字符串
+func pyOpenString(line string) string {
+	for _, delim := range []string{`"""`, `'''`} {
+		if strings.Count(line, delim)%2 == 1 {
+			return delim
+		}
+	}
+	return ""
+}
+
+// pyCode 去掉行尾注释与空白
+func pyCode(s string) string {
+	if i := strings.Index(s, "#"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,318 @@
+package pack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project"
+)
+
+// SplitLimit 分卷大小上限，Tokens 为 true 时按 token（helper.EstimateTokens）计算，否则按字节，Size 为 0 表示不分卷
+type SplitLimit struct {
+	Size   int
+	Tokens bool
+}
+
+// ParseSplitSize 解析分卷大小：纯数字或带 K、M、G 后缀（可再加 B，按 1024 进位）表示字节数，
+// 以 t 或 tokens 结尾表示 token 数（K、M 按 1000 进位），如 8MB、500k、100kt、50000 tokens
+func ParseSplitSize(s string) (SplitLimit, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	if text == "" {
+		return SplitLimit{}, nil
+	}
+	var limit SplitLimit
+	for _, suffix := range []string{"tokens", "token", "t"} {
+		if strings.HasSuffix(text, suffix) {
+			limit.Tokens = true
+			text = strings.TrimSpace(strings.TrimSuffix(text, suffix))
+			break
+		}
+	}
+	base := 1024
+	if limit.Tokens {
+		base = 1000
+	} else {
+		text = strings.TrimSuffix(text, "b")
+	}
+	multiplier := 1
+	if text != "" {
+		switch text[len(text)-1] {
+		case 'k':
+			multiplier = base
+		case 'm':
+			multiplier = base * base
+		case 'g':
+			multiplier = base * base * base
+		}
+		if multiplier > 1 {
+			text = text[:len(text)-1]
+		}
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n <= 0 {
+		return SplitLimit{}, fmt.Errorf("无效的分卷大小: %s（示例：8MB、500k、100kt）", s)
+	}
+	limit.Size = n * multiplier
+	return limit, nil
+}
+
+// String 返回可读的分卷大小，如 1048576 bytes、100000 tokens
+func (l SplitLimit) String() string {
+	if l.Tokens {
+		return fmt.Sprintf("%d tokens", l.Size)
+	}
+	return fmt.Sprintf("%d bytes", l.Size)
+}
+
+// measure 按分卷的计量单位计算文本大小
+func (l SplitLimit) measure(s string) int {
+	if l.Tokens {
+		return helper.EstimateTokens(s)
+	}
+	return len(s)
+}
+
+// VolumeFormatter 可选接口：分卷打包时输出带序号的头部与尾部，part 从 1 开始
+// 没有实现时使用 Header("标题 (part/total)") 与 Footer()
+type VolumeFormatter interface {
+	VolumeHeader(title string, part, total int) string
+	VolumeFooter(part, total int) string
+}
+
+// Volume 分卷打包中的一个分卷
+type Volume struct {
+	Path string `json:"path"`
+	// 分卷中的文件，超出分卷大小而被拆开的文件会出现在多个分卷中
+	Files  []string `json:"files"`
+	Size   int      `json:"size"`
+	Tokens int      `json:"tokens"`
+}
+
+// volumeIndex 分卷索引文件的内容
+type volumeIndex struct {
+	Project string   `json:"project"`
+	Limit   string   `json:"limit"`
+	Volumes []Volume `json:"volumes"`
+	Omitted []string `json:"omitted,omitempty"`
+}
+
+// VolumePath 返回第 part 个分卷的文件路径，如 packed.md -> packed.part001.md
+func VolumePath(outputPath string, part int) string {
+	ext := filepath.Ext(outputPath)
+	return fmt.Sprintf("%s.part%03d%s", strings.TrimSuffix(outputPath, ext), part, ext)
+}
+
+// IndexPath 返回分卷索引文件的路径，如 packed.md -> packed.index.json
+func IndexPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".index.json"
+}
+
+// volumeItem 装入分卷的一段内容：完整文件，或超出分卷大小的文件的一个片段
+type volumeItem struct {
+	node    *project.Node
+	path    string
+	label   string
+	content string
+	size    int
+}
+
+// packVolumes 把打包结果按 options.Split 写入多个分卷，并写出索引文件
+// 文件按路径顺序依次装入分卷，只有单个文件本身超出分卷大小时才按行拆到多个分卷中
+func packVolumes(node *project.Node, outputPath string, options *PackOptions) error {
+	if options.Changes != nil {
+		return fmt.Errorf("只打包变更文件时不支持分卷")
+	}
+
+	var files []textFile
+	var omitted []string
+	if node.IsDir {
+		var err error
+		if files, omitted, err = selectTextFiles(node, options); err != nil {
+			return err
+		}
+	} else {
+		if !shouldIncludeFile(node, options) {
+			return fmt.Errorf("文件类型不在允许范围内")
+		}
+		files = []textFile{{node: node, path: node.Name}}
+	}
+	if filepath.Ext(outputPath) == "" {
+		outputPath = outputPath + options.Formatter.FileExtension()
+	}
+
+	// 头部与尾部按最大序号估算，每个分卷都需要预留
+	limit := options.Split
+	overhead := limit.measure(volumeHeader(options.Formatter, node.Name, 99999, 99999) + signaturesMarker(options) + volumeFooter(options.Formatter, 99999, 99999))
+	if overhead >= limit.Size {
+		return fmt.Errorf("分卷大小 %s 不足以容纳头部与尾部", limit)
+	}
+
+	// 先格式化一遍以计算每个文件的大小，Header 用于重置有状态格式化器
+	options.Formatter.Header(node.Name)
+	var items []volumeItem
+	var included []string
+	for _, file := range files {
+		content, err := fileContent(file.node, file.path, options)
+		if err != nil {
+			continue
+		}
+		included = append(included, file.path)
+		size := limit.measure(options.Formatter.Format(file.node, content, file.path))
+		if size+overhead <= limit.Size {
+			items = append(items, volumeItem{node: file.node, path: file.path, label: file.path, content: content, size: size})
+			continue
+		}
+		pieces, err := splitFileContent(file, content, limit.Size-overhead, options)
+		if err != nil {
+			return err
+		}
+		items = append(items, pieces...)
+	}
+
+	// 依次装入分卷，放不下时开始新的分卷
+	var groups [][]volumeItem
+	used := 0
+	for _, item := range items {
+		if len(groups) == 0 || used+item.size+overhead > limit.Size {
+			groups = append(groups, nil)
+			used = 0
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], item)
+		used += item.size
+	}
+	if len(groups) == 0 {
+		groups = append(groups, nil)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	index := volumeIndex{Project: node.Name, Limit: limit.String(), Omitted: omitted}
+	for i, group := range groups {
+		part, total := i+1, len(groups)
+		var builder strings.Builder
+		builder.WriteString(volumeHeader(options.Formatter, node.Name, part, total))
+		builder.WriteString(signaturesMarker(options))
+		volume := Volume{Path: VolumePath(outputPath, part), Files: []string{}}
+		for _, item := range group {
+			builder.WriteString(options.Formatter.Format(item.node, item.content, item.label))
+			if n := len(volume.Files); n == 0 || volume.Files[n-1] != item.path {
+				volume.Files = append(volume.Files, item.path)
+			}
+		}
+		// 未打包的文件列在最后一卷，不计入分卷大小
+		if part == total && len(omitted) > 0 {
+			if of, ok := options.Formatter.(OmittedFormatter); ok {
+				builder.WriteString(of.Omitted(omitted))
+			}
+		}
+		builder.WriteString(volumeFooter(options.Formatter, part, total))
+
+		content := builder.String()
+		if err := os.WriteFile(volume.Path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("写入分卷失败: %w", err)
+		}
+		volume.Size = len(content)
+		volume.Tokens = helper.EstimateTokens(content)
+		index.Volumes = append(index.Volumes, volume)
+	}
+
+	// 索引中的分卷路径相对索引文件所在目录
+	entries := make([]Volume, len(index.Volumes))
+	for i, v := range index.Volumes {
+		v.Path = filepath.Base(v.Path)
+		entries[i] = v
+	}
+	data, err := json.MarshalIndent(volumeIndex{Project: index.Project, Limit: index.Limit, Volumes: entries, Omitted: omitted}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(IndexPath(outputPath), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("写入分卷索引失败: %w", err)
+	}
+	options.IncludedFiles = append(options.IncludedFiles, included...)
+	options.Volumes = append(options.Volumes, index.Volumes...)
+	return nil
+}
+
+// splitFileContent 把超出分卷大小的文件按行拆成多个片段，每个片段格式化后不超过 avail
+// 格式化可能放大内容（如 JSON 转义），超出时按比例缩小片段重新拆分
+func splitFileContent(file textFile, content string, avail int, options *PackOptions) ([]volumeItem, error) {
+	limit := options.Split
+	label := func(k, m int) string { return fmt.Sprintf("%s (%d/%d)", file.path, k, m) }
+	wrapper := limit.measure(options.Formatter.Format(file.node, "", label(99999, 99999)))
+	budget := avail - wrapper
+	if limit.Tokens {
+		budget *= 4
+	}
+	for budget > 0 {
+		pieces := splitLines(content, budget)
+		items := make([]volumeItem, 0, len(pieces))
+		largest := 0
+		for k, piece := range pieces {
+			name := label(k+1, len(pieces))
+			size := limit.measure(options.Formatter.Format(file.node, piece, name))
+			largest = max(largest, size)
+			items = append(items, volumeItem{node: file.node, path: file.path, label: name, content: piece, size: size})
+		}
+		if largest <= avail {
+			return items, nil
+		}
+		budget = budget * avail / largest * 9 / 10
+	}
+	return nil, fmt.Errorf("分卷大小 %s 过小，无法容纳文件 %s", limit, file.path)
+}
+
+// splitLines 按行把文本拆成不超过 budget 字节的片段，单行超出时在字符边界处截断
+func splitLines(content string, budget int) []string {
+	var pieces []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			pieces = append(pieces, current.String())
+			current.Reset()
+		}
+	}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		for len(line) > budget {
+			flush()
+			cut := budget
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(line)
+			}
+			pieces = append(pieces, line[:cut])
+			line = line[cut:]
+		}
+		if current.Len()+len(line) > budget {
+			flush()
+		}
+		current.WriteString(line)
+	}
+	flush()
+	return pieces
+}
+
+// volumeHeader 返回带序号的分卷头部
+func volumeHeader(f Formatter, title string, part, total int) string {
+	if vf, ok := f.(VolumeFormatter); ok {
+		return vf.VolumeHeader(title, part, total)
+	}
+	return f.Header(fmt.Sprintf("%s (%d/%d)", title, part, total))
+}
+
+// volumeFooter 返回带序号的分卷尾部
+func volumeFooter(f Formatter, part, total int) string {
+	if vf, ok := f.(VolumeFormatter); ok {
+		return vf.VolumeFooter(part, total)
+	}
+	return f.Footer()
+}
@@ -0,0 +1,508 @@
+package pack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/diff"
+)
+
+// BundleFile 从打包文档中还原的文件，Path 为相对打包目录的路径
+// Signatures 表示文件来自只包含签名的打包文档（pack --signatures），内容不完整
+type BundleFile struct {
+	Path       string
+	Content    []byte
+	Signatures bool
+}
+
+// BundleParser 可选接口：解析该格式化器生成的打包文档，还原每个文件的路径与内容
+// 差异块、未打包文件列表与分卷信息被忽略
+type BundleParser interface {
+	Parse(data []byte) ([]BundleFile, error)
+}
+
+var (
+	// markdownSectionRe Markdown 打包文档中的二级、三级标题：文件、变更与未打包文件列表
+	markdownSectionRe = regexp.MustCompile(`^(## 📄 |### 🔀 变更: |## ✂️ )(.*)$`)
+	// textSectionRe 纯文本打包文档中的 ==> 标题 <== 行
+	textSectionRe = regexp.MustCompile(`(?m)^==> (.*) <==$`)
+	// textSpecialRe 纯文本中不是文件的标题：变更、未打包文件列表、只包含签名的标记与分卷结束行
+	textSpecialRe = regexp.MustCompile(`^(变更: |未打包的文件|` + signaturesNotice + `$|第 \d+/\d+ 卷结束$)`)
+	// textUnescapeRe 被 escapeTextContent 转义的内容行
+	textUnescapeRe = regexp.MustCompile(`(?m)^\\+==> `)
+	// pieceRe 分卷时被拆开的文件片段的名称后缀，如 main.go (2/3)
+	pieceRe = regexp.MustCompile(`^(.+) \((\d+)/(\d+)\)$`)
+)
+
+// DetectFormat 根据内容推断打包文档的格式名称，无法识别时返回 markdown
+func DetectFormat(data []byte) string {
+	text := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	switch {
+	case bytes.HasPrefix(text, []byte("<?xml")) || bytes.HasPrefix(text, []byte("<documents")):
+		return "xml"
+	case bytes.HasPrefix(text, []byte("{")):
+		var doc map[string]json.RawMessage
+		if json.Unmarshal(text, &doc) == nil && doc["files"] != nil {
+			return "json"
+		}
+		return "jsonl"
+	case bytes.HasPrefix(text, []byte("==> ")) || bytes.HasPrefix(text, []byte("项目打包: ")):
+		return "text"
+	default:
+		return "markdown"
+	}
+}
+
+// ParseBundle 解析打包文档，format 为空时按内容推断
+// 分卷打包中被拆开的文件片段（path (k/m)）合并为一个文件，同一路径出现多次时以最后一次为准
+func ParseBundle(data []byte, format string) ([]BundleFile, error) {
+	files, err := parseBundle(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return mergeBundleFiles(files)
+}
+
+// ReadBundles 依次读取磁盘上的打包文档并合并解析结果，分卷索引（*.index.json）按顺序读取其中的全部分卷
+func ReadBundles(paths []string, format string) ([]BundleFile, error) {
+	var files []BundleFile
+	for _, p := range paths {
+		volumes := []string{p}
+		if strings.HasSuffix(p, ".index.json") {
+			var err error
+			if volumes, err = readVolumeIndex(p); err != nil {
+				return nil, err
+			}
+		}
+		for _, v := range volumes {
+			data, err := os.ReadFile(v)
+			if err != nil {
+				return nil, fmt.Errorf("读取打包文档失败: %w", err)
+			}
+			parsed, err := parseBundle(data, format)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", v, err)
+			}
+			files = append(files, parsed...)
+		}
+	}
+	return mergeBundleFiles(files)
+}
+
+// readVolumeIndex 返回分卷索引中列出的分卷路径，相对路径以索引文件所在目录为准
+func readVolumeIndex(indexPath string) ([]string, error) {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取分卷索引失败: %w", err)
+	}
+	var index volumeIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("解析分卷索引失败: %w", err)
+	}
+	paths := make([]string, 0, len(index.Volumes))
+	for _, v := range index.Volumes {
+		p := v.Path
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(indexPath), p)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+func parseBundle(data []byte, format string) ([]BundleFile, error) {
+	if format == "" {
+		format = DetectFormat(data)
+	}
+	formatter := GetFormatter(format)
+	if formatter == nil {
+		return nil, fmt.Errorf("不支持的格式: %s", format)
+	}
+	parser, ok := formatter.(BundleParser)
+	if !ok {
+		return nil, fmt.Errorf("格式 %s 不支持解包", format)
+	}
+	return parser.Parse(bytes.TrimPrefix(data, []byte("\ufeff")))
+}
+
+// markSignatures 将文件标记为来自只包含签名的打包文档
+func markSignatures(files []BundleFile) {
+	for i := range files {
+		files[i].Signatures = true
+	}
+}
+
+// mergeBundleFiles 合并被拆开的文件片段，并按路径去重
+func mergeBundleFiles(files []BundleFile) ([]BundleFile, error) {
+	type pieces struct {
+		parts      map[int][]byte
+		total      int
+		signatures bool
+	}
+	split := make(map[string]*pieces)
+	var merged []BundleFile
+	position := make(map[string]int)
+	add := func(file BundleFile) {
+		if i, ok := position[file.Path]; ok {
+			merged[i] = file
+			return
+		}
+		position[file.Path] = len(merged)
+		merged = append(merged, file)
+	}
+
+	for _, file := range files {
+		m := pieceRe.FindStringSubmatch(file.Path)
+		if m == nil {
+			add(file)
+			continue
+		}
+		k, _ := strconv.Atoi(m[2])
+		total, _ := strconv.Atoi(m[3])
+		if k < 1 || k > total {
+			add(file)
+			continue
+		}
+		p, ok := split[m[1]]
+		if !ok {
+			p = &pieces{parts: make(map[int][]byte), total: total}
+			split[m[1]] = p
+			add(BundleFile{Path: m[1]})
+		}
+		if p.total != total {
+			return nil, fmt.Errorf("文件 %s 的片段数不一致", m[1])
+		}
+		p.parts[k] = file.Content
+		p.signatures = p.signatures || file.Signatures
+	}
+
+	for name, p := range split {
+		var content []byte
+		for k := 1; k <= p.total; k++ {
+			part, ok := p.parts[k]
+			if !ok {
+				return nil, fmt.Errorf("文件 %s 缺少第 %d/%d 个片段", name, k, p.total)
+			}
+			content = append(content, part...)
+		}
+		merged[position[name]].Content = content
+		merged[position[name]].Signatures = p.signatures
+	}
+	return merged, nil
+}
+
+// parseMarkdownBundle 解析 MarkdownFormatter 的输出：每个 ## 📄 标题之后的第一个代码块为文件内容
+// 按 CommonMark 的规则逐行跟踪围栏，代码块中的内容（包括形似标题的行）不会被当作标题；
+// 结束围栏由相同的字符组成且不短于开始围栏，因此内容中较短的围栏不会提前结束代码块
+func parseMarkdownBundle(data []byte) ([]BundleFile, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	var files []BundleFile
+	var body []string
+	name, fence := "", ""
+	pending, collecting, headed, signatures := false, false, false, false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if isClosingFence(trimmed, fence) {
+				if collecting {
+					content := strings.Join(body, "\n")
+					if len(body) > 0 {
+						content += "\n"
+					}
+					files = append(files, BundleFile{Path: name, Content: []byte(content)})
+					collecting = false
+				}
+				fence = ""
+			} else if collecting {
+				body = append(body, line)
+			}
+			continue
+		}
+
+		if m := markdownSectionRe.FindStringSubmatch(line); m != nil {
+			if pending {
+				return nil, fmt.Errorf("文件 %s 没有代码块", name)
+			}
+			headed = true
+			pending = m[1] == "## 📄 "
+			name = strings.Trim(strings.TrimSpace(m[2]), "`")
+			continue
+		}
+		if !headed && trimmed == "> "+signaturesNotice {
+			// 只包含签名的标记位于第一个标题之前
+			signatures = true
+			continue
+		}
+		if f := openingFence(trimmed); f != "" {
+			fence = f
+			if pending {
+				pending, collecting, body = false, true, nil
+			}
+		}
+	}
+	if collecting {
+		return nil, fmt.Errorf("文件 %s 的代码块没有结束", name)
+	}
+	if pending {
+		return nil, fmt.Errorf("文件 %s 没有代码块", name)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("打包文档中没有文件")
+	}
+	if signatures {
+		markSignatures(files)
+	}
+	return files, nil
+}
+
+// openingFence 返回代码块开始行中的围栏（行首连续三个以上的 ` 或 ~），不是围栏时返回空字符串
+func openingFence(line string) string {
+	if !strings.HasPrefix(line, "```") && !strings.HasPrefix(line, "~~~") {
+		return ""
+	}
+	return line[:len(line)-len(strings.TrimLeft(line, line[:1]))]
+}
+
+// isClosingFence 判断是否为 fence 的结束行：只由相同字符组成且不短于开始围栏
+func isClosingFence(line, fence string) bool {
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// parseXMLBundle 解析 XMLFormatter 的输出，文档之前的说明文字被忽略
+func parseXMLBundle(data []byte) ([]BundleFile, error) {
+	if i := bytes.Index(data, []byte("<documents")); i >= 0 {
+		data = data[i:]
+	}
+	var doc struct {
+		Signatures *struct{} `xml:"signatures"`
+		Documents  []struct {
+			Path    string `xml:"path,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"document"`
+	}
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析 XML 失败: %w", err)
+	}
+	files := make([]BundleFile, 0, len(doc.Documents))
+	for _, d := range doc.Documents {
+		// CDATA 前后各有一个格式化器添加的换行
+		content := strings.TrimPrefix(d.Content, "\n")
+		content = strings.TrimSuffix(content, "\n")
+		files = append(files, BundleFile{Path: d.Path, Content: []byte(content)})
+	}
+	if doc.Signatures != nil {
+		markSignatures(files)
+	}
+	return files, nil
+}
+
+// parseJSONBundle 解析 JSONFormatter 的输出
+func parseJSONBundle(data []byte) ([]BundleFile, error) {
+	var doc struct {
+		Files      []packedFile `json:"files"`
+		Signatures bool         `json:"signatures"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+	files := make([]BundleFile, 0, len(doc.Files))
+	for _, f := range doc.Files {
+		files = append(files, BundleFile{Path: f.Path, Content: []byte(f.Content)})
+	}
+	if doc.Signatures {
+		markSignatures(files)
+	}
+	return files, nil
+}
+
+// parseJSONLBundle 解析 JSONLFormatter 的输出，没有 path 字段的行（分卷信息、变更、未打包文件）被忽略
+func parseJSONLBundle(data []byte) ([]BundleFile, error) {
+	var files []BundleFile
+	signatures := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var record struct {
+			Path       *string `json:"path"`
+			Content    string  `json:"content"`
+			Signatures bool    `json:"signatures"`
+		}
+		if err := json.Unmarshal(text, &record); err != nil {
+			return nil, fmt.Errorf("第 %d 行解析失败: %w", line, err)
+		}
+		if record.Path != nil {
+			files = append(files, BundleFile{Path: *record.Path, Content: []byte(record.Content)})
+		} else if record.Signatures {
+			signatures = true
+		}
+	}
+	if signatures {
+		markSignatures(files)
+	}
+	return files, scanner.Err()
+}
+
+// parseTextBundle 解析 TextFormatter 的输出：==> 路径 <== 行之后直到下一个标题行为文件内容
+func parseTextBundle(data []byte) ([]BundleFile, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	matches := textSectionRe.FindAllStringSubmatchIndex(text, -1)
+	var files []BundleFile
+	signatures := false
+	for i, m := range matches {
+		name := text[m[2]:m[3]]
+		if name == signaturesNotice {
+			signatures = true
+		}
+		if textSpecialRe.MatchString(name) {
+			continue
+		}
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		// 跳过标题行的换行，去掉格式化器在文件之间添加的空行
+		content := strings.TrimPrefix(text[m[1]:end], "\n")
+		content = strings.TrimSuffix(content, "\n")
+		files = append(files, BundleFile{Path: name, Content: []byte(unescapeTextContent(content))})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("打包文档中没有文件")
+	}
+	if signatures {
+		markSignatures(files)
+	}
+	return files, nil
+}
+
+// unescapeTextContent 去掉 escapeTextContent 在形似标题的内容行前添加的反斜杠
+func unescapeTextContent(content string) string {
+	return textUnescapeRe.ReplaceAllStringFunc(content, func(m string) string { return m[1:] })
+}
+
+// UnpackChange 解包时一个文件的变更
+type UnpackChange struct {
+	Path       string `json:"path"` // 项目中的路径
+	Added      bool   `json:"added"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Diff       string `json:"diff"` // 统一格式的差异块（不含文件头）
+	Old        []byte `json:"-"`
+	New        []byte `json:"-"`
+}
+
+// UnpackWriter 解包写入的目标，由 project.Project 与 project.Transaction 实现
+type UnpackWriter interface {
+	FindNode(path string) (*project.Node, error)
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, content []byte) error
+	CreateDir(path string) error
+}
+
+// PlanUnpack 计算把打包文档中的文件写入 root 目录后每个文件的变更与差异，不修改任何文件
+// 只返回内容发生变化的文件（按路径排序）；打包格式无法表示文件末尾是否有换行，因此沿用现有文件的写法
+// 来自只包含签名的打包文档的文件会用声明覆盖完整源码，遇到时返回错误；
+// 不在项目树中却已存在于磁盘上的路径（如被忽略的文件）同样返回错误，避免覆盖项目之外的内容
+func PlanUnpack(root *project.Node, files []BundleFile, contextLines int) ([]UnpackChange, error) {
+	if root == nil || !root.IsDir {
+		return nil, fmt.Errorf("解包目标必须是目录")
+	}
+	proj := root.GetProject()
+	var plan []UnpackChange
+	for _, file := range files {
+		if file.Signatures {
+			return nil, fmt.Errorf("打包文档只包含签名（pack --signatures），不能用于解包: %s", file.Path)
+		}
+		rel := path.Clean(filepath.ToSlash(strings.TrimSpace(file.Path)))
+		if rel == "." || path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return nil, fmt.Errorf("打包文档中的路径无效: %s", file.Path)
+		}
+
+		change := UnpackChange{Path: path.Join(root.Path, rel), New: file.Content}
+		if node := findChildNode(root, rel); node != nil {
+			if node.IsDir {
+				return nil, fmt.Errorf("路径已存在且为目录: %s", rel)
+			}
+			old, err := node.ReadContent()
+			if err != nil {
+				return nil, fmt.Errorf("读取文件失败: %s: %w", rel, err)
+			}
+			if !bytes.HasSuffix(old, []byte("\n")) {
+				change.New = bytes.TrimSuffix(change.New, []byte("\n"))
+			}
+			if bytes.Equal(old, change.New) {
+				continue
+			}
+			change.Old = old
+		} else {
+			if proj != nil && proj.GetRootPath() != "" {
+				if _, err := os.Lstat(proj.GetAbsolutePath(change.Path)); err == nil {
+					return nil, fmt.Errorf("路径已存在于磁盘上但不在项目中（可能被忽略规则排除）: %s", rel)
+				}
+			}
+			change.Added = true
+		}
+		change.Diff, change.Insertions, change.Deletions = diff.Unified(change.Old, change.New, contextLines)
+		plan = append(plan, change)
+	}
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Path < plan[j].Path })
+	return plan, nil
+}
+
+// ApplyUnpack 将 PlanUnpack 的结果写入 w，按需创建上级目录，返回成功写入的文件数
+// 写入前核对文件内容，自预览以来被修改或新建的文件会中止解包并返回错误
+func ApplyUnpack(w UnpackWriter, plan []UnpackChange) (int, error) {
+	for i, c := range plan {
+		if c.Added {
+			if _, err := w.FindNode(c.Path); err == nil {
+				return i, fmt.Errorf("预览之后文件已被创建: %s", c.Path)
+			}
+			if err := ensureDir(w, path.Dir(c.Path)); err != nil {
+				return i, err
+			}
+		} else {
+			current, err := w.ReadFile(c.Path)
+			if err != nil {
+				return i, err
+			}
+			if !bytes.Equal(current, c.Old) {
+				return i, fmt.Errorf("预览之后文件已被修改: %s", c.Path)
+			}
+		}
+		if err := w.WriteFile(c.Path, c.New); err != nil {
+			return i, err
+		}
+	}
+	return len(plan), nil
+}
+
+// ensureDir 依次创建不存在的上级目录
+func ensureDir(w UnpackWriter, dir string) error {
+	if dir == "/" || dir == "." {
+		return nil
+	}
+	if node, err := w.FindNode(dir); err == nil {
+		if !node.IsDir {
+			return fmt.Errorf("路径已存在且不是目录: %s", dir)
+		}
+		return nil
+	}
+	if err := ensureDir(w, path.Dir(dir)); err != nil {
+		return err
+	}
+	return w.CreateDir(dir)
+}
@@ -62,11 +62,17 @@ func (p *Project) SetInGit(inGit bool) {
 	p.inGit = inGit
 }
 
+// IsOnDisk 项目内容是否直接来自本地磁盘，由 fs.FS 或 git 版本构建的项目返回 false
+func (p *Project) IsOnDisk() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.source == nil && p.rootPath != ""
+}
+
 // GetAbsolutePath 获取项目中节点的绝对路径
 func (p *Project) GetAbsolutePath(relativePath string) string {
-	// 统一为相对路径（去掉前导 /）
-	clean := strings.TrimPrefix(relativePath, "/")
-	return filepath.Join(p.rootPath, clean)
+	// 统一为以 / 开头的项目路径，工作区按根目录映射
+	return p.fsPath("/" + strings.TrimPrefix(relativePath, "/"))
 }
 
 // 项目注册表，用于根据根节点查找项目实例
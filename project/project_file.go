@@ -1,9 +1,12 @@
 package project
 
 import (
+	"bytes"
 	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/sjzsdu/tong/helper"
 )
@@ -12,6 +15,15 @@ import (
 
 // createNodeInFS 在文件系统中创建节点并获取文件信息
 func (p *Project) createNodeInFS(fsPath string, isDir bool, content []byte) (os.FileInfo, error) {
+	// 由 fs.FS 构建的项目只在内存中创建
+	if p.source != nil {
+		return newMemFileInfo(filepath.Base(fsPath), isDir, len(content)), nil
+	}
+	// 工作区中不属于任何根目录的路径
+	if fsPath == "" {
+		return nil, ErrOutsideWorkspace
+	}
+
 	if isDir {
 		// 创建目录
 		err := os.MkdirAll(fsPath, 0755)
@@ -54,7 +66,8 @@ func (p *Project) addNodeToProject(node *Node, parent *Node, name string, cleanP
 }
 
 // CreateDir 创建目录
-func (p *Project) CreateDir(path string) error {
+func (p *Project) CreateDir(path string) (err error) {
+	defer p.recordOp(JournalMkdir, path)(&err)
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -83,7 +96,7 @@ func (p *Project) CreateDir(path string) error {
 	}
 
 	// 创建实际的文件系统目录并获取文件信息
-	fsPath := filepath.Join(p.rootPath, cleanPath[1:])
+	fsPath := p.fsPath(cleanPath)
 	fileInfo, err := p.createNodeInFS(fsPath, true, nil)
 	if err != nil {
 		return err
@@ -99,7 +112,8 @@ func (p *Project) CreateDir(path string) error {
 }
 
 // CreateFile 创建文件
-func (p *Project) CreateFile(path string, content []byte) error {
+func (p *Project) CreateFile(path string, content []byte) (err error) {
+	defer p.recordOp(JournalCreate, path)(&err)
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -129,7 +143,7 @@ func (p *Project) CreateFile(path string, content []byte) error {
 	}
 
 	// 创建实际的文件系统文件并获取文件信息
-	fsPath := filepath.Join(p.rootPath, cleanPath[1:])
+	fsPath := p.fsPath(cleanPath)
 	fileInfo, err := p.createNodeInFS(fsPath, false, content)
 	if err != nil {
 		return err
@@ -174,12 +188,17 @@ func (p *Project) CreateFileNode(path string) error {
 	}
 
 	// 获取文件系统路径
-	fsPath := filepath.Join(p.rootPath, cleanPath[1:])
+	fsPath := p.fsPath(cleanPath)
 	
 	// 检查文件是否已存在
-	fileInfo, err := os.Stat(fsPath)
+	var fileInfo os.FileInfo
+	if p.source != nil {
+		fileInfo, err = fs.Stat(p.source, cleanPath[1:])
+	} else {
+		fileInfo, err = os.Stat(fsPath)
+	}
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			// 文件不存在，创建空文件
 			fileInfo, err = p.createNodeInFS(fsPath, false, []byte{})
 			if err != nil {
@@ -204,27 +223,38 @@ func (p *Project) CreateFileWithContent(path string, content []byte) error {
 	return p.CreateFile(path, content)
 }
 
-// ReadFile 读取文件内容
+// ReadFile 读取文件内容，同时实现 fs.ReadFileFS
+// path 可以是以 / 开头的项目路径，也可以是 fs.FS 风格的相对路径
 func (p *Project) ReadFile(path string) ([]byte, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	// fs.FS 风格的路径中反斜杠不是分隔符
+	if fs.ValidPath(path) && strings.Contains(path, "\\") {
+		return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+	}
+
 	// 查找节点
 	node, err := p.FindNode(path)
 	if err != nil {
-		return nil, err
+		return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
 	}
 
 	if node.IsDir {
 		return nil, errors.New("cannot read directory")
 	}
 
-	// 使用Node的ReadContent方法读取内容
-	return node.ReadContent()
+	// 使用Node的ReadContent方法读取内容，返回副本以便调用方修改（fs.ReadFileFS 的约定）
+	content, err := node.ReadContent()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(content), nil
 }
 
 // WriteFile 写入文件内容
-func (p *Project) WriteFile(path string, content []byte) error {
+func (p *Project) WriteFile(path string, content []byte) (err error) {
+	defer p.recordOp(JournalWrite, path)(&err)
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -253,7 +283,7 @@ func (p *Project) WriteFile(path string, content []byte) error {
 		}
 
 		// 创建实际的文件系统文件并获取文件信息
-		fsPath := filepath.Join(p.rootPath, cleanPath[1:])
+		fsPath := p.fsPath(cleanPath)
 		fileInfo, err := p.createNodeInFS(fsPath, false, content)
 		if err != nil {
 			return err
@@ -271,8 +301,9 @@ func (p *Project) WriteFile(path string, content []byte) error {
 	return node.WriteContent(content)
 }
 
-// DeleteNode 删除节点
-func (p *Project) DeleteNode(path string) error {
+// DeleteNode 删除节点，磁盘项目同时删除磁盘上的文件或目录
+func (p *Project) DeleteNode(path string) (err error) {
+	defer p.recordOp(JournalDelete, path)(&err)
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -283,6 +314,10 @@ func (p *Project) DeleteNode(path string) error {
 	if cleanPath == "/" {
 		return errors.New("cannot delete root node")
 	}
+	// 工作区的根目录节点不能删除
+	if err := p.checkMounted(cleanPath); err != nil {
+		return err
+	}
 
 	// 查找节点
 	node, exists := p.nodes[cleanPath]
@@ -296,22 +331,72 @@ func (p *Project) DeleteNode(path string) error {
 		return errors.New("node has no parent")
 	}
 
-	// 从父节点中移除
-	parent.mu.Lock()
-	delete(parent.Children, node.Name)
-	parent.mu.Unlock()
+	// 由 fs.FS 构建的项目只修改内存
+	if p.source == nil {
+		if err := os.RemoveAll(p.fsPath(cleanPath)); err != nil {
+			return err
+		}
+	}
 
-	// 从节点映射中移除当前节点及其所有子节点
-	if node.IsDir {
-		// 递归删除子节点
-		for _, child := range node.Children {
-			childPath := p.GetNodePath(child)
-			delete(p.nodes, childPath)
+	// 从父节点与节点映射中移除当前节点及其所有子节点
+	p.detachNode(node)
+
+	return nil
+}
+
+// detachNode 将节点从父节点和节点映射中移除（包括整个子树），调用方需持有 p.mu 写锁
+func (p *Project) detachNode(node *Node) {
+	if parent := node.Parent; parent != nil {
+		parent.mu.Lock()
+		if parent.Children[node.Name] == node {
+			delete(parent.Children, node.Name)
 		}
+		parent.mu.Unlock()
+	}
+	p.forgetSubtree(node)
+}
+
+// forgetSubtree 从节点映射中移除节点及其所有后代，调用方需持有 p.mu 写锁
+func (p *Project) forgetSubtree(node *Node) {
+	delete(p.nodes, node.Path)
+	for _, child := range node.GetChildrenNodes() {
+		p.forgetSubtree(child)
+	}
+}
+
+// attachNode 将已脱离的节点挂载到新路径下，并递归更新子树的 Path，调用方需持有 p.mu 写锁
+func (p *Project) attachNode(node *Node, path string) error {
+	cleanPath := p.NormalizePath(path)
+	if _, exists := p.nodes[cleanPath]; exists {
+		return errors.New("node already exists: " + cleanPath)
 	}
 
-	// 从节点映射中移除当前节点
-	delete(p.nodes, cleanPath)
+	parent, name, err := p.resolvePath(cleanPath)
+	if err != nil {
+		return err
+	}
+
+	node.mu.Lock()
+	node.Name = name
+	node.Parent = parent
+	node.mu.Unlock()
+	p.relocateSubtree(node, cleanPath)
 
+	parent.mu.Lock()
+	parent.Children[name] = node
+	parent.mu.Unlock()
 	return nil
 }
+
+// relocateSubtree 递归重写子树路径并登记到节点映射，调用方需持有 p.mu 写锁
+// 文件分类与路径有关（如 vendor 目录），路径变化后重新识别
+func (p *Project) relocateSubtree(node *Node, path string) {
+	node.mu.Lock()
+	node.Path = path
+	node.kind = nil
+	node.mu.Unlock()
+	p.nodes[path] = node
+	for _, child := range node.GetChildrenNodes() {
+		p.relocateSubtree(child, strings.TrimSuffix(path, "/")+"/"+child.Name)
+	}
+}
@@ -2,6 +2,7 @@ package project
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -307,6 +308,7 @@ func TestDeleteNode(t *testing.T) {
 	// 验证文件是否已删除
 	_, err = proj.FindNode("/file2.txt")
 	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(tempDir, "file2.txt"))
 
 	// 测试删除目录
 	err = proj.DeleteNode("/dir1")
@@ -317,6 +319,7 @@ func TestDeleteNode(t *testing.T) {
 	assert.Error(t, err)
 	_, err = proj.FindNode("/dir1/file1.txt")
 	assert.Error(t, err)
+	assert.NoDirExists(t, filepath.Join(tempDir, "dir1"))
 
 	// 测试删除不存在的节点
 	err = proj.DeleteNode("/nonexistent")
@@ -0,0 +1,315 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// 移动、重命名与复制节点
+
+// MoveNode 将节点（文件或整个目录）移动到新路径，目标路径的父目录必须已存在
+// 磁盘项目直接重命名磁盘上的文件或目录；由 fs.FS 构建的项目只修改内存，移动的文件标记为已修改
+// 节点对象保持不变，未保存的修改随节点一起移动，之后由 SaveToFS 写入新路径
+func (p *Project) MoveNode(src, dst string) (err error) {
+	defer p.recordOp(JournalMove, src, dst)(&err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	node, dstPath, err := p.checkTransfer(src, dst, true)
+	if err != nil || node.Path == dstPath {
+		return err
+	}
+	srcPath := node.Path
+
+	if p.source == nil {
+		if err := moveOnDisk(p.fsPath(srcPath), p.fsPath(dstPath)); err != nil {
+			return err
+		}
+	} else {
+		if err := keepInMemory(node); err != nil {
+			return err
+		}
+		node.Parent.MarkModified()
+	}
+
+	p.detachNode(node)
+	if err := p.attachNode(node, dstPath); err != nil {
+		// 目标已通过检查，这里只可能是树结构异常，恢复到原位置
+		p.attachNode(node, srcPath)
+		return err
+	}
+
+	if p.source != nil {
+		node.mu.Lock()
+		node.Info = newMemFileInfo(node.Name, node.IsDir, infoSize(node.Info))
+		node.mu.Unlock()
+		node.Parent.MarkModified()
+		return nil
+	}
+	if info, statErr := os.Stat(p.fsPath(dstPath)); statErr == nil {
+		node.mu.Lock()
+		node.Info = info
+		node.mu.Unlock()
+	}
+	return nil
+}
+
+// RenameNode 在同一目录下重命名节点，newName 不能包含路径分隔符
+func (p *Project) RenameNode(path, newName string) error {
+	dst, err := renameTarget(p.NormalizePath(path), newName)
+	if err != nil {
+		return err
+	}
+	return p.MoveNode(path, dst)
+}
+
+// CopyNode 将节点（文件或整个目录）复制到新路径，目标路径的父目录必须已存在
+// 只复制项目树中的节点，被忽略规则排除的文件不会被复制；未保存的修改按内存中的版本写入副本
+// 由 fs.FS 构建的项目只在内存中创建副本，副本标记为已修改
+func (p *Project) CopyNode(src, dst string) (err error) {
+	defer p.recordOp(JournalCopy, dst)(&err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	node, dstPath, err := p.checkTransfer(src, dst, false)
+	if err != nil {
+		return err
+	}
+	parent, name, err := p.resolvePath(dstPath)
+	if err != nil {
+		return err
+	}
+
+	clone, err := p.copySubtree(node, parent, name, dstPath)
+	if err != nil {
+		if p.source == nil {
+			os.RemoveAll(p.fsPath(dstPath))
+		}
+		return err
+	}
+	p.addNodeToProject(clone, parent, name, dstPath)
+	p.relocateSubtree(clone, dstPath)
+	if p.source != nil {
+		parent.MarkModified()
+	}
+	return nil
+}
+
+// checkTransfer 检查移动或复制的源与目标路径，返回源节点与标准化后的目标路径，调用方需持有 p.mu 写锁
+func (p *Project) checkTransfer(src, dst string, move bool) (*Node, string, error) {
+	srcPath := p.NormalizePath(src)
+	dstPath := p.NormalizePath(dst)
+	if srcPath == "/" {
+		return nil, "", errors.New("cannot move or copy root node")
+	}
+	if dstPath == "/" {
+		return nil, "", errors.New("node already exists: /")
+	}
+	// 工作区的根目录节点不能移动，也不能移动或复制到根目录之外
+	if move {
+		if err := p.checkMounted(srcPath); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := p.checkMounted(dstPath); err != nil {
+		return nil, "", err
+	}
+
+	node, exists := p.nodes[srcPath]
+	if !exists {
+		return nil, "", errors.New("node not found: " + srcPath)
+	}
+	if srcPath == dstPath {
+		if move {
+			return node, dstPath, nil
+		}
+		return nil, "", errors.New("node already exists: " + dstPath)
+	}
+	if strings.HasPrefix(dstPath, srcPath+"/") {
+		return nil, "", fmt.Errorf("cannot move or copy %s into itself: %s", srcPath, dstPath)
+	}
+	if _, exists := p.nodes[dstPath]; exists {
+		return nil, "", errors.New("node already exists: " + dstPath)
+	}
+	if _, _, err := p.resolvePath(dstPath); err != nil {
+		return nil, "", err
+	}
+	if p.source == nil {
+		if _, err := os.Lstat(p.fsPath(dstPath)); err == nil {
+			return nil, "", errors.New("path already exists on disk: " + dstPath)
+		}
+	}
+	return node, dstPath, nil
+}
+
+// copySubtree 创建节点子树的副本（不登记到节点映射），磁盘项目同时复制磁盘上的文件，调用方需持有 p.mu 写锁
+func (p *Project) copySubtree(node, parent *Node, name, path string) (*Node, error) {
+	clone := &Node{
+		Name:     name,
+		Path:     path,
+		IsDir:    node.IsDir,
+		Children: make(map[string]*Node),
+		Parent:   parent,
+	}
+
+	if node.IsDir {
+		if p.source == nil {
+			if err := os.MkdirAll(p.fsPath(path), 0755); err != nil {
+				return nil, err
+			}
+		}
+		for _, child := range node.GetChildrenNodes() {
+			childPath := strings.TrimSuffix(path, "/") + "/" + child.Name
+			c, err := p.copySubtree(child, clone, child.Name, childPath)
+			if err != nil {
+				return nil, err
+			}
+			clone.Children[child.Name] = c
+		}
+	} else if p.source != nil {
+		content, err := node.ReadContent()
+		if err != nil {
+			return nil, err
+		}
+		clone.Content = append([]byte{}, content...)
+		clone.ContentLoaded = true
+		clone.modified = true
+	} else if err := p.copyFileToDisk(node, p.fsPath(path)); err != nil {
+		return nil, err
+	}
+
+	if p.source != nil {
+		clone.Info = newMemFileInfo(name, node.IsDir, len(clone.Content))
+		return clone, nil
+	}
+	info, err := os.Stat(p.fsPath(path))
+	if err != nil {
+		return nil, err
+	}
+	clone.Info = info
+	return clone, nil
+}
+
+// copyFileToDisk 将文件节点写入磁盘路径 target：未保存的修改按内存中的内容写入，否则从磁盘复制
+func (p *Project) copyFileToDisk(node *Node, target string) error {
+	mode := os.FileMode(0644)
+	node.mu.RLock()
+	if node.Info != nil {
+		mode = node.Info.Mode().Perm()
+	}
+	if node.modified && node.ContentLoaded {
+		content := node.Content
+		node.mu.RUnlock()
+		return os.WriteFile(target, content, mode)
+	}
+	node.mu.RUnlock()
+	return copyFile(p.fsPath(node.Path), target, mode)
+}
+
+// moveOnDisk 重命名磁盘上的文件或目录，跨文件系统时（如工作区的根目录位于不同磁盘）改为复制后删除
+// 源路径不在磁盘上时只移动项目树中的节点
+func moveOnDisk(from, to string) error {
+	if _, err := os.Lstat(from); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	err := os.Rename(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyDiskTree(from, to); err != nil {
+		os.RemoveAll(to)
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// copyDiskTree 递归复制磁盘上的文件或目录
+func copyDiskTree(from, to string) error {
+	return filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+// copyFile 复制单个文件
+func copyFile(from, to string, mode os.FileMode) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// keepInMemory 读取子树中所有文件的内容并标记为已修改，用于移动 fs.FS 项目中的节点：
+// 移动后无法再按原路径从来源读取，已修改的内容也不会被内容缓存卸载
+func keepInMemory(node *Node) error {
+	if node.IsDir {
+		for _, child := range node.GetChildrenNodes() {
+			if err := keepInMemory(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	content, err := node.ReadContent()
+	if err != nil {
+		return err
+	}
+	node.mu.Lock()
+	node.Content = content
+	node.ContentLoaded = true
+	node.MarkModified()
+	node.mu.Unlock()
+	if cache := node.GetProject().ContentCache(); cache != nil {
+		cache.remove(node)
+	}
+	return nil
+}
+
+// renameTarget 返回同一目录下重命名后的路径
+func renameTarget(path, newName string) (string, error) {
+	if newName == "" || newName == "." || newName == ".." || strings.ContainsAny(newName, `/\`) {
+		return "", fmt.Errorf("invalid name: %q", newName)
+	}
+	if path == "/" {
+		return "", errors.New("cannot rename root node")
+	}
+	return strings.TrimSuffix(parentPath(path), "/") + "/" + newName, nil
+}
+
+// infoSize 返回文件信息中的大小，信息缺失时为 0
+func infoSize(info os.FileInfo) int {
+	if info == nil {
+		return 0
+	}
+	return int(info.Size())
+}
@@ -0,0 +1,187 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveNode(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	require.NoError(t, proj.CreateDir("/src/util"))
+	require.NoError(t, proj.CreateFile("/src/util/str.go", []byte("package util")))
+
+	// 未保存的修改随节点一起移动
+	node, err := proj.FindNode("/src/main.go")
+	require.NoError(t, err)
+	node.mu.Lock()
+	node.Content = []byte("package main // edited")
+	node.ContentLoaded = true
+	node.MarkModified()
+	node.mu.Unlock()
+
+	require.NoError(t, proj.MoveNode("/src", "/docs/src"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "src"))
+	assert.FileExists(t, filepath.Join(tempDir, "docs", "src", "util", "str.go"))
+
+	moved, err := proj.FindNode("/docs/src/main.go")
+	require.NoError(t, err)
+	assert.Same(t, node, moved)
+	assert.Equal(t, "/docs/src/main.go", moved.Path)
+	assert.True(t, moved.IsModified())
+	docs, err := proj.FindNode("/docs")
+	require.NoError(t, err)
+	assert.Same(t, docs, moved.Parent.Parent)
+	for _, path := range []string{"/src", "/src/main.go", "/src/util/str.go"} {
+		_, err := proj.FindNode(path)
+		assert.Error(t, err, path)
+	}
+	util, err := proj.FindNode("/docs/src/util/str.go")
+	require.NoError(t, err)
+	assert.Equal(t, "/docs/src/util/str.go", util.Path)
+	_, ok := proj.Root().GetChild("src")
+	assert.False(t, ok)
+
+	require.NoError(t, proj.SaveToFS())
+	assert.Equal(t, "package main // edited", readDisk(t, filepath.Join(tempDir, "docs", "src", "main.go")))
+
+	// 重命名
+	require.NoError(t, proj.RenameNode("/docs/a.md", "b.md"))
+	assert.NoFileExists(t, filepath.Join(tempDir, "docs", "a.md"))
+	renamed, err := proj.FindNode("/docs/b.md")
+	require.NoError(t, err)
+	assert.Equal(t, "b.md", renamed.Name)
+	assert.Equal(t, "b.md", renamed.Info.Name())
+	content, err := proj.ReadFile("/docs/b.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# a", string(content))
+
+	// 错误情况
+	assert.Error(t, proj.MoveNode("/", "/x"))
+	assert.Error(t, proj.MoveNode("/missing", "/x"))
+	assert.Error(t, proj.MoveNode("/docs", "/docs/src/docs"))
+	assert.Error(t, proj.MoveNode("/docs/b.md", "/docs/src/main.go"))
+	assert.Error(t, proj.MoveNode("/docs/b.md", "/nodir/b.md"))
+	assert.Error(t, proj.RenameNode("/docs/b.md", "x/y.md"))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "ignored.log"), nil, 0644))
+	assert.Error(t, proj.MoveNode("/docs/b.md", "/ignored.log"))
+	assert.NoError(t, proj.MoveNode("/docs/b.md", "/docs/b.md"))
+}
+
+func TestCopyNode(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	require.NoError(t, proj.WriteFile("/src/main.go", []byte("package main // v2")))
+	node, err := proj.FindNode("/src/main.go")
+	require.NoError(t, err)
+	node.mu.Lock()
+	node.Content = []byte("package main // unsaved")
+	node.MarkModified()
+	node.mu.Unlock()
+
+	require.NoError(t, proj.CopyNode("/src", "/docs/src"))
+	assert.Equal(t, "package main // unsaved", readDisk(t, filepath.Join(tempDir, "docs", "src", "main.go")))
+	assert.Equal(t, "package main // v2", readDisk(t, filepath.Join(tempDir, "src", "main.go")))
+
+	clone, err := proj.FindNode("/docs/src/main.go")
+	require.NoError(t, err)
+	assert.NotSame(t, node, clone)
+	assert.False(t, clone.IsModified())
+	docs, err := proj.FindNode("/docs")
+	require.NoError(t, err)
+	assert.Same(t, docs, clone.Parent.Parent)
+
+	// 副本与原节点相互独立
+	require.NoError(t, proj.WriteFile("/docs/src/main.go", []byte("package copy")))
+	content, err := proj.ReadFile("/src/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main // unsaved", string(content))
+
+	require.NoError(t, proj.CopyNode("/docs/a.md", "/a.md"))
+	assert.Equal(t, "# a", readDisk(t, filepath.Join(tempDir, "a.md")))
+	assert.Error(t, proj.CopyNode("/docs/a.md", "/a.md"))
+	assert.Error(t, proj.CopyNode("/docs", "/docs/sub"))
+	assert.Error(t, proj.CopyNode("/docs/a.md", "/docs/a.md"))
+}
+
+func TestMoveCopyFromFS(t *testing.T) {
+	proj, err := BuildProjectTreeFromFS(fstest.MapFS{
+		"pkg/a.go":   {Data: []byte("package pkg")},
+		"pkg/b/b.go": {Data: []byte("package b")},
+	}, helper.WalkDirOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, proj.MoveNode("/pkg", "/lib"))
+	content, err := proj.ReadFile("/lib/b/b.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package b", string(content))
+	node, err := proj.FindNode("/lib/a.go")
+	require.NoError(t, err)
+	assert.True(t, node.IsModified())
+
+	require.NoError(t, proj.CopyNode("/lib/a.go", "/a.go"))
+	content, err = proj.ReadFile("/a.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg", string(content))
+	assert.ErrorIs(t, proj.SaveToFS(), ErrNotOnDisk)
+}
+
+func TestJournalMoveCopy(t *testing.T) {
+	proj, tempDir := newJournalTestProject(t)
+
+	require.NoError(t, proj.MoveNode("/src", "/pkg"))
+	require.NoError(t, proj.CopyNode("/pkg", "/src2"))
+	records, _ := proj.Journal().History()
+	require.Len(t, records, 2)
+	assert.Equal(t, JournalMove, records[0].Op)
+	assert.Equal(t, []string{"/src", "/pkg"}, records[0].Paths)
+	assert.Equal(t, JournalCopy, records[1].Op)
+
+	_, err := proj.Undo(false)
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(tempDir, "src2"))
+	_, err = proj.Undo(false)
+	require.NoError(t, err)
+	assert.Equal(t, "package main", readDisk(t, filepath.Join(tempDir, "src", "main.go")))
+	assert.NoDirExists(t, filepath.Join(tempDir, "pkg"))
+	_, err = proj.FindNode("/src/main.go")
+	assert.NoError(t, err)
+
+	// 重做会恢复移动后目录中的文件
+	_, err = proj.Redo(false)
+	require.NoError(t, err)
+	assert.Equal(t, "package main", readDisk(t, filepath.Join(tempDir, "pkg", "main.go")))
+	_, err = proj.FindNode("/pkg/main.go")
+	assert.NoError(t, err)
+}
+
+func TestTransactionMoveCopy(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+
+	tx := proj.Begin()
+	require.NoError(t, tx.CreateFile("/src/new.go", []byte("package main // new")))
+	require.NoError(t, tx.MoveNode("/src", "/cmd"))
+	require.NoError(t, tx.CopyNode("/docs", "/docs2"))
+	require.NoError(t, tx.RenameNode("/docs2/a.md", "b.md"))
+
+	content, err := tx.ReadFile("/cmd/new.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main // new", string(content))
+	_, err = tx.FindNode("/src/main.go")
+	assert.Error(t, err)
+	assert.Error(t, tx.MoveNode("/cmd", "/cmd/sub"))
+	assert.DirExists(t, filepath.Join(tempDir, "src"))
+
+	require.NoError(t, tx.Commit())
+	assert.NoDirExists(t, filepath.Join(tempDir, "src"))
+	assert.Equal(t, "package main", readDisk(t, filepath.Join(tempDir, "cmd", "main.go")))
+	assert.Equal(t, "package main // new", readDisk(t, filepath.Join(tempDir, "cmd", "new.go")))
+	assert.Equal(t, "# a", readDisk(t, filepath.Join(tempDir, "docs2", "b.md")))
+	assert.Equal(t, "# a", readDisk(t, filepath.Join(tempDir, "docs", "a.md")))
+	_, err = proj.FindNode("/cmd/main.go")
+	assert.NoError(t, err)
+}
@@ -117,3 +117,23 @@ func (p *Project) NormalizePath(path string) string {
 	
 	return cleanPath
 }
+
+// ProjectPathOf 将文件系统绝对路径转换为项目路径，工作区按所属的根目录换算，路径不在项目中时返回 false
+func (p *Project) ProjectPathOf(absPath string) (string, bool) {
+	return p.projectPathOf(absPath)
+}
+
+// projectPathOf 将文件系统绝对路径转换为项目路径，路径不在项目根目录下时返回 false
+func (p *Project) projectPathOf(absPath string) (string, bool) {
+	if p.IsWorkspace() {
+		return p.mountedProjectPath(absPath)
+	}
+	relPath, err := filepath.Rel(p.rootPath, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if relPath == "." {
+		return "/", true
+	}
+	return "/" + filepath.ToSlash(relPath), true
+}
@@ -18,6 +18,9 @@ func (p *Project) SaveToFS() error {
 		return errors.New("project is empty")
 	}
 
+	if p.source != nil {
+		return ErrNotOnDisk
+	}
 	if p.rootPath == "" {
 		return errors.New("project has no root path")
 	}
@@ -37,7 +40,7 @@ func (p *Project) SyncFromFS() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.rootPath == "" {
+	if p.rootPath == "" && p.source == nil {
 		return errors.New("project has no root path")
 	}
 	// 清空当前项目
@@ -45,28 +48,41 @@ func (p *Project) SyncFromFS() error {
 	p.nodes = make(map[string]*Node)
 	p.nodes["/"] = p.root
 
-	// 递归加载文件系统
-	return filepath.Walk(p.rootPath, func(path string, info os.FileInfo, err error) error {
+	// 由 fs.FS 构建的项目从来源重新加载
+	if p.source != nil {
+		return p.walkSource(p.filter, false)
+	}
+
+	// 工作区逐个加载根目录
+	if p.IsWorkspace() {
+		for _, m := range p.mounts {
+			if err := p.syncDisk(m.rootPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return p.syncDisk(p.rootPath)
+}
+
+// syncDisk 递归加载磁盘目录 dir 下的所有文件与目录，调用方需持有 p.mu 写锁
+func (p *Project) syncDisk(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			log.Printf("Warning: Error accessing path %s: %v", path, err)
 			return nil // 跳过无法访问的文件或目录
 		}
 
-		// 跳过根目录
-		if path == p.rootPath {
+		// 转换为项目路径格式，跳过项目根目录
+		projPath, ok := p.projectPathOf(path)
+		if !ok {
+			log.Printf("Warning: Cannot get project path for %s", path)
 			return nil
 		}
-
-		// 计算相对路径
-		relPath, err := filepath.Rel(p.rootPath, path)
-		if err != nil {
-			log.Printf("Warning: Cannot get relative path for %s: %v", path, err)
+		if projPath == "/" {
 			return nil
 		}
 
-		// 转换为项目路径格式
-		projPath := "/" + filepath.ToSlash(relPath)
-
 		// 创建节点（使用内部方法，避免重复加锁）
 		if info.IsDir() {
 			err = p.createDirInternal(projPath, info)
@@ -0,0 +1,426 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sjzsdu/tong/share"
+)
+
+// 文件监听与变更事件相关方法
+
+// ChangeType 项目变更类型
+type ChangeType int
+
+const (
+	ChangeCreated ChangeType = iota + 1
+	ChangeModified
+	ChangeRemoved
+	ChangeRenamed
+)
+
+// String 返回变更类型的名称
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeCreated:
+		return "created"
+	case ChangeModified:
+		return "modified"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeRenamed:
+		return "renamed"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(t))
+	}
+}
+
+// ChangeEvent 描述项目树中的一次变更
+type ChangeEvent struct {
+	Type    ChangeType
+	Path    string // 变更后的项目路径，如 /src/main.go
+	OldPath string // 仅在 ChangeRenamed 时有效，为变更前的项目路径
+	IsDir   bool
+}
+
+// 订阅通道的默认缓冲大小
+const defaultSubscribeBuffer = 64
+
+// 重命名事件配对窗口：fsnotify 会先后发出 Rename(旧路径) 与 Create(新路径)
+const renamePairWindow = 100 * time.Millisecond
+
+// Subscribe 订阅项目变更事件，返回事件通道与取消订阅函数
+// 通道写满时新事件会被丢弃，订阅者应及时消费
+func (p *Project) Subscribe(buffer int) (<-chan ChangeEvent, func()) {
+	if buffer <= 0 {
+		buffer = defaultSubscribeBuffer
+	}
+	ch := make(chan ChangeEvent, buffer)
+
+	p.subsMu.Lock()
+	if p.subscribers == nil {
+		p.subscribers = make(map[int]chan ChangeEvent)
+	}
+	id := p.nextSubID
+	p.nextSubID++
+	p.subscribers[id] = ch
+	p.subsMu.Unlock()
+
+	cancel := func() {
+		p.subsMu.Lock()
+		defer p.subsMu.Unlock()
+		if c, ok := p.subscribers[id]; ok {
+			delete(p.subscribers, id)
+			close(c)
+		}
+	}
+	return ch, cancel
+}
+
+// publish 向所有订阅者发送变更事件
+func (p *Project) publish(event ChangeEvent) {
+	p.subsMu.Lock()
+	defer p.subsMu.Unlock()
+
+	for _, ch := range p.subscribers {
+		select {
+		case ch <- event:
+		default:
+			if share.GetDebug() {
+				log.Printf("变更事件被丢弃（订阅者通道已满）: %s %s", event.Type, event.Path)
+			}
+		}
+	}
+}
+
+// Watch 监听项目根目录的文件系统变化，增量更新项目树并发布变更事件，直到 ctx 被取消
+// 监听遵循与 BuildProjectTree 相同的排除目录、.gitignore 与扩展名规则
+func (p *Project) Watch(ctx context.Context) error {
+	if p.source != nil {
+		return ErrNotOnDisk
+	}
+	if p.rootPath == "" {
+		return errors.New("project has no root path")
+	}
+	w, err := newProjectWatcher(p)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+	return w.run(ctx)
+}
+
+// pendingRename 等待与 Create 事件配对的重命名
+type pendingRename struct {
+	path string
+	node *Node
+}
+
+// projectWatcher 将 fsnotify 事件转换为项目树的增量更新
+type projectWatcher struct {
+	p       *Project
+	watcher *fsnotify.Watcher
+	filters []*pathFilter // 每个监听根目录一个，工作区有多个
+	pending *pendingRename
+	timer   *time.Timer
+}
+
+// newProjectWatcher 创建监听器并为项目中的所有目录注册监听
+func newProjectWatcher(p *Project) (*projectWatcher, error) {
+	if p.rootPath == "" {
+		return nil, errors.New("project has no root path")
+	}
+
+	var filters []*pathFilter
+	if p.IsWorkspace() {
+		// 工作区的每个根目录使用各自的过滤规则
+		for _, m := range p.mounts {
+			filters = append(filters, m.filter)
+		}
+	} else if p.filter != nil {
+		filters = []*pathFilter{p.filter}
+	} else {
+		// 非 BuildProjectTree 构建的项目（如 SyncFromFS）只排除系统目录
+		filters = []*pathFilter{newSystemFilter(p.rootPath)}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("创建文件监控失败: %w", err)
+	}
+
+	w := &projectWatcher{p: p, watcher: watcher, filters: filters}
+	for _, filter := range filters {
+		if err := w.watchTree(filter.rootPath, false); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// filterOf 返回磁盘路径所属根目录的过滤规则，嵌套时取最深的根目录
+func (w *projectWatcher) filterOf(path string) *pathFilter {
+	found := w.filters[0]
+	for _, f := range w.filters[1:] {
+		if withinDir(f.rootPath, path) && len(f.rootPath) > len(found.rootPath) {
+			found = f
+		}
+	}
+	return found
+}
+
+// close 关闭底层监听器
+func (w *projectWatcher) close() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.watcher.Close()
+}
+
+// run 事件主循环
+func (w *projectWatcher) run(ctx context.Context) error {
+	for {
+		var renameDeadline <-chan time.Time
+		if w.timer != nil {
+			renameDeadline = w.timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			w.flushRename()
+			return nil
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("文件监控错误: %v", err)
+		case <-renameDeadline:
+			w.timer = nil
+			w.flushRename()
+		}
+	}
+}
+
+// watchTree 为目录及其未被排除的子目录注册监听
+// addNodes 为 true 时同时把遍历到的文件与目录加入项目树并发布 Created 事件
+func (w *projectWatcher) watchTree(dir string, addNodes bool) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// 遍历期间被删除的路径直接忽略
+			return nil
+		}
+		filter := w.filterOf(path)
+		if path != filter.rootPath {
+			skip, skipDir, skipErr := filter.skip(path, info)
+			if skipErr != nil {
+				return skipErr
+			}
+			if skipDir {
+				return filepath.SkipDir
+			}
+			if skip {
+				return nil
+			}
+		}
+
+		if info.IsDir() {
+			filter.loadDir(path)
+			if err := w.watcher.Add(path); err != nil {
+				return fmt.Errorf("监听目录失败 %s: %w", path, err)
+			}
+		}
+
+		if addNodes {
+			if projPath, ok := w.p.projectPathOf(path); ok && projPath != "/" {
+				if w.insert(projPath, info) {
+					w.p.publish(ChangeEvent{Type: ChangeCreated, Path: projPath, IsDir: info.IsDir()})
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// handle 处理单个 fsnotify 事件
+func (w *projectWatcher) handle(event fsnotify.Event) {
+	absPath := filepath.Clean(event.Name)
+	projPath, ok := w.p.projectPathOf(absPath)
+	if !ok || projPath == "/" {
+		return
+	}
+
+	switch {
+	case event.Has(fsnotify.Rename):
+		w.flushRename()
+		if node := w.detach(projPath); node != nil {
+			w.pending = &pendingRename{path: projPath, node: node}
+			w.timer = time.NewTimer(renamePairWindow)
+		}
+	case event.Has(fsnotify.Remove):
+		if node := w.detach(projPath); node != nil {
+			w.p.publish(ChangeEvent{Type: ChangeRemoved, Path: projPath, IsDir: node.IsDir})
+		}
+	case event.Has(fsnotify.Create):
+		w.handleCreate(absPath, projPath)
+	case event.Has(fsnotify.Write):
+		w.handleWrite(absPath, projPath)
+	}
+}
+
+// handleCreate 处理新建事件，若存在待配对的重命名则视为重命名
+func (w *projectWatcher) handleCreate(absPath, projPath string) {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return
+	}
+	filter := w.filterOf(absPath)
+	filter.loadAncestors(filepath.Dir(absPath))
+	skip, _, err := filter.skip(absPath, info)
+	if err != nil || skip {
+		return
+	}
+
+	if w.pending != nil {
+		pending := w.pending
+		w.pending = nil
+		if w.timer != nil {
+			w.timer.Stop()
+			w.timer = nil
+		}
+		if w.reattach(pending.node, projPath, info) {
+			if info.IsDir() {
+				if err := w.watchTree(absPath, false); err != nil {
+					log.Printf("文件监控错误: %v", err)
+				}
+			}
+			w.p.publish(ChangeEvent{Type: ChangeRenamed, Path: projPath, OldPath: pending.path, IsDir: info.IsDir()})
+			return
+		}
+		w.p.publish(ChangeEvent{Type: ChangeRemoved, Path: pending.path, IsDir: pending.node.IsDir})
+	}
+
+	if _, err := w.p.FindNode(projPath); err == nil {
+		w.handleWrite(absPath, projPath)
+		return
+	}
+
+	if info.IsDir() {
+		if err := w.watchTree(absPath, true); err != nil {
+			log.Printf("文件监控错误: %v", err)
+		}
+		return
+	}
+	if w.insert(projPath, info) {
+		w.p.publish(ChangeEvent{Type: ChangeCreated, Path: projPath})
+	}
+}
+
+// handleWrite 处理写入事件：刷新文件信息并丢弃未修改节点的缓存内容
+func (w *projectWatcher) handleWrite(absPath, projPath string) {
+	info, err := os.Stat(absPath)
+	if err != nil || info.IsDir() {
+		return
+	}
+
+	if filter := w.filterOf(absPath); filter.isIgnoreFile(filepath.Base(absPath)) {
+		filter.reloadDir(filepath.Dir(absPath))
+	}
+
+	node, err := w.p.FindNode(projPath)
+	if err != nil {
+		w.handleCreate(absPath, projPath)
+		return
+	}
+
+	node.mu.Lock()
+	node.Info = info
+	if !node.modified {
+		node.Content = nil
+		node.ContentLoaded = false
+		if cache := w.p.ContentCache(); cache != nil {
+			cache.remove(node)
+		}
+	}
+	node.mu.Unlock()
+	w.p.publish(ChangeEvent{Type: ChangeModified, Path: projPath})
+}
+
+// flushRename 未能配对的重命名视为删除
+func (w *projectWatcher) flushRename() {
+	if w.pending == nil {
+		return
+	}
+	pending := w.pending
+	w.pending = nil
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.p.publish(ChangeEvent{Type: ChangeRemoved, Path: pending.path, IsDir: pending.node.IsDir})
+}
+
+// insert 将磁盘上的路径加入项目树，节点已存在时返回 false
+func (w *projectWatcher) insert(projPath string, info os.FileInfo) bool {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+
+	if _, exists := w.p.nodes[projPath]; exists {
+		return false
+	}
+	var err error
+	if info.IsDir() {
+		err = w.p.createDirInternal(projPath, info)
+	} else {
+		err = w.p.createFileNodeInternal(projPath, info)
+	}
+	if err != nil {
+		if share.GetDebug() {
+			log.Printf("监听更新项目树失败 %s: %v", projPath, err)
+		}
+		return false
+	}
+	return true
+}
+
+// detach 从项目树中移除路径对应的节点并返回该节点
+func (w *projectWatcher) detach(projPath string) *Node {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+
+	node, exists := w.p.nodes[projPath]
+	if !exists {
+		return nil
+	}
+	w.p.detachNode(node)
+	return node
+}
+
+// reattach 将重命名前脱离的节点挂载到新路径
+func (w *projectWatcher) reattach(node *Node, projPath string, info os.FileInfo) bool {
+	if node.IsDir != info.IsDir() {
+		return false
+	}
+
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+
+	if err := w.p.attachNode(node, projPath); err != nil {
+		return false
+	}
+	node.mu.Lock()
+	node.Info = info
+	node.mu.Unlock()
+	return true
+}
@@ -0,0 +1,181 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startWatch 构建项目树并启动监听，返回项目与事件通道
+func startWatch(t *testing.T, dir string, options helper.WalkDirOptions) (*Project, <-chan ChangeEvent) {
+	t.Helper()
+
+	proj, err := BuildProjectTree(dir, options)
+	require.NoError(t, err)
+
+	events, unsubscribe := proj.Subscribe(0)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w, err := newProjectWatcher(proj)
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer w.close()
+		w.run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		unsubscribe()
+	})
+	return proj, events
+}
+
+// waitEvent 等待满足条件的事件，超时则测试失败
+func waitEvent(t *testing.T, events <-chan ChangeEvent, match func(ChangeEvent) bool) ChangeEvent {
+	t.Helper()
+
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if match(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("等待变更事件超时")
+			return ChangeEvent{}
+		}
+	}
+}
+
+func TestProjectWatch(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "main.go"), []byte("package main"), 0644))
+
+	proj, events := startWatch(t, tempDir, helper.WalkDirOptions{})
+
+	t.Run("Create", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "util.go"), []byte("package main"), 0644))
+		event := waitEvent(t, events, func(e ChangeEvent) bool {
+			return e.Type == ChangeCreated && e.Path == "/src/util.go"
+		})
+		assert.False(t, event.IsDir)
+
+		content, err := proj.ReadFile("/src/util.go")
+		assert.NoError(t, err)
+		assert.Equal(t, "package main", string(content))
+	})
+
+	t.Run("Modify", func(t *testing.T) {
+		_, err := proj.ReadFile("/src/main.go")
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "main.go"), []byte("package changed"), 0644))
+		waitEvent(t, events, func(e ChangeEvent) bool {
+			return e.Type == ChangeModified && e.Path == "/src/main.go"
+		})
+
+		content, err := proj.ReadFile("/src/main.go")
+		assert.NoError(t, err)
+		assert.Equal(t, "package changed", string(content))
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		require.NoError(t, os.Rename(filepath.Join(tempDir, "src", "util.go"), filepath.Join(tempDir, "src", "helper.go")))
+		event := waitEvent(t, events, func(e ChangeEvent) bool {
+			return e.Type == ChangeRenamed || e.Type == ChangeRemoved
+		})
+		assert.Equal(t, ChangeRenamed, event.Type)
+		assert.Equal(t, "/src/util.go", event.OldPath)
+		assert.Equal(t, "/src/helper.go", event.Path)
+
+		_, err := proj.FindNode("/src/util.go")
+		assert.Error(t, err)
+		node, err := proj.FindNode("/src/helper.go")
+		assert.NoError(t, err)
+		assert.Equal(t, "helper.go", node.Name)
+	})
+
+	t.Run("NewDirectory", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "pkg"), 0755))
+		waitEvent(t, events, func(e ChangeEvent) bool {
+			return e.Type == ChangeCreated && e.Path == "/pkg" && e.IsDir
+		})
+
+		// 新目录创建后应立即被监听
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "pkg", "a.go"), []byte("package pkg"), 0644))
+		waitEvent(t, events, func(e ChangeEvent) bool {
+			return e.Type == ChangeCreated && e.Path == "/pkg/a.go"
+		})
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(filepath.Join(tempDir, "pkg")))
+		waitEvent(t, events, func(e ChangeEvent) bool {
+			return e.Type == ChangeRemoved && e.Path == "/pkg"
+		})
+
+		_, err := proj.FindNode("/pkg/a.go")
+		assert.Error(t, err)
+		_, err = proj.FindNode("/pkg")
+		assert.Error(t, err)
+	})
+}
+
+func TestProjectWatchRespectsFilters(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("*.log\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main"), 0644))
+
+	proj, events := startWatch(t, tempDir, helper.WalkDirOptions{Extensions: []string{"go", "log"}})
+
+	// 被 .gitignore 排除与扩展名不匹配的文件都不应出现在项目树中
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "debug.log"), []byte("log"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("txt"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "node_modules"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "ok.go"), []byte("package main"), 0644))
+
+	waitEvent(t, events, func(e ChangeEvent) bool {
+		assert.NotEqual(t, "/debug.log", e.Path)
+		assert.NotEqual(t, "/notes.txt", e.Path)
+		assert.NotEqual(t, "/node_modules", e.Path)
+		return e.Type == ChangeCreated && e.Path == "/ok.go"
+	})
+
+	_, err := proj.FindNode("/debug.log")
+	assert.Error(t, err)
+	_, err = proj.FindNode("/notes.txt")
+	assert.Error(t, err)
+	_, err = proj.FindNode("/node_modules")
+	assert.Error(t, err)
+}
+
+func TestProjectSubscribe(t *testing.T) {
+	proj := NewProject(t.TempDir())
+
+	events, unsubscribe := proj.Subscribe(1)
+	proj.publish(ChangeEvent{Type: ChangeCreated, Path: "/a"})
+	// 通道已满时事件被丢弃而不是阻塞
+	proj.publish(ChangeEvent{Type: ChangeCreated, Path: "/b"})
+
+	event := <-events
+	assert.Equal(t, "/a", event.Path)
+	assert.Equal(t, "created", event.Type.String())
+
+	unsubscribe()
+	_, ok := <-events
+	assert.False(t, ok)
+
+	// 重复取消订阅不应 panic
+	unsubscribe()
+}
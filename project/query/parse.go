@@ -0,0 +1,579 @@
+package query
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sjzsdu/tong/helper/filekind"
+	"github.com/sjzsdu/tong/helper/ignore"
+	"github.com/sjzsdu/tong/project"
+)
+
+// Error 查询语法错误
+type Error struct {
+	Query string // 原始查询
+	Pos   int    // 出错位置（字节偏移）
+	Msg   string
+}
+
+// Error 返回带列号与位置标记的错误信息
+func (e *Error) Error() string {
+	col := utf8.RuneCountInString(e.Query[:e.Pos])
+	return fmt.Sprintf("invalid query at column %d: %s\n  %s\n  %s^", col+1, e.Msg, e.Query, strings.Repeat(" ", col))
+}
+
+// 比较运算符
+const (
+	opColon = ":"
+	opEq    = "="
+	opLt    = "<"
+	opLe    = "<="
+	opGt    = ">"
+	opGe    = ">="
+)
+
+// keys 支持的键及其允许的运算符
+var keys = map[string][]string{
+	"lang":    {opColon},
+	"ext":     {opColon},
+	"name":    {opColon},
+	"path":    {opColon},
+	"content": {opColon},
+	"size":    {opEq, opLt, opLe, opGt, opGe},
+	"mtime":   {opLt, opLe, opGt, opGe},
+	"depth":   {opEq, opLt, opLe, opGt, opGe},
+}
+
+// flags 不带值的条件
+var flags = map[string]func(*project.Node) bool{
+	"generated": func(n *project.Node) bool { return n.Kind().Generated },
+	"vendored":  func(n *project.Node) bool { return n.Kind().Vendored },
+	"binary":    func(n *project.Node) bool { return n.Kind().Binary },
+	"lfs":       func(n *project.Node) bool { return n.Kind().LFSPointer },
+	"hidden":    func(n *project.Node) bool { return isHidden(n.Path) },
+	"dir":       func(n *project.Node) bool { return n.IsDir },
+	"file":      func(n *project.Node) bool { return !n.IsDir },
+	"modified":  func(n *project.Node) bool { return n.IsModified() },
+}
+
+// parser 递归下降解析器：or = and { "OR" and }；and = unary { ["AND"] unary }；
+// unary = ("!" | "-" | "NOT") unary | "(" or ")" | term
+type parser struct {
+	src string
+	pos int
+	now time.Time
+}
+
+// Parse 解析查询，空查询匹配所有节点
+func Parse(src string) (*Query, error) {
+	return parse(src, time.Now())
+}
+
+// parse 以 now 作为 mtime 条件的当前时间解析查询
+func parse(src string, now time.Time) (*Query, error) {
+	p := &parser{src: src, now: now}
+	p.skipSpace()
+	if p.eof() {
+		return &Query{src: src}, nil
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		if p.src[p.pos] == ')' {
+			return nil, p.errorf(p.pos, `unexpected ")"`)
+		}
+		return nil, p.errorf(p.pos, "unexpected %q", p.word())
+	}
+	return &Query{src: src, root: e}, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []expr{first}
+	for {
+		p.skipSpace()
+		start := p.pos
+		if !p.keyword("OR") && !p.symbol("|") {
+			break
+		}
+		p.skipSpace()
+		if p.eof() || p.src[p.pos] == ')' {
+			return nil, p.errorf(start, "expected a condition after %q", p.src[start:p.pos])
+		}
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, next)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return orExpr(terms), nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	var terms []expr
+	for {
+		p.skipSpace()
+		if p.eof() || p.src[p.pos] == ')' || p.peekKeyword("OR") || p.src[p.pos] == '|' {
+			break
+		}
+		if len(terms) > 0 {
+			start := p.pos
+			if p.keyword("AND") {
+				p.skipSpace()
+				if p.eof() || p.src[p.pos] == ')' || p.peekKeyword("OR") {
+					return nil, p.errorf(start, `expected a condition after "AND"`)
+				}
+			}
+		}
+		term, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		if p.eof() {
+			return nil, p.errorf(p.pos, "expected a condition")
+		}
+		return nil, p.errorf(p.pos, "expected a condition before %q", p.word())
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return newAnd(terms), nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	start := p.pos
+	if p.symbol("!") || p.symbol("-") || p.keyword("NOT") {
+		p.skipSpace()
+		if p.eof() || p.src[p.pos] == ')' {
+			return nil, p.errorf(start, "expected a condition after %q", strings.TrimSpace(p.src[start:p.pos]))
+		}
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	if p.symbol("(") {
+		p.skipSpace()
+		if p.symbol(")") {
+			return nil, p.errorf(start, "empty parentheses")
+		}
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.symbol(")") {
+			return nil, p.errorf(start, `missing ")" for this "("`)
+		}
+		return e, nil
+	}
+	return p.parseTerm()
+}
+
+// parseTerm 解析 key:value、key>value 或不带值的条件
+func (p *parser) parseTerm() (expr, error) {
+	start := p.pos
+	for !p.eof() && isKeyChar(p.src[p.pos]) {
+		p.pos++
+	}
+	key := strings.ToLower(p.src[start:p.pos])
+	if key == "" {
+		return nil, p.errorf(start, "expected a condition, found %q", p.word())
+	}
+
+	opStart := p.pos
+	op := p.operator()
+	if op == "" {
+		if !p.atTermEnd() {
+			return nil, p.errorf(p.pos, "unexpected %q after %q", string(p.src[p.pos]), key)
+		}
+		fn, ok := flags[key]
+		if !ok {
+			if _, isKey := keys[key]; isKey {
+				return nil, p.errorf(start, "%q needs a value, e.g. %s", key, example(key))
+			}
+			return nil, p.errorf(start, "unknown condition %q%s", key, suggest(key))
+		}
+		return flagExpr{name: key, fn: fn}, nil
+	}
+
+	allowed, ok := keys[key]
+	if !ok {
+		return nil, p.errorf(start, "unknown key %q%s", key, suggest(key))
+	}
+	if !contains(allowed, op) {
+		return nil, p.errorf(opStart, "%q does not support %q, e.g. %s", key, op, example(key))
+	}
+
+	valueStart := p.pos
+	value, isRegex, flagsStr, err := p.value(key)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" && !isRegex {
+		return nil, p.errorf(valueStart, "missing value after %q, e.g. %s", key+op, example(key))
+	}
+	text := p.src[start:p.pos]
+	return p.predicate(key, op, value, isRegex, flagsStr, text, valueStart)
+}
+
+// predicate 根据键创建条件
+func (p *parser) predicate(key, op, value string, isRegex bool, reFlags, text string, pos int) (expr, error) {
+	switch key {
+	case "lang":
+		langs := splitList(value)
+		for i, lang := range langs {
+			langs[i] = filekind.NormalizeLanguage(lang)
+		}
+		return langExpr{text: text, langs: langs}, nil
+	case "ext":
+		exts := splitList(value)
+		for i, ext := range exts {
+			exts[i] = "." + strings.TrimPrefix(strings.ToLower(ext), ".")
+		}
+		return extExpr{text: text, exts: exts}, nil
+	case "name", "content":
+		m := matcher{substr: value}
+		if isRegex {
+			if strings.Contains(reFlags, "i") {
+				value = "(?i)" + value
+			}
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, p.errorf(pos, "invalid regular expression: %v", err)
+			}
+			m = matcher{re: re}
+		} else if key == "name" {
+			if _, err := path.Match(value, ""); err != nil {
+				return nil, p.errorf(pos, "invalid pattern %q: %v", value, err)
+			}
+			m = matcher{glob: value}
+		}
+		if key == "name" {
+			return nameExpr{text: text, m: m}, nil
+		}
+		return contentExpr{text: text, m: m}, nil
+	case "path":
+		rule := ignore.ParseRule(value, "")
+		if rule == nil {
+			return nil, p.errorf(pos, "invalid path pattern %q", value)
+		}
+		return pathExpr{text: text, rule: rule}, nil
+	case "size":
+		n, ok := parseSize(value)
+		if !ok {
+			return nil, p.errorf(pos, "invalid size %q: use a number with an optional unit b, k, m or g, e.g. 10k", value)
+		}
+		return compareExpr{text: text, op: op, value: n, get: sizeOf}, nil
+	case "depth":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, p.errorf(pos, "invalid depth %q: use a non-negative integer", value)
+		}
+		return compareExpr{text: text, op: op, value: int64(n), get: depthOf}, nil
+	case "mtime":
+		if d, ok := parseAge(value); ok {
+			// 按距今时长比较：mtime<7d 表示 7 天内修改过
+			now := p.now
+			return compareExpr{text: text, op: op, value: int64(d), get: func(n *project.Node) (int64, bool) {
+				t, ok := modTimeOf(n)
+				return int64(now.Sub(t)), ok
+			}}, nil
+		}
+		if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+			// 按日期比较：mtime>2024-01-31 表示该日期之后修改过
+			return compareExpr{text: text, op: op, value: t.UnixNano(), get: func(n *project.Node) (int64, bool) {
+				t, ok := modTimeOf(n)
+				return t.UnixNano(), ok
+			}}, nil
+		}
+		return nil, p.errorf(pos, "invalid time %q: use an age such as 30m, 12h, 7d, 2w or a date such as 2024-01-31", value)
+	}
+	return nil, p.errorf(pos, "unknown key %q", key)
+}
+
+// value 读取值：带引号的字符串、/正则/标志 或直到空白与 ")" 的普通文本
+func (p *parser) value(key string) (value string, isRegex bool, reFlags string, err error) {
+	if p.eof() {
+		return "", false, "", nil
+	}
+	start := p.pos
+	switch p.src[p.pos] {
+	case '"':
+		var b strings.Builder
+		p.pos++
+		for !p.eof() {
+			c := p.src[p.pos]
+			switch {
+			case c == '\\' && p.pos+1 < len(p.src):
+				b.WriteByte(p.src[p.pos+1])
+				p.pos += 2
+			case c == '"':
+				p.pos++
+				if !p.atTermEnd() {
+					return "", false, "", p.errorf(p.pos, "unexpected %q after closing quote", string(p.src[p.pos]))
+				}
+				return b.String(), false, "", nil
+			default:
+				b.WriteByte(c)
+				p.pos++
+			}
+		}
+		return "", false, "", p.errorf(start, "unterminated quoted string")
+	case '/':
+		if key != "content" && key != "name" {
+			break
+		}
+		var b strings.Builder
+		p.pos++
+		for !p.eof() {
+			c := p.src[p.pos]
+			switch {
+			case c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '/':
+				b.WriteByte('/')
+				p.pos += 2
+			case c == '/':
+				p.pos++
+				flagStart := p.pos
+				for !p.eof() && p.src[p.pos] == 'i' {
+					p.pos++
+				}
+				if !p.atTermEnd() {
+					return "", false, "", p.errorf(p.pos, "unknown regular expression flag %q, only i is supported", string(p.src[p.pos]))
+				}
+				return b.String(), true, p.src[flagStart:p.pos], nil
+			default:
+				b.WriteByte(c)
+				p.pos++
+			}
+		}
+		return "", false, "", p.errorf(start, "unterminated regular expression, missing closing \"/\"")
+	}
+	for !p.atTermEnd() {
+		p.pos++
+	}
+	return p.src[start:p.pos], false, "", nil
+}
+
+// operator 读取比较运算符
+func (p *parser) operator() string {
+	for _, op := range []string{opLe, opGe, opColon, opEq, opLt, opGt} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+// keyword 读取大写关键字（AND、OR、NOT），关键字后必须是空白或括号
+func (p *parser) keyword(kw string) bool {
+	if !p.peekKeyword(kw) {
+		return false
+	}
+	p.pos += len(kw)
+	return true
+}
+
+func (p *parser) peekKeyword(kw string) bool {
+	if !strings.HasPrefix(p.src[p.pos:], kw) {
+		return false
+	}
+	end := p.pos + len(kw)
+	return end == len(p.src) || p.src[end] == ' ' || p.src[end] == '\t' || p.src[end] == '('
+}
+
+func (p *parser) symbol(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+// atTermEnd 判断是否到达条件末尾（空白、")" 或查询末尾）
+func (p *parser) atTermEnd() bool {
+	return p.eof() || unicode.IsSpace(rune(p.src[p.pos])) || p.src[p.pos] == ')'
+}
+
+// word 返回当前位置直到空白的文本，用于错误信息
+func (p *parser) word() string {
+	end := p.pos
+	for end < len(p.src) && !unicode.IsSpace(rune(p.src[end])) {
+		end++
+	}
+	return p.src[p.pos:end]
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+	return &Error{Query: p.src, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func isKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// example 返回键的用法示例
+func example(key string) string {
+	switch key {
+	case "lang":
+		return "lang:go"
+	case "ext":
+		return "ext:go,md"
+	case "name":
+		return `name:*_test.go or name:/^main\./`
+	case "path":
+		return "path:cmd/**"
+	case "content":
+		return "content:TODO or content:/fixme/i"
+	case "size":
+		return "size>10k"
+	case "mtime":
+		return "mtime<7d"
+	case "depth":
+		return "depth<=2"
+	}
+	return key
+}
+
+// suggest 为拼写错误的键或条件给出建议
+func suggest(word string) string {
+	best, bestDist := "", 3
+	candidates := make([]string, 0, len(keys)+len(flags))
+	for k := range keys {
+		candidates = append(candidates, k)
+	}
+	for k := range flags {
+		candidates = append(candidates, k)
+	}
+	for _, c := range candidates {
+		if d := editDistance(word, c); d < bestDist || d == bestDist && best != "" && c < best {
+			best, bestDist = c, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance 计算两个字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// parseSize 解析大小，支持 b、k、m、g 单位（1k = 1024 字节），可带 b 后缀如 10kb
+func parseSize(s string) (int64, bool) {
+	s = strings.TrimSuffix(strings.ToLower(s), "b")
+	mult := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, false
+	}
+	return int64(f * float64(mult)), true
+}
+
+// parseAge 解析时长，支持 s、m、h、d、w 单位
+func parseAge(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n * float64(unit)), true
+}
+
+// splitList 拆分逗号分隔的值
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
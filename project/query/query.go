@@ -0,0 +1,291 @@
+package query
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sjzsdu/tong/helper/ignore"
+	"github.com/sjzsdu/tong/project"
+)
+
+// Query 解析后的节点查询，由 Parse 创建，可并发使用
+type Query struct {
+	src  string
+	root expr
+}
+
+// Match 判断节点是否满足查询，空查询匹配所有节点
+func (q *Query) Match(n *project.Node) bool {
+	if q == nil || q.root == nil || n == nil {
+		return true
+	}
+	return q.root.match(n)
+}
+
+// IsEmpty 判断查询是否为空
+func (q *Query) IsEmpty() bool {
+	return q == nil || q.root == nil
+}
+
+// String 返回规范化后的查询，AND 中的条件按求值顺序排列
+func (q *Query) String() string {
+	if q.IsEmpty() {
+		return ""
+	}
+	return q.root.String()
+}
+
+// expr 查询表达式
+type expr interface {
+	match(n *project.Node) bool
+	// cost 求值代价，AND 中代价低的条件先求值
+	cost() int
+	String() string
+}
+
+// 求值代价：只看节点元数据、需要读取文件开头、需要读取整个文件
+const (
+	costMeta = iota
+	costHead
+	costContent
+)
+
+type andExpr []expr
+
+// newAnd 创建 AND 表达式，按代价稳定排序，使读取内容的条件最后求值
+func newAnd(terms []expr) andExpr {
+	sort.SliceStable(terms, func(i, j int) bool { return terms[i].cost() < terms[j].cost() })
+	return andExpr(terms)
+}
+
+func (e andExpr) match(n *project.Node) bool {
+	for _, t := range e {
+		if !t.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e andExpr) cost() int { return maxCost(e) }
+
+func (e andExpr) String() string {
+	parts := make([]string, len(e))
+	for i, t := range e {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+type orExpr []expr
+
+func (e orExpr) match(n *project.Node) bool {
+	for _, t := range e {
+		if t.match(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e orExpr) cost() int { return maxCost(e) }
+
+func (e orExpr) String() string {
+	parts := make([]string, len(e))
+	for i, t := range e {
+		parts[i] = t.String()
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+type notExpr struct{ e expr }
+
+func (e notExpr) match(n *project.Node) bool { return !e.e.match(n) }
+func (e notExpr) cost() int                  { return e.e.cost() }
+func (e notExpr) String() string             { return "!" + e.e.String() }
+
+// flagExpr 不带值的条件，如 generated、dir
+type flagExpr struct {
+	name string
+	fn   func(*project.Node) bool
+}
+
+func (e flagExpr) match(n *project.Node) bool { return e.fn(n) }
+func (e flagExpr) String() string             { return e.name }
+
+func (e flagExpr) cost() int {
+	switch e.name {
+	case "generated", "binary", "lfs":
+		return costHead
+	}
+	return costMeta
+}
+
+// langExpr lang:go,python，按 Node.Kind 识别的语言匹配
+type langExpr struct {
+	text  string
+	langs []string
+}
+
+func (e langExpr) match(n *project.Node) bool {
+	if n.IsDir {
+		return false
+	}
+	lang := n.Kind().Language
+	return lang != "" && contains(e.langs, lang)
+}
+
+func (e langExpr) cost() int      { return costHead }
+func (e langExpr) String() string { return e.text }
+
+// extExpr ext:go,md
+type extExpr struct {
+	text string
+	exts []string
+}
+
+func (e extExpr) match(n *project.Node) bool {
+	return !n.IsDir && contains(e.exts, strings.ToLower(path.Ext(n.Name)))
+}
+
+func (e extExpr) cost() int      { return costMeta }
+func (e extExpr) String() string { return e.text }
+
+// pathExpr path:cmd/**，使用 gitignore 语法匹配相对项目根的路径
+type pathExpr struct {
+	text string
+	rule *ignore.Rule
+}
+
+func (e pathExpr) match(n *project.Node) bool {
+	p := strings.TrimPrefix(n.Path, "/")
+	return p != "" && e.rule.Match(p, n.IsDir)
+}
+
+func (e pathExpr) cost() int      { return costMeta }
+func (e pathExpr) String() string { return e.text }
+
+// matcher 子串、通配符或正则匹配
+type matcher struct {
+	substr string
+	glob   string
+	re     *regexp.Regexp
+}
+
+// nameExpr name:*_test.go 或 name:/regex/
+type nameExpr struct {
+	text string
+	m    matcher
+}
+
+func (e nameExpr) match(n *project.Node) bool {
+	if e.m.re != nil {
+		return e.m.re.MatchString(n.Name)
+	}
+	ok, _ := path.Match(e.m.glob, n.Name)
+	return ok
+}
+
+func (e nameExpr) cost() int      { return costMeta }
+func (e nameExpr) String() string { return e.text }
+
+// contentExpr content:TODO 或 content:/regex/i，只匹配文件
+type contentExpr struct {
+	text string
+	m    matcher
+}
+
+func (e contentExpr) match(n *project.Node) bool {
+	if n.IsDir {
+		return false
+	}
+	content, err := n.ReadContent()
+	if err != nil {
+		return false
+	}
+	if e.m.re != nil {
+		return e.m.re.Match(content)
+	}
+	return bytes.Contains(content, []byte(e.m.substr))
+}
+
+func (e contentExpr) cost() int      { return costContent }
+func (e contentExpr) String() string { return e.text }
+
+// compareExpr size>10k、depth<=2、mtime<7d 等比较条件
+type compareExpr struct {
+	text  string
+	op    string
+	value int64
+	get   func(*project.Node) (int64, bool)
+}
+
+func (e compareExpr) match(n *project.Node) bool {
+	v, ok := e.get(n)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case opEq:
+		return v == e.value
+	case opLt:
+		return v < e.value
+	case opLe:
+		return v <= e.value
+	case opGt:
+		return v > e.value
+	case opGe:
+		return v >= e.value
+	}
+	return false
+}
+
+func (e compareExpr) cost() int      { return costMeta }
+func (e compareExpr) String() string { return e.text }
+
+// sizeOf 返回文件大小，目录不参与比较
+func sizeOf(n *project.Node) (int64, bool) {
+	if n.IsDir || n.Info == nil {
+		return 0, false
+	}
+	return n.Info.Size(), true
+}
+
+// depthOf 返回节点相对项目根的深度，根为 0
+func depthOf(n *project.Node) (int64, bool) {
+	p := strings.Trim(n.Path, "/")
+	if p == "" {
+		return 0, true
+	}
+	return int64(strings.Count(p, "/") + 1), true
+}
+
+// modTimeOf 返回节点的修改时间
+func modTimeOf(n *project.Node) (time.Time, bool) {
+	if n.Info == nil {
+		return time.Time{}, false
+	}
+	return n.Info.ModTime(), true
+}
+
+// isHidden 判断路径中是否有以 . 开头的部分
+func isHidden(p string) bool {
+	for _, part := range strings.Split(strings.Trim(p, "/"), "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+func maxCost(terms []expr) int {
+	c := costMeta
+	for _, t := range terms {
+		c = max(c, t.cost())
+	}
+	return c
+}
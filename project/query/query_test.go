@@ -0,0 +1,167 @@
+package query
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupProject 创建测试项目，old.md 的修改时间为 30 天前
+func setupProject(t *testing.T) *project.Project {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":        "package main\n// TODO: refactor\n",
+		"cmd/root.go":    "package cmd\n",
+		"cmd/sub/run.go": "package sub\n",
+		"api/api.pb.go":  "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n",
+		"vendor/x/x.go":  "package x\n// TODO: upstream\n",
+		"docs/old.md":    "# Old\n",
+		"docs/big.txt":   string(make([]byte, 20*1024)),
+		".github/ci.yml": "on: push\n",
+		"scripts/deploy": "#!/bin/sh\necho FIXME\n",
+	}
+	for name, content := range files {
+		full := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "docs/old.md"), old, old))
+
+	proj, err := project.BuildProjectTree(dir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+	return proj
+}
+
+// matchPaths 返回满足查询的所有节点路径
+func matchPaths(t *testing.T, proj *project.Project, src string) []string {
+	t.Helper()
+	q, err := Parse(src)
+	require.NoError(t, err, src)
+	var paths []string
+	require.NoError(t, proj.Visit(func(path string, n *project.Node, depth int) error {
+		if n.Path != "/" && q.Match(n) {
+			paths = append(paths, n.Path)
+		}
+		return nil
+	}))
+	return paths
+}
+
+func TestMatch(t *testing.T) {
+	proj := setupProject(t)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"lang:go !generated !vendored", []string{"/cmd/root.go", "/cmd/sub/run.go", "/main.go"}},
+		{"lang:golang,sh", []string{"/api/api.pb.go", "/cmd/root.go", "/cmd/sub/run.go", "/main.go", "/scripts/deploy", "/vendor/x/x.go"}},
+		{"path:cmd/** file", []string{"/cmd/root.go", "/cmd/sub/run.go"}},
+		{"path:cmd dir", []string{"/cmd"}},
+		{"name:*.md OR name:/^ci\\./", []string{"/.github/ci.yml", "/docs/old.md"}},
+		{"size>10k", []string{"/docs/big.txt"}},
+		{"size<=12 ext:go", []string{"/cmd/root.go", "/cmd/sub/run.go"}},
+		{"mtime>7d", []string{"/docs/old.md"}},
+		{"file mtime<7d ext:md", nil},
+		{"depth>=3", []string{"/cmd/sub/run.go", "/vendor/x/x.go"}},
+		{"content:TODO", []string{"/main.go", "/vendor/x/x.go"}},
+		{"content:/fixme|todo/i -vendored", []string{"/main.go", "/scripts/deploy"}},
+		{"hidden", []string{"/.github", "/.github/ci.yml"}},
+		{"NOT (dir OR ext:go OR hidden) AND !binary", []string{"/docs/old.md", "/scripts/deploy"}},
+		{"binary", []string{"/docs/big.txt"}},
+		{"generated | lang:markdown", []string{"/api/api.pb.go", "/docs/old.md"}},
+		{"   ", nil},
+	}
+	for _, tt := range tests {
+		got := matchPaths(t, proj, tt.query)
+		if tt.query == "   " {
+			assert.NotEmpty(t, got, "empty query matches everything")
+			continue
+		}
+		assert.ElementsMatch(t, tt.want, got, tt.query)
+	}
+
+	// mtime 也支持日期
+	future := time.Now().Add(48 * time.Hour).Format("2006-01-02")
+	assert.Empty(t, matchPaths(t, proj, "file mtime>"+future))
+}
+
+func TestParseString(t *testing.T) {
+	tests := map[string]string{
+		"lang:go":                         "lang:go",
+		"content:TODO size>10k lang:go":   "size>10k lang:go content:TODO",
+		"(dir OR file) AND !hidden":       "(dir OR file) !hidden",
+		`name:"my file.txt"`:              `name:"my file.txt"`,
+		"NOT generated":                   "!generated",
+		"lang:go OR (ext:md depth<2)":     "(lang:go OR ext:md depth<2)",
+		`content:/a\/b/ path:src/** -dir`: `path:src/** !dir content:/a\/b/`,
+	}
+	for src, want := range tests {
+		q, err := Parse(src)
+		require.NoError(t, err, src)
+		assert.Equal(t, want, q.String(), src)
+	}
+
+	q, err := Parse("")
+	require.NoError(t, err)
+	assert.True(t, q.IsEmpty())
+	assert.True(t, q.Match(&project.Node{Name: "x"}))
+	var nilQuery *Query
+	assert.True(t, nilQuery.Match(&project.Node{Name: "x"}))
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		col   int
+		msg   string
+	}{
+		{"sze>10k", 1, `unknown key "sze" (did you mean "size"?)`},
+		{"lang:go genrated", 9, `unknown condition "genrated" (did you mean "generated"?)`},
+		{"lang>go", 5, `"lang" does not support ">", e.g. lang:go`},
+		{"size:10k", 5, `"size" does not support ":", e.g. size>10k`},
+		{"size>10q", 6, `invalid size "10q"`},
+		{"mtime<7x", 7, `invalid time "7x"`},
+		{"mtime=7d", 6, `"mtime" does not support "="`},
+		{"depth>-1", 7, `invalid depth "-1"`},
+		{"lang:", 6, `missing value after "lang:", e.g. lang:go`},
+		{"size", 1, `"size" needs a value, e.g. size>10k`},
+		{`name:"abc`, 6, "unterminated quoted string"},
+		{"content:/abc", 9, "unterminated regular expression"},
+		{"content:/a(/", 9, "invalid regular expression"},
+		{"content:/a/x", 12, `unknown regular expression flag "x"`},
+		{"(lang:go", 1, `missing ")" for this "("`},
+		{"lang:go)", 8, `unexpected ")"`},
+		{"()", 1, "empty parentheses"},
+		{"lang:go OR", 9, `expected a condition after "OR"`},
+		{"lang:go AND", 9, `expected a condition after "AND"`},
+		{"!", 1, `expected a condition after "!"`},
+		{"lang:go 123", 9, `expected a condition, found "123"`},
+		{"dir=1", 1, `unknown key "dir"`},
+		{"文件 lang:go", 1, `expected a condition, found "文件"`},
+		{"lang:go 文件", 9, `expected a condition, found "文件"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		require.Error(t, err, tt.query)
+		var qe *Error
+		require.True(t, errors.As(err, &qe), tt.query)
+		assert.Contains(t, qe.Msg, tt.msg, tt.query)
+		assert.Contains(t, err.Error(), "column "+strconv.Itoa(tt.col)+":", tt.query)
+	}
+
+	// 错误信息带位置标记
+	_, err := Parse("lang:go sze>1")
+	require.Error(t, err)
+	assert.Equal(t, "invalid query at column 9: unknown key \"sze\" (did you mean \"size\"?)\n  lang:go sze>1\n          ^", err.Error())
+}
@@ -0,0 +1,298 @@
+package search
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/share"
+)
+
+// Format 搜索结果的输出格式
+type Format string
+
+const (
+	FormatText    Format = "text"    // 面向人的默认输出，由命令行自行处理
+	FormatJSON    Format = "json"    // 单个 JSON 文档
+	FormatJSONL   Format = "jsonl"   // 每行一条记录
+	FormatVimgrep Format = "vimgrep" // 路径:行:列:内容，可直接作为编辑器 quickfix 列表
+	FormatSARIF   Format = "sarif"   // SARIF 2.1.0，供 CI 标注使用
+)
+
+// ParseFormat 解析输出格式名称，空字符串为 text
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatJSON, FormatJSONL, FormatVimgrep, FormatSARIF:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported format: %s (want text, json, jsonl, vimgrep or sarif)", s)
+}
+
+// 记录类型
+const (
+	RecordMatch = "match"
+	RecordFile  = "file"
+	RecordDir   = "dir"
+)
+
+// Record 一条机器可读的搜索结果：逐行匹配，或没有行级信息的文件、目录
+// 路径相对项目根（不以 / 开头），行号与列号从 1 开始，列号按字节计算
+type Record struct {
+	Type     string   `json:"type"`
+	Path     string   `json:"path"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Text     string   `json:"text,omitempty"`
+	LineText string   `json:"lineText,omitempty"`
+	Before   []string `json:"before,omitempty"`
+	After    []string `json:"after,omitempty"`
+	Score    int      `json:"score,omitempty"` // 模糊查找的得分
+}
+
+// Report JSON 格式的输出文档
+type Report struct {
+	Files   int      `json:"files"`   // 结果涉及的文件（与目录）数
+	Matches int      `json:"matches"` // 逐行匹配数
+	Results []Record `json:"results"`
+}
+
+// MatchRecords 把逐行匹配转换为记录
+func MatchRecords(matches []Match) []Record {
+	records := make([]Record, 0, len(matches))
+	for _, m := range matches {
+		records = append(records, Record{
+			Type:     RecordMatch,
+			Path:     strings.TrimPrefix(m.Path, "/"),
+			Line:     m.Line,
+			Column:   m.Column,
+			Text:     m.Text,
+			LineText: m.LineText,
+			Before:   m.Before,
+			After:    m.After,
+		})
+	}
+	return records
+}
+
+// NodeRecords 把 Search 返回的节点转换为记录
+func NodeRecords(nodes []*project.Node) []Record {
+	records := make([]Record, 0, len(nodes))
+	for _, n := range nodes {
+		records = append(records, Record{Type: nodeType(n.IsDir), Path: displayPath(n.Path)})
+	}
+	return records
+}
+
+// FuzzyRecords 把模糊查找结果转换为记录
+func FuzzyRecords(results []FuzzyMatch) []Record {
+	records := make([]Record, 0, len(results))
+	for _, r := range results {
+		records = append(records, Record{Type: nodeType(r.IsDir), Path: displayPath(r.Path), Score: r.Score})
+	}
+	return records
+}
+
+func nodeType(isDir bool) string {
+	if isDir {
+		return RecordDir
+	}
+	return RecordFile
+}
+
+// displayPath 返回相对项目根的路径，根目录为 .
+func displayPath(p string) string {
+	if p = strings.TrimPrefix(p, "/"); p == "" {
+		return "."
+	}
+	return p
+}
+
+// WriteRecords 按格式输出记录，text 格式不在此处理
+func WriteRecords(w io.Writer, format Format, records []Record) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, records)
+	case FormatJSONL:
+		return writeJSONL(w, records)
+	case FormatVimgrep:
+		return writeVimgrep(w, records)
+	case FormatSARIF:
+		return writeSARIF(w, records)
+	}
+	return fmt.Errorf("unsupported format: %s", format)
+}
+
+func writeJSON(w io.Writer, records []Record) error {
+	report := Report{Results: records}
+	seen := make(map[string]bool)
+	for _, r := range records {
+		if r.Type == RecordMatch {
+			report.Matches++
+		}
+		if !seen[r.Path] {
+			seen[r.Path] = true
+			report.Files++
+		}
+	}
+	if report.Results == nil {
+		report.Results = []Record{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func writeJSONL(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeVimgrep 每条记录一行；没有行级信息的文件定位到第 1 行第 1 列
+func writeVimgrep(w io.Writer, records []Record) error {
+	bw := bufio.NewWriter(w)
+	for _, r := range records {
+		line, column := r.Line, r.Column
+		if line == 0 {
+			line, column = 1, 1
+		}
+		fmt.Fprintf(bw, "%s:%d:%d:%s\n", r.Path, line, column, r.LineText)
+	}
+	return bw.Flush()
+}
+
+// SARIF 2.1.0 中用到的结构
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	EndColumn   int           `json:"endColumn,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+const (
+	sarifSchema    = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifRuleMatch = "search-match"
+	sarifRuleFile  = "search-file"
+)
+
+// writeSARIF 输出 SARIF 日志，列号按 Unicode 码点计算（columnKind 为 unicodeCodePoints）
+func writeSARIF(w io.Writer, records []Record) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "tong",
+			Version:        share.VERSION,
+			InformationURI: "https://github.com/sjzsdu/tong",
+			Rules: []sarifRule{
+				{ID: sarifRuleMatch, ShortDescription: sarifMessage{Text: "Content search match"}},
+				{ID: sarifRuleFile, ShortDescription: sarifMessage{Text: "Search result file"}},
+			},
+		}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+	for _, r := range records {
+		result := sarifResult{
+			RuleID:  sarifRuleFile,
+			Level:   "note",
+			Message: sarifMessage{Text: r.Path},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(r.Path)},
+			}}},
+		}
+		if r.Type == RecordMatch {
+			start := runeColumn(r.LineText, r.Column)
+			result.RuleID = sarifRuleMatch
+			result.Message.Text = "match: " + r.Text
+			result.Locations[0].PhysicalLocation.Region = &sarifRegion{
+				StartLine:   r.Line,
+				StartColumn: start,
+				EndColumn:   start + utf8.RuneCountInString(r.Text),
+				Snippet:     &sarifMessage{Text: r.LineText},
+			}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
+
+// sarifURI 把相对路径转换为 SARIF 要求的 URI 引用，逐段进行百分号编码
+func sarifURI(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// runeColumn 把按字节计算的列号转换为按码点计算的列号
+func runeColumn(line string, column int) int {
+	if column <= 1 || column-1 > len(line) {
+		return column
+	}
+	return utf8.RuneCountInString(line[:column-1]) + 1
+}
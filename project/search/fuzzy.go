@@ -0,0 +1,237 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sjzsdu/tong/project"
+)
+
+// 模糊匹配的计分规则（与 fzf 类似）：查询字符按顺序出现在路径中即可匹配，
+// 每个匹配字符得基础分，位于路径分隔符、单词分隔符或驼峰边界之后的字符、连续匹配与文件名中的字符额外加分，
+// 匹配之间的间隔扣分
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyGapStart         = -3
+	fuzzyGapExtension     = -1
+	fuzzyBonusSeparator   = 10 // 路径开头或 / 之后
+	fuzzyBonusDelimiter   = 8  // _ - . 空格之后
+	fuzzyBonusCamel       = 7  // 小写到大写、字母到数字
+	fuzzyBonusConsecutive = 5
+	fuzzyBonusFileName    = 3 // 每个落在文件名中的匹配字符
+	fuzzyDefaultLimit     = 20
+)
+
+// fuzzyUnmatched 表示无法匹配的得分
+const fuzzyUnmatched = -1 << 30
+
+// FuzzyMatch 模糊匹配结果
+type FuzzyMatch struct {
+	Path      string `json:"path"`
+	Score     int    `json:"score"`
+	Positions []int  `json:"positions,omitempty"` // 匹配字符在路径（不含开头的 /）中的字节位置
+	IsDir     bool   `json:"isDir,omitempty"`
+}
+
+// FuzzyFind 在 root 子树中按 opts 过滤节点（名称与内容条件同样生效），再按模糊匹配得分排序，返回前 limit 个结果
+// 查询中的空格分隔多个词，每个词都必须匹配；查询全为小写时不区分大小写，否则区分
+// limit 小于等于 0 时使用默认值 20
+func FuzzyFind(ctx context.Context, root *project.Node, query string, opts *SearchOptions, limit int) ([]FuzzyMatch, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = fuzzyDefaultLimit
+	}
+	nodes, err := Search(ctx, root, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []FuzzyMatch
+	for _, n := range nodes {
+		path := strings.TrimPrefix(n.Path, "/")
+		if path == "" {
+			continue
+		}
+		total := 0
+		var positions []int
+		matched := true
+		for _, term := range terms {
+			score, pos, ok := FuzzyScore(term, path)
+			if !ok {
+				matched = false
+				break
+			}
+			total += score
+			positions = mergePositions(positions, pos)
+		}
+		if !matched {
+			continue
+		}
+		results = append(results, FuzzyMatch{Path: path, Score: total, Positions: positions, IsDir: n.IsDir})
+	}
+
+	// 得分高的在前，同分时路径短的在前
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) < len(b.Path)
+		}
+		return a.Path < b.Path
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// FuzzyScore 计算查询在目标中的最优子序列匹配，返回得分与匹配字符的字节位置；不匹配时 ok 为 false
+// 查询全为小写时不区分大小写
+func FuzzyScore(query, target string) (score int, positions []int, ok bool) {
+	q := []rune(query)
+	if len(q) == 0 {
+		return 0, nil, true
+	}
+	caseSensitive := strings.ToLower(query) != query
+
+	runes := make([]rune, 0, len(target))
+	offsets := make([]int, 0, len(target))
+	for i, r := range target {
+		runes = append(runes, r)
+		offsets = append(offsets, i)
+	}
+	n, m := len(runes), len(q)
+	if m > n {
+		return 0, nil, false
+	}
+
+	// 先确认是子序列，避免对大多数不匹配的路径做完整计算
+	matched := 0
+	for j := 0; j < n && matched < m; j++ {
+		if fuzzyEqual(q[matched], runes[j], caseSensitive) {
+			matched++
+		}
+	}
+	if matched < m {
+		return 0, nil, false
+	}
+
+	nameStart := 0
+	for j, r := range runes {
+		if r == '/' {
+			nameStart = j + 1
+		}
+	}
+	bonus := make([]int, n)
+	for j := range runes {
+		bonus[j] = fuzzyBonus(runes, j)
+		if j >= nameStart {
+			bonus[j] += fuzzyBonusFileName
+		}
+	}
+
+	// score[i][j]：查询前 i+1 个字符匹配完毕且第 i 个字符落在 j 时的最高得分，from 记录上一个字符的位置
+	scores := make([][]int, m)
+	from := make([][]int, m)
+	for i := range scores {
+		scores[i] = make([]int, n)
+		from[i] = make([]int, n)
+		for j := range scores[i] {
+			scores[i][j] = fuzzyUnmatched
+			from[i][j] = -1
+		}
+	}
+	for j := 0; j < n; j++ {
+		if fuzzyEqual(q[0], runes[j], caseSensitive) {
+			scores[0][j] = fuzzyScoreMatch + bonus[j]
+		}
+	}
+	for i := 1; i < m; i++ {
+		// gap/gapFrom：在 j 之前至少间隔一个字符的最优前驱（已扣除间隔分）
+		gap, gapFrom := fuzzyUnmatched, -1
+		for j := 1; j < n; j++ {
+			if gap != fuzzyUnmatched {
+				gap += fuzzyGapExtension
+			}
+			if j >= 2 && scores[i-1][j-2] != fuzzyUnmatched && scores[i-1][j-2]+fuzzyGapStart > gap {
+				gap, gapFrom = scores[i-1][j-2]+fuzzyGapStart, j-2
+			}
+			if !fuzzyEqual(q[i], runes[j], caseSensitive) {
+				continue
+			}
+			best, bestFrom := gap, gapFrom
+			if prev := scores[i-1][j-1]; prev != fuzzyUnmatched && prev+fuzzyBonusConsecutive >= best {
+				best, bestFrom = prev+fuzzyBonusConsecutive, j-1
+			}
+			if best == fuzzyUnmatched {
+				continue
+			}
+			scores[i][j] = best + fuzzyScoreMatch + bonus[j]
+			from[i][j] = bestFrom
+		}
+	}
+
+	end := -1
+	for j := 0; j < n; j++ {
+		if scores[m-1][j] != fuzzyUnmatched && (end < 0 || scores[m-1][j] > scores[m-1][end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	score = scores[m-1][end]
+	positions = make([]int, m)
+	for i, j := m-1, end; i >= 0; i-- {
+		positions[i] = offsets[j]
+		j = from[i][j]
+	}
+	return score, positions, true
+}
+
+// fuzzyEqual 比较查询字符与目标字符，不区分大小写时查询字符已是小写
+func fuzzyEqual(q, r rune, caseSensitive bool) bool {
+	if caseSensitive {
+		return q == r
+	}
+	return q == unicode.ToLower(r)
+}
+
+// fuzzyBonus 返回位置 j 处字符的边界加分
+func fuzzyBonus(runes []rune, j int) int {
+	if j == 0 {
+		return fuzzyBonusSeparator
+	}
+	prev, cur := runes[j-1], runes[j]
+	switch {
+	case prev == '/':
+		return fuzzyBonusSeparator
+	case prev == '_' || prev == '-' || prev == '.' || prev == ' ':
+		return fuzzyBonusDelimiter
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return fuzzyBonusCamel
+	case unicode.IsLetter(prev) && unicode.IsDigit(cur):
+		return fuzzyBonusCamel
+	}
+	return 0
+}
+
+// mergePositions 合并两组匹配位置，结果有序且不重复
+func mergePositions(a, b []int) []int {
+	merged := append(append([]int(nil), a...), b...)
+	sort.Ints(merged)
+	out := merged[:0]
+	for i, p := range merged {
+		if i == 0 || p != merged[i-1] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/sjzsdu/tong/project"
+)
+
+// Match 一处内容匹配，行号与列号均从 1 开始，列号按字节计算
+type Match struct {
+	Path     string   `json:"path"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Text     string   `json:"text"`     // 匹配到的文本
+	LineText string   `json:"lineText"` // 匹配所在的整行（不含换行符）
+	Before   []string `json:"before,omitempty"`
+	After    []string `json:"after,omitempty"`
+}
+
+// SearchMatches 与 Search 使用相同的过滤条件，返回内容条件在每个文件中的逐行匹配（按路径、行、列排序）
+// 每处匹配带 BeforeContext/AfterContext 行上下文；同一行的多处匹配分别返回
+// 只有设置了 ContentContains 或 ContentRegex 时才有结果，二进制文件与跨行的正则匹配不返回行级结果
+func SearchMatches(ctx context.Context, root *project.Node, opts *SearchOptions) ([]Match, error) {
+	if opts == nil {
+		opts = DefaultSearchOptions()
+	}
+	if opts.ContentContains == "" && opts.ContentRegex == "" {
+		return nil, nil
+	}
+	nodes, err := Search(ctx, root, opts)
+	if err != nil {
+		return nil, err
+	}
+	return LineMatches(ctx, nodes, opts)
+}
+
+// LineMatches 在已由 Search 得到的节点中查找内容条件的逐行匹配，用于同时需要节点与行级结果的场景
+func LineMatches(ctx context.Context, nodes []*project.Node, opts *SearchOptions) ([]Match, error) {
+	re, err := lineMatcher(opts)
+	if err != nil || re == nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, n := range nodes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if n.IsDir || n.Kind().Binary {
+			continue
+		}
+		content, err := n.ReadContent()
+		if err != nil {
+			continue
+		}
+		limit := 0
+		if opts.MaxMatches > 0 {
+			limit = opts.MaxMatches - len(matches)
+		}
+		matches = append(matches, matchLines(n.Path, string(content), re, opts.BeforeContext, opts.AfterContext, limit)...)
+		if opts.MaxMatches > 0 && len(matches) >= opts.MaxMatches {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// lineMatcher 把内容条件编译为正则，子串条件按字面量匹配；未设置内容条件时返回 nil
+func lineMatcher(opts *SearchOptions) (*regexp.Regexp, error) {
+	pattern := opts.ContentRegex
+	if pattern == "" {
+		if opts.ContentContains == "" {
+			return nil, nil
+		}
+		pattern = regexp.QuoteMeta(opts.ContentContains)
+	}
+	if opts.CaseInsensitive && !strings.HasPrefix(pattern, "(?i)") {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// matchLines 在文本中逐行查找匹配，limit 大于 0 时最多返回 limit 处
+func matchLines(path, text string, re *regexp.Regexp, before, after, limit int) []Match {
+	lines := strings.Split(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	var matches []Match
+	for i, line := range lines {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			matches = append(matches, Match{
+				Path:     path,
+				Line:     i + 1,
+				Column:   loc[0] + 1,
+				Text:     line[loc[0]:loc[1]],
+				LineText: line,
+				Before:   contextLines(lines, i-before, i),
+				After:    contextLines(lines, i+1, i+1+after),
+			})
+			if limit > 0 && len(matches) >= limit {
+				return matches
+			}
+		}
+	}
+	return matches
+}
+
+// contextLines 返回 lines[from:to]，越界部分被截去
+func contextLines(lines []string, from, to int) []string {
+	from = max(from, 0)
+	to = min(to, len(lines))
+	if from >= to {
+		return nil
+	}
+	return append([]string(nil), lines[from:to]...)
+}
@@ -0,0 +1,131 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/diff"
+)
+
+// ReplaceOptions 定义替换选项，文件过滤条件沿用 SearchOptions
+type ReplaceOptions struct {
+	// 要查找的内容，Regex 为 false 时按字面量匹配
+	Pattern string
+	// 替换文本；正则模式下支持 $1、${name} 引用捕获组，字面量模式下原样写入
+	Replacement string
+	// Pattern 是否为正则表达式
+	Regex bool
+	// 预览差异的上下文行数
+	Context int
+}
+
+// FileReplacement 单个文件的替换结果
+type FileReplacement struct {
+	Path       string `json:"path"`
+	Count      int    `json:"count"` // 替换的处数
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Diff       string `json:"diff"` // 统一格式的差异块（不含文件头）
+	Old        []byte `json:"-"`
+	New        []byte `json:"-"`
+}
+
+// FileWriter 替换写入的目标，由 project.Project 与 project.Transaction 实现
+type FileWriter interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, content []byte) error
+}
+
+// PlanReplace 在 root 子树中按 opts 过滤文件，计算每个文件替换后的内容与差异，不修改任何文件
+// 只返回内容发生变化的文件（按路径排序），二进制文件被跳过
+func PlanReplace(ctx context.Context, root *project.Node, opts *SearchOptions, repl *ReplaceOptions) ([]FileReplacement, error) {
+	if repl == nil || repl.Pattern == "" {
+		return nil, fmt.Errorf("replace pattern is empty")
+	}
+	if opts == nil {
+		opts = DefaultSearchOptions()
+	}
+
+	// 内容条件由替换模式决定，只搜索文件
+	filter := *opts
+	filter.ContentContains = ""
+	filter.ContentRegex = replacePattern(repl)
+	filter.IncludeDirs = false
+	filter.IncludeFiles = true
+	filter.MatchAny = false
+	re, err := lineMatcher(&filter)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := Search(ctx, root, &filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []FileReplacement
+	for _, n := range nodes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if n.Kind().Binary {
+			continue
+		}
+		content, err := n.ReadContent()
+		if err != nil {
+			continue
+		}
+		count := len(re.FindAllIndex(content, -1))
+		if count == 0 {
+			continue
+		}
+		var updated []byte
+		if repl.Regex {
+			updated = re.ReplaceAll(content, []byte(repl.Replacement))
+		} else {
+			updated = re.ReplaceAllLiteral(content, []byte(repl.Replacement))
+		}
+		if bytes.Equal(content, updated) {
+			continue
+		}
+		hunks, ins, del := diff.Unified(content, updated, repl.Context)
+		plan = append(plan, FileReplacement{
+			Path:       n.Path,
+			Count:      count,
+			Insertions: ins,
+			Deletions:  del,
+			Diff:       hunks,
+			Old:        content,
+			New:        updated,
+		})
+	}
+	return plan, nil
+}
+
+// ApplyReplace 将 PlanReplace 的结果写入 w，返回成功写入的文件数
+// 写入前核对文件内容，自预览以来被修改过的文件会中止替换并返回错误，之前已写入的文件保持替换后的内容
+func ApplyReplace(w FileWriter, plan []FileReplacement) (int, error) {
+	for i, r := range plan {
+		current, err := w.ReadFile(r.Path)
+		if err != nil {
+			return i, err
+		}
+		if !bytes.Equal(current, r.Old) {
+			return i, fmt.Errorf("file changed since preview: %s", r.Path)
+		}
+		if err := w.WriteFile(r.Path, r.New); err != nil {
+			return i, err
+		}
+	}
+	return len(plan), nil
+}
+
+// replacePattern 返回替换模式对应的正则，字面量模式先转义
+func replacePattern(repl *ReplaceOptions) string {
+	if repl.Regex {
+		return repl.Pattern
+	}
+	return regexp.QuoteMeta(repl.Pattern)
+}
@@ -6,7 +6,10 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/sjzsdu/tong/helper/filekind"
 	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/index"
+	"github.com/sjzsdu/tong/project/query"
 )
 
 // SearchOptions 定义搜索选项
@@ -21,6 +24,10 @@ type SearchOptions struct {
 	ContentRegex string
 	// 仅对文件生效的扩展名过滤，如 []string{"go","md"}；为空或包含 "*" 表示不过滤
 	Extensions []string
+	// 按文件分类过滤（语言、生成代码、第三方依赖等），仅对文件生效；零值不过滤
+	Kinds filekind.Filter
+	// 节点查询条件（见 project/query），对文件与目录均生效；nil 不过滤
+	Where *query.Query
 	// 是否包含隐藏文件/目录（以 . 开头）
 	IncludeHidden bool
 	// 是否在结果中包含目录
@@ -35,6 +42,11 @@ type SearchOptions struct {
 	CaseInsensitive bool
 	// MatchAny 为 true 时，名称或内容任一匹配即算命中；为 false 时采用“与”逻辑
 	MatchAny bool
+	// 行级匹配（SearchMatches）前后附带的上下文行数
+	BeforeContext int
+	AfterContext  int
+	// SearchMatches 最多返回的匹配数，0 表示不限制
+	MaxMatches int
 }
 
 // DefaultSearchOptions 返回默认搜索选项
@@ -86,6 +98,15 @@ func Search(ctx context.Context, root *project.Node, opts *SearchOptions) ([]*pr
 	applyName := opts.NameRegex != "" || opts.NameContains != ""
 	applyContent := opts.ContentRegex != "" || opts.ContentContains != ""
 
+	// 已建立内容索引时，先用索引排除不可能满足内容条件的文件，索引未覆盖或已变化的文件照常读取
+	var ix *index.Index
+	var iq *index.Query
+	if applyContent {
+		if ix = index.For(root.GetProject()); ix != nil {
+			iq = contentQuery(opts, contentRe)
+		}
+	}
+
 	rootSeg := pathSegments(root.Path)
 
 	// 使用并发 BFS 遍历整个子树
@@ -114,9 +135,12 @@ func Search(ctx context.Context, root *project.Node, opts *SearchOptions) ([]*pr
 		if !n.IsDir && !allowByExt(n.Name, opts.Extensions) {
 			return nil, nil
 		}
+		if !n.MatchKind(opts.Kinds) || !opts.Where.Match(n) {
+			return nil, nil
+		}
 
 		nameOK := matchNodeName(n, opts, nameRe)
-		contentOK := matchNodeContent(n, opts, contentRe)
+		contentOK := ix.MayContain(n, iq) && matchNodeContent(n, opts, contentRe)
 
 		if opts.MatchAny {
 			matched := false
@@ -157,6 +181,14 @@ func Search(ctx context.Context, root *project.Node, opts *SearchOptions) ([]*pr
 	return matched, nil
 }
 
+// contentQuery 把内容条件转换为索引查询
+func contentQuery(opts *SearchOptions, re *regexp.Regexp) *index.Query {
+	if re != nil {
+		return index.RegexQuery(re.String())
+	}
+	return index.LiteralQuery(opts.ContentContains)
+}
+
 func matchNodeName(n *project.Node, opts *SearchOptions, re *regexp.Regexp) bool {
 	name := n.Name
 	if re != nil {
@@ -1,12 +1,21 @@
 package search
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/sjzsdu/tong/helper/filekind"
 	"github.com/sjzsdu/tong/project"
+	"github.com/sjzsdu/tong/project/index"
+	"github.com/sjzsdu/tong/project/query"
 )
 
 // 准备测试用的临时项目结构
@@ -217,3 +226,447 @@ func TestSearch_RegexAndCase(t *testing.T) {
 		t.Fatalf("expect /README.md matched by case-insensitive regex, got %v", paths)
 	}
 }
+
+func TestSearch_KindsFilter(t *testing.T) {
+	proj, cleanup := setupTestProject(t)
+	defer cleanup()
+	if err := proj.CreateFile("/src/api.pb.go", []byte("// Code generated by protoc-gen-go. DO NOT EDIT.\npackage src\n")); err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	root := getRootNode(t, proj)
+
+	opts := DefaultSearchOptions()
+	opts.Kinds = filekind.Filter{Languages: []string{"golang"}, SkipGenerated: true}
+	matched, err := Search(context.Background(), root, opts)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	paths := collectPaths(matched)
+	if len(paths) != 2 || !contains(paths, "/src/main.go") || !contains(paths, "/src/utils/helper.go") {
+		t.Fatalf("expect only hand-written go files, got %v", paths)
+	}
+}
+
+func TestSearch_Where(t *testing.T) {
+	proj, cleanup := setupTestProject(t)
+	defer cleanup()
+	root := getRootNode(t, proj)
+
+	where, err := query.Parse("path:src/** content:/println/i")
+	if err != nil {
+		t.Fatalf("parse query: %v", err)
+	}
+	opts := DefaultSearchOptions()
+	opts.Where = where
+	matched, err := Search(context.Background(), root, opts)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	paths := collectPaths(matched)
+	if len(paths) != 1 || paths[0] != "/src/main.go" {
+		t.Fatalf("expect only /src/main.go, got %v", paths)
+	}
+}
+
+func TestSearch_Index(t *testing.T) {
+	old := index.IndexDir
+	index.IndexDir = t.TempDir()
+	defer func() { index.IndexDir = old }()
+
+	proj, cleanup := setupTestProject(t)
+	defer cleanup()
+	root := getRootNode(t, proj)
+	if _, _, err := index.Build(context.Background(), proj, false); err != nil {
+		t.Fatalf("build index: %v", err)
+	}
+
+	search := func(opts *SearchOptions) []string {
+		t.Helper()
+		matched, err := Search(context.Background(), root, opts)
+		if err != nil {
+			t.Fatalf("search error: %v", err)
+		}
+		return collectPaths(matched)
+	}
+	opts := DefaultSearchOptions()
+	opts.ContentRegex = `func\s+Helper`
+	if got := search(opts); strings.Join(got, ",") != "/src/utils/helper.go" {
+		t.Fatalf("unexpected regex results with index: %v", got)
+	}
+	opts = DefaultSearchOptions()
+	opts.ContentContains = "hello, world"
+	opts.CaseInsensitive = true
+	if got := search(opts); strings.Join(got, ",") != "/src/main.go" {
+		t.Fatalf("unexpected case-insensitive results with index: %v", got)
+	}
+
+	// 建立索引后在磁盘上修改的文件照常读取
+	full := proj.GetAbsolutePath("docs/api.md")
+	if err := os.WriteFile(full, []byte("# API\nHello, World!\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(full, later, later)
+	if node, err := proj.FindNode("/docs/api.md"); err == nil {
+		node.UnloadContent()
+	}
+	if got := search(opts); strings.Join(got, ",") != "/docs/api.md,/src/main.go" {
+		t.Fatalf("changed file should be searched directly: %v", got)
+	}
+}
+
+func TestSearchMatches(t *testing.T) {
+	proj, cleanup := setupTestProject(t)
+	defer cleanup()
+	if err := proj.CreateFile("/notes.txt", []byte("alpha\nTODO one\r\nbeta\ngamma TODO todo\ndelta\n")); err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	root := getRootNode(t, proj)
+
+	// 子串匹配，带前后上下文
+	opts := DefaultSearchOptions()
+	opts.ContentContains = "TODO"
+	opts.BeforeContext = 1
+	opts.AfterContext = 1
+	matches, err := SearchMatches(context.Background(), root, opts)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expect 2 matches, got %+v", matches)
+	}
+	m := matches[0]
+	if m.Path != "/notes.txt" || m.Line != 2 || m.Column != 1 || m.Text != "TODO" || m.LineText != "TODO one" {
+		t.Fatalf("unexpected first match: %+v", m)
+	}
+	if len(m.Before) != 1 || m.Before[0] != "alpha" || len(m.After) != 1 || m.After[0] != "beta" {
+		t.Fatalf("unexpected context: %+v", m)
+	}
+	if matches[1].Line != 4 || matches[1].Column != 7 {
+		t.Fatalf("unexpected second match: %+v", matches[1])
+	}
+
+	// 大小写不敏感时同一行的多处匹配分别返回
+	opts.CaseInsensitive = true
+	opts.AfterContext = 5
+	matches, err = SearchMatches(context.Background(), root, opts)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(matches) != 3 || matches[2].Line != 4 || matches[2].Column != 12 || matches[2].Text != "todo" {
+		t.Fatalf("unexpected case-insensitive matches: %+v", matches)
+	}
+	if len(matches[2].After) != 1 || matches[2].After[0] != "delta" {
+		t.Fatalf("after context should stop at end of file: %+v", matches[2].After)
+	}
+
+	// 正则匹配跨文件按路径排序，并受 MaxMatches 限制
+	opts = DefaultSearchOptions()
+	opts.ContentRegex = `Hello|func \w+`
+	matches, err = SearchMatches(context.Background(), root, opts)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	var got []string
+	for _, m := range matches {
+		got = append(got, fmt.Sprintf("%s:%d:%d:%s", m.Path, m.Line, m.Column, m.Text))
+	}
+	want := []string{"/src/main.go:5:1:func main", "/src/main.go:6:15:Hello", "/src/utils/helper.go:3:1:func Helper"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected regex matches: %v", got)
+	}
+	opts.MaxMatches = 2
+	matches, err = SearchMatches(context.Background(), root, opts)
+	if err != nil || len(matches) != 2 {
+		t.Fatalf("expect 2 limited matches, got %d (%v)", len(matches), err)
+	}
+
+	// 没有内容条件时没有行级结果
+	opts = DefaultSearchOptions()
+	opts.NameContains = "main"
+	matches, err = SearchMatches(context.Background(), root, opts)
+	if err != nil || len(matches) != 0 {
+		t.Fatalf("expect no matches without content condition, got %v (%v)", matches, err)
+	}
+}
+
+func TestPlanApplyReplace(t *testing.T) {
+	proj, cleanup := setupTestProject(t)
+	defer cleanup()
+	root := getRootNode(t, proj)
+
+	// 字面量模式：替换文本中的 $ 原样写入
+	opts := DefaultSearchOptions()
+	opts.Extensions = []string{"go"}
+	plan, err := PlanReplace(context.Background(), root, opts, &ReplaceOptions{Pattern: "fmt.Println", Replacement: "log.$1Print", Context: 1})
+	if err != nil {
+		t.Fatalf("plan error: %v", err)
+	}
+	if len(plan) != 1 || plan[0].Path != "/src/main.go" || plan[0].Count != 1 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if !strings.Contains(plan[0].Diff, "-\tfmt.Println(\"Hello, World!\")\n+\tlog.$1Print(\"Hello, World!\")") {
+		t.Fatalf("unexpected diff:\n%s", plan[0].Diff)
+	}
+	if plan[0].Insertions != 1 || plan[0].Deletions != 1 {
+		t.Fatalf("unexpected diff stat: %+v", plan[0])
+	}
+
+	// 正则模式支持捕获组，README.md 不在扩展名过滤范围内
+	plan, err = PlanReplace(context.Background(), root, opts, &ReplaceOptions{Pattern: `package (\w+)`, Replacement: "package ${1}_v2", Regex: true})
+	if err != nil {
+		t.Fatalf("plan error: %v", err)
+	}
+	if got := collectReplacePaths(plan); strings.Join(got, ",") != "/src/main.go,/src/utils/helper.go" {
+		t.Fatalf("unexpected files: %v", got)
+	}
+	content, _ := proj.ReadFile("/src/main.go")
+	if !strings.HasPrefix(string(content), "package main\n") {
+		t.Fatalf("plan must not modify files: %q", content)
+	}
+
+	n, err := ApplyReplace(proj, plan)
+	if err != nil || n != 2 {
+		t.Fatalf("apply: n=%d err=%v", n, err)
+	}
+	content, _ = os.ReadFile(proj.GetAbsolutePath("src/utils/helper.go"))
+	if !strings.HasPrefix(string(content), "package utils_v2\n") {
+		t.Fatalf("unexpected content on disk: %q", content)
+	}
+
+	// 预览之后被修改的文件拒绝写入
+	plan, err = PlanReplace(context.Background(), root, opts, &ReplaceOptions{Pattern: "_v2", Replacement: ""})
+	if err != nil || len(plan) != 2 {
+		t.Fatalf("plan: %+v err=%v", plan, err)
+	}
+	if err := proj.WriteFile("/src/main.go", []byte("package other\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := ApplyReplace(proj, plan); err == nil || !strings.Contains(err.Error(), "changed since preview") {
+		t.Fatalf("expect conflict error, got %v", err)
+	}
+
+	if _, err := PlanReplace(context.Background(), root, opts, &ReplaceOptions{Pattern: "(", Regex: true}); err == nil {
+		t.Fatalf("expect invalid regex error")
+	}
+	if _, err := PlanReplace(context.Background(), root, opts, &ReplaceOptions{}); err == nil {
+		t.Fatalf("expect empty pattern error")
+	}
+}
+
+func collectReplacePaths(plan []FileReplacement) []string {
+	paths := make([]string, 0, len(plan))
+	for _, r := range plan {
+		paths = append(paths, r.Path)
+	}
+	return paths
+}
+
+func TestFuzzyScore(t *testing.T) {
+	_, positions, ok := FuzzyScore("srchgo", "project/search/search.go")
+	if !ok || len(positions) != 6 {
+		t.Fatalf("expect match, got %v %v", ok, positions)
+	}
+	// 文件名中的匹配优先于目录名
+	if positions[0] != len("project/search/") {
+		t.Fatalf("expect match in file name, got %v", positions)
+	}
+	if _, _, ok := FuzzyScore("xyz", "project/search/search.go"); ok {
+		t.Fatalf("non-subsequence should not match")
+	}
+
+	// 边界与驼峰位置得分更高
+	boundary, _, _ := FuzzyScore("fb", "foo_bar.go")
+	inner, _, _ := FuzzyScore("fb", "afoobx.go")
+	if boundary <= inner {
+		t.Fatalf("expect boundary match %d > inner match %d", boundary, inner)
+	}
+	camel, _, _ := FuzzyScore("ps", "ProjectSearch.go")
+	if camel <= inner {
+		t.Fatalf("expect camelCase match %d > inner match %d", camel, inner)
+	}
+	consecutive, _, _ := FuzzyScore("main", "src/main.go")
+	scattered, _, _ := FuzzyScore("main", "src/mxaxixn.go")
+	if consecutive <= scattered {
+		t.Fatalf("expect consecutive match %d > scattered match %d", consecutive, scattered)
+	}
+
+	// 查询含大写字母时区分大小写
+	if _, _, ok := FuzzyScore("Main", "src/main.go"); ok {
+		t.Fatalf("smart case: uppercase query should be case sensitive")
+	}
+	if _, _, ok := FuzzyScore("readme", "README.md"); !ok {
+		t.Fatalf("lowercase query should be case insensitive")
+	}
+}
+
+func TestFuzzyFind(t *testing.T) {
+	proj, cleanup := setupTestProject(t)
+	defer cleanup()
+	root := getRootNode(t, proj)
+
+	results, err := FuzzyFind(context.Background(), root, "hlp", nil, 0)
+	if err != nil {
+		t.Fatalf("fuzzy find error: %v", err)
+	}
+	if len(results) == 0 || results[0].Path != "src/utils/helper.go" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	// 多个词都要匹配，limit 截断结果
+	results, err = FuzzyFind(context.Background(), root, "md", nil, 2)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("expect 2 results, got %+v err=%v", results, err)
+	}
+	results, err = FuzzyFind(context.Background(), root, "docs gui", nil, 0)
+	if err != nil || len(results) != 1 || results[0].Path != "docs/guide.md" {
+		t.Fatalf("unexpected multi-term results: %+v err=%v", results, err)
+	}
+
+	// 沿用搜索过滤条件
+	opts := DefaultSearchOptions()
+	opts.Extensions = []string{"json"}
+	results, err = FuzzyFind(context.Background(), root, "c", opts, 0)
+	if err != nil || len(results) != 1 || results[0].Path != "config.json" {
+		t.Fatalf("unexpected filtered results: %+v err=%v", results, err)
+	}
+}
+
+func TestWriteRecords(t *testing.T) {
+	proj, cleanup := setupTestProject(t)
+	defer cleanup()
+	if err := proj.CreateFile("/notes.txt", []byte("héllo TODO <a&b>\nnothing\n")); err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	root := getRootNode(t, proj)
+
+	opts := DefaultSearchOptions()
+	opts.ContentRegex = `TODO|Hello`
+	matches, err := SearchMatches(context.Background(), root, opts)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	records := MatchRecords(matches)
+
+	// JSON：解析后与原记录一致，且不转义 HTML 字符
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, FormatJSON, records); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<a&b>") {
+		t.Fatalf("json should not escape html: %s", buf.String())
+	}
+	var report Report
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("parse json: %v", err)
+	}
+	if report.Files != 2 || report.Matches != 2 || !reflect.DeepEqual(report.Results, records) {
+		t.Fatalf("json round trip mismatch: %+v", report)
+	}
+	if report.Results[0].Path != "notes.txt" || report.Results[0].Column != 8 || report.Results[0].LineText != "héllo TODO <a&b>" {
+		t.Fatalf("unexpected first record: %+v", report.Results[0])
+	}
+
+	// JSONL：每行一条记录
+	buf.Reset()
+	if err := WriteRecords(&buf, FormatJSONL, records); err != nil {
+		t.Fatalf("write jsonl: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expect 2 jsonl lines, got %q", buf.String())
+	}
+	for i, line := range lines {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("parse jsonl line %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(r, records[i]) {
+			t.Fatalf("jsonl round trip mismatch: %+v", r)
+		}
+	}
+
+	// vimgrep：路径:行:列:整行，文件记录定位到 1:1
+	buf.Reset()
+	files := NodeRecords([]*project.Node{root.GetProject().Root()})
+	files = append(files, FuzzyRecords([]FuzzyMatch{{Path: "docs/api.md", Score: 42}})...)
+	if err := WriteRecords(&buf, FormatVimgrep, append(records, files...)); err != nil {
+		t.Fatalf("write vimgrep: %v", err)
+	}
+	want := "notes.txt:1:8:héllo TODO <a&b>\nsrc/main.go:6:15:\tfmt.Println(\"Hello, World!\")\n.:1:1:\ndocs/api.md:1:1:\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected vimgrep output:\n%s", buf.String())
+	}
+	if files[0].Type != RecordDir || files[1].Score != 42 {
+		t.Fatalf("unexpected node records: %+v", files)
+	}
+
+	// SARIF：解析后检查规则、位置与按码点计算的列号，URI 中的特殊字符逐段编码
+	buf.Reset()
+	escaped := FuzzyRecords([]FuzzyMatch{{Path: "docs/my notes #1%/文档.md"}})
+	if err := WriteRecords(&buf, FormatSARIF, append(append(records, files[1]), escaped...)); err != nil {
+		t.Fatalf("write sarif: %v", err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			ColumnKind string `json:"columnKind"`
+			Results    []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+							EndColumn   int `json:"endColumn"`
+							Snippet     struct {
+								Text string `json:"text"`
+							} `json:"snippet"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("parse sarif: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "tong" || len(log.Runs[0].Tool.Driver.Rules) != 2 {
+		t.Fatalf("unexpected sarif header: %+v", log)
+	}
+	run := log.Runs[0]
+	if run.ColumnKind != "unicodeCodePoints" || len(run.Results) != 4 {
+		t.Fatalf("unexpected sarif run: %+v", run)
+	}
+	first := run.Results[0].Locations[0].PhysicalLocation
+	if run.Results[0].RuleID != "search-match" || first.ArtifactLocation.URI != "notes.txt" || first.Region == nil ||
+		first.Region.StartLine != 1 || first.Region.StartColumn != 7 || first.Region.EndColumn != 11 || first.Region.Snippet.Text != "héllo TODO <a&b>" {
+		t.Fatalf("unexpected sarif match result: %+v", run.Results[0])
+	}
+	last := run.Results[2]
+	if last.RuleID != "search-file" || last.Locations[0].PhysicalLocation.ArtifactLocation.URI != "docs/api.md" || last.Locations[0].PhysicalLocation.Region != nil {
+		t.Fatalf("unexpected sarif file result: %+v", last)
+	}
+	if uri := run.Results[3].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "docs/my%20notes%20%231%25/%E6%96%87%E6%A1%A3.md" {
+		t.Fatalf("unexpected escaped sarif uri: %s", uri)
+	}
+
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatalf("unknown format should fail")
+	}
+	if f, err := ParseFormat(" SARIF "); err != nil || f != FormatSARIF {
+		t.Fatalf("parse format: %v %v", f, err)
+	}
+}
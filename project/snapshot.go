@@ -0,0 +1,502 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/sjzsdu/tong/share"
+)
+
+// 项目树快照缓存：将项目树持久化到 ~/.tong/snapshots，
+// 后续构建时从快照恢复，只重新读取修改时间发生变化的目录
+
+// SnapshotDir 快照文件存放目录，测试时可替换
+var SnapshotDir = helper.GetPath("snapshots")
+
+// 快照格式版本，结构变化时递增以使旧快照失效
+const snapshotVersion = 2
+
+// errGitignoreChanged 忽略文件变化会影响整棵树的过滤结果，需要完整重建
+var errGitignoreChanged = errors.New("gitignore changed since snapshot")
+
+// projectSnapshot 快照文件内容
+type projectSnapshot struct {
+	Version  int                       `json:"version"`
+	RootPath string                    `json:"rootPath"`
+	Options  helper.WalkDirOptions     `json:"options"`
+	Ignores  map[string]snapshotIgnore `json:"ignores,omitempty"` // 忽略文件绝对路径 -> 文件状态与规则
+	Nodes    []snapshotNode            `json:"nodes"`
+}
+
+// snapshotNode 快照中的单个节点
+type snapshotNode struct {
+	Path        string      `json:"path"`
+	IsDir       bool        `json:"isDir,omitempty"`
+	Size        int64       `json:"size"`
+	Mode        os.FileMode `json:"mode"`
+	ModTime     time.Time   `json:"modTime"`
+	Hash        string      `json:"hash,omitempty"`
+	HashSize    int64       `json:"hashSize,omitempty"`
+	HashModTime time.Time   `json:"hashModTime"`
+}
+
+// snapshotIgnore 快照中记录的忽略文件（.gitignore、.tongignore 与全局排除文件）
+type snapshotIgnore struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Rules   []string  `json:"rules,omitempty"`
+}
+
+// fileStamp 用于判断磁盘文件是否变化的大小与修改时间
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// stampOf 从文件信息生成 fileStamp
+func stampOf(info os.FileInfo) fileStamp {
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}
+}
+
+// equal 比较两个 fileStamp（时间使用 Equal 比较，忽略时区与单调时钟）
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.size == other.size && s.modTime.Equal(other.modTime)
+}
+
+// fileInfo 将快照节点转换为 os.FileInfo
+func (n *snapshotNode) fileInfo() os.FileInfo {
+	return &memFileInfo{
+		name:    filepath.Base(n.Path),
+		size:    n.Size,
+		mode:    n.Mode,
+		modTime: n.ModTime,
+	}
+}
+
+// BuildProjectTreeCached 与 BuildProjectTree 相同，但优先从快照恢复项目树，
+// 只重新读取修改时间发生变化的目录；快照不存在或已失效时完整构建并写入快照
+func BuildProjectTreeCached(targetPath string, options helper.WalkDirOptions) (*Project, error) {
+	// 立即加载内容时所有文件都要读取，快照无法带来收益
+	if options.LoadContent {
+		return BuildProjectTree(targetPath, options)
+	}
+
+	key := snapshotKey(targetPath, options)
+	if snap, err := loadSnapshot(key); err == nil {
+		doc, err := restoreSnapshot(targetPath, options, snap)
+		if err == nil {
+			doc.snapshotKey = key
+			doc.saveSnapshotQuietly()
+			return doc, nil
+		}
+		if share.GetDebug() {
+			log.Printf("项目快照已失效，重新构建: %v", err)
+		}
+	}
+
+	doc, err := BuildProjectTree(targetPath, options)
+	if err != nil {
+		return nil, err
+	}
+	doc.snapshotKey = key
+	doc.snapshotDirty.Store(true)
+	doc.saveSnapshotQuietly()
+	return doc, nil
+}
+
+// SaveSnapshot 将项目树（包括已计算的文件哈希）写入快照缓存
+// 仅对 BuildProjectTreeCached 构建且自上次保存后有变化的项目生效
+func (p *Project) SaveSnapshot() error {
+	if p.snapshotKey == "" || !p.snapshotDirty.Load() {
+		return nil
+	}
+
+	data, err := json.Marshal(p.takeSnapshot())
+	if err != nil {
+		return fmt.Errorf("序列化项目快照失败: %w", err)
+	}
+
+	if err := os.MkdirAll(SnapshotDir, 0755); err != nil {
+		return fmt.Errorf("创建快照目录失败: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免并发运行时读到不完整的快照
+	tmp, err := os.CreateTemp(SnapshotDir, p.snapshotKey+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("写入项目快照失败: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入项目快照失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入项目快照失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), snapshotFile(p.snapshotKey)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入项目快照失败: %w", err)
+	}
+
+	p.snapshotDirty.Store(false)
+	return nil
+}
+
+// saveSnapshotQuietly 保存快照，失败时只在调试模式下输出日志
+func (p *Project) saveSnapshotQuietly() {
+	if err := p.SaveSnapshot(); err != nil && share.GetDebug() {
+		log.Printf("%v", err)
+	}
+}
+
+// snapshotKey 根据根路径与构建选项生成快照文件名
+func snapshotKey(targetPath string, options helper.WalkDirOptions) string {
+	absPath, err := filepath.Abs(targetPath)
+	if err != nil {
+		absPath = filepath.Clean(targetPath)
+	}
+	opts, _ := json.Marshal(options)
+	sum := sha256.Sum256(append([]byte(absPath+"\x00"), opts...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// snapshotFile 返回快照文件路径
+func snapshotFile(key string) string {
+	return filepath.Join(SnapshotDir, key+".json")
+}
+
+// loadSnapshot 读取快照文件
+func loadSnapshot(key string) (*projectSnapshot, error) {
+	data, err := os.ReadFile(snapshotFile(key))
+	if err != nil {
+		return nil, err
+	}
+	var snap projectSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version: %d", snap.Version)
+	}
+	return &snap, nil
+}
+
+// takeSnapshot 生成当前项目树的快照
+func (p *Project) takeSnapshot() *projectSnapshot {
+	p.mu.RLock()
+	nodes := make([]*Node, 0, len(p.nodes))
+	for _, node := range p.nodes {
+		nodes = append(nodes, node)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Path < nodes[j].Path
+	})
+
+	rootPath, _ := filepath.Abs(p.rootPath)
+	snap := &projectSnapshot{
+		Version:  snapshotVersion,
+		RootPath: rootPath,
+		Nodes:    make([]snapshotNode, 0, len(nodes)),
+	}
+
+	for _, node := range nodes {
+		node.mu.RLock()
+		entry := snapshotNode{Path: node.Path, IsDir: node.IsDir}
+		info := node.Info
+		if node == p.root {
+			// 根节点没有保存文件信息，记录根目录的修改时间
+			info, _ = os.Stat(p.rootPath)
+		}
+		if info != nil {
+			entry.Size = info.Size()
+			entry.Mode = info.Mode()
+			entry.ModTime = info.ModTime()
+		}
+		if node.hash != "" && !node.modified {
+			entry.Hash = node.hash
+			entry.HashSize = node.hashStamp.size
+			entry.HashModTime = node.hashStamp.modTime
+		}
+		node.mu.RUnlock()
+		snap.Nodes = append(snap.Nodes, entry)
+	}
+
+	if p.filter != nil {
+		snap.Options = p.filter.options
+		snap.Ignores = p.filter.snapshotIgnores()
+	}
+	return snap
+}
+
+// snapshotIgnores 记录全局排除文件与已读取过的目录中存在的忽略文件
+func (f *pathFilter) snapshotIgnores() map[string]snapshotIgnore {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	files := append([]string{}, f.globalFiles...)
+	for dir := range f.loadedDirs {
+		for _, name := range f.ignoreNames {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+
+	ignores := make(map[string]snapshotIgnore)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		absFile, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		ignores[absFile] = snapshotIgnore{
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			Rules:   f.ignoreRules[file],
+		}
+	}
+	return ignores
+}
+
+// snapshotRestorer 从快照恢复项目树
+type snapshotRestorer struct {
+	doc      *Project
+	filter   *pathFilter
+	snap     *projectSnapshot
+	entries  map[string]*snapshotNode
+	children map[string][]*snapshotNode
+}
+
+// restoreSnapshot 根据快照恢复项目树，修改时间变化的目录会被重新读取
+func restoreSnapshot(targetPath string, options helper.WalkDirOptions, snap *projectSnapshot) (*Project, error) {
+	absPath, err := filepath.Abs(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	if snap.RootPath != absPath {
+		return nil, fmt.Errorf("snapshot root mismatch: %s", snap.RootPath)
+	}
+
+	doc := NewProject(targetPath)
+	doc.filter = newPathFilter(filepath.Clean(targetPath), options)
+
+	r := &snapshotRestorer{
+		doc:      doc,
+		filter:   doc.filter,
+		snap:     snap,
+		entries:  make(map[string]*snapshotNode, len(snap.Nodes)),
+		children: make(map[string][]*snapshotNode),
+	}
+	for i := range snap.Nodes {
+		entry := &snap.Nodes[i]
+		r.entries[entry.Path] = entry
+		if entry.Path != "/" {
+			parent := filepath.ToSlash(filepath.Dir(entry.Path))
+			r.children[parent] = append(r.children[parent], entry)
+		}
+	}
+
+	err = r.restore()
+	if err != nil {
+		UnregisterProject(doc.root)
+		return nil, err
+	}
+	return doc, nil
+}
+
+// restore 校验 .gitignore 后从根目录开始恢复
+func (r *snapshotRestorer) restore() error {
+	root, ok := r.entries["/"]
+	if !ok {
+		return errors.New("snapshot has no root entry")
+	}
+	if err := r.restoreIgnores(); err != nil {
+		return err
+	}
+	return r.restoreDir(root)
+}
+
+// restoreIgnores 校验快照中的忽略文件是否仍然一致，并将目录级规则装入过滤器
+func (r *snapshotRestorer) restoreIgnores() error {
+	for file, ignore := range r.snap.Ignores {
+		info, err := os.Stat(file)
+		if err != nil || !stampOf(info).equal(fileStamp{size: ignore.Size, modTime: ignore.ModTime}) {
+			return errGitignoreChanged
+		}
+	}
+	// 快照之后新建的全局排除文件
+	globals := make(map[string]bool)
+	for _, file := range r.filter.globalFiles {
+		absFile, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		globals[absFile] = true
+		if _, ok := r.snap.Ignores[absFile]; ok {
+			continue
+		}
+		if _, err := os.Stat(absFile); err == nil {
+			return errGitignoreChanged
+		}
+	}
+
+	r.filter.mu.Lock()
+	defer r.filter.mu.Unlock()
+
+	dirs := make(map[string]bool)
+	for file, ignore := range r.snap.Ignores {
+		rel, err := filepath.Rel(r.snap.RootPath, file)
+		if globals[file] || err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		file = filepath.Join(r.filter.rootPath, rel)
+		dir := filepath.Dir(file)
+		dirs[dir] = true
+		r.filter.loadedDirs[dir] = true
+		if len(ignore.Rules) > 0 {
+			r.filter.ignoreRules[file] = ignore.Rules
+		}
+	}
+	for dir := range dirs {
+		r.filter.compileDir(dir)
+	}
+	return nil
+}
+
+// restoreDir 恢复目录节点；目录修改时间未变时直接使用快照中的子节点，否则重新读取该目录
+func (r *snapshotRestorer) restoreDir(entry *snapshotNode) error {
+	dir := r.absPath(entry.Path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		if entry.Path != "/" && os.IsNotExist(err) {
+			r.doc.snapshotDirty.Store(true)
+			return nil
+		}
+		return err
+	}
+
+	if entry.Path != "/" {
+		r.doc.mu.Lock()
+		err = r.doc.createDirInternal(entry.Path, info)
+		r.doc.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	// 目录下的忽略文件已由 restoreIgnores 装入，这里只标记为已读取
+	r.filter.mu.Lock()
+	r.filter.loadedDirs[dir] = true
+	r.filter.mu.Unlock()
+
+	if !info.ModTime().Equal(entry.ModTime) {
+		r.doc.snapshotDirty.Store(true)
+		return r.rereadDir(entry, dir)
+	}
+
+	for _, child := range r.children[entry.Path] {
+		if child.IsDir {
+			if err := r.restoreDir(child); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.addFile(child.Path, child.fileInfo(), child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rereadDir 重新读取目录内容，新出现的子目录完整遍历，已有子目录继续按快照恢复
+func (r *snapshotRestorer) rereadDir(entry *snapshotNode, dir string) error {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, dirEntry := range dirEntries {
+		path := filepath.Join(dir, dirEntry.Name())
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+
+		// 新增的忽略文件会改变整棵子树的过滤结果
+		if r.filter.isIgnoreFile(dirEntry.Name()) {
+			if _, ok := r.snap.Ignores[filepath.Join(r.snap.RootPath, entry.Path, dirEntry.Name())]; !ok {
+				return errGitignoreChanged
+			}
+		}
+
+		skip, skipDir, err := r.filter.skip(path, info)
+		if err != nil {
+			return err
+		}
+		if skip || skipDir {
+			continue
+		}
+
+		projPath := entry.Path + "/" + dirEntry.Name()
+		if entry.Path == "/" {
+			projPath = "/" + dirEntry.Name()
+		}
+
+		old, known := r.entries[projPath]
+		if info.IsDir() {
+			if known && old.IsDir {
+				err = r.restoreDir(old)
+			} else {
+				_, err = r.doc.walkDisk(path, r.filter, false)
+			}
+		} else {
+			if known && old.IsDir {
+				old = nil
+			}
+			err = r.addFile(projPath, info, old)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFile 添加文件节点，并沿用快照中仍可能有效的哈希（使用时会再次校验）
+func (r *snapshotRestorer) addFile(path string, info os.FileInfo, old *snapshotNode) error {
+	r.doc.mu.Lock()
+	defer r.doc.mu.Unlock()
+
+	if err := r.doc.createFileNodeInternal(path, info); err != nil {
+		return err
+	}
+	if old != nil && old.Hash != "" {
+		if node, ok := r.doc.nodes[path]; ok {
+			node.hash = old.Hash
+			node.hashStamp = fileStamp{size: old.HashSize, modTime: old.HashModTime}
+		}
+	}
+	return nil
+}
+
+// absPath 将项目路径转换为磁盘路径
+func (r *snapshotRestorer) absPath(projPath string) string {
+	if projPath == "/" {
+		return r.filter.rootPath
+	}
+	return filepath.Join(r.filter.rootPath, filepath.FromSlash(projPath[1:]))
+}
@@ -0,0 +1,155 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTempSnapshotDir 将快照目录替换为临时目录
+func useTempSnapshotDir(t *testing.T) {
+	t.Helper()
+	old := SnapshotDir
+	SnapshotDir = t.TempDir()
+	t.Cleanup(func() { SnapshotDir = old })
+}
+
+// projectPaths 返回项目中除根节点外的所有路径
+func projectPaths(p *Project) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	paths := make([]string, 0, len(p.nodes))
+	for path := range p.nodes {
+		if path != "/" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// touchDir 推进目录的修改时间，避免文件系统时间精度导致变化不可见
+func touchDir(t *testing.T, dir string) {
+	t.Helper()
+	future := time.Now().Add(2 * time.Second)
+	require.NoError(t, os.Chtimes(dir, future, future))
+}
+
+func TestBuildProjectTreeCached(t *testing.T) {
+	useTempSnapshotDir(t)
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "src", "pkg"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("*.log\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "main.go"), []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "pkg", "a.go"), []byte("package pkg"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "debug.log"), []byte("log"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "docs", "README.md"), []byte("# docs"), 0644))
+
+	options := helper.WalkDirOptions{}
+
+	t.Run("FirstBuildWritesSnapshot", func(t *testing.T) {
+		proj, err := BuildProjectTreeCached(tempDir, options)
+		require.NoError(t, err)
+		assert.FileExists(t, snapshotFile(snapshotKey(tempDir, options)))
+
+		expected, err := BuildProjectTree(tempDir, options)
+		require.NoError(t, err)
+		assert.Equal(t, projectPaths(expected), projectPaths(proj))
+	})
+
+	t.Run("RestoreUnchanged", func(t *testing.T) {
+		proj, err := BuildProjectTreeCached(tempDir, options)
+		require.NoError(t, err)
+		assert.NotContains(t, projectPaths(proj), "/src/debug.log")
+
+		content, err := proj.ReadFile("/src/main.go")
+		assert.NoError(t, err)
+		assert.Equal(t, "package main", string(content))
+	})
+
+	t.Run("RereadChangedDirectories", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "pkg", "b.go"), []byte("package pkg"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "pkg", "trace.log"), []byte("log"), 0644))
+		touchDir(t, filepath.Join(tempDir, "src", "pkg"))
+		require.NoError(t, os.RemoveAll(filepath.Join(tempDir, "docs")))
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "api"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "api", "api.go"), []byte("package api"), 0644))
+		touchDir(t, tempDir)
+
+		proj, err := BuildProjectTreeCached(tempDir, options)
+		require.NoError(t, err)
+
+		expected, err := BuildProjectTree(tempDir, options)
+		require.NoError(t, err)
+		assert.Equal(t, projectPaths(expected), projectPaths(proj))
+		assert.Contains(t, projectPaths(proj), "/src/pkg/b.go")
+		assert.Contains(t, projectPaths(proj), "/api/api.go")
+		assert.NotContains(t, projectPaths(proj), "/docs")
+	})
+
+	t.Run("GitignoreChangeRebuilds", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("*.log\napi/\n"), 0644))
+
+		proj, err := BuildProjectTreeCached(tempDir, options)
+		require.NoError(t, err)
+		assert.NotContains(t, projectPaths(proj), "/api")
+		assert.NotContains(t, projectPaths(proj), "/api/api.go")
+	})
+
+	t.Run("NoSnapshotForOtherOptions", func(t *testing.T) {
+		other := helper.WalkDirOptions{Extensions: []string{"md"}}
+		assert.NotEqual(t, snapshotKey(tempDir, options), snapshotKey(tempDir, other))
+	})
+}
+
+func TestSnapshotHashCache(t *testing.T) {
+	useTempSnapshotDir(t)
+
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "file.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("v1"), 0644))
+
+	proj, err := BuildProjectTreeCached(tempDir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+
+	node, err := proj.FindNode("/file.txt")
+	require.NoError(t, err)
+	hash1, err := node.CalculateHash()
+	require.NoError(t, err)
+	require.NoError(t, proj.SaveSnapshot())
+
+	// 从快照恢复后，哈希直接来自缓存
+	restored, err := BuildProjectTreeCached(tempDir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+	node, err = restored.FindNode("/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, hash1, node.hash)
+	assert.False(t, node.ContentLoaded)
+
+	hash, err := node.CalculateHash()
+	require.NoError(t, err)
+	assert.Equal(t, hash1, hash)
+	assert.False(t, node.ContentLoaded, "缓存命中时不应读取文件内容")
+
+	// 磁盘文件变化后缓存失效
+	require.NoError(t, os.WriteFile(filePath, []byte("version 2"), 0644))
+	hash2, err := node.CalculateHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash1, hash2)
+
+	// 通过项目写入后缓存被清除
+	require.NoError(t, restored.WriteFile("/file.txt", []byte("v3")))
+	assert.Empty(t, node.hash)
+	hash3, err := node.CalculateHash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash2, hash3)
+}
@@ -0,0 +1,759 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 事务相关方法：在写时复制的覆盖层上批量编辑，提交时一次性落盘
+
+// ErrTxDone 事务已提交或已回滚
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// TxOp 事务中的变更类型
+type TxOp string
+
+const (
+	TxWrite  TxOp = "write"
+	TxMkdir  TxOp = "mkdir"
+	TxDelete TxOp = "delete"
+)
+
+// TxChange 描述事务中的一项待提交变更
+type TxChange struct {
+	Op   TxOp   `json:"op"`
+	Path string `json:"path"`
+}
+
+// txEntry 覆盖层中的节点状态
+type txEntry struct {
+	node    *Node       // nil 表示节点被删除
+	replace bool        // 覆盖了同一路径上已删除的节点，底层项目中的原有子树不再可见
+	mode    os.FileMode // 移动或复制而来的文件沿用源文件的权限，0 表示未指定
+}
+
+// Transaction 项目上的批量编辑事务
+// 所有修改先写入覆盖层，项目树与磁盘在 Commit 之前保持不变
+type Transaction struct {
+	p       *Project
+	mu      sync.Mutex
+	overlay map[string]*txEntry
+	done    bool
+}
+
+// Begin 开始一个新事务
+func (p *Project) Begin() *Transaction {
+	return &Transaction{
+		p:       p,
+		overlay: make(map[string]*txEntry),
+	}
+}
+
+// Project 返回事务所属的项目
+func (tx *Transaction) Project() *Project {
+	return tx.p
+}
+
+// NormalizePath 标准化路径，与 Project.NormalizePath 相同
+func (tx *Transaction) NormalizePath(path string) string {
+	return tx.p.NormalizePath(path)
+}
+
+// FindNode 在事务视图中查找节点
+// 事务中新建或修改的节点是独立的副本，不挂载在项目树上
+func (tx *Transaction) FindNode(path string) (*Node, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	node, ok := tx.lookup(tx.p.NormalizePath(path))
+	if !ok {
+		return nil, errors.New("node not found: " + path)
+	}
+	return node, nil
+}
+
+// View 返回事务视图中 path 处子树的独立副本，目录的子节点按事务视图重新组织，用于遍历与搜索
+// 文件节点与项目树或事务共享，调用方不应修改
+func (tx *Transaction) View(path string) (*Node, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	cleanPath := tx.p.NormalizePath(path)
+	node, ok := tx.lookup(cleanPath)
+	if !ok {
+		return nil, errors.New("node not found: " + path)
+	}
+	return tx.view(node, cleanPath, nil), nil
+}
+
+// ReadFile 读取事务视图中的文件内容
+func (tx *Transaction) ReadFile(path string) ([]byte, error) {
+	node, err := tx.FindNode(path)
+	if err != nil {
+		return nil, err
+	}
+	if node.IsDir {
+		return nil, errors.New("cannot read directory")
+	}
+	return node.ReadContent()
+}
+
+// WriteFile 写入文件内容，文件不存在时创建
+func (tx *Transaction) WriteFile(path string, content []byte) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	cleanPath := tx.p.NormalizePath(path)
+	if node, ok := tx.lookup(cleanPath); ok && node.IsDir {
+		return errors.New("cannot write to directory")
+	}
+	return tx.put(cleanPath, false, content)
+}
+
+// CreateFile 创建文件
+func (tx *Transaction) CreateFile(path string, content []byte) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	cleanPath := tx.p.NormalizePath(path)
+	if _, ok := tx.lookup(cleanPath); ok {
+		return errors.New("node already exists: " + cleanPath)
+	}
+	return tx.put(cleanPath, false, content)
+}
+
+// CreateFileWithContent 创建文件并设置内容
+func (tx *Transaction) CreateFileWithContent(path string, content []byte) error {
+	return tx.CreateFile(path, content)
+}
+
+// CreateFileNode 创建空文件
+func (tx *Transaction) CreateFileNode(path string) error {
+	return tx.CreateFile(path, []byte{})
+}
+
+// CreateDir 创建目录
+func (tx *Transaction) CreateDir(path string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	cleanPath := tx.p.NormalizePath(path)
+	if _, ok := tx.lookup(cleanPath); ok {
+		return errors.New("node already exists: " + cleanPath)
+	}
+	return tx.put(cleanPath, true, nil)
+}
+
+// DeleteNode 删除节点，提交时同时从磁盘移除
+func (tx *Transaction) DeleteNode(path string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	cleanPath := tx.p.NormalizePath(path)
+	if cleanPath == "/" {
+		return errors.New("cannot delete root node")
+	}
+	if _, ok := tx.lookup(cleanPath); !ok {
+		return errors.New("node not found: " + cleanPath)
+	}
+	tx.remove(cleanPath)
+	return nil
+}
+
+// MoveNode 移动节点，提交时写入新路径并从磁盘移除原路径
+func (tx *Transaction) MoveNode(src, dst string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	srcPath := tx.p.NormalizePath(src)
+	dstPath := tx.p.NormalizePath(dst)
+	if srcPath == dstPath && srcPath != "/" {
+		if _, ok := tx.lookup(srcPath); !ok {
+			return errors.New("node not found: " + srcPath)
+		}
+		return nil
+	}
+	if err := tx.copyTree(srcPath, dstPath); err != nil {
+		return err
+	}
+	tx.remove(srcPath)
+	return nil
+}
+
+// RenameNode 在同一目录下重命名节点
+func (tx *Transaction) RenameNode(path, newName string) error {
+	dst, err := renameTarget(tx.p.NormalizePath(path), newName)
+	if err != nil {
+		return err
+	}
+	return tx.MoveNode(path, dst)
+}
+
+// CopyNode 复制节点（文件或整个目录）
+func (tx *Transaction) CopyNode(src, dst string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	return tx.copyTree(tx.p.NormalizePath(src), tx.p.NormalizePath(dst))
+}
+
+// Changes 返回按路径排序的待提交变更
+func (tx *Transaction) Changes() []TxChange {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	paths := tx.sortedPaths()
+	changes := make([]TxChange, 0, len(paths))
+	for _, path := range paths {
+		entry := tx.overlay[path]
+		switch {
+		case entry.node == nil:
+			changes = append(changes, TxChange{Op: TxDelete, Path: path})
+		case entry.node.IsDir:
+			changes = append(changes, TxChange{Op: TxMkdir, Path: path})
+		default:
+			changes = append(changes, TxChange{Op: TxWrite, Path: path})
+		}
+	}
+	return changes
+}
+
+// Rollback 丢弃事务中的所有修改
+func (tx *Transaction) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.overlay = nil
+	tx.done = true
+	return nil
+}
+
+// Commit 将事务中的所有修改写入磁盘与项目树
+// 文件内容先写入同目录下的临时文件，再统一通过重命名替换；任一步骤失败时撤销已完成的磁盘操作
+func (tx *Transaction) Commit() (err error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+
+	p := tx.p
+	paths := tx.sortedPaths()
+	defer p.recordOp(JournalCommit, paths...)(&err)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.source != nil {
+		return ErrNotOnDisk
+	}
+	if p.rootPath == "" {
+		return errors.New("project root path is empty")
+	}
+	// 工作区只能修改根目录之内的路径；新建的路径不能覆盖项目树之外的磁盘内容
+	for _, path := range paths {
+		if err = p.checkMounted(path); err != nil {
+			return err
+		}
+		if err = tx.checkDiskConflict(path); err != nil {
+			return err
+		}
+	}
+
+	c := &txCommitter{p: p}
+	if err = c.apply(tx.overlay, paths); err != nil {
+		c.undo()
+		return err
+	}
+	c.cleanup()
+
+	tx.updateTree(paths)
+	tx.overlay = nil
+	tx.done = true
+	return nil
+}
+
+// checkDiskConflict 检查事务中新建的路径：不在项目树中、也不在被删除或替换的目录之下，
+// 却已存在于磁盘上（例如被忽略规则排除的文件）时返回错误，与 MoveNode、CopyNode 相同；调用方需持有 tx.mu 与 p.mu
+func (tx *Transaction) checkDiskConflict(path string) error {
+	entry := tx.overlay[path]
+	if entry.node == nil || entry.replace {
+		return nil
+	}
+	if _, exists := tx.p.nodes[path]; exists {
+		return nil
+	}
+	for dir := parentPath(path); dir != ""; dir = parentPath(dir) {
+		if e, ok := tx.overlay[dir]; ok && (e.node == nil || e.replace) {
+			return nil
+		}
+	}
+	if _, err := os.Lstat(tx.p.fsPath(path)); err == nil {
+		return errors.New("path already exists on disk: " + path)
+	}
+	return nil
+}
+
+// lookup 在事务视图中查找节点，调用方需持有 tx.mu
+func (tx *Transaction) lookup(path string) (*Node, bool) {
+	if entry, ok := tx.overlay[path]; ok {
+		return entry.node, entry.node != nil
+	}
+
+	// 祖先目录被删除或替换时，底层项目中的节点不可见
+	for dir := parentPath(path); dir != ""; dir = parentPath(dir) {
+		if entry, ok := tx.overlay[dir]; ok && (entry.node == nil || entry.replace) {
+			return nil, false
+		}
+	}
+
+	node, err := tx.p.FindNode(path)
+	if err != nil {
+		return nil, false
+	}
+	return node, true
+}
+
+// put 在覆盖层中写入文件或目录，调用方需持有 tx.mu
+func (tx *Transaction) put(path string, isDir bool, content []byte) error {
+	if path == "/" {
+		return errors.New("cannot modify root node")
+	}
+	parent, ok := tx.lookup(parentPath(path))
+	if !ok {
+		return errors.New("parent directory does not exist: " + parentPath(path))
+	}
+	if !parent.IsDir {
+		return errors.New("path component is not a directory: " + parentPath(path))
+	}
+
+	node := &Node{
+		Name:     filepath.Base(path),
+		Path:     path,
+		IsDir:    isDir,
+		Children: make(map[string]*Node),
+	}
+	if !isDir {
+		node.Content = append([]byte{}, content...)
+		node.ContentLoaded = true
+		node.modified = true
+	}
+
+	entry := &txEntry{node: node}
+	if old, exists := tx.overlay[path]; exists {
+		// 替换删除标记，或继承此前的替换状态与权限
+		entry.replace = old.node == nil || old.replace
+		if old.node != nil && !isDir {
+			entry.mode = old.mode
+		}
+	}
+	tx.overlay[path] = entry
+	return nil
+}
+
+// remove 在覆盖层中标记删除，事务中在该路径下新建的节点一并丢弃，调用方需持有 tx.mu
+func (tx *Transaction) remove(path string) {
+	prefix := path + "/"
+	for p := range tx.overlay {
+		if strings.HasPrefix(p, prefix) {
+			delete(tx.overlay, p)
+		}
+	}
+	tx.overlay[path] = &txEntry{}
+}
+
+// copyTree 在覆盖层中复制事务视图里的子树，调用方需持有 tx.mu
+func (tx *Transaction) copyTree(src, dst string) error {
+	if src == "/" {
+		return errors.New("cannot move or copy root node")
+	}
+	node, ok := tx.lookup(src)
+	if !ok {
+		return errors.New("node not found: " + src)
+	}
+	if dst == "/" || dst == src {
+		return errors.New("node already exists: " + dst)
+	}
+	if strings.HasPrefix(dst, src+"/") {
+		return fmt.Errorf("cannot move or copy %s into itself: %s", src, dst)
+	}
+	if _, ok := tx.lookup(dst); ok {
+		return errors.New("node already exists: " + dst)
+	}
+	return tx.copyNode(node, src, dst)
+}
+
+// copyNode 递归地将 src 处的节点写入覆盖层中的 dst，调用方需持有 tx.mu
+func (tx *Transaction) copyNode(node *Node, src, dst string) error {
+	if !node.IsDir {
+		content, err := node.ReadContent()
+		if err != nil {
+			return err
+		}
+		mode := tx.modeOf(src, node)
+		if err := tx.put(dst, false, content); err != nil {
+			return err
+		}
+		tx.overlay[dst].mode = mode
+		return nil
+	}
+	if err := tx.put(dst, true, nil); err != nil {
+		return err
+	}
+	for _, name := range tx.childNames(src) {
+		child, ok := tx.lookup(src + "/" + name)
+		if !ok {
+			continue
+		}
+		if err := tx.copyNode(child, src+"/"+name, dst+"/"+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modeOf 返回事务视图中文件的权限：覆盖层中记录的权限，或项目树中节点的磁盘权限，调用方需持有 tx.mu
+func (tx *Transaction) modeOf(path string, node *Node) os.FileMode {
+	if entry, ok := tx.overlay[path]; ok {
+		return entry.mode
+	}
+	if node.Info != nil && node.Info.Mode().IsRegular() {
+		return node.Info.Mode().Perm()
+	}
+	return 0
+}
+
+// view 复制事务视图中的目录节点及其子树，调用方需持有 tx.mu
+func (tx *Transaction) view(node *Node, path string, parent *Node) *Node {
+	if !node.IsDir {
+		return node
+	}
+	dir := &Node{
+		Name:     node.Name,
+		Path:     path,
+		IsDir:    true,
+		Info:     node.Info,
+		Children: make(map[string]*Node),
+		Parent:   parent,
+	}
+	for _, name := range tx.childNames(path) {
+		child, ok := tx.lookup(childPath(path, name))
+		if !ok {
+			continue
+		}
+		dir.Children[name] = tx.view(child, childPath(path, name), dir)
+	}
+	return dir
+}
+
+// childNames 返回事务视图中目录的子节点名称（已排序），调用方需持有 tx.mu
+func (tx *Transaction) childNames(dir string) []string {
+	seen := make(map[string]bool)
+	if entry, ok := tx.overlay[dir]; !ok || !entry.replace {
+		if node, err := tx.p.FindNode(dir); err == nil && node.IsDir {
+			for _, child := range node.GetChildrenNodes() {
+				seen[child.Name] = true
+			}
+		}
+	}
+	for path := range tx.overlay {
+		if parentPath(path) == dir {
+			seen[filepath.Base(path)] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		if _, ok := tx.lookup(childPath(dir, name)); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedPaths 返回覆盖层中按路径排序的路径（父目录在前），调用方需持有 tx.mu
+func (tx *Transaction) sortedPaths() []string {
+	paths := make([]string, 0, len(tx.overlay))
+	for path := range tx.overlay {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// updateTree 在磁盘提交成功后更新项目树，调用方需持有 p.mu 写锁
+func (tx *Transaction) updateTree(paths []string) {
+	p := tx.p
+	for _, path := range paths {
+		entry := tx.overlay[path]
+		existing, exists := p.nodes[path]
+
+		if entry.node == nil || entry.replace || (exists && existing.IsDir != entry.node.IsDir) {
+			if exists {
+				p.detachNode(existing)
+				exists = false
+			}
+			if entry.node == nil {
+				continue
+			}
+		}
+
+		info, err := os.Stat(p.fsPath(path))
+		if err != nil {
+			continue
+		}
+
+		if entry.node.IsDir {
+			if !exists {
+				p.createDirInternal(path, info)
+			}
+			continue
+		}
+
+		if !exists {
+			if err := p.createFileNodeInternal(path, info); err != nil {
+				continue
+			}
+			existing = p.nodes[path]
+		}
+		existing.mu.Lock()
+		existing.Content = entry.node.Content
+		existing.ContentLoaded = true
+		existing.Info = info
+		existing.hash = ""
+		existing.kind = nil
+		existing.mu.Unlock()
+	}
+}
+
+// fsPath 返回项目路径对应的磁盘路径
+// 工作区中不属于任何根目录的路径返回 ""
+func (p *Project) fsPath(path string) string {
+	if p.IsWorkspace() {
+		return p.mountedPath(path)
+	}
+	if path == "/" {
+		return p.rootPath
+	}
+	return filepath.Join(p.rootPath, filepath.FromSlash(path[1:]))
+}
+
+// parentPath 返回项目路径的父路径，根路径返回空字符串
+func parentPath(path string) string {
+	if path == "/" || path == "" {
+		return ""
+	}
+	dir := filepath.ToSlash(filepath.Dir(path))
+	if dir == "." {
+		return "/"
+	}
+	return dir
+}
+
+// childPath 返回目录下子节点的路径
+func childPath(dir, name string) string {
+	if dir == "/" {
+		return "/" + name
+	}
+	return dir + "/" + name
+}
+
+// txCommitter 执行提交的磁盘操作，并记录撤销所需的信息
+type txCommitter struct {
+	p           *Project
+	createdDirs []string          // 新建的目录（按创建顺序）
+	temps       map[string]string // 目标路径 -> 已写好内容的临时文件
+	backups     map[string]string // 目标路径 -> 原有文件或目录的备份位置
+	installed   []string          // 已被替换为新内容的目标路径
+}
+
+// apply 依次执行：备份将被删除或替换的路径、创建目录并写入临时文件、重命名安装新文件
+func (c *txCommitter) apply(overlay map[string]*txEntry, paths []string) error {
+	c.temps = make(map[string]string)
+	c.backups = make(map[string]string)
+
+	// 1. 备份将被删除或替换的路径
+	for _, path := range paths {
+		entry := overlay[path]
+		if entry.node != nil && !entry.replace {
+			continue
+		}
+		if err := c.backup(c.p.fsPath(path)); err != nil {
+			return fmt.Errorf("删除失败 %s: %w", path, err)
+		}
+	}
+
+	// 2. 创建目录并写入临时文件
+	for _, path := range paths {
+		entry := overlay[path]
+		if entry.node == nil {
+			continue
+		}
+		target := c.p.fsPath(path)
+		if entry.node.IsDir {
+			if err := c.mkdir(target); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := c.mkdir(filepath.Dir(target)); err != nil {
+			return err
+		}
+		tmp, err := writeTempFile(target, entry.node.Content, entry.mode)
+		if err != nil {
+			return fmt.Errorf("写入临时文件失败 %s: %w", path, err)
+		}
+		c.temps[target] = tmp
+	}
+
+	// 3. 通过重命名安装新文件，被覆盖的旧文件先移到备份位置
+	for _, path := range paths {
+		entry := overlay[path]
+		if entry.node == nil || entry.node.IsDir {
+			continue
+		}
+		target := c.p.fsPath(path)
+		if err := c.backup(target); err != nil {
+			return fmt.Errorf("写入失败 %s: %w", path, err)
+		}
+		if err := os.Rename(c.temps[target], target); err != nil {
+			return fmt.Errorf("写入失败 %s: %w", path, err)
+		}
+		delete(c.temps, target)
+		c.installed = append(c.installed, target)
+	}
+	return nil
+}
+
+// mkdir 创建目录及缺失的父目录，并记录新建的目录
+func (c *txCommitter) mkdir(dir string) error {
+	var missing []string
+	for cur := dir; ; cur = filepath.Dir(cur) {
+		if _, err := os.Lstat(cur); err == nil {
+			break
+		}
+		missing = append(missing, cur)
+		if cur == filepath.Dir(cur) {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], 0755); err != nil {
+			return fmt.Errorf("创建目录失败 %s: %w", missing[i], err)
+		}
+		c.createdDirs = append(c.createdDirs, missing[i])
+	}
+	return nil
+}
+
+// backup 将已存在的路径重命名为同目录下的备份，路径不存在时忽略
+func (c *txCommitter) backup(target string) error {
+	if _, exists := c.backups[target]; exists {
+		return nil
+	}
+	if _, err := os.Lstat(target); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	backup := ""
+	for i := 0; ; i++ {
+		backup = filepath.Join(filepath.Dir(target), fmt.Sprintf(".%s.tong-bak-%d-%d", filepath.Base(target), os.Getpid(), i))
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			break
+		}
+	}
+	if err := os.Rename(target, backup); err != nil {
+		return err
+	}
+	c.backups[target] = backup
+	return nil
+}
+
+// undo 撤销已完成的磁盘操作
+func (c *txCommitter) undo() {
+	for i := len(c.installed) - 1; i >= 0; i-- {
+		os.Remove(c.installed[i])
+	}
+	for _, tmp := range c.temps {
+		os.Remove(tmp)
+	}
+	for target, backup := range c.backups {
+		os.RemoveAll(target)
+		os.Rename(backup, target)
+	}
+	for i := len(c.createdDirs) - 1; i >= 0; i-- {
+		os.Remove(c.createdDirs[i])
+	}
+}
+
+// cleanup 提交成功后删除备份
+func (c *txCommitter) cleanup() {
+	for _, backup := range c.backups {
+		os.RemoveAll(backup)
+	}
+}
+
+// writeTempFile 在目标文件所在目录写入临时文件，保证之后的重命名是同一文件系统内的原子操作
+// 临时文件沿用目标文件现有的权限（如可执行位）；新文件使用 mode，为 0 时使用 0644
+func writeTempFile(target string, content []byte, mode os.FileMode) (string, error) {
+	if info, err := os.Stat(target); err == nil && info.Mode().IsRegular() {
+		mode = info.Mode().Perm()
+	} else if mode == 0 {
+		mode = 0644
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tong-tx-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,265 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTxTestProject 创建包含 /src/main.go 与 /docs/a.md 的测试项目
+func newTxTestProject(t *testing.T) (*Project, string) {
+	t.Helper()
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "src"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "main.go"), []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "docs", "a.md"), []byte("# a"), 0644))
+
+	proj := NewProject(tempDir)
+	require.NoError(t, proj.SyncFromFS())
+	return proj, tempDir
+}
+
+func TestTransactionIsolation(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	tx := proj.Begin()
+
+	require.NoError(t, tx.WriteFile("/src/main.go", []byte("package changed")))
+	require.NoError(t, tx.CreateDir("/pkg"))
+	require.NoError(t, tx.CreateFile("/pkg/util.go", []byte("package pkg")))
+	require.NoError(t, tx.DeleteNode("/docs"))
+
+	// 事务视图中可见
+	content, err := tx.ReadFile("/src/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package changed", string(content))
+	_, err = tx.FindNode("/pkg/util.go")
+	assert.NoError(t, err)
+	_, err = tx.FindNode("/docs/a.md")
+	assert.Error(t, err)
+
+	// 项目树与磁盘保持不变
+	content, err = proj.ReadFile("/src/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(content))
+	_, err = proj.FindNode("/pkg")
+	assert.Error(t, err)
+	assert.NoDirExists(t, filepath.Join(tempDir, "pkg"))
+	assert.FileExists(t, filepath.Join(tempDir, "docs", "a.md"))
+
+	// 父目录不存在、重复创建等错误与 Project 保持一致
+	assert.Error(t, tx.CreateFile("/missing/x.go", nil))
+	assert.Error(t, tx.CreateFile("/pkg/util.go", nil))
+	assert.Error(t, tx.DeleteNode("/"))
+	assert.Error(t, tx.WriteFile("/src", []byte("x")))
+
+	assert.Equal(t, []TxChange{
+		{Op: TxDelete, Path: "/docs"},
+		{Op: TxMkdir, Path: "/pkg"},
+		{Op: TxWrite, Path: "/pkg/util.go"},
+		{Op: TxWrite, Path: "/src/main.go"},
+	}, tx.Changes())
+}
+
+func TestTransactionCommit(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	tx := proj.Begin()
+
+	require.NoError(t, tx.WriteFile("/src/main.go", []byte("package changed")))
+	require.NoError(t, tx.CreateDir("/pkg"))
+	require.NoError(t, tx.CreateFile("/pkg/util.go", []byte("package pkg")))
+	require.NoError(t, tx.DeleteNode("/docs"))
+	require.NoError(t, tx.Commit())
+
+	// 磁盘
+	data, err := os.ReadFile(filepath.Join(tempDir, "src", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package changed", string(data))
+	assert.FileExists(t, filepath.Join(tempDir, "pkg", "util.go"))
+	assert.NoDirExists(t, filepath.Join(tempDir, "docs"))
+
+	// 项目树
+	content, err := proj.ReadFile("/src/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package changed", string(content))
+	_, err = proj.FindNode("/pkg/util.go")
+	assert.NoError(t, err)
+	_, err = proj.FindNode("/docs/a.md")
+	assert.Error(t, err)
+
+	// 不应残留临时文件或备份
+	entries, err := os.ReadDir(filepath.Join(tempDir, "src"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	entries, err = os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// 已提交的事务不能再使用
+	assert.ErrorIs(t, tx.Commit(), ErrTxDone)
+	assert.ErrorIs(t, tx.WriteFile("/a", nil), ErrTxDone)
+}
+
+func TestTransactionReplaceDeletedDirectory(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	tx := proj.Begin()
+
+	// 删除目录后以同名目录重建，原有子节点不再可见
+	require.NoError(t, tx.DeleteNode("/docs"))
+	require.NoError(t, tx.CreateDir("/docs"))
+	_, err := tx.FindNode("/docs/a.md")
+	assert.Error(t, err)
+	require.NoError(t, tx.CreateFile("/docs/b.md", []byte("# b")))
+	require.NoError(t, tx.Commit())
+
+	assert.NoFileExists(t, filepath.Join(tempDir, "docs", "a.md"))
+	assert.FileExists(t, filepath.Join(tempDir, "docs", "b.md"))
+	_, err = proj.FindNode("/docs/a.md")
+	assert.Error(t, err)
+	_, err = proj.FindNode("/docs/b.md")
+	assert.NoError(t, err)
+}
+
+func TestTransactionCommitKeepsFileMode(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	script := filepath.Join(tempDir, "src", "run.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, proj.SyncFromFS())
+
+	// 修改已有文件保留其权限，新建文件为 0644
+	tx := proj.Begin()
+	require.NoError(t, tx.WriteFile("/src/run.sh", []byte("#!/bin/sh\necho hi\n")))
+	require.NoError(t, tx.CreateFile("/src/new.sh", []byte("#!/bin/sh\n")))
+	require.NoError(t, tx.Commit())
+
+	info, err := os.Stat(script)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(tempDir, "src", "new.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// 移动或复制的文件沿用源文件的权限，复制后再修改或再复制也不丢失
+	tx = proj.Begin()
+	require.NoError(t, tx.CopyNode("/src/run.sh", "/src/copy.sh"))
+	require.NoError(t, tx.WriteFile("/src/copy.sh", []byte("#!/bin/sh\necho copy\n")))
+	require.NoError(t, tx.CopyNode("/src/copy.sh", "/src/copy2.sh"))
+	require.NoError(t, tx.MoveNode("/src", "/bin"))
+	require.NoError(t, tx.Commit())
+
+	for _, name := range []string{"run.sh", "copy.sh", "copy2.sh"} {
+		info, err = os.Stat(filepath.Join(tempDir, "bin", name))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), info.Mode().Perm(), name)
+	}
+	info, err = os.Stat(filepath.Join(tempDir, "bin", "new.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestTransactionCommitRejectsIgnoredPaths(t *testing.T) {
+	_, tempDir := newTxTestProject(t)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("build/\nlocal.json\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "build"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "build", "out.bin"), []byte("out"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "local.json"), []byte(`{"secret":1}`), 0644))
+	proj, err := BuildProjectTree(tempDir, helper.WalkDirOptions{})
+	require.NoError(t, err)
+	_, err = proj.FindNode("/local.json")
+	require.Error(t, err)
+
+	// 被忽略的文件与目录不在项目树中，提交不能覆盖它们，其余修改也不落盘
+	for _, path := range []string{"/build", "/local.json"} {
+		tx := proj.Begin()
+		require.NoError(t, tx.WriteFile("/src/main.go", []byte("package changed")))
+		require.NoError(t, tx.WriteFile(path, []byte("{}")))
+		err := tx.Commit()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already exists on disk")
+	}
+	assert.Equal(t, "out", readDisk(t, filepath.Join(tempDir, "build", "out.bin")))
+	assert.Equal(t, `{"secret":1}`, readDisk(t, filepath.Join(tempDir, "local.json")))
+	assert.Equal(t, "package main", readDisk(t, filepath.Join(tempDir, "src", "main.go")))
+}
+
+func TestTransactionView(t *testing.T) {
+	proj, _ := newTxTestProject(t)
+	tx := proj.Begin()
+	require.NoError(t, tx.CreateDir("/pkg"))
+	require.NoError(t, tx.CreateFile("/pkg/util.go", []byte("package pkg")))
+	require.NoError(t, tx.WriteFile("/src/main.go", []byte("package changed")))
+	require.NoError(t, tx.DeleteNode("/docs"))
+
+	root, err := tx.View("/")
+	require.NoError(t, err)
+	var names []string
+	for _, child := range root.GetChildrenNodes() {
+		names = append(names, child.Name)
+	}
+	assert.ElementsMatch(t, []string{"src", "pkg"}, names)
+	pkg, ok := root.GetChild("pkg")
+	require.True(t, ok)
+	util, ok := pkg.GetChild("util.go")
+	require.True(t, ok)
+	assert.Equal(t, "/pkg/util.go", util.Path)
+	src, _ := root.GetChild("src")
+	main, _ := src.GetChild("main.go")
+	content, err := main.ReadContent()
+	require.NoError(t, err)
+	assert.Equal(t, "package changed", string(content))
+
+	// 项目树不受影响
+	_, ok = proj.Root().GetChild("pkg")
+	assert.False(t, ok)
+	_, err = tx.View("/docs")
+	assert.Error(t, err)
+}
+
+func TestTransactionRollback(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	tx := proj.Begin()
+
+	require.NoError(t, tx.WriteFile("/src/main.go", []byte("package changed")))
+	require.NoError(t, tx.DeleteNode("/docs/a.md"))
+	require.NoError(t, tx.Rollback())
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "src", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(data))
+	_, err = proj.FindNode("/docs/a.md")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, tx.Rollback(), ErrTxDone)
+}
+
+func TestTransactionCommitFailureRestoresDisk(t *testing.T) {
+	proj, tempDir := newTxTestProject(t)
+	tx := proj.Begin()
+
+	require.NoError(t, tx.WriteFile("/src/main.go", []byte("package changed")))
+	require.NoError(t, tx.DeleteNode("/docs/a.md"))
+	require.NoError(t, tx.CreateDir("/locked"))
+	require.NoError(t, tx.CreateFile("/locked/x.go", nil))
+
+	// 磁盘上存在未被项目跟踪的同名普通文件，使新文件无法写入，提交应整体失败
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "locked"), []byte("file"), 0644))
+
+	assert.Error(t, tx.Commit())
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "src", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(data))
+	assert.FileExists(t, filepath.Join(tempDir, "docs", "a.md"))
+	entries, err := os.ReadDir(filepath.Join(tempDir, "docs"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	content, err := proj.ReadFile("/src/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(content))
+	_, err = proj.FindNode("/docs/a.md")
+	assert.NoError(t, err)
+}
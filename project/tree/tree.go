@@ -40,6 +40,11 @@ func Tree(node *project.Node) string {
 
 // TreeWithOptions 生成带选项的树状结构
 func TreeWithOptions(node *project.Node, showFiles bool, showHidden bool, maxDepth int) string {
+	return TreeWithFilter(node, showFiles, showHidden, maxDepth, nil)
+}
+
+// TreeWithFilter 与 TreeWithOptions 相同，但只显示 match 返回 true 的文件，match 为 nil 时显示所有文件
+func TreeWithFilter(node *project.Node, showFiles bool, showHidden bool, maxDepth int, match func(*project.Node) bool) string {
 	if node == nil {
 		return ""
 	}
@@ -48,7 +53,7 @@ func TreeWithOptions(node *project.Node, showFiles bool, showHidden bool, maxDep
 	ctx := context.Background()
 	maxWorkers := 10
 	// 将初始深度设为0（根为0层），当 maxDepth=2 时，仅包含第0层和第1层（根的直接子节点）
-	rootInfo := collectNodeInfoParallelWithOptions(ctx, node, "", true, true, showFiles, showHidden, 0, maxDepth, maxWorkers)
+	rootInfo := collectNodeInfoParallelWithOptions(ctx, node, "", true, true, showFiles, showHidden, match, 0, maxDepth, maxWorkers)
 
 	// 构建树状结构
 	var result strings.Builder
@@ -198,7 +203,7 @@ func collectNodeInfoSequential(ctx context.Context, node *project.Node, prefix s
 }
 
 // collectNodeInfoParallelWithOptions 带选项的并行收集节点信息
-func collectNodeInfoParallelWithOptions(ctx context.Context, node *project.Node, prefix string, isLast bool, isRoot bool, showFiles bool, showHidden bool, currentDepth int, maxDepth int, maxWorkers int) *NodeInfo {
+func collectNodeInfoParallelWithOptions(ctx context.Context, node *project.Node, prefix string, isLast bool, isRoot bool, showFiles bool, showHidden bool, match func(*project.Node) bool, currentDepth int, maxDepth int, maxWorkers int) *NodeInfo {
 	// 检查深度限制
 	// 注意：currentDepth 表示当前节点的深度，根节点为 0
 	if maxDepth > 0 && currentDepth >= maxDepth {
@@ -241,6 +246,9 @@ func collectNodeInfoParallelWithOptions(ctx context.Context, node *project.Node,
 			if !showFiles && !child.IsDir {
 				continue
 			}
+			if match != nil && !child.IsDir && !match(child) {
+				continue
+			}
 			children = append(children, child)
 		}
 
@@ -277,6 +285,7 @@ func collectNodeInfoParallelWithOptions(ctx context.Context, node *project.Node,
 						false,
 						showFiles,
 						showHidden,
+						match,
 						currentDepth+1,
 						maxDepth,
 					), nil
@@ -304,6 +313,7 @@ func collectNodeInfoParallelWithOptions(ctx context.Context, node *project.Node,
 					false,
 					showFiles,
 					showHidden,
+					match,
 					currentDepth+1,
 					maxDepth,
 				)
@@ -323,7 +333,7 @@ func collectNodeInfoParallelWithOptions(ctx context.Context, node *project.Node,
 }
 
 // collectNodeInfoSequentialWithOptions 带选项的顺序收集节点信息（非并行）
-func collectNodeInfoSequentialWithOptions(ctx context.Context, node *project.Node, prefix string, isLast bool, isRoot bool, showFiles bool, showHidden bool, currentDepth int, maxDepth int) *NodeInfo {
+func collectNodeInfoSequentialWithOptions(ctx context.Context, node *project.Node, prefix string, isLast bool, isRoot bool, showFiles bool, showHidden bool, match func(*project.Node) bool, currentDepth int, maxDepth int) *NodeInfo {
 	// 检查深度限制
 	// 注意：currentDepth 表示当前节点的深度，根节点为 0
 	if maxDepth > 0 && currentDepth >= maxDepth {
@@ -366,6 +376,9 @@ func collectNodeInfoSequentialWithOptions(ctx context.Context, node *project.Nod
 			if !showFiles && !child.IsDir {
 				continue
 			}
+			if match != nil && !child.IsDir && !match(child) {
+				continue
+			}
 			children = append(children, child)
 		}
 
@@ -399,6 +412,7 @@ func collectNodeInfoSequentialWithOptions(ctx context.Context, node *project.Nod
 				false,
 				showFiles,
 				showHidden,
+				match,
 				currentDepth+1,
 				maxDepth,
 			)
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/sjzsdu/tong/helper/filekind"
 	"github.com/sjzsdu/tong/project"
 )
 
@@ -192,4 +193,36 @@ func ExampleTree() {
 	// 获取统计信息
 	// stats := Stats(root)
 	// fmt.Printf("Project contains: %s\n", stats.String())
-}
\ No newline at end of file
+}
+func TestTreeWithFilter(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":       "package main",
+		"go.sum":        "github.com/x v1.0.0 h1:abc=",
+		"vendor/x/x.go": "package x",
+		"docs/guide.md": "# Guide",
+	} {
+		fullPath := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	proj := project.NewProject(tempDir)
+	if err := proj.SyncFromFS(); err != nil {
+		t.Fatalf("Failed to sync project: %v", err)
+	}
+
+	filter := filekind.Filter{Languages: []string{"go"}, SkipGenerated: true, SkipVendored: true}
+	output := TreeWithFilter(proj.Root(), true, false, 0, func(n *project.Node) bool { return n.MatchKind(filter) })
+	if !containsAll(output, []string{"main.go", "vendor/", "docs/"}) {
+		t.Errorf("filtered tree missing expected entries:\n%s", output)
+	}
+	for _, unwanted := range []string{"go.sum", "x.go", "guide.md"} {
+		if contains(output, unwanted) {
+			t.Errorf("filtered tree should not contain %s:\n%s", unwanted, output)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package project
 
 import (
+	"io/fs"
 	"os"
 	"sync"
+	"sync/atomic"
+
+	"github.com/sjzsdu/tong/helper/filekind"
 )
 
 type Node struct {
@@ -16,6 +20,14 @@ type Node struct {
 	Children      map[string]*Node
 	Parent        *Node
 	mu            sync.RWMutex
+
+	// 缓存的文件哈希，仅在磁盘文件的大小与修改时间仍等于 hashStamp 时有效
+	hash      string
+	hashStamp fileStamp
+
+	// 缓存的文件分类，由 Kind 计算，磁盘文件的大小与修改时间仍等于 kindStamp 时有效
+	kind      *filekind.Kind
+	kindStamp fileStamp
 }
 
 // Project 表示整个文档树
@@ -25,6 +37,30 @@ type Project struct {
 	inGit    bool
 	nodes    map[string]*Node
 	mu       sync.RWMutex
+
+	// filter 构建项目树时使用的过滤规则，Watch 复用
+	filter *pathFilter
+
+	// source 由 BuildProjectTreeFromFS 设置的内容来源，为 nil 时读写本地磁盘
+	source fs.FS
+
+	// mounts 由 BuildWorkspace 设置的根目录，不为空时磁盘路径按顶层目录映射到各根目录
+	mounts []*mount
+
+	// 变更事件订阅者
+	subsMu      sync.Mutex
+	subscribers map[int]chan ChangeEvent
+	nextSubID   int
+
+	// 操作日志，由 EnableJournal 启用
+	journal *Journal
+
+	// 快照缓存，由 BuildProjectTreeCached 设置
+	snapshotKey   string
+	snapshotDirty atomic.Bool
+
+	// 内容缓存，由 SetContentBudget 设置
+	contentCache atomic.Pointer[ContentCache]
 }
 
 // VisitorFunc 定义了访问节点的函数类型
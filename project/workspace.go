@@ -0,0 +1,207 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sjzsdu/tong/helper"
+)
+
+// ErrOutsideWorkspace 工作区中的路径不属于任何根目录（如工作区根下的顶层文件），无法对应到磁盘
+var ErrOutsideWorkspace = errors.New("path is not inside a workspace root")
+
+// WorkspaceRoot 工作区中的一个根目录，挂载为项目树中名为 Name 的顶层目录
+type WorkspaceRoot struct {
+	Name  string // 顶层节点名称，为空时使用目录名
+	Path  string // 磁盘路径，相对路径基于工作区目录
+	InGit bool   // 根目录是否位于 git 仓库中，由 BuildWorkspace 填写
+}
+
+// mount 工作区根目录的挂载信息，每个根目录有独立的过滤规则
+type mount struct {
+	name     string
+	rootPath string
+	inGit    bool
+	filter   *pathFilter
+}
+
+// BuildWorkspace 把多个目录挂载到同一棵项目树中，每个根目录成为一个顶层目录节点
+// dir 为工作区目录，用于解析相对路径并作为 GetRootPath 的返回值；每个根目录按 BuildProjectTree
+// 的规则独立构建，使用各自的 .gitignore 与 git 状态。工作区根下不能创建顶层文件
+func BuildWorkspace(dir string, roots []WorkspaceRoot, options helper.WalkDirOptions) (*Project, error) {
+	return buildWorkspace(dir, roots, options, BuildProjectTree)
+}
+
+// BuildWorkspaceCached 与 BuildWorkspace 相同，但每个根目录使用 BuildProjectTreeCached 构建
+// 快照在构建时写入，之后计算的文件哈希不会回写到各根目录的快照中
+func BuildWorkspaceCached(dir string, roots []WorkspaceRoot, options helper.WalkDirOptions) (*Project, error) {
+	return buildWorkspace(dir, roots, options, BuildProjectTreeCached)
+}
+
+// buildWorkspace 用 build 构建每个根目录，再把它们的节点移入工作区项目
+func buildWorkspace(dir string, roots []WorkspaceRoot, options helper.WalkDirOptions,
+	build func(string, helper.WalkDirOptions) (*Project, error)) (*Project, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("workspace has no roots")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := NewProject(dir)
+	ws.mounts = make([]*mount, 0, len(roots))
+	for _, root := range roots {
+		m, err := newMount(dir, root)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ws.nodes["/"+m.name]; exists {
+			return nil, fmt.Errorf("duplicate workspace root name: %s", m.name)
+		}
+
+		sub, err := build(m.rootPath, options)
+		if err != nil {
+			return nil, fmt.Errorf("构建工作区根目录 %s 失败: %w", m.rootPath, err)
+		}
+		m.filter = sub.filter
+		m.inGit = sub.inGit
+
+		info, err := os.Stat(m.rootPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := ws.graft(sub, m.name, info); err != nil {
+			return nil, err
+		}
+		ws.mounts = append(ws.mounts, m)
+	}
+	return ws, nil
+}
+
+// newMount 校验根目录的名称与路径
+func newMount(dir string, root WorkspaceRoot) (*mount, error) {
+	if root.Path == "" {
+		return nil, errors.New("workspace root path is empty")
+	}
+	rootPath := root.Path
+	if !filepath.IsAbs(rootPath) {
+		rootPath = filepath.Join(dir, rootPath)
+	}
+	rootPath = filepath.Clean(rootPath)
+
+	name := root.Name
+	if name == "" {
+		name = filepath.Base(rootPath)
+	}
+	if name == "." || name == ".." || name == "/" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid workspace root name: %q", name)
+	}
+	return &mount{name: name, rootPath: rootPath}, nil
+}
+
+// graft 把 sub 的整棵树挂到工作区的 /name 下，sub 之后不再可用
+func (p *Project) graft(sub *Project, name string, info os.FileInfo) error {
+	root := sub.root
+	UnregisterProject(root)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	root.mu.Lock()
+	root.Info = info
+	root.mu.Unlock()
+	return p.attachNode(root, "/"+name)
+}
+
+// IsWorkspace 判断项目是否由 BuildWorkspace 构建
+func (p *Project) IsWorkspace() bool {
+	return len(p.mounts) > 0
+}
+
+// WorkspaceRoots 返回工作区的根目录（路径为绝对路径），普通项目返回 nil
+func (p *Project) WorkspaceRoots() []WorkspaceRoot {
+	roots := make([]WorkspaceRoot, 0, len(p.mounts))
+	for _, m := range p.mounts {
+		roots = append(roots, WorkspaceRoot{Name: m.name, Path: m.rootPath, InGit: m.inGit})
+	}
+	if len(roots) == 0 {
+		return nil
+	}
+	return roots
+}
+
+// IsPathInGit 判断项目路径所在的目录是否位于 git 仓库中，工作区按路径所属的根目录判断
+func (p *Project) IsPathInGit(path string) bool {
+	if !p.IsWorkspace() {
+		return p.inGit
+	}
+	if m, _ := p.mountOf(path); m != nil {
+		return m.inGit
+	}
+	return false
+}
+
+// mountOf 返回项目路径所属的根目录及其在根目录中的相对路径（斜杠分隔，根目录本身为 ""）
+func (p *Project) mountOf(path string) (*mount, string) {
+	path = strings.TrimPrefix(path, "/")
+	name, rel, _ := strings.Cut(path, "/")
+	for _, m := range p.mounts {
+		if m.name == name {
+			return m, rel
+		}
+	}
+	return nil, ""
+}
+
+// mountedPath 返回工作区中项目路径对应的磁盘路径，不属于任何根目录时返回 ""
+func (p *Project) mountedPath(path string) string {
+	m, rel := p.mountOf(path)
+	if m == nil {
+		return ""
+	}
+	if rel == "" {
+		return m.rootPath
+	}
+	return filepath.Join(m.rootPath, filepath.FromSlash(rel))
+}
+
+// checkMounted 检查工作区中的路径位于某个根目录之内（不包括根目录本身），普通项目总是返回 nil
+func (p *Project) checkMounted(path string) error {
+	if !p.IsWorkspace() {
+		return nil
+	}
+	if m, rel := p.mountOf(path); m == nil || rel == "" {
+		return fmt.Errorf("%w: %s", ErrOutsideWorkspace, path)
+	}
+	return nil
+}
+
+// mountedProjectPath 把磁盘绝对路径转换为工作区中的项目路径，嵌套的根目录取最深的一个
+func (p *Project) mountedProjectPath(absPath string) (string, bool) {
+	mounts := make([]*mount, len(p.mounts))
+	copy(mounts, p.mounts)
+	sort.SliceStable(mounts, func(i, j int) bool {
+		return len(mounts[i].rootPath) > len(mounts[j].rootPath)
+	})
+	for _, m := range mounts {
+		if !withinDir(m.rootPath, absPath) {
+			continue
+		}
+		rel, _ := filepath.Rel(m.rootPath, absPath)
+		if rel == "." {
+			return "/" + m.name, true
+		}
+		return "/" + m.name + "/" + filepath.ToSlash(rel), true
+	}
+	return "", false
+}
+
+// withinDir 判断磁盘路径 path 是否为 dir 或位于 dir 之下
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
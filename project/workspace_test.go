@@ -0,0 +1,146 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sjzsdu/tong/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWorkspace 在同一个父目录下创建 api（git 仓库）与 web 两个根目录，返回工作区目录
+func newTestWorkspace(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"api/.gitignore":      "*.log\n",
+		"api/main.go":         "package main",
+		"api/debug.log":       "",
+		"api/pkg/util.go":     "package pkg",
+		"web/.gitignore":      "dist-local/\n",
+		"web/index.html":      "<html></html>",
+		"web/debug.log":       "",
+		"web/dist-local/a.js": "",
+		"ws/tong.json":        "{}",
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "api", ".git"), 0755))
+	return filepath.Join(dir, "ws")
+}
+
+func TestBuildWorkspace(t *testing.T) {
+	wsDir := newTestWorkspace(t)
+	proj, err := BuildWorkspace(wsDir, []WorkspaceRoot{
+		{Path: "../api"},
+		{Name: "site", Path: filepath.Join(wsDir, "..", "web")},
+	}, helper.WalkDirOptions{})
+	require.NoError(t, err)
+
+	// 每个根目录使用自己的 .gitignore
+	assert.Equal(t, []string{
+		"/api", "/api/.gitignore", "/api/main.go", "/api/pkg", "/api/pkg/util.go",
+		"/site", "/site/.gitignore", "/site/debug.log", "/site/index.html",
+	}, projectPaths(proj))
+	assert.True(t, proj.IsWorkspace())
+	assert.Equal(t, wsDir, proj.GetRootPath())
+	assert.True(t, proj.IsPathInGit("/api/main.go"))
+	assert.False(t, proj.IsPathInGit("/site/index.html"))
+
+	roots := proj.WorkspaceRoots()
+	require.Len(t, roots, 2)
+	assert.Equal(t, WorkspaceRoot{Name: "api", Path: filepath.Join(filepath.Dir(wsDir), "api"), InGit: true}, roots[0])
+
+	// 节点按所属根目录读写磁盘
+	node, err := proj.FindNode("/api/pkg/util.go")
+	require.NoError(t, err)
+	content, err := node.ReadContent()
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg", string(content))
+	assert.Same(t, proj, node.GetProject())
+
+	webDir := roots[1].Path
+	require.NoError(t, proj.CreateFile("/site/about.html", []byte("about")))
+	data, err := os.ReadFile(filepath.Join(webDir, "about.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "about", string(data))
+	assert.Equal(t, filepath.Join(webDir, "about.html"), proj.GetAbsolutePath("/site/about.html"))
+
+	path, ok := proj.ProjectPathOf(filepath.Join(webDir, "index.html"))
+	assert.True(t, ok)
+	assert.Equal(t, "/site/index.html", path)
+	_, ok = proj.ProjectPathOf(wsDir)
+	assert.False(t, ok)
+
+	// 工作区根下没有对应的磁盘目录，根目录节点不能删除
+	assert.ErrorIs(t, proj.CreateFile("/top.txt", nil), ErrOutsideWorkspace)
+	assert.ErrorIs(t, proj.DeleteNode("/site"), ErrOutsideWorkspace)
+	assert.ErrorIs(t, proj.RenameNode("/site", "web"), ErrOutsideWorkspace)
+	assert.ErrorIs(t, proj.CopyNode("/site/about.html", "/about.html"), ErrOutsideWorkspace)
+
+	// 在根目录之间移动文件
+	require.NoError(t, proj.MoveNode("/site/about.html", "/api/about.html"))
+	assert.NoFileExists(t, filepath.Join(webDir, "about.html"))
+	assert.FileExists(t, filepath.Join(roots[0].Path, "about.html"))
+	require.NoError(t, proj.MoveNode("/api/about.html", "/site/about.html"))
+	tx := proj.Begin()
+	require.NoError(t, tx.WriteFile("/readme.md", []byte("x")))
+	assert.ErrorIs(t, tx.Commit(), ErrOutsideWorkspace)
+
+	// SyncFromFS 重新加载所有根目录
+	require.NoError(t, proj.SyncFromFS())
+	files, err := proj.GetAllFiles()
+	require.NoError(t, err)
+	assert.Contains(t, files, "/site/about.html")
+	assert.Contains(t, files, "/api/main.go")
+}
+
+func TestBuildWorkspaceInvalidRoots(t *testing.T) {
+	wsDir := newTestWorkspace(t)
+	options := helper.WalkDirOptions{}
+
+	_, err := BuildWorkspace(wsDir, nil, options)
+	assert.Error(t, err)
+	_, err = BuildWorkspace(wsDir, []WorkspaceRoot{{Path: "../api"}, {Name: "api", Path: "../web"}}, options)
+	assert.ErrorContains(t, err, "duplicate workspace root name")
+	_, err = BuildWorkspace(wsDir, []WorkspaceRoot{{Name: "a/b", Path: "../api"}}, options)
+	assert.ErrorContains(t, err, "invalid workspace root name")
+	_, err = BuildWorkspace(wsDir, []WorkspaceRoot{{Path: "../missing"}}, options)
+	assert.Error(t, err)
+}
+
+func TestWorkspaceWatch(t *testing.T) {
+	wsDir := newTestWorkspace(t)
+	proj, err := BuildWorkspace(wsDir, []WorkspaceRoot{{Path: "../api"}, {Path: "../web"}}, helper.WalkDirOptions{})
+	require.NoError(t, err)
+
+	events, unsubscribe := proj.Subscribe(0)
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	w, err := newProjectWatcher(proj)
+	require.NoError(t, err)
+	go func() {
+		defer close(done)
+		defer w.close()
+		w.run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	webDir := filepath.Join(filepath.Dir(wsDir), "web")
+	writeFiles(t, webDir, map[string]string{"new.css": "body{}"})
+	event := waitEvent(t, events, func(e ChangeEvent) bool { return e.Type == ChangeCreated })
+	assert.Equal(t, "/web/new.css", event.Path)
+
+	// api 的 .gitignore 规则只作用于 api
+	writeFiles(t, filepath.Join(filepath.Dir(wsDir), "api"), map[string]string{"trace.log": "", "cmd.go": ""})
+	event = waitEvent(t, events, func(e ChangeEvent) bool { return e.Type == ChangeCreated })
+	assert.Equal(t, "/api/cmd.go", event.Path)
+	_, err = proj.FindNode("/api/trace.log")
+	assert.Error(t, err)
+}
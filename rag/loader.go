@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/sjzsdu/tong/helper/filekind"
 	"github.com/sjzsdu/tong/lang"
 	"github.com/tmc/langchaingo/documentloaders"
 	"github.com/tmc/langchaingo/schema"
@@ -72,8 +73,8 @@ func LoadDocument(ctx context.Context, path string) ([]schema.Document, error) {
 	return docs, nil
 }
 
-// LoadDocumentsFromDir 从目录加载所有文档
-func LoadDocumentsFromDir(ctx context.Context, dir string) ([]schema.Document, error) {
+// LoadDocumentsFromDir 从目录加载所有文档，只加载满足 filter 的文件
+func LoadDocumentsFromDir(ctx context.Context, dir string, filter DocumentFilter) ([]schema.Document, error) {
 	var allDocs []schema.Document
 	var supportedCount, unsupportedCount int
 
@@ -113,6 +114,11 @@ func LoadDocumentsFromDir(ctx context.Context, dir string) ([]schema.Document, e
 			return nil
 		}
 
+		if !matchDocument(dir, path, filter) {
+			unsupportedCount++
+			return nil
+		}
+
 		// 加载文档
 		docs, err := LoadDocument(ctx, path)
 		if err != nil {
@@ -147,6 +153,22 @@ func LoadDocumentsFromDir(ctx context.Context, dir string) ([]schema.Document, e
 	return allDocs, nil
 }
 
+// matchDocument 判断目录 dir 中的文件是否满足过滤条件，分类过滤器为零值时不读取文件
+func matchDocument(dir, path string, filter DocumentFilter) bool {
+	if filter.Include != nil && !filter.Include(path) {
+		return false
+	}
+	if filter.Kinds.IsZero() {
+		return true
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	kind, err := filekind.DetectFile(path, "/"+filepath.ToSlash(rel))
+	return err == nil && filter.Kinds.Match(kind)
+}
+
 // CreateLoader 根据文件路径创建合适的文档加载器
 func CreateLoader(file *os.File, path string) (documentloaders.Loader, error) {
 	ext := strings.ToLower(filepath.Ext(path))
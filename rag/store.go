@@ -151,7 +151,7 @@ func StoreDocuments(ctx context.Context, vectorStore qdrant.Store, docs []schema
 func IndexDocuments(ctx context.Context, vectorStore qdrant.Store, docsDir string, options RAGOptions) error {
 	// 加载文档
 	fmt.Println(lang.T("开始加载文档..."))
-	docs, err := LoadDocumentsFromDir(ctx, docsDir)
+	docs, err := LoadDocumentsFromDir(ctx, docsDir, options.Filter)
 	if err != nil {
 		return &RagError{
 			Code:    "load_documents_failed",
@@ -30,6 +30,7 @@ type DocumentSyncManager struct {
 	Metadata        map[string]DocumentMetadata
 	StorageOptions  StorageOptions
 	SplitterOptions SplitterOptions
+	Filter          DocumentFilter
 }
 
 // NewDocumentSyncManager 创建新的文档同步管理器
@@ -116,7 +117,7 @@ func (m *DocumentSyncManager) scanDocumentDirectory() (map[string]os.FileInfo, e
 
 		// 检查是否是支持的文件类型
 		ext := filepath.Ext(path)
-		if supported, known := supportedExtensions[ext]; known && supported {
+		if supported, known := supportedExtensions[ext]; known && supported && matchDocument(m.DocsDirectory, path, m.Filter) {
 			result[path] = info
 		}
 
@@ -297,6 +298,7 @@ func SyncDocumentsForRAG(rag *RAG) error {
 	// 确保同步管理器已初始化
 	if rag.SyncManager == nil {
 		rag.SyncManager = NewDocumentSyncManager(rag.VectorStore, rag.Options.Storage, rag.Options.Splitter, rag.Options.DocsDir)
+		rag.SyncManager.Filter = rag.Options.Filter
 	}
 
 	// 执行同步
@@ -316,6 +318,7 @@ func StartAutomaticSync(rag *RAG) error {
 	// 确保同步管理器已初始化
 	if rag.SyncManager == nil {
 		rag.SyncManager = NewDocumentSyncManager(rag.VectorStore, rag.Options.Storage, rag.Options.Splitter, rag.Options.DocsDir)
+		rag.SyncManager.Filter = rag.Options.Filter
 	}
 
 	// 创建上下文
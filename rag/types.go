@@ -3,6 +3,8 @@ package rag
 import (
 	"time"
 
+	"github.com/sjzsdu/tong/helper/filekind"
+
 	"github.com/tmc/langchaingo/chains"
 	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/llms"
@@ -107,6 +109,13 @@ type RAGOptions struct {
 	Session   SessionOptions
 	DocsDir   string
 	Sync      SyncOptions
+	Filter    DocumentFilter // 筛选要索引的文档
+}
+
+// DocumentFilter 筛选要索引的文档，零值不过滤
+type DocumentFilter struct {
+	Kinds   filekind.Filter        // 按文件分类筛选
+	Include func(path string) bool // 按文件路径筛选，nil 不过滤
 }
 
 // RAG 表示一个完整的RAG系统
@@ -92,6 +92,11 @@ func MergeConfig(target *SchemaConfig, source *SchemaConfig) {
 	if !isZeroRagConfig(source.Rag) {
 		target.Rag = source.Rag
 	}
+
+	// 合并 Workspace（整体覆盖）
+	if len(source.Workspace.Roots) > 0 {
+		target.Workspace = source.Workspace
+	}
 }
 
 // 判断 RagConfig 是否为零值（用于决定是否覆盖）
@@ -117,6 +122,17 @@ func isZeroRagConfig(r RagConfig) bool {
 	return true
 }
 
+// UnmarshalJSON 支持以字符串或对象表示工作区根目录
+func (r *WorkspaceRootConfig) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*r = WorkspaceRootConfig{Path: path}
+		return nil
+	}
+	type plain WorkspaceRootConfig
+	return json.Unmarshal(data, (*plain)(r))
+}
+
 // GetServerConfig 获取指定服务器的配置
 func (c *SchemaConfig) GetServerConfig(name string) *MCPServerConfig {
 	if c == nil {
@@ -168,3 +168,21 @@ func TestMergeConfig(t *testing.T) {
 	assert.Equal(t, "overridden_test", testServer.Command)
 	assert.Equal(t, 30, testServer.Timeout)
 }
+
+func TestLoadWorkspaceConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	data := `{"workspace": {"roots": ["../api", {"name": "site", "path": "../web"}]}}`
+	err := os.WriteFile(filepath.Join(tmpDir, share.SCHEMA_CONFIG_FILE), []byte(data), 0644)
+	assert.NoError(t, err)
+
+	config, err := LoadMCPConfig(tmpDir, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []WorkspaceRootConfig{
+		{Path: "../api"},
+		{Name: "site", Path: "../web"},
+	}, config.Workspace.Roots)
+
+	// 未配置 workspace 的来源不覆盖已有的根目录
+	MergeConfig(config, &SchemaConfig{})
+	assert.Len(t, config.Workspace.Roots, 2)
+}
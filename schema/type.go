@@ -61,6 +61,18 @@ type SchemaConfig struct {
 	EmbeddingLLM EmbeddingConfig            `json:"embeddingLLM"`
 	Rag          RagConfig                  `json:"rag,omitempty"`
 	Agent        AgentConfig                `json:"agent,omitempty"`
+	Workspace    WorkspaceConfig            `json:"workspace,omitempty"`
+}
+
+// WorkspaceConfig 多根工作区配置（对应 tong.json 的 workspace 节）
+type WorkspaceConfig struct {
+	Roots []WorkspaceRootConfig `json:"roots,omitempty"`
+}
+
+// WorkspaceRootConfig 工作区中的一个根目录，JSON 中可以直接写路径字符串
+type WorkspaceRootConfig struct {
+	Name string `json:"name,omitempty"` // 顶层节点名称，默认为目录名
+	Path string `json:"path"`           // 相对路径基于 tong.json 所在目录
 }
 
 type AgentConfig struct {